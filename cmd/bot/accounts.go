@@ -0,0 +1,124 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// --- LOGIC TÀI KHOẢN / VÍ ---
+
+// formatAccountBalance: "1,250,000 đ" với tài khoản VND, "0.5 BTC" với tài khoản ngoại tệ
+func formatAccountBalance(ab model.AccountBalance) string {
+	if ab.Account.Currency == "VND" {
+		return formatMoney(ab.Balance) + " đ"
+	}
+	return formatAssetQty(ab.Balance) + " " + ab.Account.Currency
+}
+
+// handleAccounts xử lý:
+//   - /accounts                              xem tài khoản và số dư
+//   - /accounts add <tên> [số dư đầu] [đơn vị]  VD: /accounts add momo 500k, /accounts add binance 0.1 BTC
+//   - /accounts delete <tên>                 xóa tài khoản chưa có giao dịch
+func handleAccounts(bot *tgbotapi.BotAPI, chatID int64, userID string, text string) {
+	args := strings.Fields(strings.TrimPrefix(text, "/accounts"))
+	usage := "🏦 Cú pháp:\n- /accounts\n- /accounts add <tên> [số dư đầu] [đơn vị: VND, USD, EUR, BTC, ETH, GOLD...]\n- /accounts delete <tên>\nDùng trong giao dịch: chi 50k cafe @momo, chuyển 2m @vcb @momo"
+
+	if len(args) == 0 {
+		var balances []model.AccountBalance
+		if err := callAPI(http.MethodGet, "/accounts?user_id="+url.QueryEscape(userID), nil, &balances); err != nil {
+			log.Printf("[BOT ERROR] List accounts failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy danh sách tài khoản."))
+			return
+		}
+		if len(balances) == 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, "🏦 Bạn chưa có tài khoản nào.\n"+usage))
+			return
+		}
+		msg := "🏦 TÀI KHOẢN\n"
+		for _, ab := range balances {
+			msg += fmt.Sprintf("• @%s: %s\n", ab.Account.Name, formatAccountBalance(ab))
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, msg))
+		return
+	}
+
+	var apiErr *apiError
+	switch {
+	case args[0] == "add" && len(args) >= 2 && len(args) <= 4:
+		a := model.Account{UserID: userID, Name: args[1], Currency: "VND"}
+		if len(args) >= 3 {
+			opening, err := service.ParseAmount(args[2])
+			if err != nil {
+				bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Số dư đầu không hợp lệ.\n"+usage))
+				return
+			}
+			a.OpeningBalance = opening
+		}
+		if len(args) == 4 {
+			a.Currency = strings.ToUpper(args[3])
+			if code := service.AssetForAlias(args[3]); code != "" { // Viết như trong tin nhắn: $, €, bitcoin...
+				a.Currency = code
+			}
+		}
+
+		var saved model.Account
+		err := callAPI(http.MethodPost, "/accounts", a, &saved)
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Bạn đã có tài khoản @%s.", strings.ToLower(args[1]))))
+			return
+		}
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+			bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Giá trị không hợp lệ: "+apiErr.Body))
+			return
+		}
+		if err != nil {
+			log.Printf("[BOT ERROR] Create account failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể tạo tài khoản."))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Đã tạo tài khoản @%s, số dư đầu %s",
+			saved.Name, formatAccountBalance(model.AccountBalance{Account: saved, Balance: saved.OpeningBalance}))))
+
+	case args[0] == "delete" && len(args) == 2:
+		name := strings.ToLower(strings.TrimPrefix(args[1], "@"))
+		var balances []model.AccountBalance
+		if err := callAPI(http.MethodGet, "/accounts?user_id="+url.QueryEscape(userID), nil, &balances); err != nil {
+			log.Printf("[BOT ERROR] List accounts failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy danh sách tài khoản."))
+			return
+		}
+		id := 0
+		for _, ab := range balances {
+			if ab.Account.Name == name {
+				id = ab.Account.ID
+			}
+		}
+		if id == 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Không tìm thấy tài khoản @%s.", name)))
+			return
+		}
+
+		err := callAPI(http.MethodDelete, fmt.Sprintf("/accounts/%d?user_id=%s", id, url.QueryEscape(userID)), nil, nil)
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusConflict {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Tài khoản @%s đã có giao dịch nên không xóa được.", name)))
+			return
+		}
+		if err != nil {
+			log.Printf("[BOT ERROR] Delete account failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể xóa tài khoản."))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🗑 Đã xóa tài khoản @%s.", name)))
+
+	default:
+		bot.Send(tgbotapi.NewMessage(chatID, usage))
+	}
+}
@@ -0,0 +1,206 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// --- LOGIC CẢNH BÁO GIÁ ---
+
+// isAlertCommand: "báo khi vàng > 90tr", "/alerts"
+func isAlertCommand(text string) bool {
+	if strings.HasPrefix(strings.ToLower(strings.TrimSpace(text)), "/alerts") {
+		return true
+	}
+	_, ok := service.MatchCommand(text, "báo khi")
+	return ok
+}
+
+// alertAssetName tên tài sản và hậu tố đơn vị giá: ("Vàng SJC", " đ/lượng"), ("Bitcoin", " đ")
+func alertAssetName(code, unit string) (string, string) {
+	name := code
+	if a, ok := service.LookupAsset(code); ok {
+		name = a.Name
+	}
+	if u, ok := service.LookupMetalUnit(unit); ok {
+		return name, " đ/" + u.Name
+	}
+	return name, " đ"
+}
+
+// describeAlert: "#3 Vàng SJC ≥ 90,000,000 đ/lượng (1 lần)", "#4 Bitcoin biến động ±5% (lặp lại, gốc 2,500,000,000 đ)"
+func describeAlert(a model.PriceAlert) string {
+	name, suffix := alertAssetName(a.Asset, a.Unit)
+	var desc string
+	switch a.Condition {
+	case model.AlertAbove:
+		desc = fmt.Sprintf("#%d %s ≥ %s%s", a.ID, name, formatMoney(a.Threshold), suffix)
+	case model.AlertBelow:
+		desc = fmt.Sprintf("#%d %s ≤ %s%s", a.ID, name, formatMoney(a.Threshold), suffix)
+	default:
+		desc = fmt.Sprintf("#%d %s biến động ±%s%%", a.ID, name, a.Threshold.String())
+	}
+
+	mode := "1 lần"
+	if a.Repeat {
+		mode = "lặp lại"
+	}
+	if a.Condition == model.AlertChange && a.BasePrice.IsPositive() {
+		mode += ", gốc " + formatMoney(a.BasePrice) + suffix
+	}
+	if !a.Active {
+		return desc + " (đã báo, đã tắt)"
+	}
+	return desc + " (" + mode + ")"
+}
+
+// formatAlertNotification nội dung tin nhắn khi cảnh báo kích hoạt
+func formatAlertNotification(n model.AlertNotification) string {
+	name, suffix := alertAssetName(n.Asset, n.Unit)
+	var msg string
+	switch n.Condition {
+	case model.AlertAbove:
+		msg = fmt.Sprintf("📈 %s đã lên %s%s (mốc %s%s)", name, formatMoney(n.Price), suffix, formatMoney(n.Threshold), suffix)
+	case model.AlertBelow:
+		msg = fmt.Sprintf("📉 %s đã xuống %s%s (mốc %s%s)", name, formatMoney(n.Price), suffix, formatMoney(n.Threshold), suffix)
+	default:
+		percent := decimal.Zero
+		if n.BasePrice.IsPositive() {
+			percent = n.Price.Sub(n.BasePrice).Div(n.BasePrice).Mul(decimal.NewFromInt(100))
+		}
+		sign := "+"
+		if percent.IsNegative() {
+			sign = ""
+		}
+		msg = fmt.Sprintf("📊 %s biến động %s%s%%: %s%s (từ %s%s)", name, sign, percent.StringFixed(2),
+			formatMoney(n.Price), suffix, formatMoney(n.BasePrice), suffix)
+	}
+
+	msg = fmt.Sprintf("🔔 CẢNH BÁO GIÁ #%d\n%s", n.AlertID, msg)
+	if !n.Repeat {
+		msg += fmt.Sprintf("\nCảnh báo 1 lần nên đã tự tắt, /alerts delete %d để xóa.", n.AlertID)
+	}
+	return msg
+}
+
+// handleAlerts xử lý:
+//   - báo khi vàng > 90tr, báo khi usd < 25000, báo khi btc biến động 5% lặp lại
+//   - /alerts              xem danh sách
+//   - /alerts delete <id>  xóa
+func handleAlerts(bot *tgbotapi.BotAPI, chatID int64, userID string, text string) {
+	usage := "🔔 Cú pháp:\n- báo khi vàng > 90tr (giá 1 lượng), báo khi vàng > 9tr/chỉ\n- báo khi usd < 25000, báo khi btc > 2000m lặp lại\n- báo khi btc biến động 5%\n- /alerts (xem), /alerts delete <id>"
+
+	lower := strings.ToLower(strings.TrimSpace(text))
+	if !strings.HasPrefix(lower, "/alerts") {
+		a, ok := service.ParseAlertText(text)
+		if !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, usage))
+			return
+		}
+		a.UserID = userID
+		var saved model.PriceAlert
+		err := callAPI(http.MethodPost, "/alerts", a, &saved)
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+			bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Không thể tạo cảnh báo: "+apiErr.Body))
+			return
+		}
+		if err != nil {
+			log.Printf("[BOT ERROR] Create alert failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể tạo cảnh báo giá."))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, "🔔 Đã tạo cảnh báo "+describeAlert(saved)))
+		return
+	}
+
+	args := strings.Fields(strings.TrimPrefix(text, "/alerts"))
+	if len(args) == 0 {
+		var alerts []model.PriceAlert
+		if err := callAPI(http.MethodGet, "/alerts?user_id="+url.QueryEscape(userID), nil, &alerts); err != nil {
+			log.Printf("[BOT ERROR] List alerts failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy danh sách cảnh báo giá."))
+			return
+		}
+		if len(alerts) == 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, "🔔 Bạn chưa có cảnh báo giá nào.\nVD: báo khi vàng > 90tr"))
+			return
+		}
+		lines := make([]string, 0, len(alerts))
+		for _, a := range alerts {
+			lines = append(lines, describeAlert(a))
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, "🔔 CẢNH BÁO GIÁ\n"+strings.Join(lines, "\n")))
+		return
+	}
+
+	if len(args) != 2 || (args[0] != "delete" && args[0] != "xóa" && args[0] != "xoa") {
+		bot.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+	var deleted model.PriceAlert
+	err = callAPI(http.MethodDelete, fmt.Sprintf("/alerts/%d?user_id=%s", id, url.QueryEscape(userID)), nil, &deleted)
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && (apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusForbidden) {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Không tìm thấy cảnh báo #%d của bạn.", id)))
+		return
+	}
+	if err != nil {
+		log.Printf("[BOT ERROR] Delete alert failed: %v", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể xóa cảnh báo giá."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, "🗑 Đã xóa cảnh báo "+describeAlert(deleted)))
+}
+
+// startAlertNotifier gửi các thông báo cảnh báo giá API đã tạo, kiểm tra mỗi 30 giây.
+// Chỉ xác nhận (ack) sau khi gửi được nên bot restart giữa chừng không mất tin (có thể gửi lặp 1 lần)
+func startAlertNotifier(bot *tgbotapi.BotAPI) {
+	ticker := time.NewTicker(30 * time.Second)
+	defer ticker.Stop()
+	for range ticker.C {
+		deliverAlertNotifications(bot)
+	}
+}
+
+func deliverAlertNotifications(bot *tgbotapi.BotAPI) {
+	var pending []model.AlertNotification
+	if err := callAPI(http.MethodGet, "/alerts/notifications", nil, &pending); err != nil {
+		log.Printf("[ALERT ERROR] Không thể lấy thông báo cảnh báo giá: %v", err)
+		return
+	}
+	for _, n := range pending {
+		chatID, err := strconv.ParseInt(n.UserID, 10, 64)
+		if err != nil {
+			log.Printf("[ALERT ERROR] Bỏ thông báo #%d: user_id %q không hợp lệ", n.ID, n.UserID)
+		} else if _, err = bot.Send(tgbotapi.NewMessage(chatID, formatAlertNotification(n))); err != nil {
+			var tgErr *tgbotapi.Error
+			if !errors.As(err, &tgErr) || (tgErr.Code != http.StatusBadRequest && tgErr.Code != http.StatusForbidden) {
+				// Lỗi mạng/Telegram tạm thời: để lần sau gửi lại
+				log.Printf("[ALERT ERROR] Gửi cảnh báo #%d cho user %s thất bại: %v", n.AlertID, n.UserID, err)
+				continue
+			}
+			// User chặn bot hoặc chat không tồn tại: gửi lại cũng không được, bỏ qua để không chặn hàng đợi
+			log.Printf("[ALERT ERROR] Bỏ thông báo #%d của user %s: %v", n.ID, n.UserID, err)
+		}
+		if err := callAPI(http.MethodPost, fmt.Sprintf("/alerts/notifications/%d/ack", n.ID), nil, nil); err != nil {
+			log.Printf("[ALERT ERROR] Không thể xác nhận thông báo #%d: %v", n.ID, err)
+		}
+	}
+}
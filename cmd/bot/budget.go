@@ -0,0 +1,179 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// --- LOGIC NGÂN SÁCH ---
+
+// budgetThresholds các mốc % ngân sách sẽ cảnh báo khi chi vượt qua, tăng dần.
+// Cấu hình bằng BUDGET_ALERT_THRESHOLDS (VD: "50,80,100"), mặc định 80% và 100%
+var budgetThresholds = loadBudgetThresholds(os.Getenv("BUDGET_ALERT_THRESHOLDS"))
+
+func loadBudgetThresholds(env string) []int {
+	var result []int
+	for _, part := range strings.Split(env, ",") {
+		n, err := strconv.Atoi(strings.TrimSpace(part))
+		if err == nil && n > 0 {
+			result = append(result, n)
+		}
+	}
+	if len(result) == 0 {
+		if env != "" {
+			log.Printf("[CONFIG WARN] BUDGET_ALERT_THRESHOLDS=%q không hợp lệ, dùng mặc định 80,100", env)
+		}
+		return []int{80, 100}
+	}
+	sort.Ints(result)
+	return result
+}
+
+var periodNames = map[string]string{"week": "tuần", "month": "tháng"}
+
+// isBudgetCommand tin nhắn bắt đầu bằng "ngân sách"
+func isBudgetCommand(text string) bool {
+	_, ok := service.MatchCommand(text, "ngân sách")
+	return ok
+}
+
+// handleBudget xử lý:
+//   - ngân sách                      xem tình hình các ngân sách
+//   - ngân sách ăn uống 3m/tháng     đặt (hoặc đổi) ngân sách
+//   - ngân sách xóa <id>             xóa ngân sách
+func handleBudget(bot *tgbotapi.BotAPI, chatID int64, userID string, text string) {
+	args := strings.Fields(strings.ToLower(text))[2:]
+
+	switch {
+	case len(args) == 0:
+		sendBudgetStatus(bot, chatID, userID)
+
+	case len(args) == 2 && (args[0] == "xóa" || args[0] == "xoa"):
+		id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+		if err != nil {
+			bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Cú pháp: ngân sách xóa <id>"))
+			return
+		}
+		path := fmt.Sprintf("/budgets/%d?user_id=%s", id, url.QueryEscape(userID))
+		var b model.Budget
+		err = callAPI(http.MethodDelete, path, nil, &b)
+		var apiErr *apiError
+		if errors.As(err, &apiErr) && (apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusForbidden) {
+			bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Không tìm thấy ngân sách #%d của bạn.", id)))
+			return
+		}
+		if err != nil {
+			log.Printf("[BOT ERROR] Delete budget failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể xóa ngân sách."))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("🗑 Đã xóa ngân sách #%d %s/%s.", b.ID, b.Category, periodNames[b.Period])))
+
+	default:
+		b, ok := service.ParseBudgetText(text)
+		if !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, "💼 Cú pháp:\n- ngân sách (xem)\n- ngân sách ăn uống 3m/tháng\n- ngân sách cafe 500k/tuần\n- ngân sách xóa <id>"))
+			return
+		}
+		b.UserID = userID
+		var saved model.Budget
+		if err := callAPI(http.MethodPost, "/budgets", b, &saved); err != nil {
+			log.Printf("[BOT ERROR] Save budget failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể lưu ngân sách."))
+			return
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("✅ Ngân sách #%d: %s tối đa %s đ/%s",
+			saved.ID, saved.Category, formatMoney(saved.Limit), periodNames[saved.Period])))
+	}
+}
+
+// getBudgetStatus lấy tình hình ngân sách kỳ hiện tại, category rỗng là tất cả
+func getBudgetStatus(userID, category string) ([]model.BudgetStatus, error) {
+	params := url.Values{"user_id": {userID}}
+	if category != "" {
+		params.Set("category", category)
+	}
+	var statuses []model.BudgetStatus
+	err := callAPI(http.MethodGet, "/budgets/status?"+params.Encode(), nil, &statuses)
+	return statuses, err
+}
+
+func sendBudgetStatus(bot *tgbotapi.BotAPI, chatID int64, userID string) {
+	statuses, err := getBudgetStatus(userID, "")
+	if err != nil {
+		log.Printf("[BOT ERROR] Get budget status failed: %v", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy thông tin ngân sách."))
+		return
+	}
+	if len(statuses) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "💼 Bạn chưa đặt ngân sách nào.\nVD: ngân sách ăn uống 3m/tháng"))
+		return
+	}
+
+	msg := "💼 NGÂN SÁCH\n"
+	for _, st := range statuses {
+		msg += fmt.Sprintf("#%d %s/%s (%s → %s)\n   %s\n",
+			st.Budget.ID, strings.Title(st.Budget.Category), periodNames[st.Budget.Period],
+			formatDate(st.StartDate), formatDate(st.EndDate), formatBudgetLine(st))
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, msg))
+}
+
+// formatBudgetLine: "2,500,000 / 3,000,000 đ (83.3%) - còn 500,000 đ"
+func formatBudgetLine(st model.BudgetStatus) string {
+	line := fmt.Sprintf("%s / %s đ (%s%%)", formatMoney(st.Spent), formatMoney(st.Budget.Limit), st.Percent.String())
+	if st.Remaining.IsNegative() {
+		return line + fmt.Sprintf(" - ⛔ vượt %s đ", formatMoney(st.Remaining.Neg()))
+	}
+	return line + fmt.Sprintf(" - còn %s đ", formatMoney(st.Remaining))
+}
+
+// checkBudgetAlerts gọi sau khi lưu giao dịch chi: nếu khoản vừa chi (added) làm tổng chi
+// của danh mục vượt qua một mốc cảnh báo thì nhắn cho user (chỉ báo mốc cao nhất vừa vượt)
+func checkBudgetAlerts(bot *tgbotapi.BotAPI, chatID int64, userID string, category string, added decimal.Decimal) {
+	statuses, err := getBudgetStatus(userID, category)
+	if err != nil {
+		log.Printf("[BOT ERROR] Get budget status failed: %v", err)
+		return
+	}
+
+	hundred := decimal.NewFromInt(100)
+	for _, st := range statuses {
+		if !st.Budget.Limit.IsPositive() {
+			continue
+		}
+		before := st.Spent.Sub(added).Mul(hundred).Div(st.Budget.Limit)
+		after := st.Spent.Mul(hundred).Div(st.Budget.Limit)
+
+		crossed := 0
+		for _, th := range budgetThresholds {
+			mark := decimal.NewFromInt(int64(th))
+			if before.LessThan(mark) && after.GreaterThanOrEqual(mark) {
+				crossed = th
+			}
+		}
+		if crossed == 0 {
+			continue
+		}
+
+		icon := "⚠️"
+		if crossed >= 100 {
+			icon = "⛔"
+		}
+		msg := fmt.Sprintf("%s Ngân sách %s/%s đã dùng %d%%:\n%s",
+			icon, st.Budget.Category, periodNames[st.Budget.Period], crossed, formatBudgetLine(st))
+		bot.Send(tgbotapi.NewMessage(chatID, msg))
+	}
+}
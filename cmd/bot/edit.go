@@ -0,0 +1,116 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// --- LOGIC SỬA / XÓA GIAO DỊCH ---
+
+// describeTransaction: "#12 chi 50000 VND (cafe)", "#13 chi 75000 VND [3x25k] (trà sữa)"
+func describeTransaction(t model.Transaction) string {
+	desc := fmt.Sprintf("#%d %s %s %s", t.ID, t.Type, t.OriginalAmount.String(), t.Currency)
+	if t.AmountExpr != "" {
+		desc += " [" + t.AmountExpr + "]"
+	}
+	if t.Note != "" {
+		desc += fmt.Sprintf(" (%s)", t.Note)
+	}
+	return desc
+}
+
+// handleUndo xử lý /undo: xóa giao dịch vừa ghi gần nhất
+func handleUndo(bot *tgbotapi.BotAPI, chatID int64, userID string) {
+	var t model.Transaction
+	err := callAPI(http.MethodDelete, "/transactions/last?user_id="+url.QueryEscape(userID), nil, &t)
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusNotFound {
+		bot.Send(tgbotapi.NewMessage(chatID, "ℹ️ Bạn chưa có giao dịch nào để hoàn tác."))
+		return
+	}
+	if err != nil {
+		log.Printf("[BOT ERROR] Undo failed: %v", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể hoàn tác."))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(chatID, "↩️ Đã xóa giao dịch "+describeTransaction(t)))
+}
+
+// handleEdit xử lý "/edit <id> <nội dung mới>", nội dung mới dùng cùng cú pháp như khi ghi
+func handleEdit(bot *tgbotapi.BotAPI, chatID int64, userID string, text string) {
+	usage := "✏️ Cú pháp: /edit <id> <nội dung mới>\nVí dụ: /edit 12 chi 50k cafe"
+
+	fields := strings.Fields(text)
+	if len(fields) < 3 {
+		bot.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(fields[1], "#"))
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+
+	newText := strings.Join(fields[2:], " ")
+	txs, err := service.ParseTransactionText(newText)
+	var parseErr *service.ParseError
+	if len(txs) == 0 && errors.As(err, &parseErr) {
+		bot.Send(tgbotapi.NewMessage(chatID, describeRejected(parseErr.Segments)+"\n"+usage))
+		return
+	}
+	if len(txs) != 1 || err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Nội dung mới phải là đúng 1 giao dịch hợp lệ.\n"+usage))
+		return
+	}
+	if txs[0].OccurredAt != nil {
+		// Sửa giao dịch giữ nguyên thời điểm ghi
+		bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Không đổi được ngày khi sửa. Dùng /undo hoặc xóa rồi ghi lại kèm ngày (VD: chi 50k cafe hôm qua)."))
+		return
+	}
+
+	tx := txs[0]
+	update := model.TransactionUpdate{
+		UserID:   userID,
+		Type:     &tx.Type,
+		Amount:   &tx.Amount,
+		Note:     &tx.Note,
+		Currency: &tx.Currency,
+		Category: &tx.Category,
+		// Nội dung mới không có biểu thức thì xóa biểu thức cũ
+		AmountExpr: &tx.AmountExpr,
+		// Nội dung mới thay toàn bộ, không ghi @tài_khoản nghĩa là bỏ gắn tài khoản
+		Account:   &tx.Account,
+		ToAccount: &tx.ToAccount,
+	}
+
+	var t model.Transaction
+	err = callAPI(http.MethodPatch, fmt.Sprintf("/transactions/%d", id), update, &t)
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && (apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusForbidden) {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Không tìm thấy giao dịch #%d của bạn.", id)))
+		return
+	}
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+		bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Không thể sửa: "+apiErr.Body))
+		return
+	}
+	if err != nil {
+		log.Printf("[BOT ERROR] Edit failed: %v", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể sửa giao dịch."))
+		return
+	}
+
+	bot.Send(tgbotapi.NewMessage(chatID, "✏️ Đã sửa thành "+describeTransaction(t)))
+}
@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// --- LOGIC XEM LỊCH SỬ GIAO DỊCH ---
+
+const listPageSize = 10
+
+// listCursors nhớ next_cursor của lần /list gần nhất theo từng chat để "/list tiếp"
+var listCursors sync.Map
+
+// handleList xử lý "/list" (trang mới nhất), "/list tiếp" (trang kế) và "/list <từ khóa>"
+func handleList(bot *tgbotapi.BotAPI, chatID int64, userID string, text string) {
+	arg := strings.TrimSpace(strings.TrimPrefix(text, "/list"))
+
+	params := url.Values{}
+	params.Set("user_id", userID)
+	params.Set("limit", fmt.Sprint(listPageSize))
+
+	switch service.FoldText(arg) {
+	case "":
+	case "tiep", "more", "next":
+		c, ok := listCursors.Load(chatID)
+		if !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, "ℹ️ Không còn giao dịch cũ hơn. Gõ /list để xem từ đầu."))
+			return
+		}
+		params.Set("cursor", c.(string))
+	default:
+		params.Set("q", arg)
+	}
+
+	var page model.TransactionPage
+	if err := callAPI(http.MethodGet, "/transactions?"+params.Encode(), nil, &page); err != nil {
+		log.Printf("[BOT ERROR] List transactions failed: %v", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy danh sách giao dịch"))
+		return
+	}
+
+	if len(page.Items) == 0 {
+		listCursors.Delete(chatID)
+		bot.Send(tgbotapi.NewMessage(chatID, "ℹ️ Không có giao dịch nào."))
+		return
+	}
+
+	var msg strings.Builder
+	msg.WriteString("🧾 LỊCH SỬ GIAO DỊCH\n\n")
+	for _, t := range page.Items {
+		msg.WriteString(fmt.Sprintf("%s %s\n", t.CreatedAt.Format("02/01"), describeTransaction(t)))
+	}
+
+	if page.NextCursor != "" {
+		listCursors.Store(chatID, page.NextCursor)
+		msg.WriteString("\n👉 Gõ /list tiếp để xem cũ hơn")
+	} else {
+		listCursors.Delete(chatID)
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, msg.String()))
+}
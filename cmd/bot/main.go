@@ -3,26 +3,33 @@ package main
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"go-finance/internal/model"
 	"go-finance/internal/service"
-	"io"
 	"log"
 	"math"
 	"net/http"
+	"net/url"
 	"os"
 	"strconv"
 	"strings"
 	"time"
+	_ "time/tzdata" // Image alpine không có sẵn dữ liệu múi giờ IANA
 
 	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
 	"github.com/joho/godotenv"
+	"github.com/shopspring/decimal"
 )
 
 var apiURL string
 
 func main() {
 	_ = godotenv.Load()
+	// Tài sản tự định nghĩa dùng chung env CUSTOM_ASSETS với API để đọc được trong tin nhắn
+	if err := service.LoadAssetsFromEnv(); err != nil {
+		log.Fatal("Invalid asset config:", err)
+	}
 	token := os.Getenv("TELEGRAM_TOKEN")
 	apiURL = os.Getenv("API_URL")
 	// Chạy ngầm nhiệm vụ Ping API cứ 10 phút/lần
@@ -121,6 +128,8 @@ func main() {
 
 	// Bắt đầu chạy lịch trình gửi tin 7h sáng/tối
 	go startScheduler(bot)
+	// Gửi cảnh báo giá do API tạo sau mỗi lần cập nhật giá
+	go startAlertNotifier(bot)
 
 	for update := range updates {
 		if update.Message == nil {
@@ -134,16 +143,59 @@ func main() {
 
 			log.Printf("[BOT RECV] User: %s, Text: %s", userID, text) // [Update] Log tin nhắn đến
 
-			if strings.Contains(strings.ToLower(text), "báo cáo") {
-				handleReport(bot, chatID, userID)
+			if text == "/undo" {
+				handleUndo(bot, chatID, userID)
+				return
+			}
+			if strings.HasPrefix(text, "/edit") {
+				handleEdit(bot, chatID, userID, text)
+				return
+			}
+			if strings.HasPrefix(text, "/settings") {
+				handleSettings(bot, chatID, userID, text)
+				return
+			}
+			if strings.HasPrefix(text, "/recurring") {
+				handleRecurring(bot, chatID, userID, text)
+				return
+			}
+			if strings.HasPrefix(text, "/accounts") {
+				handleAccounts(bot, chatID, userID, text)
+				return
+			}
+			if strings.HasPrefix(text, "/list") {
+				handleList(bot, chatID, userID, text)
+				return
+			}
+
+			if isPortfolioCommand(text) {
+				handlePortfolio(bot, chatID, userID)
 				return
 			}
 
-			if strings.Contains(strings.ToLower(text), "giá vàng") {
+			// Trước "báo cáo" và parser giao dịch: "báo khi vàng > 90tr"
+			if isAlertCommand(text) {
+				handleAlerts(bot, chatID, userID, text)
+				return
+			}
+
+			if isBudgetCommand(text) {
+				handleBudget(bot, chatID, userID, text)
+				return
+			}
+
+			// Lệnh gõ không dấu hoặc sai 1 ký tự vẫn nhận: "bao cao", "gia vang", "bap cao".
+			// Lệnh ở giữa tin nhắn có giao dịch thì là ghi chú: "chi 200k mua gia vang cho me"
+			if service.IsCommandMessage(text, "báo cáo") {
+				handleReport(bot, chatID, userID, text)
+				return
+			}
+
+			if service.IsCommandMessage(text, "giá vàng") {
 				handlePrice(bot, chatID, "gold")
 				return
 			}
-			if strings.Contains(strings.ToLower(text), "giá bạc") {
+			if service.IsCommandMessage(text, "giá bạc") {
 				handlePrice(bot, chatID, "silver")
 				return
 			}
@@ -151,17 +203,35 @@ func main() {
 			// Test gửi thông báo định kỳ
 			if text == "/test_noti" {
 				bot.Send(tgbotapi.NewMessage(chatID, "🚀 Đang chạy thử tính năng gửi Noti..."))
-				sendDailyUpdate(bot)
+				sendAllUsersUpdate(bot)
 				return
 			}
 
-			txs, _ := service.ParseTransactionText(text)
+			var txs []model.TransactionCreate
+			var rejected []service.ParsedSegment
+			recognized := false // Có đoạn nào giống lệnh giao dịch (có từ khóa hoặc +/-) không
+			for _, seg := range parseTransactionMessage(userID, text) {
+				if seg.Tx != nil {
+					txs = append(txs, *seg.Tx)
+				} else {
+					rejected = append(rejected, seg)
+				}
+				if seg.Reason != service.ReasonUnrecognized {
+					recognized = true
+				}
+			}
+			if len(txs) == 0 && recognized {
+				// Đúng dạng lệnh nhưng sai quy tắc: nói rõ đoạn nào sai thay vì gửi cả hướng dẫn
+				bot.Send(tgbotapi.NewMessage(chatID, describeRejected(rejected)))
+				return
+			}
 			if len(txs) == 0 {
 				// (Giữ nguyên phần helpMsg của bạn ở đây...)
 				helpMsg := `Không hiểu lệnh. Vui lòng nhập đúng cú pháp.
 					👋 Chào bạn! Tôi là Bot quản lý tài chính.
 
 					📖 *HƯỚNG DẪN SỬ DỤNG:*
+					_(Gõ không dấu cũng được: chi 50k an sang, bao cao thang nay, gia vang)_
 
 					1️⃣ *Ghi chép Thu / Chi (VND):*
 					_(Bắt buộc phải kèm lý do)_
@@ -169,29 +239,78 @@ func main() {
 					- thu 10m lương t10
 					- -10k trà đá
 					- +1,5m tiền lãi bank
+					- chi 2 triệu rưỡi tiền nhà, chi 1tr2 đổ xăng, thu 1 củ, chi năm trăm nghìn quà
+					- chi 3x25k trà sữa, chi 120k/4 lẩu chia đều, chi 50k+30k cafe và bánh
 
 					2️⃣ *Ghi chép Tiết kiệm / Đầu tư:*
 					_(Chỉ nhập số tiền & đơn vị, KHÔNG ghi chú)_
 					- tk 2m
 					- tiết kiệm 100 usd
 					- tk 0.1 btc
-					- tk 5 chỉ vàng
+					- tk 5 chỉ vàng, tk 2 lượng bạc
+					- tk 1 lượng vàng, tk 5g vàng 9999, tk 1kg bạc, tk 2 oz gold
+					- tk 100 eur, tk 0.5 eth, tk 50 usdt (cả yên, nhân dân tệ)
+					- rút tk 2 chỉ vàng, bán 0.01 btc
+					- tài sản (toàn bộ tài sản đang giữ, lãi/lỗ, biến động giá)
 
 					3️⃣ *Tiện ích khác:*
 					- giá vàng, giá bạc
-					- báo cáo`
+					- báo khi vàng > 90tr, báo khi usd < 25000, báo khi btc biến động 5% lặp lại, /alerts (xem, delete)
+					- báo cáo (tuần + tháng này)
+					- báo cáo tháng trước, báo cáo quý này, báo cáo 2025
+					- báo cáo 01/03-15/03
+					- /list (lịch sử), /list tiếp, /list <từ khóa>
+					- /undo (xóa giao dịch vừa ghi)
+					- /settings (múi giờ, ngày đầu tuần, cách tính giá vốn, đơn vị vàng/bạc)
+					- /edit <id> <nội dung mới>
+					- ngân sách ăn uống 3m/tháng, ngân sách (xem), ngân sách xóa <id>
+					- chi 5m tiền nhà hàng tháng ngày 1 (định kỳ), /recurring (xem, pause, resume, delete)
+					- chi 50k cafe @momo, chuyển 2m @vcb @momo, /accounts (tài khoản, số dư)`
 				bot.Send(tgbotapi.NewMessage(chatID, helpMsg))
 				return
 			}
 
 			count := 0
 			var details []string
+			spent := make(map[string]decimal.Decimal) // Tổng chi vừa lưu theo danh mục, để kiểm tra ngân sách
 			for _, tx := range txs {
 				tx.UserID = userID
-				if sendTransactionToAPI(tx) {
+				if tx.Recurrence != nil {
+					rule, err := createRecurringFromText(tx)
+					if err != nil {
+						log.Printf("[BOT ERROR] Create recurring failed: %v", err)
+						bot.Send(tgbotapi.NewMessage(chatID, "❌ Không thể tạo giao dịch định kỳ: "+err.Error()))
+						continue
+					}
+					bot.Send(tgbotapi.NewMessage(chatID, "🔁 Đã tạo giao dịch định kỳ "+describeRule(rule)))
+					continue
+				}
+				id, err := sendTransactionToAPI(tx)
+				var apiErr *apiError
+				switch {
+				case err == nil:
 					count++
-					details = append(details, fmt.Sprintf("%s %.2f %s", tx.Type, tx.Amount, tx.Currency))
-				} else {
+					detail := fmt.Sprintf("#%d %s %s %s", id, tx.Type, tx.Amount.String(), tx.Currency)
+					if tx.AmountExpr != "" {
+						detail += " [" + tx.AmountExpr + "]"
+					}
+					if tx.Account != "" {
+						detail += " @" + tx.Account
+					}
+					if tx.ToAccount != "" {
+						detail += " → @" + tx.ToAccount
+					}
+					if tx.OccurredAt != nil {
+						detail += " (" + tx.OccurredAt.Format("15:04 02/01/2006") + ")"
+					}
+					details = append(details, detail)
+					if tx.Type == "chi" {
+						spent[tx.Category] = spent[tx.Category].Add(tx.Amount)
+					}
+				case errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest:
+					// VD: tài khoản @xyz chưa được tạo
+					bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Không thể lưu: "+apiErr.Body))
+				default:
 					// [Update] Báo lỗi ngay cho user nếu lưu thất bại
 					bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể lưu giao dịch."))
 				}
@@ -201,42 +320,87 @@ func main() {
 				reply := fmt.Sprintf("✅ Đã lưu %d giao dịch:\n%s", count, strings.Join(details, "\n"))
 				bot.Send(tgbotapi.NewMessage(chatID, reply))
 			}
+			if len(rejected) > 0 {
+				bot.Send(tgbotapi.NewMessage(chatID, describeRejected(rejected)))
+			}
+			for category, amount := range spent {
+				checkBudgetAlerts(bot, chatID, userID, category, amount)
+			}
 		}(update)
 
 	}
 }
 
 // --- LOGIC THU, CHI, TIẾT KIỆM ---
-func sendTransactionToAPI(t model.TransactionCreate) bool {
-	data, _ := json.Marshal(t)
-	resp, err := http.Post(apiURL+"/transactions", "application/json", bytes.NewBuffer(data))
 
-	// [Update] Log chi tiết lỗi kết nối
-	if err != nil {
-		log.Printf("[BOT ERROR] Call API /transactions failed: %v", err)
-		return false
+// parseTransactionMessage đọc tin nhắn thành các đoạn giao dịch. Tin nhắn có ngày giờ ("hôm qua", "20h")
+// được đọc lại theo múi giờ của user, tin nhắn thường không cần gọi API lấy cài đặt
+func parseTransactionMessage(userID, text string) []service.ParsedSegment {
+	segs := service.ParseTransactionSegments(text)
+	for _, seg := range segs {
+		if (seg.Tx != nil && seg.Tx.OccurredAt != nil) || seg.Reason == service.ReasonInvalidDate {
+			return service.ParseTransactionSegmentsAt(text, time.Now().In(userLocation(userID)))
+		}
 	}
-	defer resp.Body.Close()
+	return segs
+}
 
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		log.Printf("[BOT ERROR] API returned status %d: %s", resp.StatusCode, string(body))
-		return false
+// describeRejected liệt kê các đoạn tin nhắn không lưu được và lý do
+func describeRejected(segs []service.ParsedSegment) string {
+	lines := make([]string, len(segs))
+	for i, seg := range segs {
+		lines[i] = "- " + seg.Message()
 	}
-	return true
+	return "⚠️ Không lưu được:\n" + strings.Join(lines, "\n")
+}
+
+// Trả về id giao dịch vừa tạo để user có thể /edit
+func sendTransactionToAPI(t model.TransactionCreate) (int, error) {
+	var result struct {
+		ID int `json:"id"`
+	}
+	// [Update] Log chi tiết lỗi kết nối / status lỗi
+	if err := callAPI(http.MethodPost, "/transactions", t, &result); err != nil {
+		log.Printf("[BOT ERROR] Call API /transactions failed: %v", err)
+		return 0, err
+	}
+	return result.ID, nil
 }
 
 // --- LOGIC BÁO CÁO ---
-func handleReport(bot *tgbotapi.BotAPI, chatID int64, userID string) {
+func handleReport(bot *tgbotapi.BotAPI, chatID int64, userID string, text string) {
+	// "báo cáo tháng trước", "báo cáo 2025", "báo cáo 01/03-15/03"...
+	if q, ok := service.ParseReportQuery(text, time.Now()); ok {
+		params := url.Values{}
+		if q.From != "" {
+			params.Set("from", q.From)
+			params.Set("to", q.To)
+		} else {
+			params.Set("period", q.Period)
+			params.Set("offset", strconv.Itoa(q.Offset))
+		}
+
+		report, err := getReportData(userID, params)
+		if err != nil {
+			log.Printf("[BOT ERROR] Get report %s failed: %v", q.Label, err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy báo cáo "+strings.ToLower(q.Label)))
+			return
+		}
+		finalMsg := "📊 BÁO CÁO TÀI CHÍNH\n\n"
+		finalMsg += buildSectionReport(fmt.Sprintf("%s (%s → %s)", q.Label, formatDate(report.StartDate), formatDate(report.EndDate)), report)
+		bot.Send(tgbotapi.NewMessage(chatID, finalMsg))
+		return
+	}
+
 	// [Update] Thêm log lỗi vào đây
-	weekReport, err := getReportData(userID, "week")
+	weekReport, err := getReportData(userID, url.Values{"period": {"week"}})
 	if err != nil {
 		log.Printf("[BOT ERROR] Get week report failed: %v", err)
 		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy báo cáo tuần"))
 		return
 	}
 
-	monthReport, err := getReportData(userID, "month")
+	monthReport, err := getReportData(userID, url.Values{"period": {"month"}})
 	if err != nil {
 		log.Printf("[BOT ERROR] Get month report failed: %v", err)
 		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy báo cáo tháng"))
@@ -252,35 +416,36 @@ func handleReport(bot *tgbotapi.BotAPI, chatID int64, userID string) {
 	bot.Send(tgbotapi.NewMessage(chatID, finalMsg))
 }
 
-// Hàm gọi API lấy báo cáo
-func getReportData(userID string, period string) (*model.ReportOutput, error) {
-	url := fmt.Sprintf("%s/report?user_id=%s&period=%s", apiURL, userID, period)
-	resp, err := http.Get(url)
-	if err != nil {
+// Hàm gọi API lấy báo cáo, params gồm period/offset hoặc from/to
+func getReportData(userID string, params url.Values) (*model.ReportOutput, error) {
+	params.Set("user_id", userID)
+	var r model.ReportOutput
+	if err := callAPI(http.MethodGet, "/report?"+params.Encode(), nil, &r); err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != 200 {
-		body, _ := io.ReadAll(resp.Body)
-		return nil, fmt.Errorf("API status %d: %s", resp.StatusCode, string(body))
-	}
+	return &r, nil
+}
 
-	var r model.ReportOutput
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		return nil, fmt.Errorf("Decode json error: %v", err)
+// formatDate: 2025-03-01 -> 01/03/2025
+func formatDate(isoDate string) string {
+	t, err := time.Parse("2006-01-02", isoDate)
+	if err != nil {
+		return isoDate
 	}
-	return &r, nil
+	return t.Format("02/01/2006")
 }
 
 // Hàm build string cho một phần báo cáo (Tuần hoặc Tháng)
 func buildSectionReport(title string, r *model.ReportOutput) string {
 	// Thu - Chi - Dư
 	text := fmt.Sprintf("📅 *%s:*\n", title)
-	text += fmt.Sprintf("   📈 Thu: %s đ\n", formatCurrency(r.TotalIncome))
-	text += fmt.Sprintf("   📉 Chi: %s đ\n", formatCurrency(r.TotalExpense))
-	text += fmt.Sprintf("   🐷 Đã nạp tiết kiệm: %s đ\n", formatCurrency(r.TotalSavingsVND))
-	text += fmt.Sprintf("   👉 Dư(Thu - Chi tiêu - Tiền đem đi cất): %s đ\n", formatCurrency(r.Balance))
+	text += fmt.Sprintf("   📈 Thu: %s đ\n", formatMoney(r.TotalIncome))
+	text += fmt.Sprintf("   📉 Chi: %s đ\n", formatMoney(r.TotalExpense))
+	text += fmt.Sprintf("   🐷 Đã nạp tiết kiệm: %s đ\n", formatMoney(r.TotalSavingsVND))
+	if r.TotalWithdrawnVND.IsPositive() {
+		text += fmt.Sprintf("   🔓 Đã rút/bán tiết kiệm: %s đ\n", formatMoney(r.TotalWithdrawnVND))
+	}
+	text += fmt.Sprintf("   👉 Dư(Thu - Chi tiêu - Tiền đem đi cất + Tiền rút về): %s đ\n", formatMoney(r.Balance))
 
 	// Chi theo nhóm
 	if len(r.ExpenseByCategory) > 0 {
@@ -288,7 +453,7 @@ func buildSectionReport(title string, r *model.ReportOutput) string {
 		for cat, val := range r.ExpenseByCategory {
 			// Viết hoa chữ cái đầu category cho đẹp
 			catName := strings.Title(cat)
-			text += fmt.Sprintf("     + %s: %s đ\n", catName, formatCurrency(val))
+			text += fmt.Sprintf("     + %s: %s đ\n", catName, formatMoney(val))
 		}
 	}
 
@@ -296,28 +461,64 @@ func buildSectionReport(title string, r *model.ReportOutput) string {
 	text += fmt.Sprintf("   💰 Tài sản tích lũy theo %s:\n", strings.ToLower(title))
 	hasAsset := false
 	for currency, asset := range r.Assets {
-		if asset.Quantity > 0 {
+		if !asset.Quantity.IsZero() { // Âm nếu trong kỳ rút nhiều hơn nạp
 			hasAsset = true
-			// Format: - 4,010 USD (Tỷ giá: 26,229) = 105,176,294 đ
-			text += fmt.Sprintf("     - %s %s (Tỷ giá: %s) = %s đ\n",
-				formatAssetQty(asset.Quantity),
-				currency,
-				formatCurrency(asset.Rate),
-				formatCurrency(asset.CurrentVND))
+			// Format: - 4,010 USD (Tỷ giá: 26,229 đ) = 105,176,294 đ, vàng/bạc: - 1.5 lượng GOLD (Tỷ giá: 85,000,000 đ/lượng)
+			text += fmt.Sprintf("     - %s (Tỷ giá: %s) = %s đ\n",
+				formatHolding(currency, asset.DisplayUnit, asset.Quantity, asset.DisplayQuantity),
+				formatUnitRate(currency, asset.DisplayUnit, asset.Rate, asset.DisplayRate),
+				formatMoney(asset.CurrentVND))
 		}
 	}
 	if !hasAsset {
 		text += "     (Chưa có tài sản mới)\n"
 	}
-	text += fmt.Sprintf("   👉 Tổng trị giá tài sản tích lũy theo %s: %s đ\n", strings.ToLower(title), formatCurrency(r.TotalAssetsVND))
+	text += fmt.Sprintf("   👉 Tổng trị giá tài sản tích lũy theo %s: %s đ\n", strings.ToLower(title), formatMoney(r.TotalAssetsVND))
+	if r.RatesStale {
+		text += "   ⚠️ Giá trị tài sản đang tính theo giá cũ/ước lượng, có thể chưa chính xác\n"
+	}
+
+	// Tài sản đang giữ và lãi/lỗ theo giá hiện tại
+	if len(r.Holdings) > 0 {
+		text += fmt.Sprintf("   📊 Lãi/lỗ tài sản (giá vốn %s):\n", costBasisNames[r.CostBasis])
+		for currency, hd := range r.Holdings {
+			text += fmt.Sprintf("     + %s: vốn %s đ, hiện %s đ, lãi chưa chốt %s đ",
+				formatHolding(currency, hd.DisplayUnit, hd.Quantity, hd.DisplayQuantity), formatMoney(hd.CostVND), formatMoney(hd.CurrentVND), formatPL(hd.UnrealizedPL))
+			if !hd.RealizedPL.IsZero() {
+				text += fmt.Sprintf(", đã chốt %s đ", formatPL(hd.RealizedPL))
+			}
+			text += "\n"
+		}
+		text += fmt.Sprintf("   👉 Lãi chưa chốt: %s đ, đã chốt trong kỳ: %s đ\n", formatPL(r.TotalUnrealizedPL), formatPL(r.TotalRealizedPL))
+	}
+
+	// Số dư tài khoản cuối kỳ
+	if len(r.Accounts) > 0 {
+		text += "   🏦 Số dư tài khoản cuối kỳ:\n"
+		for _, ab := range r.Accounts {
+			text += fmt.Sprintf("     + @%s: %s\n", ab.Account.Name, formatAccountBalance(ab))
+		}
+	}
+
+	// Ngân sách so với thực chi (chỉ có ở báo cáo tuần/tháng)
+	if len(r.Budgets) > 0 {
+		text += "   💼 Ngân sách:\n"
+		for _, st := range r.Budgets {
+			text += fmt.Sprintf("     + %s: %s\n", strings.Title(st.Budget.Category), formatBudgetLine(st))
+		}
+	}
 
 	return text
 }
 
 // Hàm định dạng tiền tệ: 1000000 -> 1,000,000
 func formatCurrency(amount float64) string {
-	// Chuyển sang int để bỏ phần thập phân nếu là số nguyên
-	s := fmt.Sprintf("%.0f", amount)
+	return formatMoney(decimal.NewFromFloat(amount))
+}
+
+// Hàm định dạng tiền dạng decimal (số liệu báo cáo), làm tròn đến đồng
+func formatMoney(amount decimal.Decimal) string {
+	s := amount.StringFixed(0)
 	// Logic thêm dấu phẩy
 	if len(s) <= 3 {
 		return s
@@ -334,10 +535,40 @@ func formatCurrency(amount float64) string {
 	return string(result)
 }
 
-// Hàm format riêng cho ngoại tệ (giữ lại số lẻ nếu cần)
-func formatAssetQty(qty float64) string {
-	// Nếu tròn chẵn thì bỏ .00, nếu lẻ thì giữ tối đa 4 số
-	return strings.TrimRight(strings.TrimRight(fmt.Sprintf("%.4f", qty), "0"), ".")
+// formatPL định dạng lãi/lỗ kèm dấu: +1,000,000 hoặc -500,000
+func formatPL(amount decimal.Decimal) string {
+	if amount.IsPositive() {
+		return "+" + formatMoney(amount)
+	}
+	return formatMoney(amount)
+}
+
+// Hàm format riêng cho ngoại tệ: in đúng số lẻ đã lưu (VD: 0.00012345 BTC)
+func formatAssetQty(qty decimal.Decimal) string {
+	return qty.String()
+}
+
+// formatHolding số lượng tài sản theo đơn vị hiển thị: "4010 USD", "1.5 lượng GOLD".
+// API cũ chưa có display_unit thì dùng số lượng gốc
+func formatHolding(currency, unit string, qty, displayQty decimal.Decimal) string {
+	switch unit {
+	case "":
+		return formatAssetQty(qty) + " " + currency
+	case currency:
+		return formatAssetQty(displayQty) + " " + currency
+	}
+	return formatAssetQty(displayQty) + " " + unit + " " + currency
+}
+
+// formatUnitRate giá 1 đơn vị hiển thị: "26,229 đ" với tiền tệ, "85,000,000 đ/lượng" với vàng/bạc
+func formatUnitRate(currency, unit string, rate, displayRate decimal.Decimal) string {
+	switch unit {
+	case "":
+		return formatMoney(rate) + " đ"
+	case currency:
+		return formatMoney(displayRate) + " đ"
+	}
+	return formatMoney(displayRate) + " đ/" + unit
 }
 
 // --- LOGIC GIÁ VÀNG BẠC ---
@@ -355,7 +586,6 @@ func handlePrice(bot *tgbotapi.BotAPI, chatID int64, requestType string) {
 		return
 	}
 
-	const OunceToTael = 1.20565
 	var msgBuf bytes.Buffer
 
 	// Luôn hiển thị Tỷ giá USD đầu tiên
@@ -363,12 +593,19 @@ func handlePrice(bot *tgbotapi.BotAPI, chatID int64, requestType string) {
 
 	// SECTION: VÀNG
 	if requestType == "gold" {
-		convertedGold := r.GoldUSD * r.UsdVND * OunceToTael
+		convertedGold := service.ConvertMetalPrice(r.GoldUSD*r.UsdVND, "oz", "luong")
 
 		msgBuf.WriteString("🏆 VÀNG (GOLD)\n")
 		msgBuf.WriteString(fmt.Sprintf("• Thế giới: %s USD/oz\n", formatUSD(r.GoldUSD)))
 		msgBuf.WriteString(fmt.Sprintf("• Quy đổi: %s đ/cây\n", formatCurrency(convertedGold)))
-		msgBuf.WriteString(fmt.Sprintf("• SJC (Thực tế): %s đ/cây\n", formatCurrency(r.VnSJC*10)))
+		sjcBid := service.ConvertMetalPrice(r.Bid(service.AssetSJC), "chi", "luong")
+		sjcAsk := service.ConvertMetalPrice(r.Ask(service.AssetSJC), "chi", "luong")
+		if sjcBid != sjcAsk {
+			msgBuf.WriteString(fmt.Sprintf("• SJC mua vào: %s đ/cây, bán ra: %s đ/cây\n", formatCurrency(sjcBid), formatCurrency(sjcAsk)))
+			msgBuf.WriteString(fmt.Sprintf("↔️ Chênh lệch mua/bán: %s đ/cây (%.1f%%)\n", formatCurrency(sjcAsk-sjcBid), (sjcAsk-sjcBid)/sjcAsk*100))
+		} else {
+			msgBuf.WriteString(fmt.Sprintf("• SJC (Thực tế): %s đ/cây\n", formatCurrency(sjcAsk)))
+		}
 
 		// Chênh lệch Vàng
 		statusGold := "VN cao hơn"
@@ -380,11 +617,10 @@ func handlePrice(bot *tgbotapi.BotAPI, chatID int64, requestType string) {
 
 	// SECTION: BẠC
 	if requestType == "silver" {
-		convertedSilver := r.SilverUSD * r.UsdVND * OunceToTael
-		// Tính toán giá theo Kg (1 cây = 37.5g = 0.0375 kg)
-		// Công thức: Giá 1 cây / 0.0375 = Giá 1 kg
-		convertedSilverKg := convertedSilver / 0.0375
-		vnSilverKg := r.VnSilver / 0.0375
+		convertedSilver := service.ConvertMetalPrice(r.SilverUSD*r.UsdVND, "oz", "luong")
+		// Giá theo kg (1 cây = 37.5g)
+		convertedSilverKg := service.ConvertMetalPrice(convertedSilver, "luong", "kg")
+		vnSilverKg := service.ConvertMetalPrice(r.VnSilver, "luong", "kg")
 
 		msgBuf.WriteString("ww BẠC (SILVER)\n")
 		// Thêm hiển thị tỷ giá USD
@@ -404,9 +640,51 @@ func handlePrice(bot *tgbotapi.BotAPI, chatID int64, requestType string) {
 		msgBuf.WriteString(fmt.Sprintf("⚖️ Chênh lệch: %s %s đ", statusSilver, formatCurrency(math.Abs(diffSilver))))
 	}
 
+	msgBuf.WriteString(staleRatesWarning(r))
 	bot.Send(tgbotapi.NewMessage(chatID, msgBuf.String()))
 }
 
+// rateCodeNames tên hiển thị của các mã giá trong ExchangeRates.Sources
+var rateCodeNames = []struct{ code, name string }{
+	{"USD", "USD"},
+	{"XAU", "vàng thế giới"},
+	{"XAG", "bạc thế giới"},
+	{"SJC", "vàng SJC"},
+	{"BTC", "Bitcoin"},
+	{"EUR", "EUR"},
+	{"JPY", "JPY"},
+	{"CNY", "CNY"},
+	{"ETH", "Ethereum"},
+	{"USDT", "USDT"},
+}
+
+// staleRatesWarning dòng cảnh báo khi có giá là giá mặc định hoặc đã cũ, rỗng nếu mọi giá đều mới
+func staleRatesWarning(r model.ExchangeRates) string {
+	if !r.Stale {
+		return ""
+	}
+	var fallback, old []string
+	for _, c := range rateCodeNames {
+		src := r.Sources[c.code]
+		if !src.Stale {
+			continue
+		}
+		if src.Source == model.RateSourceDefault || src.FetchedAt == nil {
+			fallback = append(fallback, c.name)
+		} else {
+			old = append(old, fmt.Sprintf("%s (lúc %s)", c.name, src.FetchedAt.In(model.DefaultUserSettings("").Location()).Format("15:04 02/01")))
+		}
+	}
+	msg := "\n\n⚠️ Chưa lấy được giá mới nhất:"
+	if len(fallback) > 0 {
+		msg += "\n• Đang dùng giá ước lượng dựng sẵn cho " + strings.Join(fallback, ", ")
+	}
+	if len(old) > 0 {
+		msg += "\n• Đang dùng giá cũ của " + strings.Join(old, ", ")
+	}
+	return msg
+}
+
 // Helper: Format số USD (ví dụ: 2,645.50)
 func formatUSD(amount float64) string {
 	// Format 2 số thập phân, ví dụ: 2645.50
@@ -430,79 +708,107 @@ func formatUSD(amount float64) string {
 }
 
 // --- LOGIC SCHEDULER GỬI TIN NHẮN ĐỊNH KỲ ---
-func startScheduler(bot *tgbotapi.BotAPI) {
-	// Định nghĩa múi giờ Việt Nam (UTC+7)
-	loc := time.FixedZone("ICT", 7*3600)
 
+// Giờ gửi bản tin, tính theo múi giờ riêng của từng user
+var notifyHours = []int{7, 19}
+
+func startScheduler(bot *tgbotapi.BotAPI) {
 	// Kiểm tra mỗi phút một lần
 	ticker := time.NewTicker(1 * time.Minute)
 	defer ticker.Stop()
 
+	// Đánh dấu "user|ngày giờ" đã gửi để không gửi lặp nếu ticker lệch nhịp
+	sent := make(map[string]bool)
+
 	for t := range ticker.C {
-		// Chuyển về giờ VN
-		localTime := t.In(loc)
-		hour := localTime.Hour()
-		minute := localTime.Minute()
-
-		// Nếu là 7:00 hoặc 19:00 (7h tối)
-		if (hour == 7 || hour == 19) && minute == 0 {
-			log.Println("[SCHEDULER] Bắt đầu gửi thông báo định kỳ...")
-			sendDailyUpdate(bot)
-			// Ngủ 65 giây để tránh gửi lặp lại trong cùng 1 phút đó
-			time.Sleep(65 * time.Second)
+		// Mọi múi giờ thực tế đều lệch UTC bội số của 15 phút,
+		// nên chỉ các phút này mới có thể là đúng 0 phút ở giờ địa phương của ai đó
+		if t.UTC().Minute()%15 != 0 {
+			continue
+		}
+
+		var settings []model.UserSettings
+		if err := callAPI(http.MethodGet, "/users/settings", nil, &settings); err != nil {
+			log.Printf("[SCHEDULER ERROR] Không thể lấy cài đặt user: %v", err)
+			continue
+		}
+
+		// Gom user theo giờ địa phương cần gửi (7h hay 19h)
+		due := make(map[int][]string)
+		for _, st := range settings {
+			local := t.In(st.Location())
+			if local.Minute() != 0 || !containsHour(notifyHours, local.Hour()) {
+				continue
+			}
+			key := st.UserID + "|" + local.Format("2006-01-02 15")
+			if sent[key] {
+				continue
+			}
+			sent[key] = true
+			due[local.Hour()] = append(due[local.Hour()], st.UserID)
+		}
+
+		for hour, userIDs := range due {
+			log.Printf("[SCHEDULER] Bắt đầu gửi thông báo %dh cho %d user...", hour, len(userIDs))
+			sendDailyUpdate(bot, userIDs, hour)
+		}
+
+		// Dọn map để không phình mãi: chỉ cần nhớ các mốc trong ngày gần đây
+		if len(sent) > 10000 {
+			sent = make(map[string]bool)
 		}
 	}
 }
 
-func sendDailyUpdate(bot *tgbotapi.BotAPI) {
-	// 1. Lấy dữ liệu giá cả
-	resp, err := http.Get(apiURL + "/market-rates")
-	if err != nil {
-		log.Printf("[SCHEDULER ERROR] Không thể lấy giá: %v", err)
+func containsHour(hours []int, h int) bool {
+	for _, x := range hours {
+		if x == h {
+			return true
+		}
+	}
+	return false
+}
+
+// sendAllUsersUpdate gửi bản tin cho tất cả user ngay lập tức (dùng cho /test_noti)
+func sendAllUsersUpdate(bot *tgbotapi.BotAPI) {
+	var userIDs []string
+	if err := callAPI(http.MethodGet, "/users", nil, &userIDs); err != nil {
+		log.Printf("[SCHEDULER ERROR] Không thể lấy user list: %v", err)
 		return
 	}
-	defer resp.Body.Close()
+	sendDailyUpdate(bot, userIDs, time.Now().Hour())
+}
 
+func sendDailyUpdate(bot *tgbotapi.BotAPI, userIDs []string, hour int) {
+	// 1. Lấy dữ liệu giá cả
 	var r model.ExchangeRates
-	if err := json.NewDecoder(resp.Body).Decode(&r); err != nil {
-		log.Printf("[SCHEDULER ERROR] Lỗi decode giá: %v", err)
+	if err := callAPI(http.MethodGet, "/market-rates", nil, &r); err != nil {
+		log.Printf("[SCHEDULER ERROR] Không thể lấy giá: %v", err)
 		return
 	}
 
 	// 2. Soạn nội dung tin nhắn
-	const OunceToTael = 1.20565
-	goldVND := r.GoldUSD * r.UsdVND * OunceToTael
-	silverVND := r.SilverUSD * r.UsdVND * OunceToTael
+	goldVND := service.ConvertMetalPrice(r.GoldUSD*r.UsdVND, "oz", "luong")
+	silverVND := service.ConvertMetalPrice(r.SilverUSD*r.UsdVND, "oz", "luong")
 
 	msgContent := fmt.Sprintf(
-		"🔔 *BẢN TIN THỊ TRƯỜNG (7H)* 🔔\n\n"+
+		"🔔 *BẢN TIN THỊ TRƯỜNG (%dH)* 🔔\n\n"+
 			"🇺🇸 *USD:* %s VNĐ\n"+
 			"🏆 *Vàng (TG):* %s VNĐ/cây\n"+
 			"   _(Vàng SJC: %s VNĐ/cây)_\n"+
 			"ww *Bạc (TG):* %s VNĐ/cây\n"+
 			"🅱️ *Bitcoin:* %s VNĐ\n",
+		hour,
 		formatCurrency(r.UsdVND),
 		formatCurrency(goldVND),
-		formatCurrency(r.VnSJC*10),
+		formatCurrency(service.ConvertMetalPrice(r.VnSJC, "chi", "luong")),
 		formatCurrency(silverVND),
 		formatCurrency(r.BtcVND),
 	)
 
-	// 3. Lấy danh sách Users
-	userResp, err := http.Get(apiURL + "/users")
-	if err != nil {
-		log.Printf("[SCHEDULER ERROR] Không thể lấy user list: %v", err)
-		return
-	}
-	defer userResp.Body.Close()
-
-	var userIDs []string
-	if err := json.NewDecoder(userResp.Body).Decode(&userIDs); err != nil {
-		log.Printf("[SCHEDULER ERROR] Lỗi decode user list: %v", err)
-		return
-	}
+	msgContent += staleRatesWarning(r)
 
-	// 4. Gửi tin nhắn cho từng người
+	// 3. Gửi tin nhắn cho từng người
 	count := 0
 	for _, uidStr := range userIDs {
 		// Chuyển uid string -> int64
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+	"github.com/shopspring/decimal"
+)
+
+// --- LOGIC DANH MỤC TÀI SẢN ---
+
+// portfolioChangeNames tên hiển thị các mốc so sánh biến động
+var portfolioChangeNames = []struct{ key, label string }{
+	{"day", "1 ngày"},
+	{"week", "1 tuần"},
+	{"month", "1 tháng"},
+}
+
+// isPortfolioCommand: "tài sản" hoặc "tai san" (cả tin nhắn), tránh nhầm với ghi chú có chữ tài sản
+func isPortfolioCommand(text string) bool {
+	return strings.TrimSpace(text) == "/portfolio" || service.IsCommand(text, "tài sản")
+}
+
+// handlePortfolio gửi toàn bộ tài sản đang giữ và biến động giá
+func handlePortfolio(bot *tgbotapi.BotAPI, chatID int64, userID string) {
+	var p model.Portfolio
+	if err := callAPI(http.MethodGet, "/portfolio?user_id="+url.QueryEscape(userID), nil, &p); err != nil {
+		log.Printf("[BOT ERROR] Get portfolio failed: %v", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy danh mục tài sản."))
+		return
+	}
+	if len(p.Holdings) == 0 {
+		bot.Send(tgbotapi.NewMessage(chatID, "💰 Bạn chưa có tài sản tiết kiệm nào. Ghi bằng: tk 2m, tk 5 chỉ vàng, tk 0.1 btc"))
+		return
+	}
+
+	msg := fmt.Sprintf("💰 TÀI SẢN ĐANG GIỮ (giá vốn %s)\n", costBasisNames[p.CostBasis])
+	for _, ph := range p.Holdings {
+		if ph.Currency == "VND" {
+			msg += fmt.Sprintf("\n• Tiền tiết kiệm: %s đ\n", formatMoney(ph.CurrentVND))
+			continue
+		}
+		staleMark := ""
+		if ph.Stale {
+			staleMark = " ⚠️"
+		}
+		msg += fmt.Sprintf("\n• %s = %s đ%s\n", formatHolding(ph.Currency, ph.DisplayUnit, ph.Quantity, ph.DisplayQuantity), formatMoney(ph.CurrentVND), staleMark)
+		msg += fmt.Sprintf("   Giá mua TB: %s, giá hiện tại: %s\n",
+			formatUnitRate(ph.Currency, ph.DisplayUnit, ph.AvgPriceVND, ph.DisplayAvgPrice),
+			formatUnitRate(ph.Currency, ph.DisplayUnit, ph.Rate, ph.DisplayRate))
+		msg += fmt.Sprintf("   Lãi chưa chốt: %s đ", formatPL(ph.UnrealizedPL))
+		if !ph.RealizedPL.IsZero() {
+			msg += fmt.Sprintf(", đã chốt: %s đ", formatPL(ph.RealizedPL))
+		}
+		msg += "\n"
+		if changes := formatPriceChanges(ph.Changes); changes != "" {
+			msg += "   Biến động: " + changes + "\n"
+		}
+	}
+
+	msg += fmt.Sprintf("\n👉 Tổng: %s đ (vốn %s đ, lãi chưa chốt %s đ)\n",
+		formatMoney(p.TotalCurrentVND), formatMoney(p.TotalCostVND), formatPL(p.TotalUnrealizedPL))
+	if changes := formatPriceChanges(p.Changes); changes != "" {
+		msg += "📊 Biến động: " + changes + "\n"
+	}
+	if p.RatesStale {
+		msg += "⚠️ Một số tài sản đang định giá theo giá cũ/ước lượng (chưa lấy được giá mới), số liệu có thể chưa chính xác\n"
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, msg))
+}
+
+// formatPriceChanges: "1 ngày +500,000 đ (+6.25%), 1 tuần ..." theo các mốc có dữ liệu
+func formatPriceChanges(changes map[string]model.PriceChange) string {
+	var parts []string
+	for _, c := range portfolioChangeNames {
+		pc, ok := changes[c.key]
+		if !ok {
+			continue
+		}
+		percent := pc.Percent.StringFixed(2) + "%"
+		if pc.Percent.GreaterThan(decimal.Zero) {
+			percent = "+" + percent
+		}
+		parts = append(parts, fmt.Sprintf("%s %s đ (%s)", c.label, formatPL(pc.ChangeVND), percent))
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,136 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go-finance/internal/model"
+	"log"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// --- LOGIC GIAO DỊCH ĐỊNH KỲ ---
+
+var isoWeekdayNames = []string{"", "thứ 2", "thứ 3", "thứ 4", "thứ 5", "thứ 6", "thứ 7", "chủ nhật"}
+
+// describeSchedule: "hàng tháng ngày 1", "hàng tuần thứ 2", "hàng ngày"
+func describeSchedule(rec model.Recurrence) string {
+	switch rec.Frequency {
+	case "monthly":
+		return fmt.Sprintf("hàng tháng ngày %d", rec.Day)
+	case "weekly":
+		if rec.Day >= 1 && rec.Day <= 7 {
+			return "hàng tuần " + isoWeekdayNames[rec.Day]
+		}
+		return "hàng tuần"
+	}
+	return "hàng ngày"
+}
+
+// describeRule: "#3 chi 5,000,000 đ tiền nhà - hàng tháng ngày 1 (lần tới 01/06/2025)"
+func describeRule(r model.RecurringRule) string {
+	desc := fmt.Sprintf("#%d %s %s đ", r.ID, r.Type, formatMoney(r.Amount))
+	if r.Note != "" {
+		desc += " " + r.Note
+	}
+	desc += " - " + describeSchedule(r.Recurrence)
+	if r.Paused {
+		return desc + " (⏸ tạm dừng)"
+	}
+	return desc + fmt.Sprintf(" (lần tới %s)", r.NextRun.In(r.Location()).Format("02/01/2006"))
+}
+
+// createRecurringFromText tạo quy tắc định kỳ từ giao dịch có lịch lặp ("chi 5m tiền nhà hàng tháng ngày 1")
+func createRecurringFromText(tx model.TransactionCreate) (model.RecurringRule, error) {
+	if tx.Currency != "VND" {
+		return model.RecurringRule{}, fmt.Errorf("giao dịch định kỳ chỉ hỗ trợ VND")
+	}
+	if tx.Account != "" || tx.Type == "chuyen" {
+		return model.RecurringRule{}, fmt.Errorf("giao dịch định kỳ chưa hỗ trợ tài khoản")
+	}
+	rule := model.RecurringRule{
+		UserID:     tx.UserID,
+		Type:       tx.Type,
+		Amount:     tx.Amount,
+		Note:       tx.Note,
+		Category:   tx.Category,
+		Recurrence: *tx.Recurrence,
+	}
+	var saved model.RecurringRule
+	err := callAPI(http.MethodPost, "/recurring", rule, &saved)
+	return saved, err
+}
+
+// handleRecurring xử lý:
+//   - /recurring              xem danh sách
+//   - /recurring pause <id>   tạm dừng
+//   - /recurring resume <id>  chạy lại
+//   - /recurring delete <id>  xóa
+func handleRecurring(bot *tgbotapi.BotAPI, chatID int64, userID string, text string) {
+	args := strings.Fields(strings.TrimPrefix(text, "/recurring"))
+	usage := "🔁 Cú pháp:\n- /recurring\n- /recurring pause <id>\n- /recurring resume <id>\n- /recurring delete <id>\nTạo mới: chi 5m tiền nhà hàng tháng ngày 1"
+
+	if len(args) == 0 {
+		var rules []model.RecurringRule
+		if err := callAPI(http.MethodGet, "/recurring?user_id="+url.QueryEscape(userID), nil, &rules); err != nil {
+			log.Printf("[BOT ERROR] List recurring failed: %v", err)
+			bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi lấy danh sách giao dịch định kỳ."))
+			return
+		}
+		if len(rules) == 0 {
+			bot.Send(tgbotapi.NewMessage(chatID, "🔁 Bạn chưa có giao dịch định kỳ nào.\nVD: chi 5m tiền nhà hàng tháng ngày 1"))
+			return
+		}
+		lines := make([]string, 0, len(rules))
+		for _, r := range rules {
+			lines = append(lines, describeRule(r))
+		}
+		bot.Send(tgbotapi.NewMessage(chatID, "🔁 GIAO DỊCH ĐỊNH KỲ\n"+strings.Join(lines, "\n")))
+		return
+	}
+
+	if len(args) != 2 {
+		bot.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+	id, err := strconv.Atoi(strings.TrimPrefix(args[1], "#"))
+	if err != nil {
+		bot.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+	path := fmt.Sprintf("/recurring/%d", id)
+
+	var rule model.RecurringRule
+	var reply string
+	switch args[0] {
+	case "pause", "resume":
+		paused := args[0] == "pause"
+		err = callAPI(http.MethodPatch, path, model.RecurringRuleUpdate{UserID: userID, Paused: &paused}, &rule)
+		reply = "▶️ Đã chạy lại "
+		if paused {
+			reply = "⏸ Đã tạm dừng "
+		}
+	case "delete":
+		err = callAPI(http.MethodDelete, path+"?user_id="+url.QueryEscape(userID), nil, &rule)
+		reply = "🗑 Đã xóa (các giao dịch đã ghi vẫn giữ nguyên) "
+	default:
+		bot.Send(tgbotapi.NewMessage(chatID, usage))
+		return
+	}
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && (apiErr.Status == http.StatusNotFound || apiErr.Status == http.StatusForbidden) {
+		bot.Send(tgbotapi.NewMessage(chatID, fmt.Sprintf("⚠️ Không tìm thấy giao dịch định kỳ #%d của bạn.", id)))
+		return
+	}
+	if err != nil {
+		log.Printf("[BOT ERROR] Recurring %s failed: %v", args[0], err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể cập nhật giao dịch định kỳ."))
+		return
+	}
+	bot.Send(tgbotapi.NewMessage(chatID, reply+describeRule(rule)))
+}
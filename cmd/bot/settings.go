@@ -0,0 +1,119 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	tgbotapi "github.com/go-telegram-bot-api/telegram-bot-api/v5"
+)
+
+// --- LOGIC CÀI ĐẶT USER ---
+
+var weekdayNames = []string{"Chủ nhật", "Thứ 2", "Thứ 3", "Thứ 4", "Thứ 5", "Thứ 6", "Thứ 7"}
+
+// weekdayAliases cách gõ ngày đầu tuần được chấp nhận (key không dấu, tra bằng service.FoldText)
+var weekdayAliases = map[string]time.Weekday{
+	"cn": time.Sunday, "chu nhat": time.Sunday, "sunday": time.Sunday, "sun": time.Sunday,
+	"t2": time.Monday, "thu 2": time.Monday, "monday": time.Monday, "mon": time.Monday,
+	"t7": time.Saturday, "thu 7": time.Saturday, "saturday": time.Saturday, "sat": time.Saturday,
+}
+
+// costBasisNames tên hiển thị của cách tính giá vốn
+var costBasisNames = map[string]string{
+	model.CostBasisAverage: "bình quân",
+	model.CostBasisFIFO:    "FIFO",
+}
+
+// costBasisAliases cách gõ cách tính giá vốn được chấp nhận
+var costBasisAliases = map[string]string{
+	"avg": model.CostBasisAverage, "average": model.CostBasisAverage, "bq": model.CostBasisAverage,
+	"fifo": model.CostBasisFIFO,
+}
+
+// metalSettingKeys lệnh đổi đơn vị hiển thị vàng/bạc -> trường cài đặt (key không dấu)
+var metalSettingKeys = map[string]string{
+	"vang": "gold_unit", "gold": "gold_unit",
+	"bac": "silver_unit", "silver": "silver_unit",
+}
+
+// userLocation múi giờ trong cài đặt của user, lỗi API thì dùng giờ Việt Nam
+func userLocation(userID string) *time.Location {
+	settings := model.DefaultUserSettings(userID)
+	if err := callAPI(http.MethodGet, "/users/"+url.PathEscape(userID)+"/settings", nil, &settings); err != nil {
+		log.Printf("[BOT ERROR] Get settings failed: %v", err)
+	}
+	return settings.Location()
+}
+
+// handleSettings xử lý:
+//   - /settings                      xem cài đặt
+//   - /settings tz Asia/Ho_Chi_Minh  đổi múi giờ
+//   - /settings week cn              đổi ngày đầu tuần (cn, t2, t7)
+//   - /settings cost fifo            đổi cách tính giá vốn tài sản (fifo, avg)
+//   - /settings vàng lượng           đổi đơn vị hiển thị vàng (chỉ, lượng, g, kg, oz), tương tự với bạc
+func handleSettings(bot *tgbotapi.BotAPI, chatID int64, userID string, text string) {
+	path := "/users/" + url.PathEscape(userID) + "/settings"
+	args := strings.Fields(strings.TrimPrefix(text, "/settings"))
+
+	var settings model.UserSettings
+	var err error
+	switch {
+	case len(args) == 0:
+		err = callAPI(http.MethodGet, path, nil, &settings)
+
+	case len(args) == 2 && (args[0] == "tz" || args[0] == "timezone"):
+		err = callAPI(http.MethodPut, path, map[string]string{"timezone": args[1]}, &settings)
+
+	case len(args) >= 2 && args[0] == "week":
+		day, ok := weekdayAliases[service.FoldText(strings.Join(args[1:], " "))]
+		if !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Ngày đầu tuần chỉ nhận: cn, t2, t7"))
+			return
+		}
+		err = callAPI(http.MethodPut, path, map[string]int{"week_start": int(day)}, &settings)
+
+	case len(args) == 2 && args[0] == "cost":
+		method, ok := costBasisAliases[strings.ToLower(args[1])]
+		if !ok {
+			bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Cách tính giá vốn chỉ nhận: fifo, avg"))
+			return
+		}
+		err = callAPI(http.MethodPut, path, map[string]string{"cost_basis": method}, &settings)
+
+	case len(args) == 2 && metalSettingKeys[service.FoldText(args[0])] != "":
+		// API tự chuẩn hóa cách viết đơn vị ("lượng", "cây", "gram"...) và báo lỗi nếu không hợp lệ
+		err = callAPI(http.MethodPut, path, map[string]string{metalSettingKeys[service.FoldText(args[0])]: args[1]}, &settings)
+
+	default:
+		bot.Send(tgbotapi.NewMessage(chatID, "⚙️ Cú pháp:\n- /settings\n- /settings tz Asia/Ho_Chi_Minh\n- /settings week cn|t2|t7\n- /settings cost fifo|avg\n- /settings vàng chỉ|lượng|g|kg|oz\n- /settings bạc chỉ|lượng|g|kg|oz"))
+		return
+	}
+
+	var apiErr *apiError
+	if errors.As(err, &apiErr) && apiErr.Status == http.StatusBadRequest {
+		bot.Send(tgbotapi.NewMessage(chatID, "⚠️ Giá trị không hợp lệ: "+apiErr.Body))
+		return
+	}
+	if err != nil {
+		log.Printf("[BOT ERROR] Settings failed: %v", err)
+		bot.Send(tgbotapi.NewMessage(chatID, "❌ Lỗi hệ thống: Không thể lưu cài đặt."))
+		return
+	}
+
+	goldUnit, _ := service.LookupMetalUnit(settings.GoldUnit)
+	silverUnit, _ := service.LookupMetalUnit(settings.SilverUnit)
+	msg := fmt.Sprintf("⚙️ CÀI ĐẶT\n• Múi giờ: %s (bây giờ là %s)\n• Ngày đầu tuần: %s\n• Giá vốn tài sản: %s\n• Đơn vị vàng: %s, bạc: %s\n• Bản tin lúc 7h và 19h theo múi giờ này",
+		settings.Timezone,
+		time.Now().In(settings.Location()).Format("15:04 02/01"),
+		weekdayNames[settings.WeekStart],
+		costBasisNames[settings.CostBasis],
+		goldUnit.Name, silverUnit.Name)
+	bot.Send(tgbotapi.NewMessage(chatID, msg))
+}
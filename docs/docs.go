@@ -15,9 +15,51 @@ const docTemplate = `{
     "host": "{{.Host}}",
     "basePath": "{{.BasePath}}",
     "paths": {
-        "/market-rates": {
+        "/accounts": {
             "get": {
-                "description": "Lấy giá Vàng, Bạc, Bitcoin, USD từ các nguồn bên ngoài (CoinGecko, GoldAPI...).",
+                "description": "Số dư = số dư đầu + thu - chi - tiết kiệm ± chuyển khoản của các giao dịch gắn với tài khoản, tính theo đơn vị tiền của tài khoản.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounts"
+                ],
+                "summary": "Danh sách tài khoản kèm số dư",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.AccountBalance"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Tạo tài khoản (tiền mặt, ngân hàng, ví điện tử...) để gắn vào giao dịch bằng tên, VD: \"chi 50k cafe @momo\".",
                 "consumes": [
                     "application/json"
                 ],
@@ -25,18 +67,41 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Market Data"
+                    "Accounts"
+                ],
+                "summary": "Tạo tài khoản / ví",
+                "parameters": [
+                    {
+                        "description": "Tài khoản (id, created_at bỏ qua)",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Account"
+                        }
+                    }
                 ],
-                "summary": "Lấy tỷ giá thị trường",
                 "responses": {
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.ExchangeRates"
+                            "$ref": "#/definitions/model.Account"
+                        }
+                    },
+                    "400": {
+                        "description": "Lỗi dữ liệu đầu vào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Đã có tài khoản cùng tên",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "500": {
-                        "description": "Lỗi không lấy được dữ liệu",
+                        "description": "Lỗi Server",
                         "schema": {
                             "type": "string"
                         }
@@ -44,9 +109,9 @@ const docTemplate = `{
                 }
             }
         },
-        "/report": {
-            "get": {
-                "description": "Tổng hợp thu/chi, tính toán số dư và định giá tài sản tích lũy theo thời gian thực.",
+        "/accounts/{id}": {
+            "put": {
+                "description": "Đổi tên, đơn vị tiền hoặc số dư đầu. Trường không gửi sẽ giữ nguyên.",
                 "consumes": [
                     "application/json"
                 ],
@@ -54,21 +119,140 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Reports"
+                    "Accounts"
                 ],
-                "summary": "Xuất báo cáo tài chính",
+                "summary": "Sửa tài khoản",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID tài khoản",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Các trường cần sửa (bắt buộc user_id)",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Account"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Account"
+                        }
+                    },
+                    "400": {
+                        "description": "Lỗi dữ liệu đầu vào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Tài khoản không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Đã có tài khoản cùng tên",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Chỉ xóa được tài khoản chưa có giao dịch nào gắn với nó.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Accounts"
+                ],
+                "summary": "Xóa tài khoản",
                 "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID tài khoản",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
                     {
                         "type": "string",
-                        "description": "ID người dùng Telegram (VD: 123456789)",
+                        "description": "ID người dùng Telegram",
                         "name": "user_id",
                         "in": "query",
                         "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Tài khoản vừa xóa",
+                        "schema": {
+                            "$ref": "#/definitions/model.Account"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id hoặc id sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Tài khoản không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
                     },
+                    "409": {
+                        "description": "Tài khoản còn giao dịch",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/alerts": {
+            "get": {
+                "description": "Tất cả cảnh báo của user, kể cả cảnh báo 1 lần đã báo (active = false).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Alerts"
+                ],
+                "summary": "Danh sách cảnh báo giá",
+                "parameters": [
                     {
                         "type": "string",
-                        "description": "Kỳ báo cáo: 'week' (tuần này) hoặc 'month' (tháng này)",
-                        "name": "period",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
                         "in": "query",
                         "required": true
                     }
@@ -77,7 +261,16 @@ const docTemplate = `{
                     "200": {
                         "description": "OK",
                         "schema": {
-                            "$ref": "#/definitions/model.ReportOutput"
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.PriceAlert"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id",
+                        "schema": {
+                            "type": "string"
                         }
                     },
                     "500": {
@@ -87,11 +280,9 @@ const docTemplate = `{
                         }
                     }
                 }
-            }
-        },
-        "/transactions": {
+            },
             "post": {
-                "description": "API nhận dữ liệu giao dịch. Hỗ trợ tự động quy đổi tỷ giá nếu dùng ngoại tệ.\n\n### 💡 HƯỚNG DẪN TEST NHANH (Copy JSON bên dưới dán vào ô Request):\n\n**1️⃣ Trường hợp: CHI TIÊU (VND)**\n` + "`" + `` + "`" + `` + "`" + `json\n{\n\"user_id\": \"123456789\",\n\"type\": \"chi\",\n\"amount\": 55000,\n\"note\": \"Ăn trưa cơm tấm\",\n\"category\": \"ăn uống\",\n\"currency\": \"VND\"\n}\n` + "`" + `` + "`" + `` + "`" + `\n\n**2️⃣ Trường hợp: THU NHẬP (VND)**\n` + "`" + `` + "`" + `` + "`" + `json\n{\n\"user_id\": \"123456789\",\n\"type\": \"thu\",\n\"amount\": 15000000,\n\"note\": \"Lương tháng 12\",\n\"currency\": \"VND\"\n}\n` + "`" + `` + "`" + `` + "`" + `\n\n**3️⃣ Trường hợp: TIẾT KIỆM (Vàng/Ngoại tệ)**\n_(Hệ thống sẽ tự quy đổi ra VND theo tỷ giá hiện tại)_\n` + "`" + `` + "`" + `` + "`" + `json\n{\n\"user_id\": \"123456789\",\n\"type\": \"tiet_kiem\",\n\"amount\": 2,\n\"note\": \"Mua 2 chỉ vàng tích trữ\",\n\"currency\": \"GOLD\"\n}\n` + "`" + `` + "`" + `` + "`" + `",
+                "description": "Báo cho user khi giá tài sản (vàng, USD, BTC...) vượt mốc. Giá được kiểm tra sau mỗi lần cập nhật tỷ giá (10 phút).\n` + "`" + `above` + "`" + `/` + "`" + `below` + "`" + `: ` + "`" + `threshold` + "`" + ` là giá VND theo 1 ` + "`" + `unit` + "`" + ` (vàng, bạc: chi, luong, g, kg, oz; bỏ trống là đơn vị của tài sản).\n` + "`" + `change` + "`" + `: ` + "`" + `threshold` + "`" + ` là % biến động so với giá lúc tạo. ` + "`" + `repeat` + "`" + ` = false thì báo 1 lần rồi tự tắt.\nGiá cũ/mặc định (stale) không được dùng để báo.",
                 "consumes": [
                     "application/json"
                 ],
@@ -99,28 +290,25 @@ const docTemplate = `{
                     "application/json"
                 ],
                 "tags": [
-                    "Transactions"
+                    "Alerts"
                 ],
-                "summary": "Tạo giao dịch mới",
+                "summary": "Tạo cảnh báo giá",
                 "parameters": [
                     {
-                        "description": "Dữ liệu giao dịch",
+                        "description": "Cảnh báo (id, active, base_price, last_price, created_at bỏ qua)",
                         "name": "payload",
                         "in": "body",
                         "required": true,
                         "schema": {
-                            "$ref": "#/definitions/model.TransactionCreate"
+                            "$ref": "#/definitions/model.PriceAlert"
                         }
                     }
                 ],
                 "responses": {
                     "200": {
-                        "description": "Thành công",
+                        "description": "OK",
                         "schema": {
-                            "type": "object",
-                            "additionalProperties": {
-                                "type": "string"
-                            }
+                            "$ref": "#/definitions/model.PriceAlert"
                         }
                     },
                     "400": {
@@ -137,79 +325,2214 @@ const docTemplate = `{
                     }
                 }
             }
-        }
-    },
-    "definitions": {
-        "model.AssetDetail": {
-            "type": "object",
-            "properties": {
-                "current_vnd": {
-                    "type": "number"
-                },
-                "quantity": {
-                    "type": "number"
-                },
-                "rate": {
-                    "type": "number"
-                }
-            }
         },
-        "model.ExchangeRates": {
-            "type": "object",
-            "properties": {
-                "btc_vnd": {
-                    "type": "number"
-                },
-                "gold_diff": {
-                    "description": "Chênh lệch",
-                    "type": "number"
-                },
-                "gold_usd": {
-                    "type": "number"
-                },
-                "silver_diff": {
-                    "description": "Chênh lệch",
-                    "type": "number"
-                },
-                "silver_usd": {
-                    "type": "number"
-                },
-                "usd_vnd": {
-                    "type": "number"
-                },
-                "vn_silver_est": {
-                    "type": "number"
-                },
-                "vn_sjc": {
-                    "type": "number"
+        "/alerts/notifications": {
+            "get": {
+                "description": "Các lần cảnh báo đã kích hoạt nhưng chưa được gửi, cũ trước. Bot đọc định kỳ, gửi qua Telegram\nrồi gọi ack; bot restart giữa chừng thì thông báo vẫn còn và được gửi lại.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Alerts"
+                ],
+                "summary": "Thông báo cảnh báo giá chờ gửi",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "Số thông báo tối đa (mặc định 20, tối đa 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.AlertNotification"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "limit sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
                 }
             }
         },
-        "model.ReportOutput": {
-            "type": "object",
-            "properties": {
-                "assets": {
-                    "type": "object",
-                    "additionalProperties": {
-                        "$ref": "#/definitions/model.AssetDetail"
+        "/alerts/notifications/{id}/ack": {
+            "post": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Alerts"
+                ],
+                "summary": "Xác nhận đã gửi thông báo cảnh báo giá",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID thông báo",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
                     }
-                },
-                "balance": {
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Đã đánh dấu đã gửi",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "id sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/alerts/{id}": {
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Alerts"
+                ],
+                "summary": "Xóa cảnh báo giá",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID cảnh báo",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Cảnh báo vừa xóa",
+                        "schema": {
+                            "$ref": "#/definitions/model.PriceAlert"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id hoặc id sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Cảnh báo không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/assets": {
+            "get": {
+                "description": "Các loại tiền/tài sản dùng được trong giao dịch và tài khoản (currency), kèm giá VND hiện tại của 1 đơn vị\n(rate_vnd tham chiếu, bid_vnd mua vào, ask_vnd bán ra). has_spread=false: nguồn giá chỉ có giá tham chiếu nên bid_vnd = ask_vnd = rate_vnd.\naliases là cách viết trong tin nhắn bot (VD: \"tk 100 eur\", \"bán 0.5 eth\").\nThêm tài sản tự định nghĩa bằng env CUSTOM_ASSETS (mảng JSON cùng cấu trúc, giá cố định rate.price hoặc mã giá rate.code).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Market Data"
+                ],
+                "summary": "Danh sách tài sản",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.AssetQuote"
+                            }
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budgets"
+                ],
+                "summary": "Danh sách ngân sách",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.Budget"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Đặt hạn mức chi cho một danh mục theo tuần hoặc tháng. Nếu danh mục + kỳ đã có ngân sách thì ghi đè hạn mức.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budgets"
+                ],
+                "summary": "Đặt ngân sách",
+                "parameters": [
+                    {
+                        "description": "Ngân sách (id, created_at bỏ qua)",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Budget"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Budget"
+                        }
+                    },
+                    "400": {
+                        "description": "Lỗi dữ liệu đầu vào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/status": {
+            "get": {
+                "description": "So sánh hạn mức với tổng chi của danh mục trong tuần/tháng hiện tại (theo múi giờ và ngày đầu tuần của user).\nBot gọi API này sau mỗi giao dịch chi để cảnh báo khi vượt ngưỡng.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budgets"
+                ],
+                "summary": "Tình hình ngân sách kỳ hiện tại",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Chỉ lấy ngân sách của danh mục này",
+                        "name": "category",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.BudgetStatus"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/budgets/{id}": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budgets"
+                ],
+                "summary": "Xem ngân sách",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID ngân sách",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Budget"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id hoặc id sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Ngân sách không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Đổi danh mục, kỳ hoặc hạn mức. Trường không gửi sẽ giữ nguyên.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budgets"
+                ],
+                "summary": "Sửa ngân sách",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID ngân sách",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Các trường cần sửa (bắt buộc user_id)",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.Budget"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Budget"
+                        }
+                    },
+                    "400": {
+                        "description": "Lỗi dữ liệu đầu vào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Ngân sách không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "409": {
+                        "description": "Đã có ngân sách khác cho danh mục + kỳ này",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Budgets"
+                ],
+                "summary": "Xóa ngân sách",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID ngân sách",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Ngân sách vừa xóa",
+                        "schema": {
+                            "$ref": "#/definitions/model.Budget"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id hoặc id sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Ngân sách không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/market-rates": {
+            "get": {
+                "description": "Lấy giá Vàng, Bạc, Bitcoin, USD từ các nguồn bên ngoài (CoinGecko, GoldAPI...).\nGiá các mã khác (EUR, JPY, CNY, ETH, USDT...) nằm trong quotes.\nbids/asks là giá mua vào/bán ra theo mã với nguồn có 2 giá (VD: SJC, vn_sjc là giá bán ra).\nGiao dịch mua/tiết kiệm quy đổi theo giá bán ra, rút/bán và định giá tài sản đang giữ theo giá mua vào.\nsources cho biết nguồn và thời điểm lấy của từng giá. stale = true nếu có giá là giá mặc định\n(chưa lấy được giá thật) hoặc đã quá cũ do các nguồn lỗi liên tục.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Market Data"
+                ],
+                "summary": "Lấy tỷ giá thị trường",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ExchangeRates"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi không lấy được dữ liệu",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/market-rates/history": {
+            "get": {
+                "description": "Giá VND của một tài sản theo thời gian, gom theo giờ/ngày/tuần (giờ Việt Nam) để vẽ biểu đồ.\ninterval=raw trả về từng lần lấy giá. Mặc định 30 ngày gần nhất, gom theo ngày.\nMỗi lần lấy tối đa 92 ngày (raw: 7 ngày).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Market Data"
+                ],
+                "summary": "Lịch sử giá thị trường",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "Mã tài sản có nguồn giá (USD, EUR, GOLD, SILVER, BTC, ETH..., xem GET /assets)",
+                        "name": "asset",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Từ ngày (YYYY-MM-DD hoặc RFC3339)",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Đến hết ngày (YYYY-MM-DD hoặc RFC3339)",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "raw",
+                            "hour",
+                            "day",
+                            "week"
+                        ],
+                        "type": "string",
+                        "default": "day",
+                        "description": "Gom nhóm",
+                        "name": "interval",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.RatePoint"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Tham số không hợp lệ hoặc khoảng thời gian quá dài",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/market-rates/providers": {
+            "get": {
+                "description": "Thứ tự nguồn giá (dự phòng) của từng tài sản và tình trạng lần gọi gần nhất của từng nguồn.\nNguồn lỗi liên tục bị tạm ngắt (status circuit_open) tới circuit_open_until rồi mới được gọi lại.\nĐổi nguồn bằng env RATE_PROVIDERS_\u003cASSET\u003e=nguồn1,nguồn2 (VD: RATE_PROVIDERS_BTC=coinbase,coingecko).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Market Data"
+                ],
+                "summary": "Tình trạng nguồn giá",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RateProvidersStatus"
+                        }
+                    }
+                }
+            }
+        },
+        "/portfolio": {
+            "get": {
+                "description": "Tính toàn bộ tài sản tiết kiệm đang giữ (từ trước tới nay, không theo kỳ báo cáo) theo từng đơn vị tiền:\nsố lượng, giá mua bình quân, giá trị theo giá hiện tại, lãi/lỗ và biến động so với 1 ngày/1 tuần/1 tháng trước.\nBiến động chỉ tính phần do giá thay đổi trên số lượng đang giữ, thiếu mốc nào nếu server chưa có giá lịch sử tới mốc đó.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Portfolio"
+                ],
+                "summary": "Danh mục tài sản đang giữ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Portfolio"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/recurring": {
+            "get": {
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recurring"
+                ],
+                "summary": "Danh sách giao dịch định kỳ",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.RecurringRule"
+                            }
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "Tạo quy tắc tự ghi giao dịch theo lịch (VD: tiền nhà 5m mỗi tháng ngày 1). Số tiền tính bằng VND.\nGiao dịch được tạo lúc 0h theo múi giờ của user, lần đầu là lần chạy kế tiếp sau thời điểm tạo.\n` + "`" + `day` + "`" + `: ngày trong tháng (monthly) hoặc thứ 1-7 (weekly, 1 là thứ 2); bỏ trống là lấy theo hôm nay.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recurring"
+                ],
+                "summary": "Tạo giao dịch định kỳ",
+                "parameters": [
+                    {
+                        "description": "Quy tắc (id, timezone, next_run, created_at bỏ qua)",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RecurringRule"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RecurringRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Lỗi dữ liệu đầu vào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/recurring/{id}": {
+            "delete": {
+                "description": "Xóa quy tắc. Các giao dịch đã được tạo trước đó vẫn giữ nguyên.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recurring"
+                ],
+                "summary": "Xóa giao dịch định kỳ",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID quy tắc",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Quy tắc vừa xóa",
+                        "schema": {
+                            "$ref": "#/definitions/model.RecurringRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id hoặc id sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Quy tắc không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Đổi số tiền, ghi chú hoặc tạm dừng (` + "`" + `paused: true` + "`" + `) / chạy lại (` + "`" + `paused: false` + "`" + `).\nKhi chạy lại, các lần đến hạn trong lúc tạm dừng được bỏ qua.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Recurring"
+                ],
+                "summary": "Sửa / tạm dừng giao dịch định kỳ",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID quy tắc",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Các trường cần sửa",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.RecurringRuleUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.RecurringRule"
+                        }
+                    },
+                    "400": {
+                        "description": "Lỗi dữ liệu đầu vào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Quy tắc không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/report": {
+            "get": {
+                "description": "Tổng hợp thu/chi, tính toán số dư và định giá tài sản tích lũy theo thời gian thực.\nChọn kỳ bằng ` + "`" + `period` + "`" + ` (+ ` + "`" + `offset` + "`" + `, VD: period=month\u0026offset=-1 là tháng trước) hoặc khoảng tùy chọn ` + "`" + `from` + "`" + `/` + "`" + `to` + "`" + `.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Reports"
+                ],
+                "summary": "Xuất báo cáo tài chính",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram (VD: 123456789)",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "enum": [
+                            "day",
+                            "week",
+                            "month",
+                            "quarter",
+                            "year"
+                        ],
+                        "type": "string",
+                        "description": "Kỳ báo cáo (mặc định month)",
+                        "name": "period",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Dịch kỳ: 0 là kỳ hiện tại, -1 là kỳ trước...",
+                        "name": "offset",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Từ ngày (YYYY-MM-DD), dùng thay cho period",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Đến ngày (YYYY-MM-DD, tính cả ngày này), mặc định là hiện tại",
+                        "name": "to",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.ReportOutput"
+                        }
+                    },
+                    "400": {
+                        "description": "Tham số không hợp lệ",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions": {
+            "get": {
+                "description": "Lọc, tìm kiếm và phân trang giao dịch của user. Phân trang kiểu cursor: truyền lại ` + "`" + `next_cursor` + "`" + ` của trang trước để lấy trang tiếp theo, hết trang khi ` + "`" + `next_cursor` + "`" + ` rỗng.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transactions"
+                ],
+                "summary": "Danh sách giao dịch",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "Từ ngày (YYYY-MM-DD hoặc RFC3339), tính cả ngày này",
+                        "name": "from",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Đến ngày (YYYY-MM-DD hoặc RFC3339), tính cả ngày này",
+                        "name": "to",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "thu",
+                            "chi",
+                            "tiet_kiem",
+                            "rut",
+                            "chuyen"
+                        ],
+                        "type": "string",
+                        "description": "Loại giao dịch",
+                        "name": "type",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Danh mục",
+                        "name": "category",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Đơn vị tiền",
+                        "name": "currency",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Số tiền VND tối thiểu",
+                        "name": "min_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "number",
+                        "description": "Số tiền VND tối đa",
+                        "name": "max_amount",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "Tìm theo từ trong ghi chú",
+                        "name": "q",
+                        "in": "query"
+                    },
+                    {
+                        "enum": [
+                            "asc",
+                            "desc"
+                        ],
+                        "type": "string",
+                        "description": "Thứ tự thời gian (mặc định desc)",
+                        "name": "sort",
+                        "in": "query"
+                    },
+                    {
+                        "type": "string",
+                        "description": "next_cursor của trang trước",
+                        "name": "cursor",
+                        "in": "query"
+                    },
+                    {
+                        "type": "integer",
+                        "description": "Số bản ghi mỗi trang (mặc định 20, tối đa 100)",
+                        "name": "limit",
+                        "in": "query"
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionPage"
+                        }
+                    },
+                    "400": {
+                        "description": "Tham số không hợp lệ",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "post": {
+                "description": "API nhận dữ liệu giao dịch. Hỗ trợ tự động quy đổi tỷ giá nếu dùng ngoại tệ.\n\n### 💡 HƯỚNG DẪN TEST NHANH (Copy JSON bên dưới dán vào ô Request):\n\n**1️⃣ Trường hợp: CHI TIÊU (VND)**\n` + "`" + `` + "`" + `` + "`" + `json\n{\n\"user_id\": \"123456789\",\n\"type\": \"chi\",\n\"amount\": 55000,\n\"note\": \"Ăn trưa cơm tấm\",\n\"category\": \"ăn uống\",\n\"currency\": \"VND\"\n}\n` + "`" + `` + "`" + `` + "`" + `\n\n**2️⃣ Trường hợp: THU NHẬP (VND)**\n` + "`" + `` + "`" + `` + "`" + `json\n{\n\"user_id\": \"123456789\",\n\"type\": \"thu\",\n\"amount\": 15000000,\n\"note\": \"Lương tháng 12\",\n\"currency\": \"VND\"\n}\n` + "`" + `` + "`" + `` + "`" + `\n\n**3️⃣ Trường hợp: TIẾT KIỆM (Vàng/Ngoại tệ)**\n_(Hệ thống sẽ tự quy đổi ra VND theo tỷ giá hiện tại)_\n` + "`" + `` + "`" + `` + "`" + `json\n{\n\"user_id\": \"123456789\",\n\"type\": \"tiet_kiem\",\n\"amount\": 2,\n\"note\": \"Mua 2 chỉ vàng tích trữ\",\n\"currency\": \"GOLD\"\n}\n` + "`" + `` + "`" + `` + "`" + `\n_(Vàng/bạc nhập theo đơn vị khác thì gửi thêm \"unit\": chi, luong, g, kg, oz. Số lượng được quy về chỉ với vàng, lượng với bạc)_\n\n**Ghi bù giao dịch cũ:** gửi thêm ` + "`" + `\"occurred_at\": \"2025-03-15T20:00:00+07:00\"` + "`" + ` (không được ở tương lai hoặc quá 5 năm trước).\nNgoại tệ/vàng được quy đổi theo bảng giá đã lưu gần nhất trước thời điểm đó.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transactions"
+                ],
+                "summary": "Tạo giao dịch mới",
+                "parameters": [
+                    {
+                        "description": "Dữ liệu giao dịch",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionCreate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "Thành công, kèm id giao dịch vừa tạo",
+                        "schema": {
+                            "type": "object",
+                            "additionalProperties": true
+                        }
+                    },
+                    "400": {
+                        "description": "Lỗi dữ liệu đầu vào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/last": {
+            "delete": {
+                "description": "Xóa giao dịch được ghi gần nhất của user (dùng cho lệnh /undo của bot).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transactions"
+                ],
+                "summary": "Hoàn tác giao dịch gần nhất",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "User chưa có giao dịch nào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/transactions/{id}": {
+            "get": {
+                "description": "Lấy một giao dịch theo id. Chỉ chủ sở hữu (user_id) mới xem được.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transactions"
+                ],
+                "summary": "Xem chi tiết giao dịch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID giao dịch",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id hoặc id sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Giao dịch không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "delete": {
+                "description": "Xóa một giao dịch theo id. Trả về giao dịch vừa xóa.",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transactions"
+                ],
+                "summary": "Xóa giao dịch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID giao dịch",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "user_id",
+                        "in": "query",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Thiếu user_id hoặc id sai",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Giao dịch không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "patch": {
+                "description": "Sửa một phần giao dịch (chỉ gửi các trường cần đổi). Nếu đổi số tiền, đơn vị hoặc loại giao dịch, hệ thống quy đổi lại VND theo bảng giá tại thời điểm phát sinh giao dịch.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Transactions"
+                ],
+                "summary": "Sửa giao dịch",
+                "parameters": [
+                    {
+                        "type": "integer",
+                        "description": "ID giao dịch",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Các trường cần sửa",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.TransactionUpdate"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.Transaction"
+                        }
+                    },
+                    "400": {
+                        "description": "Lỗi dữ liệu đầu vào",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "403": {
+                        "description": "Giao dịch không thuộc về user",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "404": {
+                        "description": "Không tìm thấy",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/settings": {
+            "get": {
+                "description": "Dùng cho scheduler của bot: trả về múi giờ của mọi user (đã có giao dịch hoặc đã cài đặt).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Cài đặt của tất cả user",
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "type": "array",
+                            "items": {
+                                "$ref": "#/definitions/model.UserSettings"
+                            }
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        },
+        "/users/{id}/settings": {
+            "get": {
+                "description": "Trả về múi giờ, ngày đầu tuần, cách tính giá vốn và đơn vị hiển thị vàng, bạc của user (giá trị mặc định nếu user chưa cài đặt).",
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Xem cài đặt của user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserSettings"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            },
+            "put": {
+                "description": "Đổi múi giờ (tên IANA, VD: Asia/Ho_Chi_Minh), ngày đầu tuần, cách tính giá vốn (average, fifo)\nvà/hoặc đơn vị hiển thị vàng, bạc (chi, luong, g, kg, oz). Trường không gửi sẽ giữ nguyên.",
+                "consumes": [
+                    "application/json"
+                ],
+                "produces": [
+                    "application/json"
+                ],
+                "tags": [
+                    "Users"
+                ],
+                "summary": "Cập nhật cài đặt của user",
+                "parameters": [
+                    {
+                        "type": "string",
+                        "description": "ID người dùng Telegram",
+                        "name": "id",
+                        "in": "path",
+                        "required": true
+                    },
+                    {
+                        "description": "Cài đặt mới",
+                        "name": "payload",
+                        "in": "body",
+                        "required": true,
+                        "schema": {
+                            "$ref": "#/definitions/model.UserSettings"
+                        }
+                    }
+                ],
+                "responses": {
+                    "200": {
+                        "description": "OK",
+                        "schema": {
+                            "$ref": "#/definitions/model.UserSettings"
+                        }
+                    },
+                    "400": {
+                        "description": "Múi giờ, ngày đầu tuần, cách tính giá vốn hoặc đơn vị không hợp lệ",
+                        "schema": {
+                            "type": "string"
+                        }
+                    },
+                    "500": {
+                        "description": "Lỗi Server",
+                        "schema": {
+                            "type": "string"
+                        }
+                    }
+                }
+            }
+        }
+    },
+    "definitions": {
+        "model.Account": {
+            "type": "object",
+            "properties": {
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "Mã tài sản của tài khoản: VND, USD, BTC, GOLD... (xem GET /assets)",
+                    "type": "string",
+                    "example": "VND"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "name": {
+                    "description": "Tên ngắn, duy nhất với mỗi user, dùng trong tin nhắn dạng @momo",
+                    "type": "string",
+                    "example": "momo"
+                },
+                "opening_balance": {
+                    "description": "Số dư lúc bắt đầu theo dõi",
+                    "type": "number",
+                    "example": 500000
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.AccountBalance": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "$ref": "#/definitions/model.Account"
+                },
+                "balance": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.AlertNotification": {
+            "type": "object",
+            "properties": {
+                "alert_id": {
+                    "type": "integer"
+                },
+                "asset": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "base_price": {
+                    "description": "change: giá gốc lúc kích hoạt",
+                    "type": "number"
+                },
+                "condition": {
+                    "type": "string",
+                    "enum": [
+                        "above",
+                        "below",
+                        "change"
+                    ],
+                    "example": "above"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "price": {
+                    "description": "Giá lúc kích hoạt (theo unit)",
+                    "type": "number"
+                },
+                "repeat": {
+                    "type": "boolean"
+                },
+                "sent_at": {
+                    "type": "string"
+                },
+                "threshold": {
+                    "type": "number"
+                },
+                "unit": {
+                    "type": "string",
+                    "example": "luong"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.AssetDetail": {
+            "type": "object",
+            "properties": {
+                "current_vnd": {
+                    "type": "number"
+                },
+                "display_quantity": {
+                    "type": "number"
+                },
+                "display_rate": {
+                    "type": "number"
+                },
+                "display_unit": {
+                    "description": "Số lượng và giá theo đơn vị hiển thị user chọn (vàng/bạc theo gold_unit/silver_unit)",
+                    "type": "string",
+                    "example": "lượng"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "stale": {
+                    "description": "Rate là giá cũ/mặc định, CurrentVND có thể sai",
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.AssetHolding": {
+            "type": "object",
+            "properties": {
+                "cost_vnd": {
+                    "description": "Giá vốn của số đang giữ",
+                    "type": "number"
+                },
+                "current_vnd": {
+                    "type": "number"
+                },
+                "display_quantity": {
+                    "type": "number"
+                },
+                "display_rate": {
+                    "type": "number"
+                },
+                "display_unit": {
+                    "description": "Số lượng và giá theo đơn vị hiển thị user chọn",
+                    "type": "string",
+                    "example": "lượng"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "realized_pl": {
+                    "description": "Lãi/lỗ của các lần rút/bán trong kỳ",
+                    "type": "number"
+                },
+                "stale": {
+                    "description": "Rate là giá cũ/mặc định",
+                    "type": "boolean"
+                },
+                "unrealized_pl": {
+                    "description": "CurrentVND - CostVND",
+                    "type": "number"
+                }
+            }
+        },
+        "model.AssetQuote": {
+            "type": "object",
+            "properties": {
+                "aliases": {
+                    "description": "Cách viết trong tin nhắn (không phân biệt hoa thường): \"eur\", \"euro\", \"€\"",
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    }
+                },
+                "ask_vnd": {
+                    "description": "Giá bán ra, dùng để quy đổi khi mua",
+                    "type": "number"
+                },
+                "bid_vnd": {
+                    "description": "Giá mua vào, dùng để định giá tài sản đang giữ",
+                    "type": "number"
+                },
+                "code": {
+                    "description": "Mã lưu trong transaction.currency",
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "has_spread": {
+                    "description": "false nếu nguồn giá không có giá mua/bán riêng: bid_vnd và ask_vnd chỉ là giá tham chiếu",
+                    "type": "boolean"
+                },
+                "kind": {
+                    "type": "string",
+                    "enum": [
+                        "fiat",
+                        "crypto",
+                        "metal",
+                        "custom"
+                    ],
+                    "example": "fiat"
+                },
+                "name": {
+                    "description": "Tên hiển thị",
+                    "type": "string",
+                    "example": "Euro"
+                },
+                "rate": {
+                    "$ref": "#/definitions/model.AssetRate"
+                },
+                "rate_vnd": {
+                    "type": "number"
+                },
+                "stale": {
+                    "type": "boolean"
+                },
+                "unit": {
+                    "description": "Đơn vị của 1 số lượng: \"chỉ\", \"lượng\", \"EUR\"...",
+                    "type": "string",
+                    "example": "EUR"
+                }
+            }
+        },
+        "model.AssetRate": {
+            "type": "object",
+            "properties": {
+                "code": {
+                    "description": "Mã giá trong ExchangeRates, lấy từ nguồn giá RATE_PROVIDERS_\u003cCODE\u003e",
+                    "type": "string",
+                    "example": "EUR"
+                },
+                "factor": {
+                    "description": "0 là 1",
+                    "type": "number",
+                    "example": 1
+                },
+                "price": {
+                    "description": "Giá VND cố định (tài sản tự định nghĩa không có nguồn giá)",
+                    "type": "number"
+                },
+                "quote": {
+                    "description": "Đơn vị của giá Code, mặc định VND",
+                    "type": "string",
+                    "enum": [
+                        "VND",
+                        "USD"
+                    ],
+                    "example": "VND"
+                }
+            }
+        },
+        "model.Budget": {
+            "type": "object",
+            "properties": {
+                "category": {
+                    "description": "Danh mục chi tiêu, khớp với category của giao dịch chi",
+                    "type": "string",
+                    "example": "ăn uống"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "limit": {
+                    "description": "Hạn mức VND cho mỗi kỳ",
+                    "type": "number",
+                    "example": 3000000
+                },
+                "period": {
+                    "description": "Kỳ ngân sách: week hoặc month",
+                    "type": "string",
+                    "enum": [
+                        "week",
+                        "month"
+                    ],
+                    "example": "month"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.BudgetStatus": {
+            "type": "object",
+            "properties": {
+                "budget": {
+                    "$ref": "#/definitions/model.Budget"
+                },
+                "end_date": {
+                    "type": "string"
+                },
+                "percent": {
+                    "description": "Spent / Limit * 100, làm tròn 1 số lẻ",
+                    "type": "number"
+                },
+                "remaining": {
+                    "description": "Âm nghĩa là đã vượt",
+                    "type": "number"
+                },
+                "spent": {
+                    "type": "number"
+                },
+                "start_date": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ExchangeRates": {
+            "type": "object",
+            "properties": {
+                "asks": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "bids": {
+                    "description": "Giá mua vào (bid, giá nhận được khi bán ra) và giá bán ra (ask, giá phải trả khi mua) theo mã giá.\nChỉ có với nguồn trả cả 2 giá (hiện chỉ vangtoday cho SJC), thiếu thì dùng giá theo Quote",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "btc_vnd": {
+                    "type": "number"
+                },
+                "gold_diff": {
+                    "description": "Chênh lệch",
+                    "type": "number"
+                },
+                "gold_usd": {
+                    "type": "number"
+                },
+                "quotes": {
+                    "description": "Giá theo mã của các tài sản khác (EUR, JPY, ETH...), đơn vị theo AssetRate.Quote của tài sản",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "number",
+                        "format": "float64"
+                    }
+                },
+                "silver_diff": {
+                    "description": "Chênh lệch",
+                    "type": "number"
+                },
+                "silver_usd": {
+                    "type": "number"
+                },
+                "sources": {
+                    "description": "Nguồn của từng giá theo mã: USD (usd_vnd), XAU (gold_usd), XAG (silver_usd, vn_silver_est), SJC (vn_sjc), BTC (btc_vnd),\ncác mã khác theo quotes",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.RateSource"
+                    }
+                },
+                "stale": {
+                    "description": "Có ít nhất 1 giá đã cũ hoặc là giá mặc định dựng sẵn",
+                    "type": "boolean"
+                },
+                "usd_vnd": {
+                    "type": "number"
+                },
+                "vn_silver_est": {
+                    "type": "number"
+                },
+                "vn_sjc": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.Portfolio": {
+            "type": "object",
+            "properties": {
+                "as_of": {
+                    "type": "string"
+                },
+                "changes": {
+                    "description": "Biến động giá trị do giá thay đổi theo day, week, month. Thiếu key nếu chưa có giá lịch sử",
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.PriceChange"
+                    }
+                },
+                "cost_basis": {
+                    "type": "string",
+                    "enum": [
+                        "average",
+                        "fifo"
+                    ],
+                    "example": "average"
+                },
+                "holdings": {
+                    "description": "Sắp xếp theo mã tiền",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.PortfolioHolding"
+                    }
+                },
+                "rates_stale": {
+                    "description": "Có tài sản được định giá bằng giá cũ/mặc định",
+                    "type": "boolean"
+                },
+                "total_cost_vnd": {
+                    "type": "number"
+                },
+                "total_current_vnd": {
+                    "type": "number"
+                },
+                "total_realized_pl": {
+                    "type": "number"
+                },
+                "total_unrealized_pl": {
+                    "type": "number"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.PortfolioHolding": {
+            "type": "object",
+            "properties": {
+                "avg_price_vnd": {
+                    "description": "Giá mua bình quân của số đang giữ",
+                    "type": "number"
+                },
+                "changes": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.PriceChange"
+                    }
+                },
+                "cost_vnd": {
+                    "type": "number"
+                },
+                "currency": {
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "current_vnd": {
+                    "type": "number"
+                },
+                "display_avg_price": {
+                    "description": "Giá mua bình quân theo đơn vị hiển thị",
+                    "type": "number"
+                },
+                "display_quantity": {
+                    "type": "number"
+                },
+                "display_rate": {
+                    "type": "number"
+                },
+                "display_unit": {
+                    "description": "Số lượng và giá theo đơn vị hiển thị user chọn",
+                    "type": "string",
+                    "example": "lượng"
+                },
+                "quantity": {
+                    "type": "number"
+                },
+                "rate": {
+                    "type": "number"
+                },
+                "realized_pl": {
+                    "description": "Tổng lãi/lỗ các lần rút/bán từ trước tới nay",
+                    "type": "number"
+                },
+                "stale": {
+                    "description": "Rate là giá cũ/mặc định",
+                    "type": "boolean"
+                },
+                "unrealized_pl": {
+                    "type": "number"
+                }
+            }
+        },
+        "model.PriceAlert": {
+            "type": "object",
+            "properties": {
+                "active": {
+                    "type": "boolean"
+                },
+                "asset": {
+                    "description": "Mã tài sản (xem GET /assets)",
+                    "type": "string",
+                    "example": "GOLD"
+                },
+                "base_price": {
+                    "description": "change: giá gốc để tính %",
+                    "type": "number"
+                },
+                "condition": {
+                    "type": "string",
+                    "enum": [
+                        "above",
+                        "below",
+                        "change"
+                    ],
+                    "example": "above"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "last_price": {
+                    "description": "Giá (theo unit) lần kiểm tra gần nhất, 0 nếu chưa kiểm tra",
+                    "type": "number"
+                },
+                "last_triggered_at": {
+                    "type": "string"
+                },
+                "repeat": {
+                    "description": "false: báo 1 lần rồi tự tắt. true: above/below báo lại mỗi lần giá vượt mốc lại từ phía bên kia,\nchange tính lại từ giá lúc báo",
+                    "type": "boolean"
+                },
+                "threshold": {
+                    "description": "VND/1 unit với above, below; % với change",
+                    "type": "number",
+                    "example": 90000000
+                },
+                "unit": {
+                    "description": "Đơn vị khối lượng của giá với vàng, bạc (chi, luong, g, kg, oz), rỗng là đơn vị của tài sản",
+                    "type": "string",
+                    "example": "luong"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.PriceChange": {
+            "type": "object",
+            "properties": {
+                "change_vnd": {
+                    "type": "number"
+                },
+                "percent": {
+                    "type": "number"
+                },
+                "rate": {
+                    "description": "Giá lúc Since, 0 ở phần tổng",
+                    "type": "number"
+                },
+                "since": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.ProviderHealth": {
+            "type": "object",
+            "properties": {
+                "assets": {
+                    "type": "array",
+                    "items": {
+                        "type": "string"
+                    },
+                    "example": [
+                        "BTC"
+                    ]
+                },
+                "circuit_open_until": {
+                    "type": "string"
+                },
+                "consecutive_failures": {
+                    "type": "integer"
+                },
+                "last_error": {
+                    "type": "string"
+                },
+                "last_error_at": {
+                    "type": "string"
+                },
+                "last_success_at": {
+                    "type": "string"
+                },
+                "name": {
+                    "type": "string",
+                    "example": "coingecko"
+                },
+                "status": {
+                    "type": "string",
+                    "enum": [
+                        "unknown",
+                        "ok",
+                        "failing",
+                        "circuit_open"
+                    ],
+                    "example": "ok"
+                }
+            }
+        },
+        "model.RatePoint": {
+            "type": "object",
+            "properties": {
+                "close": {
+                    "type": "number"
+                },
+                "high": {
+                    "type": "number"
+                },
+                "low": {
+                    "type": "number"
+                },
+                "open": {
+                    "type": "number"
+                },
+                "samples": {
+                    "description": "Số lần lấy giá trong khoảng",
+                    "type": "integer"
+                },
+                "time": {
+                    "description": "Đầu khoảng, hoặc thời điểm lấy giá nếu interval=raw",
+                    "type": "string"
+                }
+            }
+        },
+        "model.RateProvidersStatus": {
+            "type": "object",
+            "properties": {
+                "chains": {
+                    "description": "Tài sản (USD, XAU, XAG, SJC, BTC) -\u003e nguồn theo thứ tự ưu tiên",
+                    "type": "object",
+                    "additionalProperties": {
+                        "type": "array",
+                        "items": {
+                            "type": "string"
+                        }
+                    }
+                },
+                "providers": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.ProviderHealth"
+                    }
+                }
+            }
+        },
+        "model.RateSource": {
+            "type": "object",
+            "properties": {
+                "fetched_at": {
+                    "description": "nil với giá mặc định",
+                    "type": "string"
+                },
+                "source": {
+                    "type": "string",
+                    "example": "coingecko"
+                },
+                "stale": {
+                    "type": "boolean"
+                }
+            }
+        },
+        "model.RecurringRule": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "description": "Luôn là VND",
+                    "type": "number",
+                    "example": 5000000
+                },
+                "category": {
+                    "type": "string",
+                    "example": "sinh hoạt"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "day": {
+                    "description": "monthly: ngày trong tháng 1-31 (tháng thiếu ngày thì chạy ngày cuối tháng);\nweekly: thứ theo ISO 1 (thứ 2) - 7 (chủ nhật); 0 là lấy theo ngày tạo",
+                    "type": "integer",
+                    "example": 1
+                },
+                "frequency": {
+                    "description": "Tần suất: daily, weekly, monthly",
+                    "type": "string",
+                    "enum": [
+                        "daily",
+                        "weekly",
+                        "monthly"
+                    ],
+                    "example": "monthly"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "next_run": {
+                    "description": "Lần tạo giao dịch kế tiếp",
+                    "type": "string"
+                },
+                "note": {
+                    "type": "string",
+                    "example": "tiền nhà"
+                },
+                "paused": {
+                    "type": "boolean"
+                },
+                "timezone": {
+                    "description": "Múi giờ tính lịch chạy, lấy theo cài đặt của user lúc tạo",
+                    "type": "string",
+                    "example": "Asia/Ho_Chi_Minh"
+                },
+                "type": {
+                    "type": "string",
+                    "enum": [
+                        "thu",
+                        "chi",
+                        "tiet_kiem"
+                    ],
+                    "example": "chi"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.RecurringRuleUpdate": {
+            "type": "object",
+            "properties": {
+                "amount": {
+                    "type": "number"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "paused": {
+                    "type": "boolean"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.ReportOutput": {
+            "type": "object",
+            "properties": {
+                "accounts": {
+                    "description": "Số dư các tài khoản tại cuối kỳ",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.AccountBalance"
+                    }
+                },
+                "assets": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.AssetDetail"
+                    }
+                },
+                "balance": {
+                    "description": "Thu - Chi - Tiết kiệm + Rút",
                     "type": "number"
                 },
+                "budgets": {
+                    "description": "Ngân sách cùng kỳ (week/month) so với thực chi",
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.BudgetStatus"
+                    }
+                },
+                "cost_basis": {
+                    "description": "Tài sản đang giữ tại cuối kỳ (tính từ mọi giao dịch tiết kiệm/rút) kèm lãi/lỗ",
+                    "type": "string"
+                },
+                "end_date": {
+                    "description": "Ngày cuối cùng của kỳ (tính cả ngày này)",
+                    "type": "string"
+                },
                 "expense_by_category": {
                     "type": "object",
                     "additionalProperties": {
-                        "type": "number",
-                        "format": "float64"
+                        "type": "number"
+                    }
+                },
+                "holdings": {
+                    "type": "object",
+                    "additionalProperties": {
+                        "$ref": "#/definitions/model.AssetHolding"
                     }
                 },
                 "period": {
                     "type": "string"
                 },
+                "rates_stale": {
+                    "description": "Có tài sản được định giá bằng giá cũ/mặc định (xem stale của từng tài sản)",
+                    "type": "boolean"
+                },
                 "start_date": {
                     "type": "string"
                 },
+                "timezone": {
+                    "description": "Múi giờ dùng để tính ranh giới kỳ",
+                    "type": "string"
+                },
                 "total_assets_vnd": {
                     "type": "number"
                 },
@@ -219,53 +2542,255 @@ const docTemplate = `{
                 "total_income": {
                     "type": "number"
                 },
+                "total_realized_pl": {
+                    "type": "number"
+                },
                 "total_savings_vnd": {
                     "type": "number"
+                },
+                "total_unrealized_pl": {
+                    "type": "number"
+                },
+                "total_withdrawn_vnd": {
+                    "description": "Tiền nhận về khi rút/bán tài sản tiết kiệm",
+                    "type": "number"
+                }
+            }
+        },
+        "model.Transaction": {
+            "type": "object",
+            "properties": {
+                "account_id": {
+                    "description": "Tài khoản trả/nhận tiền, nil là không gắn tài khoản",
+                    "type": "integer"
+                },
+                "amount": {
+                    "description": "Giá trị quy đổi VND",
+                    "type": "number"
+                },
+                "amount_expr": {
+                    "description": "Biểu thức gốc của số tiền (\"3x25k\"), rỗng nếu nhập số",
+                    "type": "string"
+                },
+                "category": {
+                    "description": "Có thể rỗng",
+                    "type": "string"
+                },
+                "created_at": {
+                    "type": "string"
+                },
+                "currency": {
+                    "description": "VND, USD, BTC, GOLD",
+                    "type": "string"
+                },
+                "id": {
+                    "type": "integer"
+                },
+                "note": {
+                    "type": "string"
+                },
+                "original_amount": {
+                    "description": "Số lượng gốc",
+                    "type": "number"
+                },
+                "rate_snapshot_id": {
+                    "description": "Bản ghi giá đã dùng để quy đổi ra VND",
+                    "type": "integer"
+                },
+                "to_account_id": {
+                    "description": "Tài khoản nhận của giao dịch chuyen",
+                    "type": "integer"
+                },
+                "type": {
+                    "description": "thu, chi, tiet_kiem, rut, chuyen",
+                    "type": "string"
+                },
+                "user_id": {
+                    "type": "string"
                 }
             }
         },
         "model.TransactionCreate": {
             "type": "object",
             "properties": {
+                "account": {
+                    "description": "Tên tài khoản trả/nhận tiền (tùy chọn). Với chuyen là tài khoản chuyển đi",
+                    "type": "string",
+                    "example": "momo"
+                },
                 "amount": {
-                    "description": "Số tiền (Nếu là VND thì nhập tiền Việt, nếu là GOLD thì nhập số chỉ/lượng)",
+                    "description": "Số tiền (Nếu là VND thì nhập tiền Việt, nếu là GOLD thì nhập số chỉ, SILVER số lượng)",
                     "type": "number",
                     "example": 50000
                 },
+                "amount_expr": {
+                    "description": "Biểu thức gốc đã tính ra amount (\"3x25k\", \"120k/4\"), lưu lại để đối chiếu. Rỗng nếu nhập số",
+                    "type": "string",
+                    "example": "3x25k"
+                },
                 "category": {
                     "description": "Danh mục chi tiêu (ăn uống, đi lại...)",
                     "type": "string",
                     "example": "ăn uống"
                 },
                 "currency": {
-                    "description": "Đơn vị tiền: VND, USD, BTC, GOLD",
+                    "description": "Mã tài sản: VND, USD, EUR, BTC, GOLD... (xem GET /assets). Rỗng là VND",
+                    "type": "string",
+                    "example": "VND"
+                },
+                "note": {
+                    "description": "Ghi chú chi tiết",
+                    "type": "string",
+                    "example": "Cà phê sáng"
+                },
+                "occurred_at": {
+                    "description": "Thời điểm phát sinh giao dịch (ghi bù giao dịch cũ). Bỏ trống là thời điểm gửi lên,\nkhông được ở tương lai hoặc quá 5 năm trước",
+                    "type": "string",
+                    "example": "2025-03-15T20:00:00+07:00"
+                },
+                "to_account": {
+                    "description": "Tên tài khoản nhận, chỉ dùng cho chuyen",
+                    "type": "string",
+                    "example": "vcb"
+                },
+                "type": {
+                    "description": "Loại giao dịch: thu, chi, tiet_kiem, rut (rút/bán tài sản tiết kiệm),\nchuyen (chuyển tiền giữa 2 tài khoản, không tính là thu/chi)",
                     "type": "string",
                     "enum": [
-                        "VND",
-                        "USD",
-                        "BTC",
-                        "GOLD"
+                        "thu",
+                        "chi",
+                        "tiet_kiem",
+                        "rut",
+                        "chuyen"
                     ],
+                    "example": "chi"
+                },
+                "unit": {
+                    "description": "Đơn vị khối lượng của amount với GOLD, SILVER: chi, luong, g, kg, oz. Rỗng là đơn vị gốc (chỉ, lượng)",
+                    "type": "string",
+                    "example": "g"
+                },
+                "user_id": {
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.TransactionPage": {
+            "type": "object",
+            "properties": {
+                "items": {
+                    "type": "array",
+                    "items": {
+                        "$ref": "#/definitions/model.Transaction"
+                    }
+                },
+                "next_cursor": {
+                    "type": "string"
+                }
+            }
+        },
+        "model.TransactionUpdate": {
+            "type": "object",
+            "properties": {
+                "account": {
+                    "description": "Tên tài khoản, chuỗi rỗng là bỏ gắn tài khoản",
+                    "type": "string",
+                    "example": "momo"
+                },
+                "amount": {
+                    "type": "number",
+                    "example": 50000
+                },
+                "amount_expr": {
+                    "description": "Biểu thức gốc của amount mới (\"120k/4\"). Đổi amount mà không gửi trường này thì xóa biểu thức cũ",
+                    "type": "string",
+                    "example": "120k/4"
+                },
+                "category": {
+                    "type": "string",
+                    "example": "ăn uống"
+                },
+                "currency": {
+                    "type": "string",
                     "example": "VND"
                 },
                 "note": {
-                    "description": "Ghi chú chi tiết",
                     "type": "string",
                     "example": "Cà phê sáng"
                 },
+                "to_account": {
+                    "type": "string",
+                    "example": "vcb"
+                },
                 "type": {
-                    "description": "Loại giao dịch: thu, chi, tiet_kiem",
                     "type": "string",
                     "enum": [
                         "thu",
                         "chi",
-                        "tiet_kiem"
+                        "tiet_kiem",
+                        "rut",
+                        "chuyen"
+                    ],
+                    "example": "chi"
+                },
+                "user_id": {
+                    "description": "Chủ sở hữu giao dịch, bắt buộc để kiểm tra quyền",
+                    "type": "string",
+                    "example": "123456789"
+                }
+            }
+        },
+        "model.UserSettings": {
+            "type": "object",
+            "properties": {
+                "cost_basis": {
+                    "description": "Cách tính giá vốn khi rút/bán tài sản tiết kiệm: average (bình quân) hoặc fifo (nhập trước xuất trước)",
+                    "type": "string",
+                    "enum": [
+                        "average",
+                        "fifo"
+                    ],
+                    "example": "average"
+                },
+                "gold_unit": {
+                    "description": "Đơn vị hiển thị vàng, bạc trong báo cáo: chi, luong, g, kg, oz (gửi lên nhận cả \"chỉ\", \"lượng\", \"cây\", \"gram\")",
+                    "type": "string",
+                    "enum": [
+                        "chi",
+                        "luong",
+                        "g",
+                        "kg",
+                        "oz"
                     ],
                     "example": "chi"
                 },
+                "silver_unit": {
+                    "type": "string",
+                    "enum": [
+                        "chi",
+                        "luong",
+                        "g",
+                        "kg",
+                        "oz"
+                    ],
+                    "example": "luong"
+                },
+                "timezone": {
+                    "description": "Múi giờ IANA, VD: Asia/Ho_Chi_Minh, Europe/Berlin",
+                    "type": "string",
+                    "example": "Asia/Ho_Chi_Minh"
+                },
                 "user_id": {
                     "type": "string",
                     "example": "123456789"
+                },
+                "week_start": {
+                    "description": "Ngày đầu tuần: 0 = Chủ nhật, 1 = Thứ 2, ... 6 = Thứ 7",
+                    "type": "integer",
+                    "maximum": 6,
+                    "minimum": 0,
+                    "example": 1
                 }
             }
         }
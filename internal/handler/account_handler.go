@@ -0,0 +1,343 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/store"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// normalizeAccountName tên tài khoản không phân biệt hoa thường, bỏ @ ở đầu nếu có
+func normalizeAccountName(name string) string {
+	return strings.ToLower(strings.TrimPrefix(strings.TrimSpace(name), "@"))
+}
+
+// validateAccount chuẩn hóa và kiểm tra tài khoản trước khi lưu. Trả về thông báo lỗi nếu không hợp lệ
+func validateAccount(a *model.Account) string {
+	a.Name = normalizeAccountName(a.Name)
+	if a.Name == "" || strings.ContainsAny(a.Name, " \t,@") {
+		return "name is required and must not contain spaces, commas or @"
+	}
+	currency, err := normalizeCurrency(a.Currency)
+	if err != nil {
+		return err.Error()
+	}
+	a.Currency = currency
+	return ""
+}
+
+// accountAmount số tiền giao dịch tính theo đơn vị của tài khoản: đúng số lượng gốc nếu cùng
+// đơn vị, giá trị VND nếu tài khoản là VND. ok=false nếu tài khoản không nhận được đơn vị này
+func accountAmount(t model.Transaction, a model.Account) (decimal.Decimal, bool) {
+	switch {
+	case t.Currency == a.Currency:
+		return t.OriginalAmount, true
+	case a.Currency == "VND":
+		return t.Amount, true
+	}
+	return decimal.Zero, false
+}
+
+// accountDelta số tiền giao dịch làm tài khoản a tăng (dương) hoặc giảm (âm)
+func accountDelta(t model.Transaction, a model.Account) decimal.Decimal {
+	amount, ok := accountAmount(t, a)
+	if !ok {
+		return decimal.Zero
+	}
+	delta := decimal.Zero
+	if t.AccountID != nil && *t.AccountID == a.ID {
+		if t.Type == "thu" || t.Type == "rut" { // rut: tiền bán tài sản về tài khoản
+			delta = delta.Add(amount)
+		} else { // chi, tiet_kiem (tiền đem đi cất), chuyen (chuyển đi)
+			delta = delta.Sub(amount)
+		}
+	}
+	if t.Type == "chuyen" && t.ToAccountID != nil && *t.ToAccountID == a.ID {
+		delta = delta.Add(amount)
+	}
+	return delta
+}
+
+// accountBalances số dư mọi tài khoản của user tính đến trước thời điểm until (zero là đến hiện tại)
+func (h *FinanceHandler) accountBalances(userID string, until time.Time) ([]model.AccountBalance, error) {
+	accounts, err := h.Store.ListAccounts(userID)
+	if err != nil || len(accounts) == 0 {
+		return nil, err
+	}
+	txs, err := h.Store.GetByPeriod(userID, time.Time{}, until)
+	if err != nil {
+		return nil, err
+	}
+
+	balances := make([]model.AccountBalance, 0, len(accounts))
+	for _, a := range accounts {
+		bal := a.OpeningBalance
+		for _, t := range txs {
+			bal = bal.Add(accountDelta(t, a))
+		}
+		balances = append(balances, model.AccountBalance{Account: a, Balance: bal})
+	}
+	return balances, nil
+}
+
+// resolveTransactionAccounts gắn tài khoản (theo tên) vào giao dịch và kiểm tra quy tắc:
+// chuyen phải có 2 tài khoản khác nhau, loại khác không có tài khoản nhận, và tài khoản
+// phải nhận được đơn vị tiền của giao dịch. Trả về lỗi để báo 400 cho client
+func (h *FinanceHandler) resolveTransactionAccounts(t *model.Transaction, account, toAccount string) error {
+	t.AccountID, t.ToAccountID = nil, nil
+	account, toAccount = normalizeAccountName(account), normalizeAccountName(toAccount)
+
+	if t.Type == "chuyen" {
+		if account == "" || toAccount == "" {
+			return errors.New("chuyen requires account and to_account")
+		}
+		if account == toAccount {
+			return errors.New("account and to_account must be different")
+		}
+	} else if toAccount != "" {
+		return errors.New("to_account is only allowed for chuyen")
+	}
+
+	for _, ref := range []struct {
+		name string
+		id   **int
+	}{{account, &t.AccountID}, {toAccount, &t.ToAccountID}} {
+		if ref.name == "" {
+			continue
+		}
+		a, err := h.Store.GetAccountByName(t.UserID, ref.name)
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("account %q not found", ref.name)
+		}
+		if err != nil {
+			return err
+		}
+		if _, ok := accountAmount(*t, a); !ok {
+			return fmt.Errorf("account %q (%s) cannot hold %s", a.Name, a.Currency, t.Currency)
+		}
+		id := a.ID
+		*ref.id = &id
+	}
+	return nil
+}
+
+// accountName tên tài khoản theo id, rỗng nếu nil hoặc không tìm thấy
+func (h *FinanceHandler) accountName(id *int) string {
+	if id == nil {
+		return ""
+	}
+	a, err := h.Store.GetAccount(*id)
+	if err != nil {
+		return ""
+	}
+	return a.Name
+}
+
+// CreateAccount godoc
+// @Summary      Tạo tài khoản / ví
+// @Description  Tạo tài khoản (tiền mặt, ngân hàng, ví điện tử...) để gắn vào giao dịch bằng tên, VD: "chi 50k cafe @momo".
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      model.Account  true  "Tài khoản (id, created_at bỏ qua)"
+// @Success      200      {object}  model.Account
+// @Failure      400      {string}  string  "Lỗi dữ liệu đầu vào"
+// @Failure      409      {string}  string  "Đã có tài khoản cùng tên"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /accounts [post]
+func (h *FinanceHandler) CreateAccount(w http.ResponseWriter, r *http.Request) {
+	var a model.Account
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if a.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if msg := validateAccount(&a); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	id, err := h.Store.CreateAccount(a)
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "Account name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB CreateAccount failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if a, err = h.Store.GetAccount(id); err != nil {
+		log.Printf("[API ERROR] DB GetAccount failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[API INFO] Created account #%d %s (%s) of user %s", a.ID, a.Name, a.Currency, a.UserID)
+	jsonResponse(w, http.StatusOK, a)
+}
+
+// ListAccounts godoc
+// @Summary      Danh sách tài khoản kèm số dư
+// @Description  Số dư = số dư đầu + thu - chi - tiết kiệm ± chuyển khoản của các giao dịch gắn với tài khoản, tính theo đơn vị tiền của tài khoản.
+// @Tags         Accounts
+// @Produce      json
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {array}   model.AccountBalance
+// @Failure      400      {string}  string  "Thiếu user_id"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /accounts [get]
+func (h *FinanceHandler) ListAccounts(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	balances, err := h.accountBalances(userID, time.Time{})
+	if err != nil {
+		log.Printf("[API ERROR] Account balances failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if balances == nil {
+		balances = []model.AccountBalance{}
+	}
+	jsonResponse(w, http.StatusOK, balances)
+}
+
+// loadOwnedAccount đọc {id} trên URL và kiểm tra tài khoản thuộc về user_id.
+// Tự ghi response lỗi và trả về false nếu không hợp lệ
+func (h *FinanceHandler) loadOwnedAccount(w http.ResponseWriter, r *http.Request, userID string) (model.Account, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid account id", http.StatusBadRequest)
+		return model.Account{}, false
+	}
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return model.Account{}, false
+	}
+
+	a, err := h.Store.GetAccount(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return a, false
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB GetAccount failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return a, false
+	}
+	if a.UserID != userID {
+		log.Printf("[API WARN] User %s tried to access account %d of another user", userID, id)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return a, false
+	}
+	return a, true
+}
+
+// UpdateAccount godoc
+// @Summary      Sửa tài khoản
+// @Description  Đổi tên, đơn vị tiền hoặc số dư đầu. Trường không gửi sẽ giữ nguyên.
+// @Tags         Accounts
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int            true  "ID tài khoản"
+// @Param        payload  body      model.Account  true  "Các trường cần sửa (bắt buộc user_id)"
+// @Success      200      {object}  model.Account
+// @Failure      400      {string}  string  "Lỗi dữ liệu đầu vào"
+// @Failure      403      {string}  string  "Tài khoản không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Failure      409      {string}  string  "Đã có tài khoản cùng tên"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /accounts/{id} [put]
+func (h *FinanceHandler) UpdateAccount(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID         string           `json:"user_id"`
+		Name           *string          `json:"name"`
+		Currency       *string          `json:"currency"`
+		OpeningBalance *decimal.Decimal `json:"opening_balance"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	a, ok := h.loadOwnedAccount(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	if req.Name != nil {
+		a.Name = *req.Name
+	}
+	if req.Currency != nil {
+		a.Currency = *req.Currency
+	}
+	if req.OpeningBalance != nil {
+		a.OpeningBalance = *req.OpeningBalance
+	}
+	if msg := validateAccount(&a); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	err := h.Store.UpdateAccount(a)
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "Account name already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB UpdateAccount failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, http.StatusOK, a)
+}
+
+// DeleteAccount godoc
+// @Summary      Xóa tài khoản
+// @Description  Chỉ xóa được tài khoản chưa có giao dịch nào gắn với nó.
+// @Tags         Accounts
+// @Produce      json
+// @Param        id       path      int     true  "ID tài khoản"
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.Account  "Tài khoản vừa xóa"
+// @Failure      400      {string}  string  "Thiếu user_id hoặc id sai"
+// @Failure      403      {string}  string  "Tài khoản không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Failure      409      {string}  string  "Tài khoản còn giao dịch"
+// @Router       /accounts/{id} [delete]
+func (h *FinanceHandler) DeleteAccount(w http.ResponseWriter, r *http.Request) {
+	a, ok := h.loadOwnedAccount(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	err := h.Store.DeleteAccount(a.ID)
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "Account still has transactions", http.StatusConflict)
+		return
+	}
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Account not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB DeleteAccount failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[API INFO] Deleted account #%d %s of user %s", a.ID, a.Name, a.UserID)
+	jsonResponse(w, http.StatusOK, a)
+}
@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"go-finance/internal/store"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// validateAlert chuẩn hóa và kiểm tra cảnh báo giá trước khi lưu. Trả về thông báo lỗi nếu không hợp lệ
+func validateAlert(a *model.PriceAlert) string {
+	asset, ok := service.LookupAsset(a.Asset)
+	if !ok {
+		return "unknown asset " + a.Asset
+	}
+	if len(asset.RateCodes()) == 0 {
+		return "asset " + asset.Code + " has a fixed price"
+	}
+	a.Asset = asset.Code
+
+	a.Condition = strings.ToLower(strings.TrimSpace(a.Condition))
+	switch a.Condition {
+	case model.AlertAbove, model.AlertBelow, model.AlertChange:
+	default:
+		return "condition must be above, below or change"
+	}
+	if !a.Threshold.IsPositive() {
+		return "threshold must be positive"
+	}
+
+	a.Unit = strings.TrimSpace(a.Unit)
+	if a.Unit != "" {
+		if asset.Kind != model.AssetKindMetal {
+			return "unit is only supported for metal assets, not " + asset.Code
+		}
+		u, ok := service.LookupMetalUnit(a.Unit)
+		if !ok {
+			return "unknown unit " + a.Unit + ", use one of " + strings.Join(service.MetalUnitCodes(), ", ")
+		}
+		a.Unit = u.Code
+	}
+	return ""
+}
+
+// CreateAlert godoc
+// @Summary      Tạo cảnh báo giá
+// @Description  Báo cho user khi giá tài sản (vàng, USD, BTC...) vượt mốc. Giá được kiểm tra sau mỗi lần cập nhật tỷ giá (10 phút).
+// @Description  `above`/`below`: `threshold` là giá VND theo 1 `unit` (vàng, bạc: chi, luong, g, kg, oz; bỏ trống là đơn vị của tài sản).
+// @Description  `change`: `threshold` là % biến động so với giá lúc tạo. `repeat` = false thì báo 1 lần rồi tự tắt.
+// @Description  Giá cũ/mặc định (stale) không được dùng để báo.
+// @Tags         Alerts
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      model.PriceAlert  true  "Cảnh báo (id, active, base_price, last_price, created_at bỏ qua)"
+// @Success      200      {object}  model.PriceAlert
+// @Failure      400      {string}  string  "Lỗi dữ liệu đầu vào"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /alerts [post]
+func (h *FinanceHandler) CreateAlert(w http.ResponseWriter, r *http.Request) {
+	var a model.PriceAlert
+	if err := json.NewDecoder(r.Body).Decode(&a); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if a.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if msg := validateAlert(&a); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	a.Active = true
+	a.LastTriggeredAt = nil
+	a.LastPrice = decimal.Zero // Lần kiểm tra đầu chỉ so với mốc, giá đã vượt mốc sẵn thì báo ngay
+	a.BasePrice = decimal.Zero
+	if a.Condition == model.AlertChange {
+		// Chưa có giá thật thì lần cập nhật giá đầu tiên sẽ làm gốc
+		if price, ok := service.AlertPrice(a, service.GetCurrentRates()); ok {
+			a.BasePrice = price
+		}
+	}
+	a.CreatedAt = time.Now()
+
+	id, err := h.Store.CreateAlert(a)
+	if err != nil {
+		log.Printf("[API ERROR] DB CreateAlert failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	a.ID = id
+	log.Printf("[API INFO] Created alert #%d of user %s: %s %s %s", a.ID, a.UserID, a.Asset, a.Condition, a.Threshold)
+	jsonResponse(w, http.StatusOK, a)
+}
+
+// ListAlerts godoc
+// @Summary      Danh sách cảnh báo giá
+// @Description  Tất cả cảnh báo của user, kể cả cảnh báo 1 lần đã báo (active = false).
+// @Tags         Alerts
+// @Produce      json
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {array}   model.PriceAlert
+// @Failure      400      {string}  string  "Thiếu user_id"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /alerts [get]
+func (h *FinanceHandler) ListAlerts(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	alerts, err := h.Store.ListAlerts(userID)
+	if err != nil {
+		log.Printf("[API ERROR] DB ListAlerts failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if alerts == nil {
+		alerts = []model.PriceAlert{}
+	}
+	jsonResponse(w, http.StatusOK, alerts)
+}
+
+// DeleteAlert godoc
+// @Summary      Xóa cảnh báo giá
+// @Tags         Alerts
+// @Produce      json
+// @Param        id       path      int     true  "ID cảnh báo"
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.PriceAlert  "Cảnh báo vừa xóa"
+// @Failure      400      {string}  string  "Thiếu user_id hoặc id sai"
+// @Failure      403      {string}  string  "Cảnh báo không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Router       /alerts/{id} [delete]
+func (h *FinanceHandler) DeleteAlert(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid alert id", http.StatusBadRequest)
+		return
+	}
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	a, err := h.Store.GetAlert(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Alert not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB GetAlert failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if a.UserID != userID {
+		log.Printf("[API WARN] User %s tried to access alert %d of another user", userID, id)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return
+	}
+
+	err = h.Store.DeleteAlert(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Alert not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB DeleteAlert failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[API INFO] Deleted alert #%d of user %s", a.ID, a.UserID)
+	jsonResponse(w, http.StatusOK, a)
+}
+
+// ListAlertNotifications godoc
+// @Summary      Thông báo cảnh báo giá chờ gửi
+// @Description  Các lần cảnh báo đã kích hoạt nhưng chưa được gửi, cũ trước. Bot đọc định kỳ, gửi qua Telegram
+// @Description  rồi gọi ack; bot restart giữa chừng thì thông báo vẫn còn và được gửi lại.
+// @Tags         Alerts
+// @Produce      json
+// @Param        limit  query     int  false  "Số thông báo tối đa (mặc định 20, tối đa 100)"
+// @Success      200    {array}   model.AlertNotification
+// @Failure      400    {string}  string  "limit sai"
+// @Failure      500    {string}  string  "Lỗi Server"
+// @Router       /alerts/notifications [get]
+func (h *FinanceHandler) ListAlertNotifications(w http.ResponseWriter, r *http.Request) {
+	limit := 0
+	if v := r.URL.Query().Get("limit"); v != "" {
+		n, err := strconv.Atoi(v)
+		if err != nil || n < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+	notifications, err := h.Store.PendingNotifications(limit)
+	if err != nil {
+		log.Printf("[API ERROR] DB PendingNotifications failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if notifications == nil {
+		notifications = []model.AlertNotification{}
+	}
+	jsonResponse(w, http.StatusOK, notifications)
+}
+
+// AckAlertNotification godoc
+// @Summary      Xác nhận đã gửi thông báo cảnh báo giá
+// @Tags         Alerts
+// @Produce      json
+// @Param        id   path      int     true  "ID thông báo"
+// @Success      200  {object}  map[string]interface{}  "Đã đánh dấu đã gửi"
+// @Failure      400  {string}  string  "id sai"
+// @Failure      404  {string}  string  "Không tìm thấy"
+// @Failure      500  {string}  string  "Lỗi Server"
+// @Router       /alerts/notifications/{id}/ack [post]
+func (h *FinanceHandler) AckAlertNotification(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid notification id", http.StatusBadRequest)
+		return
+	}
+	err = h.Store.MarkNotificationSent(id, time.Now())
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Notification not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB MarkNotificationSent failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, http.StatusOK, map[string]interface{}{"status": "ok", "id": id})
+}
@@ -2,19 +2,27 @@ package handler
 
 import (
 	"encoding/json"
+	"errors"
+	"fmt"
 	"go-finance/internal/model"
 	"go-finance/internal/service"
 	"go-finance/internal/store"
 	"log"
 	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
 	"time"
+
+	"github.com/shopspring/decimal"
 )
 
 type FinanceHandler struct {
-	Store *store.PostgresStore
+	Store store.Store
 }
 
-func NewFinanceHandler(s *store.PostgresStore) *FinanceHandler {
+func NewFinanceHandler(s store.Store) *FinanceHandler {
 	return &FinanceHandler{Store: s}
 }
 
@@ -64,11 +72,15 @@ func jsonResponse(w http.ResponseWriter, status int, data interface{}) {
 // @Description      "currency": "GOLD"
 // @Description  }
 // @Description  ```
+// @Description  _(Vàng/bạc nhập theo đơn vị khác thì gửi thêm "unit": chi, luong, g, kg, oz. Số lượng được quy về chỉ với vàng, lượng với bạc)_
+// @Description
+// @Description  **Ghi bù giao dịch cũ:** gửi thêm `"occurred_at": "2025-03-15T20:00:00+07:00"` (không được ở tương lai hoặc quá 5 năm trước).
+// @Description  Ngoại tệ/vàng được quy đổi theo bảng giá đã lưu gần nhất trước thời điểm đó.
 // @Tags         Transactions
 // @Accept       json
 // @Produce      json
 // @Param        payload  body      model.TransactionCreate  true  "Dữ liệu giao dịch"
-// @Success      200      {object}  map[string]string        "Thành công"
+// @Success      200      {object}  map[string]interface{}   "Thành công, kèm id giao dịch vừa tạo"
 // @Failure      400      {string}  string                   "Lỗi dữ liệu đầu vào"
 // @Failure      500      {string}  string                   "Lỗi Server"
 // @Router       /transactions [post]
@@ -82,21 +94,33 @@ func (h *FinanceHandler) CreateTransaction(w http.ResponseWriter, r *http.Reques
 
 	log.Printf("[API INFO] Received transaction request: %+v", req) // [Update] Log request nhận được
 
-	convertedAmount := req.Amount
-	originalAmount := req.Amount
-
-	rates := service.GetCurrentRates()
-
-	switch req.Currency {
-	case "USD":
-		convertedAmount = req.Amount * rates.UsdVND
-	case "GOLD":
-		convertedAmount = req.Amount * rates.VnSJC
-	case "BTC":
-		convertedAmount = req.Amount * rates.BtcVND
-	default: // VND hoặc loại khác
-		originalAmount = req.Amount
+	if !validTransactionType(req.Type) {
+		http.Error(w, transactionTypeError, http.StatusBadRequest)
+		return
+	}
+	currency, err := normalizeCurrency(req.Currency)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	req.Currency = currency
+	if req.Unit != "" {
+		if req.Amount, err = service.ConvertMetalQuantity(req.Amount, req.Unit, req.Currency); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+	}
+	snap := service.GetCurrentSnapshot()
+	var createdAt time.Time
+	if req.OccurredAt != nil {
+		if msg := validateOccurredAt(*req.OccurredAt, time.Now()); msg != "" {
+			http.Error(w, msg, http.StatusBadRequest)
+			return
+		}
+		createdAt = *req.OccurredAt
+		snap = h.ratesAt(createdAt, req.Currency, req.Type, snap)
 	}
+	convertedAmount, originalAmount, snapshotID := convertToVND(req.Amount, req.Currency, req.Type, snap)
 
 	t := model.Transaction{
 		UserID:         req.UserID,
@@ -106,48 +130,495 @@ func (h *FinanceHandler) CreateTransaction(w http.ResponseWriter, r *http.Reques
 		Note:           req.Note,
 		Currency:       req.Currency,
 		Category:       req.Category,
+		CreatedAt:      createdAt,
+		RateSnapshotID: snapshotID,
+		AmountExpr:     req.AmountExpr,
+	}
+	if err := h.resolveTransactionAccounts(&t, req.Account, req.ToAccount); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.checkWithdrawal(t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
-	if err := h.Store.Create(t); err != nil {
+	id, err := h.Store.Create(t)
+	if err != nil {
 		log.Printf("[API ERROR] DB Create failed: %v", err) // [Update] Log lỗi DB
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
-	jsonResponse(w, http.StatusOK, map[string]string{"status": "ok"})
+	jsonResponse(w, http.StatusOK, map[string]interface{}{"status": "ok", "id": id})
+}
+
+// transactionTypeError thông báo khi type không thuộc các loại giao dịch hỗ trợ
+const transactionTypeError = "type must be thu, chi, tiet_kiem, rut or chuyen"
+
+// validTransactionType loại giao dịch hợp lệ khi tạo/sửa
+func validTransactionType(txType string) bool {
+	switch txType {
+	case "thu", "chi", "tiet_kiem", "rut", "chuyen":
+		return true
+	}
+	return false
+}
+
+// Giới hạn thời điểm phát sinh giao dịch ghi bù
+const (
+	maxOccurredAtPast   = 5 * 365 * 24 * time.Hour
+	maxOccurredAtFuture = 5 * time.Minute // Cho phép lệch đồng hồ giữa bot và API
+)
+
+// validateOccurredAt kiểm tra thời điểm phát sinh do user gửi lên. Trả về thông báo lỗi nếu không hợp lệ
+func validateOccurredAt(at, now time.Time) string {
+	if at.After(now.Add(maxOccurredAtFuture)) {
+		return "occurred_at must not be in the future"
+	}
+	if at.Before(now.Add(-maxOccurredAtPast)) {
+		return "occurred_at must be within the last 5 years"
+	}
+	return ""
+}
+
+// ratesAt bảng giá đã lưu gần nhất trước thời điểm at để quy đổi giao dịch ghi bù tài sản currency.
+// Rơi về current nếu chưa có lịch sử giá từ lúc đó, hoặc bản ghi đó chưa có giá thật của tài sản
+// (bản ghi lưu trước khi thêm tài sản vào registry: chỉ có giá mặc định hoặc giá 0)
+func (h *FinanceHandler) ratesAt(at time.Time, currency, txType string, current model.RateSnapshot) model.RateSnapshot {
+	snap, err := h.Store.RatesAt(at)
+	if err != nil {
+		if !errors.Is(err, store.ErrNotFound) {
+			log.Printf("[API ERROR] DB RatesAt failed: %v", err)
+		}
+		return current
+	}
+	snap = service.HistoricalSnapshot(snap, at)
+
+	asset, ok := service.LookupAsset(currency)
+	if !ok {
+		return snap
+	}
+	for _, code := range asset.RateCodes() {
+		if snap.Rates.Sources[code].Source == model.RateSourceDefault {
+			log.Printf("[API WARN] Rate snapshot %d has no %s price, using current rates", snap.ID, code)
+			return current
+		}
+	}
+	price := asset.AskIn(snap.Rates)
+	if txType == "rut" {
+		price = asset.BidIn(snap.Rates)
+	}
+	if len(asset.RateCodes()) > 0 && !price.IsPositive() {
+		log.Printf("[API WARN] Rate snapshot %d has no %s price, using current rates", snap.ID, currency)
+		return current
+	}
+	return snap
+}
+
+// convertToVND quy đổi số lượng gốc ra VND theo bảng giá snap: rút/bán (rut) theo giá mua vào,
+// các loại khác (mua/tiết kiệm) theo giá bán ra.
+// Trả về (giá trị VND, số lượng gốc, id bản ghi giá đã dùng), VND được làm tròn đến đồng để lưu DB chính xác.
+// id là nil với VND, tài sản giá cố định hoặc khi giá chưa được lưu vào lịch sử
+func convertToVND(amount decimal.Decimal, currency, txType string, snap model.RateSnapshot) (decimal.Decimal, decimal.Decimal, *int) {
+	asset, ok := service.LookupAsset(currency)
+	if !ok || asset.Code == "VND" {
+		return amount, amount, nil
+	}
+	if len(asset.RateCodes()) == 0 { // Giá cố định
+		return amount.Mul(asset.RateIn(model.ExchangeRates{})).Round(0), amount, nil
+	}
+	var snapshotID *int
+	if snap.ID != 0 {
+		snapshotID = &snap.ID
+	}
+	rate := asset.AskIn(snap.Rates)
+	if txType == "rut" {
+		rate = asset.BidIn(snap.Rates)
+	}
+	return amount.Mul(rate).Round(0), amount, snapshotID
+}
+
+// normalizeCurrency chuẩn hóa mã tài sản (rỗng là VND), lỗi nếu không có trong registry
+func normalizeCurrency(currency string) (string, error) {
+	currency = strings.ToUpper(strings.TrimSpace(currency))
+	if currency == "" {
+		return "VND", nil
+	}
+	if _, ok := service.LookupAsset(currency); !ok {
+		return "", fmt.Errorf("unsupported currency %s (see GET /assets)", currency)
+	}
+	return currency, nil
+}
+
+// ListTransactions godoc
+// @Summary      Danh sách giao dịch
+// @Description  Lọc, tìm kiếm và phân trang giao dịch của user. Phân trang kiểu cursor: truyền lại `next_cursor` của trang trước để lấy trang tiếp theo, hết trang khi `next_cursor` rỗng.
+// @Tags         Transactions
+// @Produce      json
+// @Param        user_id     query     string  true   "ID người dùng Telegram"
+// @Param        from        query     string  false  "Từ ngày (YYYY-MM-DD hoặc RFC3339), tính cả ngày này"
+// @Param        to          query     string  false  "Đến ngày (YYYY-MM-DD hoặc RFC3339), tính cả ngày này"
+// @Param        type        query     string  false  "Loại giao dịch"  Enums(thu, chi, tiet_kiem, rut, chuyen)
+// @Param        category    query     string  false  "Danh mục"
+// @Param        currency    query     string  false  "Đơn vị tiền"
+// @Param        min_amount  query     number  false  "Số tiền VND tối thiểu"
+// @Param        max_amount  query     number  false  "Số tiền VND tối đa"
+// @Param        q           query     string  false  "Tìm theo từ trong ghi chú"
+// @Param        sort        query     string  false  "Thứ tự thời gian (mặc định desc)"  Enums(asc, desc)
+// @Param        cursor      query     string  false  "next_cursor của trang trước"
+// @Param        limit       query     int     false  "Số bản ghi mỗi trang (mặc định 20, tối đa 100)"
+// @Success      200         {object}  model.TransactionPage
+// @Failure      400         {string}  string  "Tham số không hợp lệ"
+// @Failure      500         {string}  string  "Lỗi Server"
+// @Router       /transactions [get]
+func (h *FinanceHandler) ListTransactions(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	f := model.TransactionFilter{
+		UserID:    q.Get("user_id"),
+		Type:      q.Get("type"),
+		Category:  q.Get("category"),
+		Currency:  q.Get("currency"),
+		Search:    q.Get("q"),
+		Cursor:    q.Get("cursor"),
+		Ascending: q.Get("sort") == "asc",
+	}
+	if f.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if s := q.Get("sort"); s != "" && s != "asc" && s != "desc" {
+		http.Error(w, "sort must be asc or desc", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.Store.GetSettings(f.UserID)
+	if err != nil {
+		log.Printf("[API ERROR] DB GetSettings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	loc := settings.Location()
+
+	if f.From, err = parseDateParam(q.Get("from"), false, loc); err != nil {
+		http.Error(w, "Invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.To, err = parseDateParam(q.Get("to"), true, loc); err != nil {
+		http.Error(w, "Invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if f.MinAmount, err = parseDecimalParam(q.Get("min_amount")); err != nil {
+		http.Error(w, "Invalid min_amount", http.StatusBadRequest)
+		return
+	}
+	if f.MaxAmount, err = parseDecimalParam(q.Get("max_amount")); err != nil {
+		http.Error(w, "Invalid max_amount", http.StatusBadRequest)
+		return
+	}
+	if l := q.Get("limit"); l != "" {
+		if f.Limit, err = strconv.Atoi(l); err != nil || f.Limit < 0 {
+			http.Error(w, "Invalid limit", http.StatusBadRequest)
+			return
+		}
+	}
+
+	page, err := h.Store.List(f)
+	if errors.Is(err, store.ErrInvalidCursor) {
+		http.Error(w, "Invalid cursor", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB List failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, http.StatusOK, page)
+}
+
+// parseDateParam nhận "2006-01-02" (hiểu theo múi giờ loc của user) hoặc RFC3339.
+// Với endOfDay=true, ngày không kèm giờ được hiểu là hết ngày đó
+// (trả về 0h ngày hôm sau để dùng làm cận trên loại trừ)
+func parseDateParam(value string, endOfDay bool, loc *time.Location) (time.Time, error) {
+	if value == "" {
+		return time.Time{}, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return t, nil
+	}
+	t, err := time.ParseInLocation("2006-01-02", value, loc)
+	if err != nil {
+		return t, err
+	}
+	if endOfDay {
+		t = t.AddDate(0, 0, 1)
+	}
+	return t, nil
+}
+
+func parseDecimalParam(value string) (*decimal.Decimal, error) {
+	if value == "" {
+		return nil, nil
+	}
+	d, err := decimal.NewFromString(value)
+	if err != nil {
+		return nil, err
+	}
+	return &d, nil
+}
+
+// loadOwnedTransaction đọc {id} trên URL và kiểm tra giao dịch thuộc về user_id.
+// Tự ghi response lỗi và trả về false nếu không hợp lệ
+func (h *FinanceHandler) loadOwnedTransaction(w http.ResponseWriter, r *http.Request, userID string) (model.Transaction, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid transaction id", http.StatusBadRequest)
+		return model.Transaction{}, false
+	}
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return model.Transaction{}, false
+	}
+
+	t, err := h.Store.GetByID(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return t, false
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB GetByID failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return t, false
+	}
+	if t.UserID != userID {
+		log.Printf("[API WARN] User %s tried to access transaction %d of another user", userID, id)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return t, false
+	}
+	return t, true
+}
+
+// GetTransaction godoc
+// @Summary      Xem chi tiết giao dịch
+// @Description  Lấy một giao dịch theo id. Chỉ chủ sở hữu (user_id) mới xem được.
+// @Tags         Transactions
+// @Produce      json
+// @Param        id       path      int     true  "ID giao dịch"
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.Transaction
+// @Failure      400      {string}  string  "Thiếu user_id hoặc id sai"
+// @Failure      403      {string}  string  "Giao dịch không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Router       /transactions/{id} [get]
+func (h *FinanceHandler) GetTransaction(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.loadOwnedTransaction(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	jsonResponse(w, http.StatusOK, t)
+}
+
+// UpdateTransaction godoc
+// @Summary      Sửa giao dịch
+// @Description  Sửa một phần giao dịch (chỉ gửi các trường cần đổi). Nếu đổi số tiền, đơn vị hoặc loại giao dịch, hệ thống quy đổi lại VND theo bảng giá tại thời điểm phát sinh giao dịch.
+// @Tags         Transactions
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                      true  "ID giao dịch"
+// @Param        payload  body      model.TransactionUpdate  true  "Các trường cần sửa"
+// @Success      200      {object}  model.Transaction
+// @Failure      400      {string}  string  "Lỗi dữ liệu đầu vào"
+// @Failure      403      {string}  string  "Giao dịch không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /transactions/{id} [patch]
+func (h *FinanceHandler) UpdateTransaction(w http.ResponseWriter, r *http.Request) {
+	var req model.TransactionUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	t, ok := h.loadOwnedTransaction(w, r, req.UserID)
+	if !ok {
+		return
+	}
+
+	if req.Type != nil {
+		if !validTransactionType(*req.Type) {
+			http.Error(w, transactionTypeError, http.StatusBadRequest)
+			return
+		}
+		t.Type = *req.Type
+	}
+	if req.Note != nil {
+		t.Note = *req.Note
+	}
+	if req.Category != nil {
+		t.Category = *req.Category
+	}
+	// Đổi loại giao dịch cũng quy đổi lại: rut theo giá mua vào, các loại khác theo giá bán ra
+	if req.Amount != nil || req.Currency != nil || req.Type != nil {
+		if req.Amount != nil {
+			t.OriginalAmount = *req.Amount
+			t.AmountExpr = "" // Biểu thức cũ không còn đúng với số tiền mới
+		}
+		if req.Currency != nil {
+			currency, err := normalizeCurrency(*req.Currency)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			t.Currency = currency
+		}
+		// Quy đổi lại theo giá tại thời điểm phát sinh, giao dịch ghi bù giữ giá lịch sử
+		snap := h.ratesAt(t.CreatedAt, t.Currency, t.Type, service.GetCurrentSnapshot())
+		t.Amount, t.OriginalAmount, t.RateSnapshotID = convertToVND(t.OriginalAmount, t.Currency, t.Type, snap)
+	}
+	if req.AmountExpr != nil {
+		t.AmountExpr = *req.AmountExpr
+	}
+
+	// Kiểm tra lại tài khoản vì loại giao dịch hoặc đơn vị tiền có thể đã đổi
+	account, toAccount := h.accountName(t.AccountID), h.accountName(t.ToAccountID)
+	if req.Account != nil {
+		account = *req.Account
+	}
+	if req.ToAccount != nil {
+		toAccount = *req.ToAccount
+	}
+	if t.Type != "chuyen" && req.ToAccount == nil {
+		toAccount = ""
+	}
+	if err := h.resolveTransactionAccounts(&t, account, toAccount); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err := h.checkWithdrawal(t); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.Store.Update(t); err != nil {
+		log.Printf("[API ERROR] DB Update failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[API INFO] Updated transaction %d of user %s", t.ID, t.UserID)
+	jsonResponse(w, http.StatusOK, t)
+}
+
+// DeleteTransaction godoc
+// @Summary      Xóa giao dịch
+// @Description  Xóa một giao dịch theo id. Trả về giao dịch vừa xóa.
+// @Tags         Transactions
+// @Produce      json
+// @Param        id       path      int     true  "ID giao dịch"
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.Transaction
+// @Failure      400      {string}  string  "Thiếu user_id hoặc id sai"
+// @Failure      403      {string}  string  "Giao dịch không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Router       /transactions/{id} [delete]
+func (h *FinanceHandler) DeleteTransaction(w http.ResponseWriter, r *http.Request) {
+	t, ok := h.loadOwnedTransaction(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	h.deleteAndRespond(w, t)
+}
+
+// DeleteLastTransaction godoc
+// @Summary      Hoàn tác giao dịch gần nhất
+// @Description  Xóa giao dịch được ghi gần nhất của user (dùng cho lệnh /undo của bot).
+// @Tags         Transactions
+// @Produce      json
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.Transaction
+// @Failure      400      {string}  string  "Thiếu user_id"
+// @Failure      404      {string}  string  "User chưa có giao dịch nào"
+// @Router       /transactions/last [delete]
+func (h *FinanceHandler) DeleteLastTransaction(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	t, err := h.Store.GetLatest(userID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "No transaction to undo", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB GetLatest failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.deleteAndRespond(w, t)
+}
+
+func (h *FinanceHandler) deleteAndRespond(w http.ResponseWriter, t model.Transaction) {
+	err := h.Store.Delete(t.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		// Bị xóa bởi request khác ngay trước đó
+		http.Error(w, "Transaction not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB Delete failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	log.Printf("[API INFO] Deleted transaction %d of user %s", t.ID, t.UserID)
+	jsonResponse(w, http.StatusOK, t)
 }
 
 // GenerateReport godoc
 // @Summary      Xuất báo cáo tài chính
 // @Description  Tổng hợp thu/chi, tính toán số dư và định giá tài sản tích lũy theo thời gian thực.
+// @Description  Chọn kỳ bằng `period` (+ `offset`, VD: period=month&offset=-1 là tháng trước) hoặc khoảng tùy chọn `from`/`to`.
 // @Tags         Reports
 // @Accept       json
 // @Produce      json
-// @Param        user_id  query     string  true  "ID người dùng Telegram (VD: 123456789)"
-// @Param        period   query     string  true  "Kỳ báo cáo: 'week' (tuần này) hoặc 'month' (tháng này)"
+// @Param        user_id  query     string  true   "ID người dùng Telegram (VD: 123456789)"
+// @Param        period   query     string  false  "Kỳ báo cáo (mặc định month)"  Enums(day, week, month, quarter, year)
+// @Param        offset   query     int     false  "Dịch kỳ: 0 là kỳ hiện tại, -1 là kỳ trước..."
+// @Param        from     query     string  false  "Từ ngày (YYYY-MM-DD), dùng thay cho period"
+// @Param        to       query     string  false  "Đến ngày (YYYY-MM-DD, tính cả ngày này), mặc định là hiện tại"
 // @Success      200      {object}  model.ReportOutput
+// @Failure      400      {string}  string  "Tham số không hợp lệ"
 // @Failure      500      {string}  string  "Lỗi Server"
 // @Router       /report [get]
 func (h *FinanceHandler) GenerateReport(w http.ResponseWriter, r *http.Request) {
-	userID := r.URL.Query().Get("user_id")
-	period := r.URL.Query().Get("period")
+	q := r.URL.Query()
+	userID := q.Get("user_id")
+	period := q.Get("period")
 
 	log.Printf("[API INFO] GenerateReport for User: %s, Period: %s", userID, period) // [Update]
 
-	now := time.Now()
-	var startDate time.Time
+	settings, err := h.Store.GetSettings(userID)
+	if err != nil {
+		log.Printf("[API ERROR] DB GetSettings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
 
-	if period == "week" {
-		weekday := int(now.Weekday())
-		if weekday == 0 {
-			weekday = 7
-		}
-		monday := now.AddDate(0, 0, -weekday+1)
-		startDate = time.Date(monday.Year(), monday.Month(), monday.Day(), 0, 0, 0, 0, monday.Location())
-	} else {
-		startDate = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, now.Location())
+	// Ranh giới ngày/tuần/tháng tính theo múi giờ và ngày đầu tuần của user
+	startDate, endDate, err := reportRange(q, settings)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.Get("from") != "" {
+		period = "custom"
+	} else if period == "" {
+		period = "month"
 	}
 
-	txs, err := h.Store.GetByPeriod(userID, startDate)
+	txs, err := h.Store.GetByPeriod(userID, startDate, endDate)
 	if err != nil {
 		log.Printf("[API ERROR] DB GetByPeriod failed: %v", err) // [Update]
 		http.Error(w, err.Error(), http.StatusInternalServerError)
@@ -157,51 +628,221 @@ func (h *FinanceHandler) GenerateReport(w http.ResponseWriter, r *http.Request)
 	report := model.ReportOutput{
 		Period:            period,
 		StartDate:         startDate.Format("2006-01-02"),
-		ExpenseByCategory: make(map[string]float64),
+		EndDate:           endDate.Add(-time.Nanosecond).Format("2006-01-02"),
+		Timezone:          settings.Location().String(),
+		ExpenseByCategory: make(map[string]decimal.Decimal),
 		Assets:            make(map[string]model.AssetDetail),
 	}
 
 	currentRates := service.GetCurrentRates()
 
+	// Cộng dồn bằng decimal nên tổng luôn bằng đúng tổng các dòng đã lưu
 	for _, t := range txs {
 		switch t.Type {
 		case "thu":
-			report.TotalIncome += t.Amount
+			report.TotalIncome = report.TotalIncome.Add(t.Amount)
 		case "chi":
-			report.TotalExpense += t.Amount
-			report.ExpenseByCategory[t.Category] += t.Amount
+			report.TotalExpense = report.TotalExpense.Add(t.Amount)
+			report.ExpenseByCategory[t.Category] = report.ExpenseByCategory[t.Category].Add(t.Amount)
 		case "tiet_kiem":
-			report.TotalSavingsVND += t.Amount
+			report.TotalSavingsVND = report.TotalSavingsVND.Add(t.Amount)
+			if t.Currency != "VND" {
+				asset := report.Assets[t.Currency]
+				asset.Quantity = asset.Quantity.Add(t.OriginalAmount)
+				report.Assets[t.Currency] = asset
+			}
+		case "rut":
+			report.TotalWithdrawnVND = report.TotalWithdrawnVND.Add(t.Amount)
 			if t.Currency != "VND" {
 				asset := report.Assets[t.Currency]
-				asset.Quantity += t.OriginalAmount
-				rate := 1.0
-				switch t.Currency {
-				case "USD":
-					rate = currentRates.UsdVND
-				case "GOLD":
-					rate = currentRates.VnSJC
-				case "BTC":
-					rate = currentRates.BtcVND
-				}
-				asset.Rate = rate
-				asset.CurrentVND = asset.Quantity * rate
+				asset.Quantity = asset.Quantity.Sub(t.OriginalAmount)
 				report.Assets[t.Currency] = asset
 			}
 		}
 	}
 
-	for _, a := range report.Assets {
-		report.TotalAssetsVND += a.CurrentVND
+	for currency, asset := range report.Assets {
+		asset.Rate = service.BidToVND(currency, currentRates)
+		asset.CurrentVND = asset.Quantity.Mul(asset.Rate).Round(0)
+		asset.Stale = service.RateStale(currency, currentRates)
+		asset.DisplayUnit, asset.DisplayQuantity, asset.DisplayRate = service.DisplayQuantity(currency, asset.Quantity, asset.Rate, settings)
+		report.RatesStale = report.RatesStale || asset.Stale
+		report.Assets[currency] = asset
+		report.TotalAssetsVND = report.TotalAssetsVND.Add(asset.CurrentVND)
+	}
+
+	report.Balance = report.TotalIncome.Sub(report.TotalExpense).Sub(report.TotalSavingsVND).Add(report.TotalWithdrawnVND)
+
+	// Tài sản đang giữ tính đến cuối kỳ, lãi/lỗ đã thực hiện chỉ tính các lần rút/bán trong kỳ
+	if err := h.fillHoldings(&report, userID, settings, startDate, endDate, currentRates); err != nil {
+		log.Printf("[API ERROR] Compute holdings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Số dư từng tài khoản tính đến cuối kỳ (giao dịch chuyen không tính vào thu/chi ở trên)
+	if report.Accounts, err = h.accountBalances(userID, endDate); err != nil {
+		log.Printf("[API ERROR] Account balances failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
 	}
 
-	report.Balance = report.TotalIncome - report.TotalExpense - report.TotalSavingsVND
+	// Báo cáo tuần/tháng kèm so sánh ngân sách cùng kỳ với thực chi
+	if period == "week" || period == "month" {
+		budgets, err := h.Store.ListBudgets(userID)
+		if err != nil {
+			log.Printf("[API ERROR] DB ListBudgets failed: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		for _, b := range budgets {
+			if b.Period == period {
+				report.Budgets = append(report.Budgets, model.NewBudgetStatus(b, report.ExpenseByCategory[b.Category], report.StartDate, report.EndDate))
+			}
+		}
+	}
 	jsonResponse(w, http.StatusOK, report)
 }
 
+// fillHoldings tính tài sản ngoại tệ/vàng/BTC đang giữ tại end và lãi/lỗ theo giá hiện tại
+func (h *FinanceHandler) fillHoldings(report *model.ReportOutput, userID string, settings model.UserSettings, start, end time.Time, rates model.ExchangeRates) error {
+	all, err := h.Store.GetByPeriod(userID, time.Time{}, end)
+	if err != nil {
+		return err
+	}
+
+	report.CostBasis = settings.CostBasis
+	report.Holdings = make(map[string]model.AssetHolding)
+	for currency, hd := range service.ComputeHoldings(all, settings.CostBasis) {
+		if currency == "VND" {
+			continue
+		}
+		var realized decimal.Decimal
+		for _, sale := range hd.Sales {
+			if !sale.At.Before(start) {
+				realized = realized.Add(sale.Gain())
+			}
+		}
+		if hd.Quantity.IsZero() && realized.IsZero() {
+			continue
+		}
+
+		ah := model.AssetHolding{
+			Quantity:   hd.Quantity,
+			CostVND:    hd.CostVND.Round(0),
+			Rate:       service.BidToVND(currency, rates),
+			RealizedPL: realized.Round(0),
+			Stale:      service.RateStale(currency, rates),
+		}
+		report.RatesStale = report.RatesStale || ah.Stale
+		ah.CurrentVND = ah.Quantity.Mul(ah.Rate).Round(0)
+		ah.UnrealizedPL = ah.CurrentVND.Sub(ah.CostVND)
+		ah.DisplayUnit, ah.DisplayQuantity, ah.DisplayRate = service.DisplayQuantity(currency, ah.Quantity, ah.Rate, settings)
+		report.Holdings[currency] = ah
+		report.TotalRealizedPL = report.TotalRealizedPL.Add(ah.RealizedPL)
+		report.TotalUnrealizedPL = report.TotalUnrealizedPL.Add(ah.UnrealizedPL)
+	}
+	return nil
+}
+
+// checkWithdrawal kiểm tra giao dịch rut không rút quá số tài sản đang giữ tại thời điểm rút (t.CreatedAt, rỗng là bây giờ),
+// không tính chính giao dịch t nếu đang sửa. Rút ghi bù còn phải không làm hụt các lần rút ghi sau thời điểm đó
+func (h *FinanceHandler) checkWithdrawal(t model.Transaction) error {
+	if t.Type != "rut" {
+		return nil
+	}
+	all, err := h.Store.GetByPeriod(t.UserID, time.Time{}, time.Time{})
+	if err != nil {
+		return err
+	}
+	at := t.CreatedAt
+	if at.IsZero() {
+		at = time.Now()
+	}
+
+	var timeline []model.Transaction
+	for _, o := range all {
+		if o.ID != t.ID && o.Currency == t.Currency && (o.Type == "tiet_kiem" || o.Type == "rut") {
+			timeline = append(timeline, o)
+		}
+	}
+	sort.SliceStable(timeline, func(i, j int) bool {
+		if !timeline[i].CreatedAt.Equal(timeline[j].CreatedAt) {
+			return timeline[i].CreatedAt.Before(timeline[j].CreatedAt)
+		}
+		return timeline[i].ID < timeline[j].ID
+	})
+	apply := func(held decimal.Decimal, o model.Transaction) decimal.Decimal {
+		if o.Type == "tiet_kiem" {
+			return held.Add(o.OriginalAmount)
+		}
+		return held.Sub(o.OriginalAmount)
+	}
+
+	held := decimal.Zero
+	i := 0
+	for ; i < len(timeline) && !timeline[i].CreatedAt.After(at); i++ {
+		held = apply(held, timeline[i])
+	}
+	if t.OriginalAmount.GreaterThan(held) {
+		return fmt.Errorf("not enough %s savings to withdraw: have %s at %s, want %s",
+			t.Currency, held, at.Format(time.RFC3339), t.OriginalAmount)
+	}
+	held = held.Sub(t.OriginalAmount)
+	for ; i < len(timeline); i++ {
+		held = apply(held, timeline[i])
+		if held.IsNegative() {
+			return fmt.Errorf("not enough %s savings: withdrawal at %s would leave transaction %d uncovered",
+				t.Currency, at.Format(time.RFC3339), timeline[i].ID)
+		}
+	}
+	return nil
+}
+
+// reportRange xác định khoảng [start, end) của báo cáo từ query string
+func reportRange(q url.Values, settings model.UserSettings) (time.Time, time.Time, error) {
+	loc := settings.Location()
+	now := time.Now().In(loc)
+
+	if from := q.Get("from"); from != "" {
+		start, err := parseDateParam(from, false, loc)
+		if err != nil {
+			return start, start, fmt.Errorf("Invalid from: %v", err)
+		}
+		end := now
+		if to := q.Get("to"); to != "" {
+			if end, err = parseDateParam(to, true, loc); err != nil {
+				return start, end, fmt.Errorf("Invalid to: %v", err)
+			}
+		}
+		if !end.After(start) {
+			return start, end, fmt.Errorf("to must be after from")
+		}
+		return start, end, nil
+	}
+
+	period := q.Get("period")
+	if period == "" {
+		period = "month"
+	}
+	offset := 0
+	if o := q.Get("offset"); o != "" {
+		var err error
+		if offset, err = strconv.Atoi(o); err != nil {
+			return time.Time{}, time.Time{}, fmt.Errorf("Invalid offset")
+		}
+	}
+	return service.ResolvePeriod(period, offset, now, settings.WeekStart)
+}
+
 // GetPrices godoc
 // @Summary      Lấy tỷ giá thị trường
 // @Description  Lấy giá Vàng, Bạc, Bitcoin, USD từ các nguồn bên ngoài (CoinGecko, GoldAPI...).
+// @Description  Giá các mã khác (EUR, JPY, CNY, ETH, USDT...) nằm trong quotes.
+// @Description  bids/asks là giá mua vào/bán ra theo mã với nguồn có 2 giá (VD: SJC, vn_sjc là giá bán ra).
+// @Description  Giao dịch mua/tiết kiệm quy đổi theo giá bán ra, rút/bán và định giá tài sản đang giữ theo giá mua vào.
+// @Description  sources cho biết nguồn và thời điểm lấy của từng giá. stale = true nếu có giá là giá mặc định
+// @Description  (chưa lấy được giá thật) hoặc đã quá cũ do các nguồn lỗi liên tục.
 // @Tags         Market Data
 // @Accept       json
 // @Produce      json
@@ -209,14 +850,13 @@ func (h *FinanceHandler) GenerateReport(w http.ResponseWriter, r *http.Request)
 // @Failure      500  {string}  string  "Lỗi không lấy được dữ liệu"
 // @Router       /market-rates [get]
 func (h *FinanceHandler) GetPrices(w http.ResponseWriter, r *http.Request) {
-	// [TỐI ƯU] Thay vì gọi service.GetMetalPrices() (tốn 3-5s), ta gọi service.GetCurrentRates() để lấy dữ liệu đã cache
+	// [TỐI ƯU] Thay vì gọi trực tiếp các nguồn giá (tốn 3-5s), ta gọi service.GetCurrentRates() để lấy dữ liệu đã cache
 	rates := service.GetCurrentRates()
 
-	const OunceToTael = 1.20565
-	worldGoldVND := (rates.GoldUSD * rates.UsdVND * OunceToTael)
-	rates.GoldDiff = rates.VnSJC*10 - worldGoldVND
+	worldGoldVND := service.ConvertMetalPrice(rates.GoldUSD*rates.UsdVND, "oz", "luong")
+	rates.GoldDiff = service.ConvertMetalPrice(rates.VnSJC, "chi", "luong") - worldGoldVND
 
-	worldSilverVND := rates.SilverUSD * rates.UsdVND * OunceToTael
+	worldSilverVND := service.ConvertMetalPrice(rates.SilverUSD*rates.UsdVND, "oz", "luong")
 	rates.SilverDiff = rates.VnSilver - worldSilverVND
 
 	jsonResponse(w, http.StatusOK, rates)
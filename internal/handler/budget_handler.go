@@ -0,0 +1,305 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"go-finance/internal/store"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// validateBudget chuẩn hóa và kiểm tra ngân sách trước khi lưu. Trả về thông báo lỗi nếu không hợp lệ
+func validateBudget(b *model.Budget) string {
+	b.Category = strings.ToLower(strings.TrimSpace(b.Category))
+	if b.Category == "" {
+		return "category is required"
+	}
+	if b.Period != "week" && b.Period != "month" {
+		return "period must be week or month"
+	}
+	if !b.Limit.IsPositive() {
+		return "limit must be positive"
+	}
+	return ""
+}
+
+// CreateBudget godoc
+// @Summary      Đặt ngân sách
+// @Description  Đặt hạn mức chi cho một danh mục theo tuần hoặc tháng. Nếu danh mục + kỳ đã có ngân sách thì ghi đè hạn mức.
+// @Tags         Budgets
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      model.Budget  true  "Ngân sách (id, created_at bỏ qua)"
+// @Success      200      {object}  model.Budget
+// @Failure      400      {string}  string  "Lỗi dữ liệu đầu vào"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /budgets [post]
+func (h *FinanceHandler) CreateBudget(w http.ResponseWriter, r *http.Request) {
+	var b model.Budget
+	if err := json.NewDecoder(r.Body).Decode(&b); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if b.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if msg := validateBudget(&b); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	saved, err := h.Store.SaveBudget(b)
+	if err != nil {
+		log.Printf("[API ERROR] DB SaveBudget failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[API INFO] Saved budget #%d of user %s: %s/%s %s", saved.ID, saved.UserID, saved.Category, saved.Period, saved.Limit)
+	jsonResponse(w, http.StatusOK, saved)
+}
+
+// ListBudgets godoc
+// @Summary      Danh sách ngân sách
+// @Tags         Budgets
+// @Produce      json
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {array}   model.Budget
+// @Failure      400      {string}  string  "Thiếu user_id"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /budgets [get]
+func (h *FinanceHandler) ListBudgets(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	budgets, err := h.Store.ListBudgets(userID)
+	if err != nil {
+		log.Printf("[API ERROR] DB ListBudgets failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if budgets == nil {
+		budgets = []model.Budget{}
+	}
+	jsonResponse(w, http.StatusOK, budgets)
+}
+
+// loadOwnedBudget đọc {id} trên URL và kiểm tra ngân sách thuộc về user_id.
+// Tự ghi response lỗi và trả về false nếu không hợp lệ
+func (h *FinanceHandler) loadOwnedBudget(w http.ResponseWriter, r *http.Request, userID string) (model.Budget, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid budget id", http.StatusBadRequest)
+		return model.Budget{}, false
+	}
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return model.Budget{}, false
+	}
+
+	b, err := h.Store.GetBudget(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return b, false
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB GetBudget failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return b, false
+	}
+	if b.UserID != userID {
+		log.Printf("[API WARN] User %s tried to access budget %d of another user", userID, id)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return b, false
+	}
+	return b, true
+}
+
+// GetBudget godoc
+// @Summary      Xem ngân sách
+// @Tags         Budgets
+// @Produce      json
+// @Param        id       path      int     true  "ID ngân sách"
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.Budget
+// @Failure      400      {string}  string  "Thiếu user_id hoặc id sai"
+// @Failure      403      {string}  string  "Ngân sách không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Router       /budgets/{id} [get]
+func (h *FinanceHandler) GetBudget(w http.ResponseWriter, r *http.Request) {
+	b, ok := h.loadOwnedBudget(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	jsonResponse(w, http.StatusOK, b)
+}
+
+// UpdateBudget godoc
+// @Summary      Sửa ngân sách
+// @Description  Đổi danh mục, kỳ hoặc hạn mức. Trường không gửi sẽ giữ nguyên.
+// @Tags         Budgets
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int           true  "ID ngân sách"
+// @Param        payload  body      model.Budget  true  "Các trường cần sửa (bắt buộc user_id)"
+// @Success      200      {object}  model.Budget
+// @Failure      400      {string}  string  "Lỗi dữ liệu đầu vào"
+// @Failure      403      {string}  string  "Ngân sách không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Failure      409      {string}  string  "Đã có ngân sách khác cho danh mục + kỳ này"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /budgets/{id} [put]
+func (h *FinanceHandler) UpdateBudget(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		UserID   string           `json:"user_id"`
+		Category *string          `json:"category"`
+		Period   *string          `json:"period"`
+		Limit    *decimal.Decimal `json:"limit"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	b, ok := h.loadOwnedBudget(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	if req.Category != nil {
+		b.Category = *req.Category
+	}
+	if req.Period != nil {
+		b.Period = *req.Period
+	}
+	if req.Limit != nil {
+		b.Limit = *req.Limit
+	}
+	if msg := validateBudget(&b); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+
+	err := h.Store.UpdateBudget(b)
+	if errors.Is(err, store.ErrConflict) {
+		http.Error(w, "Budget for this category and period already exists", http.StatusConflict)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB UpdateBudget failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, http.StatusOK, b)
+}
+
+// DeleteBudget godoc
+// @Summary      Xóa ngân sách
+// @Tags         Budgets
+// @Produce      json
+// @Param        id       path      int     true  "ID ngân sách"
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.Budget  "Ngân sách vừa xóa"
+// @Failure      400      {string}  string  "Thiếu user_id hoặc id sai"
+// @Failure      403      {string}  string  "Ngân sách không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Router       /budgets/{id} [delete]
+func (h *FinanceHandler) DeleteBudget(w http.ResponseWriter, r *http.Request) {
+	b, ok := h.loadOwnedBudget(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	err := h.Store.DeleteBudget(b.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Budget not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB DeleteBudget failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[API INFO] Deleted budget #%d of user %s", b.ID, b.UserID)
+	jsonResponse(w, http.StatusOK, b)
+}
+
+// GetBudgetStatus godoc
+// @Summary      Tình hình ngân sách kỳ hiện tại
+// @Description  So sánh hạn mức với tổng chi của danh mục trong tuần/tháng hiện tại (theo múi giờ và ngày đầu tuần của user).
+// @Description  Bot gọi API này sau mỗi giao dịch chi để cảnh báo khi vượt ngưỡng.
+// @Tags         Budgets
+// @Produce      json
+// @Param        user_id   query     string  true   "ID người dùng Telegram"
+// @Param        category  query     string  false  "Chỉ lấy ngân sách của danh mục này"
+// @Success      200       {array}   model.BudgetStatus
+// @Failure      400       {string}  string  "Thiếu user_id"
+// @Failure      500       {string}  string  "Lỗi Server"
+// @Router       /budgets/status [get]
+func (h *FinanceHandler) GetBudgetStatus(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	userID := q.Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	category := strings.ToLower(strings.TrimSpace(q.Get("category")))
+
+	budgets, err := h.Store.ListBudgets(userID)
+	if err != nil {
+		log.Printf("[API ERROR] DB ListBudgets failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	settings, err := h.Store.GetSettings(userID)
+	if err != nil {
+		log.Printf("[API ERROR] DB GetSettings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	now := time.Now().In(settings.Location())
+	result := []model.BudgetStatus{}
+	// Mỗi kỳ chỉ đọc giao dịch 1 lần rồi dùng chung cho các ngân sách cùng kỳ
+	spentByPeriod := make(map[string]map[string]decimal.Decimal)
+	for _, b := range budgets {
+		if category != "" && b.Category != category {
+			continue
+		}
+		start, end, _ := service.ResolvePeriod(b.Period, 0, now, settings.WeekStart)
+		spent, ok := spentByPeriod[b.Period]
+		if !ok {
+			txs, err := h.Store.GetByPeriod(userID, start, end)
+			if err != nil {
+				log.Printf("[API ERROR] DB GetByPeriod failed: %v", err)
+				http.Error(w, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			spent = expenseByCategory(txs)
+			spentByPeriod[b.Period] = spent
+		}
+		result = append(result, model.NewBudgetStatus(b, spent[b.Category],
+			start.Format("2006-01-02"), end.Add(-time.Nanosecond).Format("2006-01-02")))
+	}
+	jsonResponse(w, http.StatusOK, result)
+}
+
+// expenseByCategory cộng tổng chi (VND) theo danh mục
+func expenseByCategory(txs []model.Transaction) map[string]decimal.Decimal {
+	spent := make(map[string]decimal.Decimal)
+	for _, t := range txs {
+		if t.Type == "chi" {
+			spent[t.Category] = spent[t.Category].Add(t.Amount)
+		}
+	}
+	return spent
+}
@@ -0,0 +1,176 @@
+package handler
+
+import (
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Khoảng lấy lịch sử mặc định khi không gửi from
+const defaultHistoryRange = 30 * 24 * time.Hour
+
+// Giới hạn lịch sử giá mỗi lần gọi: giá lưu 10 phút/lần, gom OHLC trong bộ nhớ nên không đọc quá nhiều bản ghi.
+// raw tối đa 7 ngày (~1.000 bản ghi), gom nhóm tối đa 92 ngày (~13.000 bản ghi)
+const (
+	maxRawHistoryRange = 7 * 24 * time.Hour
+	maxHistoryRange    = 92 * 24 * time.Hour
+	maxHistoryRecords  = 20000
+)
+
+// GetRateHistory godoc
+// @Summary      Lịch sử giá thị trường
+// @Description  Giá VND của một tài sản theo thời gian, gom theo giờ/ngày/tuần (giờ Việt Nam) để vẽ biểu đồ.
+// @Description  interval=raw trả về từng lần lấy giá. Mặc định 30 ngày gần nhất, gom theo ngày.
+// @Description  Mỗi lần lấy tối đa 92 ngày (raw: 7 ngày).
+// @Tags         Market Data
+// @Produce      json
+// @Param        asset     query     string  true   "Mã tài sản có nguồn giá (USD, EUR, GOLD, SILVER, BTC, ETH..., xem GET /assets)"
+// @Param        from      query     string  false  "Từ ngày (YYYY-MM-DD hoặc RFC3339)"
+// @Param        to        query     string  false  "Đến hết ngày (YYYY-MM-DD hoặc RFC3339)"
+// @Param        interval  query     string  false  "Gom nhóm"  Enums(raw, hour, day, week)  default(day)
+// @Success      200       {array}   model.RatePoint
+// @Failure      400       {string}  string  "Tham số không hợp lệ hoặc khoảng thời gian quá dài"
+// @Failure      500       {string}  string  "Lỗi Server"
+// @Router       /market-rates/history [get]
+func (h *FinanceHandler) GetRateHistory(w http.ResponseWriter, r *http.Request) {
+	q := r.URL.Query()
+	asset := strings.ToUpper(q.Get("asset"))
+	if a, ok := service.LookupAsset(asset); !ok || len(a.RateCodes()) == 0 {
+		http.Error(w, "asset must be an asset with market rates (see GET /assets)", http.StatusBadRequest)
+		return
+	}
+	interval := q.Get("interval")
+	if interval == "" {
+		interval = "day"
+	}
+	if interval != "raw" && interval != "hour" && interval != "day" && interval != "week" {
+		http.Error(w, "interval must be raw, hour, day or week", http.StatusBadRequest)
+		return
+	}
+
+	loc := model.DefaultUserSettings("").Location()
+	from, err := parseDateParam(q.Get("from"), false, loc)
+	if err != nil {
+		http.Error(w, "Invalid from: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	to, err := parseDateParam(q.Get("to"), true, loc)
+	if err != nil {
+		http.Error(w, "Invalid to: "+err.Error(), http.StatusBadRequest)
+		return
+	}
+	if from.IsZero() {
+		end := to
+		if end.IsZero() {
+			end = time.Now()
+		}
+		from = end.Add(-defaultHistoryRange)
+	}
+	if !to.IsZero() && !to.After(from) {
+		http.Error(w, "to must be after from", http.StatusBadRequest)
+		return
+	}
+	end, maxRange := to, maxHistoryRange
+	if end.IsZero() {
+		end = time.Now()
+	}
+	if interval == "raw" {
+		maxRange = maxRawHistoryRange
+	}
+	if end.Sub(from) > maxRange {
+		http.Error(w, fmt.Sprintf("range must be at most %d days for interval %s", int(maxRange.Hours()/24), interval), http.StatusBadRequest)
+		return
+	}
+
+	// Đọc thừa 1 bản ghi để biết khoảng có vượt giới hạn không
+	snapshots, err := h.Store.ListRates(from, to, maxHistoryRecords+1)
+	if err != nil {
+		log.Printf("[API ERROR] DB ListRates failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if len(snapshots) > maxHistoryRecords {
+		http.Error(w, "too many rate records in range, narrow from/to", http.StatusBadRequest)
+		return
+	}
+	jsonResponse(w, http.StatusOK, rateHistory(snapshots, asset, interval, loc))
+}
+
+// rateHistory gom các bản ghi giá (đã theo thứ tự thời gian) thành các điểm OHLC theo interval
+func rateHistory(snapshots []model.RateSnapshot, asset, interval string, loc *time.Location) []model.RatePoint {
+	points := []model.RatePoint{}
+	for _, snap := range snapshots {
+		rate := service.RateToVND(asset, snap.Rates)
+		if !rate.IsPositive() {
+			continue // Lần lấy giá lỗi nguồn này
+		}
+		at := rateBucket(snap.FetchedAt, interval, loc)
+
+		if n := len(points); n > 0 && points[n-1].Time.Equal(at) {
+			p := &points[n-1]
+			if rate.GreaterThan(p.High) {
+				p.High = rate
+			}
+			if rate.LessThan(p.Low) {
+				p.Low = rate
+			}
+			p.Close = rate
+			p.Samples++
+			continue
+		}
+		points = append(points, model.RatePoint{Time: at, Open: rate, High: rate, Low: rate, Close: rate, Samples: 1})
+	}
+	return points
+}
+
+// rateBucket thời điểm bắt đầu khoảng chứa t (tuần bắt đầu từ thứ 2)
+func rateBucket(t time.Time, interval string, loc *time.Location) time.Time {
+	t = t.In(loc)
+	switch interval {
+	case "hour":
+		return time.Date(t.Year(), t.Month(), t.Day(), t.Hour(), 0, 0, 0, loc)
+	case "day":
+		return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+	case "week":
+		day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, loc)
+		return day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+	return t
+}
+
+// GetRateProviders godoc
+// @Summary      Tình trạng nguồn giá
+// @Description  Thứ tự nguồn giá (dự phòng) của từng tài sản và tình trạng lần gọi gần nhất của từng nguồn.
+// @Description  Nguồn lỗi liên tục bị tạm ngắt (status circuit_open) tới circuit_open_until rồi mới được gọi lại.
+// @Description  Đổi nguồn bằng env RATE_PROVIDERS_<ASSET>=nguồn1,nguồn2 (VD: RATE_PROVIDERS_BTC=coinbase,coingecko).
+// @Tags         Market Data
+// @Produce      json
+// @Success      200  {object}  model.RateProvidersStatus
+// @Router       /market-rates/providers [get]
+func (h *FinanceHandler) GetRateProviders(w http.ResponseWriter, r *http.Request) {
+	jsonResponse(w, http.StatusOK, service.RateProvidersStatus())
+}
+
+// ListAssets godoc
+// @Summary      Danh sách tài sản
+// @Description  Các loại tiền/tài sản dùng được trong giao dịch và tài khoản (currency), kèm giá VND hiện tại của 1 đơn vị
+// @Description  (rate_vnd tham chiếu, bid_vnd mua vào, ask_vnd bán ra). has_spread=false: nguồn giá chỉ có giá tham chiếu nên bid_vnd = ask_vnd = rate_vnd.
+// @Description  aliases là cách viết trong tin nhắn bot (VD: "tk 100 eur", "bán 0.5 eth").
+// @Description  Thêm tài sản tự định nghĩa bằng env CUSTOM_ASSETS (mảng JSON cùng cấu trúc, giá cố định rate.price hoặc mã giá rate.code).
+// @Tags         Market Data
+// @Produce      json
+// @Success      200  {array}  model.AssetQuote
+// @Router       /assets [get]
+func (h *FinanceHandler) ListAssets(w http.ResponseWriter, r *http.Request) {
+	rates := service.GetCurrentRates()
+	result := []model.AssetQuote{}
+	for _, a := range service.Assets() {
+		result = append(result, model.AssetQuote{Asset: a, RateVND: a.RateIn(rates), BidVND: a.BidIn(rates), AskVND: a.AskIn(rates),
+			HasSpread: a.SpreadIn(rates), Stale: a.StaleIn(rates)})
+	}
+	jsonResponse(w, http.StatusOK, result)
+}
@@ -0,0 +1,158 @@
+package handler
+
+import (
+	"errors"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"go-finance/internal/store"
+	"log"
+	"net/http"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// portfolioPeriods các mốc so sánh biến động giá của danh mục tài sản
+var portfolioPeriods = []struct {
+	name  string
+	since func(now time.Time) time.Time
+}{
+	{"day", func(now time.Time) time.Time { return now.Add(-24 * time.Hour) }},
+	{"week", func(now time.Time) time.Time { return now.AddDate(0, 0, -7) }},
+	{"month", func(now time.Time) time.Time { return now.AddDate(0, -1, 0) }},
+}
+
+// GetPortfolio godoc
+// @Summary      Danh mục tài sản đang giữ
+// @Description  Tính toàn bộ tài sản tiết kiệm đang giữ (từ trước tới nay, không theo kỳ báo cáo) theo từng đơn vị tiền:
+// @Description  số lượng, giá mua bình quân, giá trị theo giá hiện tại, lãi/lỗ và biến động so với 1 ngày/1 tuần/1 tháng trước.
+// @Description  Biến động chỉ tính phần do giá thay đổi trên số lượng đang giữ, thiếu mốc nào nếu server chưa có giá lịch sử tới mốc đó.
+// @Tags         Portfolio
+// @Produce      json
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.Portfolio
+// @Failure      400      {string}  string  "Thiếu user_id"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /portfolio [get]
+func (h *FinanceHandler) GetPortfolio(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+
+	settings, err := h.Store.GetSettings(userID)
+	if err != nil {
+		log.Printf("[API ERROR] DB GetSettings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	txs, err := h.Store.GetByPeriod(userID, time.Time{}, time.Time{})
+	if err != nil {
+		log.Printf("[API ERROR] DB GetByPeriod failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Giá tại các mốc so sánh, bỏ mốc chưa có lịch sử
+	now := time.Now()
+	past := make(map[string]model.ExchangeRates)
+	for _, pp := range portfolioPeriods {
+		snap, err := h.Store.RatesAt(pp.since(now))
+		if errors.Is(err, store.ErrNotFound) {
+			continue
+		}
+		if err != nil {
+			log.Printf("[API ERROR] DB RatesAt failed: %v", err)
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		past[pp.name] = snap.Rates
+	}
+
+	jsonResponse(w, http.StatusOK, buildPortfolio(userID, settings, txs, now, service.GetCurrentRates(), past))
+}
+
+// buildPortfolio gom tài sản đang giữ từ toàn bộ giao dịch của user và định giá theo rates,
+// past là giá tại các mốc so sánh (theo tên trong portfolioPeriods). Vàng/bạc hiển thị theo đơn vị trong settings
+func buildPortfolio(userID string, settings model.UserSettings, txs []model.Transaction, now time.Time, rates model.ExchangeRates, past map[string]model.ExchangeRates) model.Portfolio {
+	p := model.Portfolio{
+		UserID:    userID,
+		CostBasis: settings.CostBasis,
+		AsOf:      now,
+		Holdings:  []model.PortfolioHolding{},
+		Changes:   make(map[string]model.PriceChange),
+	}
+
+	for currency, hd := range service.ComputeHoldings(txs, settings.CostBasis) {
+		var realized decimal.Decimal
+		for _, sale := range hd.Sales {
+			realized = realized.Add(sale.Gain())
+		}
+		if !hd.Quantity.IsPositive() && realized.IsZero() {
+			continue
+		}
+
+		ph := model.PortfolioHolding{
+			Currency:   currency,
+			Quantity:   hd.Quantity,
+			CostVND:    hd.CostVND.Round(0),
+			Rate:       service.BidToVND(currency, rates),
+			RealizedPL: realized.Round(0),
+			Stale:      service.RateStale(currency, rates),
+			Changes:    make(map[string]model.PriceChange),
+		}
+		p.RatesStale = p.RatesStale || ph.Stale
+		if hd.Quantity.IsPositive() {
+			ph.AvgPriceVND = hd.CostVND.Div(hd.Quantity).Round(0)
+		}
+		ph.CurrentVND = ph.Quantity.Mul(ph.Rate).Round(0)
+		ph.UnrealizedPL = ph.CurrentVND.Sub(ph.CostVND)
+		ph.DisplayUnit, ph.DisplayQuantity, ph.DisplayRate = service.DisplayQuantity(currency, ph.Quantity, ph.Rate, settings)
+		ph.DisplayAvgPrice = service.DisplayUnitPrice(currency, ph.AvgPriceVND, settings)
+
+		if currency != "VND" {
+			for _, pp := range portfolioPeriods {
+				then, ok := past[pp.name]
+				if !ok {
+					continue
+				}
+				thenRate := service.BidToVND(currency, then)
+				if !thenRate.IsPositive() {
+					continue
+				}
+				ph.Changes[pp.name] = model.PriceChange{
+					Since:     pp.since(now),
+					Rate:      thenRate,
+					ChangeVND: ph.Quantity.Mul(ph.Rate.Sub(thenRate)).Round(0),
+					Percent:   ph.Rate.Sub(thenRate).Div(thenRate).Mul(decimal.NewFromInt(100)).Round(2),
+				}
+			}
+		}
+
+		p.Holdings = append(p.Holdings, ph)
+		p.TotalCostVND = p.TotalCostVND.Add(ph.CostVND)
+		p.TotalCurrentVND = p.TotalCurrentVND.Add(ph.CurrentVND)
+		p.TotalUnrealizedPL = p.TotalUnrealizedPL.Add(ph.UnrealizedPL)
+		p.TotalRealizedPL = p.TotalRealizedPL.Add(ph.RealizedPL)
+	}
+	sort.Slice(p.Holdings, func(i, j int) bool { return p.Holdings[i].Currency < p.Holdings[j].Currency })
+
+	// Biến động tổng: cộng biến động từng loại, phần trăm so với tổng giá trị tại mốc đó
+	for _, pp := range portfolioPeriods {
+		if _, ok := past[pp.name]; !ok {
+			continue
+		}
+		var change decimal.Decimal
+		for _, ph := range p.Holdings {
+			change = change.Add(ph.Changes[pp.name].ChangeVND)
+		}
+		total := model.PriceChange{Since: pp.since(now), ChangeVND: change}
+		if base := p.TotalCurrentVND.Sub(change); base.IsPositive() {
+			total.Percent = change.Div(base).Mul(decimal.NewFromInt(100)).Round(2)
+		}
+		p.Changes[pp.name] = total
+	}
+	return p
+}
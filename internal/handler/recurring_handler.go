@@ -0,0 +1,247 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"go-finance/internal/store"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// validateRecurrence kiểm tra lịch lặp, Day = 0 được thay bằng ngày/thứ của now.
+// Trả về thông báo lỗi nếu không hợp lệ
+func validateRecurrence(rec *model.Recurrence, now time.Time) string {
+	switch rec.Frequency {
+	case "daily":
+		rec.Day = 0
+	case "weekly":
+		if rec.Day == 0 {
+			rec.Day = (int(now.Weekday())+6)%7 + 1 // ISO: thứ 2 = 1 ... chủ nhật = 7
+		}
+		if rec.Day < 1 || rec.Day > 7 {
+			return "day must be between 1 (Monday) and 7 (Sunday) for weekly"
+		}
+	case "monthly":
+		if rec.Day == 0 {
+			rec.Day = now.Day()
+		}
+		if rec.Day < 1 || rec.Day > 31 {
+			return "day must be between 1 and 31 for monthly"
+		}
+	default:
+		return "frequency must be daily, weekly or monthly"
+	}
+	return ""
+}
+
+// CreateRecurring godoc
+// @Summary      Tạo giao dịch định kỳ
+// @Description  Tạo quy tắc tự ghi giao dịch theo lịch (VD: tiền nhà 5m mỗi tháng ngày 1). Số tiền tính bằng VND.
+// @Description  Giao dịch được tạo lúc 0h theo múi giờ của user, lần đầu là lần chạy kế tiếp sau thời điểm tạo.
+// @Description  `day`: ngày trong tháng (monthly) hoặc thứ 1-7 (weekly, 1 là thứ 2); bỏ trống là lấy theo hôm nay.
+// @Tags         Recurring
+// @Accept       json
+// @Produce      json
+// @Param        payload  body      model.RecurringRule  true  "Quy tắc (id, timezone, next_run, created_at bỏ qua)"
+// @Success      200      {object}  model.RecurringRule
+// @Failure      400      {string}  string  "Lỗi dữ liệu đầu vào"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /recurring [post]
+func (h *FinanceHandler) CreateRecurring(w http.ResponseWriter, r *http.Request) {
+	var rule model.RecurringRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	if rule.UserID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	if rule.Type != "thu" && rule.Type != "chi" && rule.Type != "tiet_kiem" {
+		http.Error(w, "type must be thu, chi or tiet_kiem", http.StatusBadRequest)
+		return
+	}
+	if !rule.Amount.IsPositive() {
+		http.Error(w, "amount must be positive", http.StatusBadRequest)
+		return
+	}
+	rule.Note = strings.TrimSpace(rule.Note)
+
+	settings, err := h.Store.GetSettings(rule.UserID)
+	if err != nil {
+		log.Printf("[API ERROR] DB GetSettings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	rule.Timezone = settings.Location().String()
+	now := time.Now().In(rule.Location())
+	if msg := validateRecurrence(&rule.Recurrence, now); msg != "" {
+		http.Error(w, msg, http.StatusBadRequest)
+		return
+	}
+	rule.Paused = false
+	rule.NextRun = service.NextOccurrence(rule, now)
+
+	id, err := h.Store.CreateRecurring(rule)
+	if err != nil {
+		log.Printf("[API ERROR] DB CreateRecurring failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rule, err = h.Store.GetRecurring(id); err != nil {
+		log.Printf("[API ERROR] DB GetRecurring failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[API INFO] Created recurring rule #%d of user %s: %s %s %s/%d, next %s",
+		rule.ID, rule.UserID, rule.Type, rule.Amount, rule.Frequency, rule.Day, rule.NextRun.Format(time.RFC3339))
+	jsonResponse(w, http.StatusOK, rule)
+}
+
+// ListRecurring godoc
+// @Summary      Danh sách giao dịch định kỳ
+// @Tags         Recurring
+// @Produce      json
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {array}   model.RecurringRule
+// @Failure      400      {string}  string  "Thiếu user_id"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /recurring [get]
+func (h *FinanceHandler) ListRecurring(w http.ResponseWriter, r *http.Request) {
+	userID := r.URL.Query().Get("user_id")
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return
+	}
+	rules, err := h.Store.ListRecurring(userID)
+	if err != nil {
+		log.Printf("[API ERROR] DB ListRecurring failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	if rules == nil {
+		rules = []model.RecurringRule{}
+	}
+	jsonResponse(w, http.StatusOK, rules)
+}
+
+// loadOwnedRecurring đọc {id} trên URL và kiểm tra quy tắc thuộc về user_id.
+// Tự ghi response lỗi và trả về false nếu không hợp lệ
+func (h *FinanceHandler) loadOwnedRecurring(w http.ResponseWriter, r *http.Request, userID string) (model.RecurringRule, bool) {
+	id, err := strconv.Atoi(r.PathValue("id"))
+	if err != nil {
+		http.Error(w, "Invalid rule id", http.StatusBadRequest)
+		return model.RecurringRule{}, false
+	}
+	if userID == "" {
+		http.Error(w, "user_id is required", http.StatusBadRequest)
+		return model.RecurringRule{}, false
+	}
+
+	rule, err := h.Store.GetRecurring(id)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Recurring rule not found", http.StatusNotFound)
+		return rule, false
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB GetRecurring failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return rule, false
+	}
+	if rule.UserID != userID {
+		log.Printf("[API WARN] User %s tried to access recurring rule %d of another user", userID, id)
+		http.Error(w, "Forbidden", http.StatusForbidden)
+		return rule, false
+	}
+	return rule, true
+}
+
+// UpdateRecurring godoc
+// @Summary      Sửa / tạm dừng giao dịch định kỳ
+// @Description  Đổi số tiền, ghi chú hoặc tạm dừng (`paused: true`) / chạy lại (`paused: false`).
+// @Description  Khi chạy lại, các lần đến hạn trong lúc tạm dừng được bỏ qua.
+// @Tags         Recurring
+// @Accept       json
+// @Produce      json
+// @Param        id       path      int                        true  "ID quy tắc"
+// @Param        payload  body      model.RecurringRuleUpdate  true  "Các trường cần sửa"
+// @Success      200      {object}  model.RecurringRule
+// @Failure      400      {string}  string  "Lỗi dữ liệu đầu vào"
+// @Failure      403      {string}  string  "Quy tắc không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /recurring/{id} [patch]
+func (h *FinanceHandler) UpdateRecurring(w http.ResponseWriter, r *http.Request) {
+	var req model.RecurringRuleUpdate
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+
+	rule, ok := h.loadOwnedRecurring(w, r, req.UserID)
+	if !ok {
+		return
+	}
+	if req.Amount != nil {
+		if !req.Amount.IsPositive() {
+			http.Error(w, "amount must be positive", http.StatusBadRequest)
+			return
+		}
+		rule.Amount = *req.Amount
+	}
+	if req.Note != nil {
+		rule.Note = strings.TrimSpace(*req.Note)
+	}
+	if req.Paused != nil {
+		// Chạy lại thì tính lịch từ bây giờ để không tạo bù các lần đã lỡ khi tạm dừng
+		if rule.Paused && !*req.Paused {
+			rule.NextRun = service.NextOccurrence(rule, time.Now())
+		}
+		rule.Paused = *req.Paused
+	}
+
+	if err := h.Store.UpdateRecurring(rule); err != nil {
+		log.Printf("[API ERROR] DB UpdateRecurring failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, http.StatusOK, rule)
+}
+
+// DeleteRecurring godoc
+// @Summary      Xóa giao dịch định kỳ
+// @Description  Xóa quy tắc. Các giao dịch đã được tạo trước đó vẫn giữ nguyên.
+// @Tags         Recurring
+// @Produce      json
+// @Param        id       path      int     true  "ID quy tắc"
+// @Param        user_id  query     string  true  "ID người dùng Telegram"
+// @Success      200      {object}  model.RecurringRule  "Quy tắc vừa xóa"
+// @Failure      400      {string}  string  "Thiếu user_id hoặc id sai"
+// @Failure      403      {string}  string  "Quy tắc không thuộc về user"
+// @Failure      404      {string}  string  "Không tìm thấy"
+// @Router       /recurring/{id} [delete]
+func (h *FinanceHandler) DeleteRecurring(w http.ResponseWriter, r *http.Request) {
+	rule, ok := h.loadOwnedRecurring(w, r, r.URL.Query().Get("user_id"))
+	if !ok {
+		return
+	}
+	err := h.Store.DeleteRecurring(rule.ID)
+	if errors.Is(err, store.ErrNotFound) {
+		http.Error(w, "Recurring rule not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		log.Printf("[API ERROR] DB DeleteRecurring failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[API INFO] Deleted recurring rule #%d of user %s", rule.ID, rule.UserID)
+	jsonResponse(w, http.StatusOK, rule)
+}
@@ -0,0 +1,126 @@
+package handler
+
+import (
+	"encoding/json"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// GetSettings godoc
+// @Summary      Xem cài đặt của user
+// @Description  Trả về múi giờ, ngày đầu tuần, cách tính giá vốn và đơn vị hiển thị vàng, bạc của user (giá trị mặc định nếu user chưa cài đặt).
+// @Tags         Users
+// @Produce      json
+// @Param        id   path      string  true  "ID người dùng Telegram"
+// @Success      200  {object}  model.UserSettings
+// @Failure      500  {string}  string  "Lỗi Server"
+// @Router       /users/{id}/settings [get]
+func (h *FinanceHandler) GetSettings(w http.ResponseWriter, r *http.Request) {
+	settings, err := h.Store.GetSettings(r.PathValue("id"))
+	if err != nil {
+		log.Printf("[API ERROR] DB GetSettings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	jsonResponse(w, http.StatusOK, settings)
+}
+
+// UpdateSettings godoc
+// @Summary      Cập nhật cài đặt của user
+// @Description  Đổi múi giờ (tên IANA, VD: Asia/Ho_Chi_Minh), ngày đầu tuần, cách tính giá vốn (average, fifo)
+// @Description  và/hoặc đơn vị hiển thị vàng, bạc (chi, luong, g, kg, oz). Trường không gửi sẽ giữ nguyên.
+// @Tags         Users
+// @Accept       json
+// @Produce      json
+// @Param        id       path      string              true  "ID người dùng Telegram"
+// @Param        payload  body      model.UserSettings  true  "Cài đặt mới"
+// @Success      200      {object}  model.UserSettings
+// @Failure      400      {string}  string  "Múi giờ, ngày đầu tuần, cách tính giá vốn hoặc đơn vị không hợp lệ"
+// @Failure      500      {string}  string  "Lỗi Server"
+// @Router       /users/{id}/settings [put]
+func (h *FinanceHandler) UpdateSettings(w http.ResponseWriter, r *http.Request) {
+	userID := r.PathValue("id")
+	settings, err := h.Store.GetSettings(userID)
+	if err != nil {
+		log.Printf("[API ERROR] DB GetSettings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	// Decode đè lên cài đặt hiện tại để các trường không gửi được giữ nguyên
+	if err := json.NewDecoder(r.Body).Decode(&settings); err != nil {
+		log.Printf("[API ERROR] Decode JSON failed: %v", err)
+		http.Error(w, "Invalid input", http.StatusBadRequest)
+		return
+	}
+	settings.UserID = userID
+
+	if _, err := time.LoadLocation(settings.Timezone); err != nil || settings.Timezone == "" {
+		http.Error(w, "Invalid timezone: "+settings.Timezone, http.StatusBadRequest)
+		return
+	}
+	if settings.WeekStart < time.Sunday || settings.WeekStart > time.Saturday {
+		http.Error(w, "week_start must be between 0 (Sunday) and 6 (Saturday)", http.StatusBadRequest)
+		return
+	}
+	if settings.CostBasis != model.CostBasisAverage && settings.CostBasis != model.CostBasisFIFO {
+		http.Error(w, "cost_basis must be average or fifo", http.StatusBadRequest)
+		return
+	}
+	for _, unit := range []*string{&settings.GoldUnit, &settings.SilverUnit} {
+		u, ok := service.LookupMetalUnit(*unit)
+		if !ok {
+			http.Error(w, "gold_unit and silver_unit must be one of "+strings.Join(service.MetalUnitCodes(), ", "), http.StatusBadRequest)
+			return
+		}
+		*unit = u.Code
+	}
+
+	if err := h.Store.SaveSettings(settings); err != nil {
+		log.Printf("[API ERROR] DB SaveSettings failed: %v", err)
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	log.Printf("[API INFO] Updated settings of user %s: %+v", userID, settings)
+	jsonResponse(w, http.StatusOK, settings)
+}
+
+// ListSettings godoc
+// @Summary      Cài đặt của tất cả user
+// @Description  Dùng cho scheduler của bot: trả về múi giờ của mọi user (đã có giao dịch hoặc đã cài đặt).
+// @Tags         Users
+// @Produce      json
+// @Success      200  {array}   model.UserSettings
+// @Failure      500  {string}  string  "Lỗi Server"
+// @Router       /users/settings [get]
+func (h *FinanceHandler) ListSettings(w http.ResponseWriter, r *http.Request) {
+	saved, err := h.Store.ListSettings()
+	if err != nil {
+		log.Printf("[API ERROR] DB ListSettings failed: %v", err)
+		http.Error(w, "Error fetching settings", http.StatusInternalServerError)
+		return
+	}
+	userIDs, err := h.Store.GetAllUserIDs()
+	if err != nil {
+		log.Printf("[API ERROR] GetUsers failed: %v", err)
+		http.Error(w, "Error fetching users", http.StatusInternalServerError)
+		return
+	}
+
+	result := make([]model.UserSettings, 0, len(userIDs))
+	seen := make(map[string]bool)
+	for _, st := range saved {
+		seen[st.UserID] = true
+		result = append(result, st)
+	}
+	for _, uid := range userIDs {
+		if !seen[uid] {
+			result = append(result, model.DefaultUserSettings(uid))
+		}
+	}
+	jsonResponse(w, http.StatusOK, result)
+}
@@ -1,57 +1,367 @@
 package model
 
-import "time"
+import (
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+func init() {
+	// Giữ tiền dạng số trong JSON (50000 thay vì "50000") để client cũ không bị ảnh hưởng.
+	// Giá trị vẫn chính xác vì decimal được encode nguyên văn, không qua float64.
+	decimal.MarshalJSONWithoutQuotes = true
+}
 
 // Transaction tương ứng với bảng trong DB
 type Transaction struct {
-	ID             int       `json:"id"`
-	UserID         string    `json:"user_id"`
-	Type           string    `json:"type"`   // thu, chi, tiet_kiem
-	Amount         float64   `json:"amount"` // Giá trị quy đổi VND
-	Note           string    `json:"note"`
-	Category       string    `json:"category"` // Có thể rỗng
-	CreatedAt      time.Time `json:"created_at"`
-	Currency       string    `json:"currency"`        // VND, USD, BTC, GOLD
-	OriginalAmount float64   `json:"original_amount"` // Số lượng gốc
+	ID             int             `json:"id"`
+	UserID         string          `json:"user_id"`
+	Type           string          `json:"type"`                        // thu, chi, tiet_kiem, rut, chuyen
+	Amount         decimal.Decimal `json:"amount" swaggertype:"number"` // Giá trị quy đổi VND
+	Note           string          `json:"note"`
+	Category       string          `json:"category"` // Có thể rỗng
+	CreatedAt      time.Time       `json:"created_at"`
+	Currency       string          `json:"currency"`                             // VND, USD, BTC, GOLD
+	OriginalAmount decimal.Decimal `json:"original_amount" swaggertype:"number"` // Số lượng gốc
+	AccountID      *int            `json:"account_id,omitempty"`                 // Tài khoản trả/nhận tiền, nil là không gắn tài khoản
+	ToAccountID    *int            `json:"to_account_id,omitempty"`              // Tài khoản nhận của giao dịch chuyen
+	RateSnapshotID *int            `json:"rate_snapshot_id,omitempty"`           // Bản ghi giá đã dùng để quy đổi ra VND
+	AmountExpr     string          `json:"amount_expr,omitempty"`                // Biểu thức gốc của số tiền ("3x25k"), rỗng nếu nhập số
 }
 
 // TransactionCreate DTO cho input
 type TransactionCreate struct {
 	UserID string `json:"user_id" example:"123456789"`
 
-	// Loại giao dịch: thu, chi, tiet_kiem
-	Type string `json:"type" example:"chi" enums:"thu,chi,tiet_kiem"`
+	// Loại giao dịch: thu, chi, tiet_kiem, rut (rút/bán tài sản tiết kiệm),
+	// chuyen (chuyển tiền giữa 2 tài khoản, không tính là thu/chi)
+	Type string `json:"type" example:"chi" enums:"thu,chi,tiet_kiem,rut,chuyen"`
 
-	// Số tiền (Nếu là VND thì nhập tiền Việt, nếu là GOLD thì nhập số chỉ/lượng)
-	Amount float64 `json:"amount" example:"50000"`
+	// Số tiền (Nếu là VND thì nhập tiền Việt, nếu là GOLD thì nhập số chỉ, SILVER số lượng)
+	Amount decimal.Decimal `json:"amount" swaggertype:"number" example:"50000"`
 
 	// Ghi chú chi tiết
 	Note string `json:"note" example:"Cà phê sáng"`
 
-	// Đơn vị tiền: VND, USD, BTC, GOLD
-	Currency string `json:"currency" example:"VND" enums:"VND,USD,BTC,GOLD"`
+	// Mã tài sản: VND, USD, EUR, BTC, GOLD... (xem GET /assets). Rỗng là VND
+	Currency string `json:"currency" example:"VND"`
+
+	// Đơn vị khối lượng của amount với GOLD, SILVER: chi, luong, g, kg, oz. Rỗng là đơn vị gốc (chỉ, lượng)
+	Unit string `json:"unit,omitempty" example:"g"`
 
 	// Danh mục chi tiêu (ăn uống, đi lại...)
 	Category string `json:"category" example:"ăn uống"`
+
+	// Tên tài khoản trả/nhận tiền (tùy chọn). Với chuyen là tài khoản chuyển đi
+	Account string `json:"account,omitempty" example:"momo"`
+
+	// Tên tài khoản nhận, chỉ dùng cho chuyen
+	ToAccount string `json:"to_account,omitempty" example:"vcb"`
+
+	// Biểu thức gốc đã tính ra amount ("3x25k", "120k/4"), lưu lại để đối chiếu. Rỗng nếu nhập số
+	AmountExpr string `json:"amount_expr,omitempty" example:"3x25k"`
+
+	// Thời điểm phát sinh giao dịch (ghi bù giao dịch cũ). Bỏ trống là thời điểm gửi lên,
+	// không được ở tương lai hoặc quá 5 năm trước
+	OccurredAt *time.Time `json:"occurred_at,omitempty" example:"2025-03-15T20:00:00+07:00"`
+
+	// Lịch lặp lại bóc từ tin nhắn ("hàng tháng ngày 1"). Chỉ dùng trong bot,
+	// khác nil thì bot tạo quy tắc định kỳ thay vì ghi giao dịch
+	Recurrence *Recurrence `json:"-" swaggerignore:"true"`
+}
+
+// Recurrence lịch lặp lại của giao dịch định kỳ
+type Recurrence struct {
+	// Tần suất: daily, weekly, monthly
+	Frequency string `json:"frequency" example:"monthly" enums:"daily,weekly,monthly"`
+
+	// monthly: ngày trong tháng 1-31 (tháng thiếu ngày thì chạy ngày cuối tháng);
+	// weekly: thứ theo ISO 1 (thứ 2) - 7 (chủ nhật); 0 là lấy theo ngày tạo
+	Day int `json:"day" example:"1"`
+}
+
+// RecurringRule quy tắc tạo giao dịch định kỳ (tiền nhà, lương, internet...)
+type RecurringRule struct {
+	ID       int             `json:"id"`
+	UserID   string          `json:"user_id" example:"123456789"`
+	Type     string          `json:"type" example:"chi" enums:"thu,chi,tiet_kiem"`
+	Amount   decimal.Decimal `json:"amount" swaggertype:"number" example:"5000000"` // Luôn là VND
+	Note     string          `json:"note" example:"tiền nhà"`
+	Category string          `json:"category" example:"sinh hoạt"`
+	Recurrence
+
+	// Múi giờ tính lịch chạy, lấy theo cài đặt của user lúc tạo
+	Timezone string `json:"timezone" example:"Asia/Ho_Chi_Minh"`
+
+	Paused    bool      `json:"paused"`
+	NextRun   time.Time `json:"next_run"` // Lần tạo giao dịch kế tiếp
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// Location múi giờ của quy tắc, mặc định Asia/Ho_Chi_Minh nếu không hợp lệ
+func (r RecurringRule) Location() *time.Location {
+	return UserSettings{Timezone: r.Timezone}.Location()
+}
+
+// RecurringRuleUpdate DTO cho PATCH /recurring/{id}. Trường nào nil thì giữ nguyên
+type RecurringRuleUpdate struct {
+	UserID string           `json:"user_id" example:"123456789"`
+	Paused *bool            `json:"paused,omitempty"`
+	Amount *decimal.Decimal `json:"amount,omitempty" swaggertype:"number"`
+	Note   *string          `json:"note,omitempty"`
+}
+
+// TransactionUpdate DTO cho PATCH /transactions/{id}.
+// Trường nào nil thì giữ nguyên giá trị cũ
+type TransactionUpdate struct {
+	// Chủ sở hữu giao dịch, bắt buộc để kiểm tra quyền
+	UserID string `json:"user_id" example:"123456789"`
+
+	Type     *string          `json:"type,omitempty" example:"chi" enums:"thu,chi,tiet_kiem,rut,chuyen"`
+	Amount   *decimal.Decimal `json:"amount,omitempty" swaggertype:"number" example:"50000"`
+	Note     *string          `json:"note,omitempty" example:"Cà phê sáng"`
+	Currency *string          `json:"currency,omitempty" example:"VND"`
+	Category *string          `json:"category,omitempty" example:"ăn uống"`
+
+	// Biểu thức gốc của amount mới ("120k/4"). Đổi amount mà không gửi trường này thì xóa biểu thức cũ
+	AmountExpr *string `json:"amount_expr,omitempty" example:"120k/4"`
+
+	// Tên tài khoản, chuỗi rỗng là bỏ gắn tài khoản
+	Account   *string `json:"account,omitempty" example:"momo"`
+	ToAccount *string `json:"to_account,omitempty" example:"vcb"`
+}
+
+// TransactionFilter điều kiện lọc cho GET /transactions.
+// Các trường zero value nghĩa là không lọc theo trường đó
+type TransactionFilter struct {
+	UserID    string
+	From      time.Time // created_at >= From
+	To        time.Time // created_at < To
+	Type      string
+	Category  string
+	Currency  string
+	MinAmount *decimal.Decimal // So với amount (VND)
+	MaxAmount *decimal.Decimal
+	Search    string // Tìm toàn văn trong note, mọi từ đều phải xuất hiện
+	Ascending bool   // Mặc định mới nhất trước
+	Cursor    string // next_cursor của trang trước
+	Limit     int
+}
+
+// TransactionPage một trang kết quả, NextCursor rỗng nghĩa là đã hết
+type TransactionPage struct {
+	Items      []Transaction `json:"items"`
+	NextCursor string        `json:"next_cursor,omitempty"`
+}
+
+// Múi giờ mặc định cho user chưa cài đặt
+const DefaultTimezone = "Asia/Ho_Chi_Minh"
+
+// UserSettings cài đặt riêng của từng user, dùng để tính kỳ báo cáo và giờ gửi thông báo
+type UserSettings struct {
+	UserID string `json:"user_id" example:"123456789"`
+
+	// Múi giờ IANA, VD: Asia/Ho_Chi_Minh, Europe/Berlin
+	Timezone string `json:"timezone" example:"Asia/Ho_Chi_Minh"`
+
+	// Ngày đầu tuần: 0 = Chủ nhật, 1 = Thứ 2, ... 6 = Thứ 7
+	WeekStart time.Weekday `json:"week_start" swaggertype:"integer" example:"1" minimum:"0" maximum:"6"`
+
+	// Cách tính giá vốn khi rút/bán tài sản tiết kiệm: average (bình quân) hoặc fifo (nhập trước xuất trước)
+	CostBasis string `json:"cost_basis" example:"average" enums:"average,fifo"`
+
+	// Đơn vị hiển thị vàng, bạc trong báo cáo: chi, luong, g, kg, oz (gửi lên nhận cả "chỉ", "lượng", "cây", "gram")
+	GoldUnit   string `json:"gold_unit" example:"chi" enums:"chi,luong,g,kg,oz"`
+	SilverUnit string `json:"silver_unit" example:"luong" enums:"chi,luong,g,kg,oz"`
+}
+
+// Cách tính giá vốn tài sản tiết kiệm
+const (
+	CostBasisAverage = "average"
+	CostBasisFIFO    = "fifo"
+)
+
+// Đơn vị hiển thị mặc định: vàng theo chỉ, bạc theo lượng (đúng đơn vị lưu số lượng)
+const (
+	DefaultGoldUnit   = "chi"
+	DefaultSilverUnit = "luong"
+)
+
+// DefaultUserSettings cài đặt mặc định: giờ Việt Nam, tuần bắt đầu từ thứ 2, giá vốn bình quân, vàng theo chỉ, bạc theo lượng
+func DefaultUserSettings(userID string) UserSettings {
+	return UserSettings{UserID: userID, Timezone: DefaultTimezone, WeekStart: time.Monday, CostBasis: CostBasisAverage,
+		GoldUnit: DefaultGoldUnit, SilverUnit: DefaultSilverUnit}
+}
+
+// Location trả về múi giờ của user, rơi về giờ Việt Nam nếu tên múi giờ không hợp lệ
+func (s UserSettings) Location() *time.Location {
+	if loc, err := time.LoadLocation(s.Timezone); err == nil && s.Timezone != "" {
+		return loc
+	}
+	loc, _ := time.LoadLocation(DefaultTimezone)
+	return loc
+}
+
+// Budget ngân sách chi tiêu cho một danh mục trong một kỳ (tuần/tháng)
+type Budget struct {
+	ID     int    `json:"id"`
+	UserID string `json:"user_id" example:"123456789"`
+
+	// Danh mục chi tiêu, khớp với category của giao dịch chi
+	Category string `json:"category" example:"ăn uống"`
+
+	// Kỳ ngân sách: week hoặc month
+	Period string `json:"period" example:"month" enums:"week,month"`
+
+	// Hạn mức VND cho mỗi kỳ
+	Limit decimal.Decimal `json:"limit" swaggertype:"number" example:"3000000"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// BudgetStatus tình hình chi tiêu so với ngân sách trong kỳ hiện tại
+type BudgetStatus struct {
+	Budget    Budget          `json:"budget"`
+	StartDate string          `json:"start_date"`
+	EndDate   string          `json:"end_date"`
+	Spent     decimal.Decimal `json:"spent" swaggertype:"number"`
+	Remaining decimal.Decimal `json:"remaining" swaggertype:"number"` // Âm nghĩa là đã vượt
+	Percent   decimal.Decimal `json:"percent" swaggertype:"number"`   // Spent / Limit * 100, làm tròn 1 số lẻ
+}
+
+// NewBudgetStatus tính phần còn lại và phần trăm đã dùng
+func NewBudgetStatus(b Budget, spent decimal.Decimal, start, end string) BudgetStatus {
+	st := BudgetStatus{Budget: b, StartDate: start, EndDate: end, Spent: spent, Remaining: b.Limit.Sub(spent)}
+	if b.Limit.IsPositive() {
+		st.Percent = spent.Mul(decimal.NewFromInt(100)).Div(b.Limit).Round(1)
+	}
+	return st
+}
+
+// Account tài khoản / ví tiền của user (tiền mặt, thẻ ngân hàng, ví điện tử...)
+type Account struct {
+	ID     int    `json:"id"`
+	UserID string `json:"user_id" example:"123456789"`
+
+	// Tên ngắn, duy nhất với mỗi user, dùng trong tin nhắn dạng @momo
+	Name string `json:"name" example:"momo"`
+
+	// Mã tài sản của tài khoản: VND, USD, BTC, GOLD... (xem GET /assets)
+	Currency string `json:"currency" example:"VND"`
+
+	// Số dư lúc bắt đầu theo dõi
+	OpeningBalance decimal.Decimal `json:"opening_balance" swaggertype:"number" example:"500000"`
+
+	CreatedAt time.Time `json:"created_at"`
+}
+
+// AccountBalance số dư tài khoản (theo đơn vị tiền của tài khoản) tại một thời điểm
+type AccountBalance struct {
+	Account Account         `json:"account"`
+	Balance decimal.Decimal `json:"balance" swaggertype:"number"`
 }
 
 // ReportOutput DTO cho báo cáo
 type ReportOutput struct {
-	Period            string                 `json:"period"`
-	StartDate         string                 `json:"start_date"`
-	TotalIncome       float64                `json:"total_income"`
-	TotalExpense      float64                `json:"total_expense"`
-	TotalSavingsVND   float64                `json:"total_savings_vnd"`
-	Balance           float64                `json:"balance"`
-	ExpenseByCategory map[string]float64     `json:"expense_by_category"`
-	Assets            map[string]AssetDetail `json:"assets"`
-	TotalAssetsVND    float64                `json:"total_assets_vnd"`
+	Period            string                     `json:"period"`
+	StartDate         string                     `json:"start_date"`
+	EndDate           string                     `json:"end_date"` // Ngày cuối cùng của kỳ (tính cả ngày này)
+	Timezone          string                     `json:"timezone"` // Múi giờ dùng để tính ranh giới kỳ
+	TotalIncome       decimal.Decimal            `json:"total_income" swaggertype:"number"`
+	TotalExpense      decimal.Decimal            `json:"total_expense" swaggertype:"number"`
+	TotalSavingsVND   decimal.Decimal            `json:"total_savings_vnd" swaggertype:"number"`
+	TotalWithdrawnVND decimal.Decimal            `json:"total_withdrawn_vnd" swaggertype:"number"` // Tiền nhận về khi rút/bán tài sản tiết kiệm
+	Balance           decimal.Decimal            `json:"balance" swaggertype:"number"`             // Thu - Chi - Tiết kiệm + Rút
+	ExpenseByCategory map[string]decimal.Decimal `json:"expense_by_category" swaggertype:"object,number"`
+	Assets            map[string]AssetDetail     `json:"assets"`
+	TotalAssetsVND    decimal.Decimal            `json:"total_assets_vnd" swaggertype:"number"`
+	Budgets           []BudgetStatus             `json:"budgets,omitempty"`  // Ngân sách cùng kỳ (week/month) so với thực chi
+	Accounts          []AccountBalance           `json:"accounts,omitempty"` // Số dư các tài khoản tại cuối kỳ
+
+	// Tài sản đang giữ tại cuối kỳ (tính từ mọi giao dịch tiết kiệm/rút) kèm lãi/lỗ
+	CostBasis         string                  `json:"cost_basis"` // Cách tính giá vốn: average hoặc fifo
+	Holdings          map[string]AssetHolding `json:"holdings"`
+	TotalRealizedPL   decimal.Decimal         `json:"total_realized_pl" swaggertype:"number"`
+	TotalUnrealizedPL decimal.Decimal         `json:"total_unrealized_pl" swaggertype:"number"`
+
+	// Có tài sản được định giá bằng giá cũ/mặc định (xem stale của từng tài sản)
+	RatesStale bool `json:"rates_stale"`
 }
 
+// AssetDetail tài sản tích lũy trong kỳ: Quantity = số đã nạp - số đã rút trong kỳ
 type AssetDetail struct {
-	Quantity   float64 `json:"quantity"`
-	CurrentVND float64 `json:"current_vnd"`
-	Rate       float64 `json:"rate"`
+	Quantity   decimal.Decimal `json:"quantity" swaggertype:"number"`
+	CurrentVND decimal.Decimal `json:"current_vnd" swaggertype:"number"`
+	Rate       decimal.Decimal `json:"rate" swaggertype:"number"`
+	Stale      bool            `json:"stale"` // Rate là giá cũ/mặc định, CurrentVND có thể sai
+
+	// Số lượng và giá theo đơn vị hiển thị user chọn (vàng/bạc theo gold_unit/silver_unit)
+	DisplayUnit     string          `json:"display_unit" example:"lượng"`
+	DisplayQuantity decimal.Decimal `json:"display_quantity" swaggertype:"number"`
+	DisplayRate     decimal.Decimal `json:"display_rate" swaggertype:"number"`
+}
+
+// AssetHolding tài sản đang giữ của một đơn vị tiền (USD, GOLD, BTC...) và lãi/lỗ theo giá hiện tại
+type AssetHolding struct {
+	Quantity     decimal.Decimal `json:"quantity" swaggertype:"number"`
+	CostVND      decimal.Decimal `json:"cost_vnd" swaggertype:"number"` // Giá vốn của số đang giữ
+	Rate         decimal.Decimal `json:"rate" swaggertype:"number"`
+	CurrentVND   decimal.Decimal `json:"current_vnd" swaggertype:"number"`
+	UnrealizedPL decimal.Decimal `json:"unrealized_pl" swaggertype:"number"` // CurrentVND - CostVND
+	RealizedPL   decimal.Decimal `json:"realized_pl" swaggertype:"number"`   // Lãi/lỗ của các lần rút/bán trong kỳ
+	Stale        bool            `json:"stale"`                              // Rate là giá cũ/mặc định
+
+	// Số lượng và giá theo đơn vị hiển thị user chọn
+	DisplayUnit     string          `json:"display_unit" example:"lượng"`
+	DisplayQuantity decimal.Decimal `json:"display_quantity" swaggertype:"number"`
+	DisplayRate     decimal.Decimal `json:"display_rate" swaggertype:"number"`
+}
+
+// Portfolio toàn bộ tài sản tiết kiệm đang giữ (từ trước tới nay, không theo kỳ)
+type Portfolio struct {
+	UserID    string             `json:"user_id" example:"123456789"`
+	CostBasis string             `json:"cost_basis" example:"average" enums:"average,fifo"`
+	AsOf      time.Time          `json:"as_of"`
+	Holdings  []PortfolioHolding `json:"holdings"` // Sắp xếp theo mã tiền
+	// Có tài sản được định giá bằng giá cũ/mặc định
+	RatesStale bool `json:"rates_stale"`
+
+	TotalCostVND      decimal.Decimal `json:"total_cost_vnd" swaggertype:"number"`
+	TotalCurrentVND   decimal.Decimal `json:"total_current_vnd" swaggertype:"number"`
+	TotalUnrealizedPL decimal.Decimal `json:"total_unrealized_pl" swaggertype:"number"`
+	TotalRealizedPL   decimal.Decimal `json:"total_realized_pl" swaggertype:"number"`
+
+	// Biến động giá trị do giá thay đổi theo day, week, month. Thiếu key nếu chưa có giá lịch sử
+	Changes map[string]PriceChange `json:"changes"`
+}
+
+// PortfolioHolding tài sản đang giữ của một đơn vị tiền
+type PortfolioHolding struct {
+	Currency     string          `json:"currency" example:"GOLD"`
+	Quantity     decimal.Decimal `json:"quantity" swaggertype:"number"`
+	AvgPriceVND  decimal.Decimal `json:"avg_price_vnd" swaggertype:"number"` // Giá mua bình quân của số đang giữ
+	CostVND      decimal.Decimal `json:"cost_vnd" swaggertype:"number"`
+	Rate         decimal.Decimal `json:"rate" swaggertype:"number"`
+	CurrentVND   decimal.Decimal `json:"current_vnd" swaggertype:"number"`
+	UnrealizedPL decimal.Decimal `json:"unrealized_pl" swaggertype:"number"`
+	RealizedPL   decimal.Decimal `json:"realized_pl" swaggertype:"number"` // Tổng lãi/lỗ các lần rút/bán từ trước tới nay
+	Stale        bool            `json:"stale"`                            // Rate là giá cũ/mặc định
+
+	// Số lượng và giá theo đơn vị hiển thị user chọn
+	DisplayUnit     string          `json:"display_unit" example:"lượng"`
+	DisplayQuantity decimal.Decimal `json:"display_quantity" swaggertype:"number"`
+	DisplayRate     decimal.Decimal `json:"display_rate" swaggertype:"number"`
+	DisplayAvgPrice decimal.Decimal `json:"display_avg_price" swaggertype:"number"` // Giá mua bình quân theo đơn vị hiển thị
+
+	Changes map[string]PriceChange `json:"changes"`
+}
+
+// PriceChange biến động giá trị số đang giữ so với giá tại thời điểm Since
+type PriceChange struct {
+	Since     time.Time       `json:"since"`
+	Rate      decimal.Decimal `json:"rate" swaggertype:"number"` // Giá lúc Since, 0 ở phần tổng
+	ChangeVND decimal.Decimal `json:"change_vnd" swaggertype:"number"`
+	Percent   decimal.Decimal `json:"percent" swaggertype:"number"`
 }
 
 // ExchangeRates DTO cho giá cả
@@ -64,4 +374,250 @@ type ExchangeRates struct {
 	GoldDiff   float64 `json:"gold_diff"`   // Chênh lệch
 	SilverDiff float64 `json:"silver_diff"` // Chênh lệch
 	BtcVND     float64 `json:"btc_vnd"`
+
+	// Giá theo mã của các tài sản khác (EUR, JPY, ETH...), đơn vị theo AssetRate.Quote của tài sản
+	Quotes map[string]float64 `json:"quotes,omitempty"`
+
+	// Giá mua vào (bid, giá nhận được khi bán ra) và giá bán ra (ask, giá phải trả khi mua) theo mã giá.
+	// Chỉ có với nguồn trả cả 2 giá (hiện chỉ vangtoday cho SJC), thiếu thì dùng giá theo Quote
+	Bids map[string]float64 `json:"bids,omitempty"`
+	Asks map[string]float64 `json:"asks,omitempty"`
+
+	// Nguồn của từng giá theo mã: USD (usd_vnd), XAU (gold_usd), XAG (silver_usd, vn_silver_est), SJC (vn_sjc), BTC (btc_vnd),
+	// các mã khác theo quotes
+	Sources map[string]RateSource `json:"sources,omitempty"`
+	// Có ít nhất 1 giá đã cũ hoặc là giá mặc định dựng sẵn
+	Stale bool `json:"stale"`
+}
+
+// Nguồn của giá mặc định dựng sẵn, dùng khi chưa lấy được giá thật
+const RateSourceDefault = "default"
+
+// RateSource nguồn và thời điểm lấy của một giá trong ExchangeRates
+type RateSource struct {
+	Source    string     `json:"source" example:"coingecko"`
+	FetchedAt *time.Time `json:"fetched_at,omitempty"` // nil với giá mặc định
+	Stale     bool       `json:"stale"`
+}
+
+// Quote giá theo mã (USD, XAU, XAG, SJC, BTC hoặc mã trong Quotes), 0 nếu chưa có giá
+func (r ExchangeRates) Quote(code string) float64 {
+	switch code {
+	case "USD":
+		return r.UsdVND
+	case "XAU":
+		return r.GoldUSD
+	case "XAG":
+		return r.SilverUSD
+	case "SJC":
+		return r.VnSJC
+	case "BTC":
+		return r.BtcVND
+	}
+	return r.Quotes[code]
+}
+
+// Bid giá mua vào theo mã, bằng Quote nếu nguồn không có giá mua/bán riêng
+func (r ExchangeRates) Bid(code string) float64 {
+	if v := r.Bids[code]; v > 0 {
+		return v
+	}
+	return r.Quote(code)
+}
+
+// Ask giá bán ra theo mã, bằng Quote nếu nguồn không có giá mua/bán riêng
+func (r ExchangeRates) Ask(code string) float64 {
+	if v := r.Asks[code]; v > 0 {
+		return v
+	}
+	return r.Quote(code)
+}
+
+// Nhóm tài sản
+const (
+	AssetKindFiat   = "fiat"
+	AssetKindCrypto = "crypto"
+	AssetKindMetal  = "metal"
+	AssetKindCustom = "custom"
+)
+
+// Asset một loại tiền/tài sản có thể ghi giao dịch, định giá ra VND và báo cáo
+type Asset struct {
+	Code string `json:"code" example:"EUR"`  // Mã lưu trong transaction.currency
+	Name string `json:"name" example:"Euro"` // Tên hiển thị
+	Unit string `json:"unit" example:"EUR"`  // Đơn vị của 1 số lượng: "chỉ", "lượng", "EUR"...
+	Kind string `json:"kind" example:"fiat" enums:"fiat,crypto,metal,custom"`
+	// Cách viết trong tin nhắn (không phân biệt hoa thường): "eur", "euro", "€"
+	Aliases []string  `json:"aliases"`
+	Rate    AssetRate `json:"rate"`
+}
+
+// AssetRate cách định giá 1 đơn vị tài sản ra VND: Price cố định, hoặc giá theo mã Code
+// (tính theo Quote, nhân thêm Factor để đổi đơn vị, VD: oz -> lượng)
+type AssetRate struct {
+	Code   string  `json:"code,omitempty" example:"EUR"`                  // Mã giá trong ExchangeRates, lấy từ nguồn giá RATE_PROVIDERS_<CODE>
+	Quote  string  `json:"quote,omitempty" example:"VND" enums:"VND,USD"` // Đơn vị của giá Code, mặc định VND
+	Factor float64 `json:"factor,omitempty" example:"1"`                  // 0 là 1
+	Price  float64 `json:"price,omitempty"`                               // Giá VND cố định (tài sản tự định nghĩa không có nguồn giá)
+}
+
+// RateCodes các mã giá cần có để định giá tài sản, rỗng nếu giá cố định
+func (a Asset) RateCodes() []string {
+	if a.Rate.Code == "" {
+		return nil
+	}
+	if a.Rate.Quote == "USD" {
+		return []string{a.Rate.Code, "USD"}
+	}
+	return []string{a.Rate.Code}
+}
+
+// RateIn giá 1 đơn vị tài sản theo VND với bảng giá r, 0 nếu chưa có giá
+func (a Asset) RateIn(r ExchangeRates) decimal.Decimal {
+	return a.priceIn(r.Quote)
+}
+
+// BidIn giá mua vào 1 đơn vị tài sản theo VND (số tiền nhận được khi bán tài sản)
+func (a Asset) BidIn(r ExchangeRates) decimal.Decimal {
+	return a.priceIn(r.Bid)
+}
+
+// AskIn giá bán ra 1 đơn vị tài sản theo VND (số tiền phải trả khi mua tài sản)
+func (a Asset) AskIn(r ExchangeRates) decimal.Decimal {
+	return a.priceIn(r.Ask)
+}
+
+// priceIn giá 1 đơn vị tài sản theo VND, quote trả giá theo mã (Quote, Bid hoặc Ask)
+func (a Asset) priceIn(quote func(code string) float64) decimal.Decimal {
+	if a.Rate.Code == "" {
+		return decimal.NewFromFloat(a.Rate.Price)
+	}
+	price := quote(a.Rate.Code)
+	if a.Rate.Quote == "USD" {
+		price *= quote("USD")
+	}
+	if a.Rate.Factor != 0 {
+		price *= a.Rate.Factor
+	}
+	return decimal.NewFromFloat(price)
+}
+
+// StaleIn giá dùng để định giá tài sản trong r có phải giá cũ/mặc định không. Giá cố định luôn false
+func (a Asset) StaleIn(r ExchangeRates) bool {
+	for _, code := range a.RateCodes() {
+		if src, ok := r.Sources[code]; !ok || src.Stale {
+			return true
+		}
+	}
+	return false
+}
+
+// SpreadIn mọi mã giá của tài sản đều có giá mua vào/bán ra riêng trong r.
+// false với giá cố định và tài sản mà nguồn chỉ có giá tham chiếu (bid = ask = giá tham chiếu)
+func (a Asset) SpreadIn(r ExchangeRates) bool {
+	codes := a.RateCodes()
+	for _, code := range codes {
+		if r.Bids[code] <= 0 || r.Asks[code] <= 0 {
+			return false
+		}
+	}
+	return len(codes) > 0
+}
+
+// AssetQuote tài sản kèm giá VND hiện tại
+type AssetQuote struct {
+	Asset
+	RateVND decimal.Decimal `json:"rate_vnd" swaggertype:"number"`
+	BidVND  decimal.Decimal `json:"bid_vnd" swaggertype:"number"` // Giá mua vào, dùng để định giá tài sản đang giữ
+	AskVND  decimal.Decimal `json:"ask_vnd" swaggertype:"number"` // Giá bán ra, dùng để quy đổi khi mua
+	// false nếu nguồn giá không có giá mua/bán riêng: bid_vnd và ask_vnd chỉ là giá tham chiếu
+	HasSpread bool `json:"has_spread"`
+	Stale     bool `json:"stale"`
+}
+
+// RateSnapshot một lần cập nhật giá thị trường đã lưu vào DB
+type RateSnapshot struct {
+	ID        int           `json:"id"`
+	FetchedAt time.Time     `json:"fetched_at"`
+	Rates     ExchangeRates `json:"rates"`
+}
+
+// RatePoint giá của một tài sản trong một khoảng (giờ/ngày/tuần), dùng để vẽ biểu đồ
+type RatePoint struct {
+	Time    time.Time       `json:"time"` // Đầu khoảng, hoặc thời điểm lấy giá nếu interval=raw
+	Open    decimal.Decimal `json:"open" swaggertype:"number"`
+	High    decimal.Decimal `json:"high" swaggertype:"number"`
+	Low     decimal.Decimal `json:"low" swaggertype:"number"`
+	Close   decimal.Decimal `json:"close" swaggertype:"number"`
+	Samples int             `json:"samples"` // Số lần lấy giá trong khoảng
+}
+
+// Tình trạng của một nguồn giá
+const (
+	ProviderUnknown     = "unknown" // Chưa gọi lần nào
+	ProviderOK          = "ok"
+	ProviderFailing     = "failing"
+	ProviderCircuitOpen = "circuit_open" // Lỗi liên tục, tạm không gọi tới CircuitOpenUntil
+)
+
+// ProviderHealth tình trạng một nguồn giá sau các lần gọi gần nhất
+type ProviderHealth struct {
+	Name                string     `json:"name" example:"coingecko"`
+	Assets              []string   `json:"assets" example:"BTC"`
+	Status              string     `json:"status" example:"ok" enums:"unknown,ok,failing,circuit_open"`
+	LastSuccessAt       *time.Time `json:"last_success_at,omitempty"`
+	LastErrorAt         *time.Time `json:"last_error_at,omitempty"`
+	LastError           string     `json:"last_error,omitempty"`
+	ConsecutiveFailures int        `json:"consecutive_failures"`
+	CircuitOpenUntil    *time.Time `json:"circuit_open_until,omitempty"`
+}
+
+// RateProvidersStatus thứ tự nguồn giá theo tài sản và tình trạng từng nguồn
+type RateProvidersStatus struct {
+	Chains    map[string][]string `json:"chains"` // Tài sản (USD, XAU, XAG, SJC, BTC) -> nguồn theo thứ tự ưu tiên
+	Providers []ProviderHealth    `json:"providers"`
+}
+
+// Điều kiện của cảnh báo giá
+const (
+	AlertAbove  = "above"  // Giá lên tới mốc threshold trở lên
+	AlertBelow  = "below"  // Giá xuống tới mốc threshold trở xuống
+	AlertChange = "change" // Giá biến động (tăng hoặc giảm) từ threshold % so với base_price
+)
+
+// PriceAlert quy tắc cảnh báo giá của user, được kiểm tra sau mỗi lần cập nhật giá
+type PriceAlert struct {
+	ID        int             `json:"id"`
+	UserID    string          `json:"user_id" example:"123456789"`
+	Asset     string          `json:"asset" example:"GOLD"` // Mã tài sản (xem GET /assets)
+	Condition string          `json:"condition" example:"above" enums:"above,below,change"`
+	Threshold decimal.Decimal `json:"threshold" swaggertype:"number" example:"90000000"` // VND/1 unit với above, below; % với change
+	// Đơn vị khối lượng của giá với vàng, bạc (chi, luong, g, kg, oz), rỗng là đơn vị của tài sản
+	Unit string `json:"unit,omitempty" example:"luong"`
+	// false: báo 1 lần rồi tự tắt. true: above/below báo lại mỗi lần giá vượt mốc lại từ phía bên kia,
+	// change tính lại từ giá lúc báo
+	Repeat bool `json:"repeat"`
+	Active bool `json:"active"`
+
+	BasePrice       decimal.Decimal `json:"base_price" swaggertype:"number"` // change: giá gốc để tính %
+	LastPrice       decimal.Decimal `json:"last_price" swaggertype:"number"` // Giá (theo unit) lần kiểm tra gần nhất, 0 nếu chưa kiểm tra
+	LastTriggeredAt *time.Time      `json:"last_triggered_at,omitempty"`
+	CreatedAt       time.Time       `json:"created_at"`
+}
+
+// AlertNotification một lần cảnh báo giá đã kích hoạt, chờ bot gửi cho user.
+// Được lưu cùng lúc với trạng thái cảnh báo, bot xác nhận (ack) sau khi gửi nên restart không mất tin
+type AlertNotification struct {
+	ID        int             `json:"id"`
+	AlertID   int             `json:"alert_id"`
+	UserID    string          `json:"user_id" example:"123456789"`
+	Asset     string          `json:"asset" example:"GOLD"`
+	Condition string          `json:"condition" example:"above" enums:"above,below,change"`
+	Threshold decimal.Decimal `json:"threshold" swaggertype:"number"`
+	Unit      string          `json:"unit,omitempty" example:"luong"`
+	Repeat    bool            `json:"repeat"`
+	Price     decimal.Decimal `json:"price" swaggertype:"number"`      // Giá lúc kích hoạt (theo unit)
+	BasePrice decimal.Decimal `json:"base_price" swaggertype:"number"` // change: giá gốc lúc kích hoạt
+	CreatedAt time.Time       `json:"created_at"`
+	SentAt    *time.Time      `json:"sent_at,omitempty"`
 }
@@ -0,0 +1,191 @@
+package service
+
+import (
+	"go-finance/internal/model"
+	"log"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// AlertChecker nơi lưu cảnh báo giá có thể kiểm tra các cảnh báo đang bật (store.Store)
+type AlertChecker interface {
+	CheckAlerts(check func(model.PriceAlert) (model.PriceAlert, *model.AlertNotification)) (int, error)
+}
+
+// AlertPrice giá hiện tại của tài sản trong cảnh báo theo đơn vị a.Unit (VD: đ/lượng vàng).
+// ok=false nếu tài sản không có giá thị trường hoặc giá đang là giá cũ/mặc định (không dùng để báo)
+func AlertPrice(a model.PriceAlert, rates model.ExchangeRates) (decimal.Decimal, bool) {
+	asset, found := LookupAsset(a.Asset)
+	if !found || len(asset.RateCodes()) == 0 || asset.StaleIn(rates) {
+		return decimal.Zero, false
+	}
+	price := asset.RateIn(rates)
+	if a.Unit != "" {
+		base, okBase := assetMetalUnit(a.Asset)
+		unit, okUnit := LookupMetalUnit(a.Unit)
+		if !okBase || !okUnit {
+			return decimal.Zero, false
+		}
+		price = price.Mul(unit.Grams).Div(base.Grams)
+	}
+	return price.Round(2), price.IsPositive()
+}
+
+// CheckAlert kiểm tra cảnh báo a với giá mới price (theo a.Unit) lúc now.
+// Trả về trạng thái mới của cảnh báo và thông báo cần gửi (nil nếu không kích hoạt).
+// above/below chỉ báo khi giá vừa vượt mốc so với lần kiểm tra trước (lần đầu thì báo ngay nếu đã vượt),
+// change báo khi giá lệch khỏi base_price từ threshold % trở lên
+func CheckAlert(a model.PriceAlert, price decimal.Decimal, now time.Time) (model.PriceAlert, *model.AlertNotification) {
+	if !a.Active || !price.IsPositive() {
+		return a, nil
+	}
+	prev := a.LastPrice
+	a.LastPrice = price
+
+	fired := false
+	switch a.Condition {
+	case model.AlertAbove:
+		fired = price.GreaterThanOrEqual(a.Threshold) && !(prev.IsPositive() && prev.GreaterThanOrEqual(a.Threshold))
+	case model.AlertBelow:
+		fired = price.LessThanOrEqual(a.Threshold) && !(prev.IsPositive() && prev.LessThanOrEqual(a.Threshold))
+	case model.AlertChange:
+		if !a.BasePrice.IsPositive() {
+			a.BasePrice = price // Cảnh báo tạo lúc chưa có giá thật: lấy giá đầu tiên làm gốc
+			return a, nil
+		}
+		percent := price.Sub(a.BasePrice).Abs().Div(a.BasePrice).Mul(decimal.NewFromInt(100))
+		fired = percent.GreaterThanOrEqual(a.Threshold)
+	}
+	if !fired {
+		return a, nil
+	}
+
+	n := &model.AlertNotification{
+		AlertID:   a.ID,
+		UserID:    a.UserID,
+		Asset:     a.Asset,
+		Condition: a.Condition,
+		Threshold: a.Threshold,
+		Unit:      a.Unit,
+		Repeat:    a.Repeat,
+		Price:     price,
+		BasePrice: a.BasePrice,
+	}
+	a.LastTriggeredAt = &now
+	if a.Condition == model.AlertChange {
+		a.BasePrice = price // Lần báo sau tính từ giá lúc báo
+	}
+	if !a.Repeat {
+		a.Active = false
+	}
+	return a, n
+}
+
+// EvaluateAlerts kiểm tra mọi cảnh báo đang bật với bảng giá rates, trả về số thông báo đã tạo
+func EvaluateAlerts(checker AlertChecker, rates model.ExchangeRates, now time.Time) (int, error) {
+	return checker.CheckAlerts(func(a model.PriceAlert) (model.PriceAlert, *model.AlertNotification) {
+		price, ok := AlertPrice(a, rates)
+		if !ok {
+			return a, nil
+		}
+		return CheckAlert(a, price, now)
+	})
+}
+
+// runAlerts kiểm tra cảnh báo sau mỗi lần cập nhật giá, lỗi chỉ ghi log để lần sau kiểm tra lại
+func runAlerts(checker AlertChecker) {
+	if checker == nil {
+		return
+	}
+	n, err := EvaluateAlerts(checker, GetCurrentRates(), time.Now())
+	if err != nil {
+		log.Printf("[ALERT ERROR] Không thể kiểm tra cảnh báo giá: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[ALERT] Đã tạo %d thông báo cảnh báo giá", n)
+	}
+}
+
+// alertAssetWords tên tài sản hay dùng khi đặt cảnh báo, ngoài alias trong registry
+var alertAssetWords = map[string]string{
+	"vàng sjc": "GOLD", "vang sjc": "GOLD", "sjc": "GOLD",
+	"đô": "USD", "đô la": "USD", "do la": "USD",
+}
+
+// alertRe bắt lệnh đặt cảnh báo giá:
+// "báo khi <tài sản> <điều kiện> <mốc>[/<đơn vị>] [lặp lại]"
+var alertRe = regexp.MustCompile(`(?i)^(?:báo|bao)\s+khi\s+(?:giá\s+|gia\s+)?(.+?)\s*(>=|<=|>|<|trên|tren|dưới|duoi|vượt|vuot|biến\s*động|bien\s*dong|thay\s*đổi|thay\s*doi|±)\s*([\d.,]+)\s*(k|m|tr|triệu|trieu|%)?\s*(?:đ|vnd)?\s*(?:/\s*(\p{L}+))?\s*(lặp\s*lại|lap\s*lai|mỗi\s*lần|moi\s*lan)?$`)
+
+// ParseAlertText bóc cảnh báo giá từ tin nhắn, VD: "báo khi vàng SJC > 90tr", "báo khi usd < 25000",
+// "báo khi btc biến động 5% lặp lại", "báo khi bạc > 1.2m/lượng".
+// Giá vàng, bạc không ghi đơn vị thì tính theo lượng (cây) như bảng giá.
+// Trả về ok=false nếu không đúng cú pháp
+func ParseAlertText(text string) (model.PriceAlert, bool) {
+	m := alertRe.FindStringSubmatch(strings.TrimSpace(NormalizeText(text)))
+	if m == nil {
+		return model.PriceAlert{}, false
+	}
+
+	name := strings.ToLower(strings.Join(strings.Fields(m[1]), " "))
+	code := alertAssetWords[name]
+	if code == "" {
+		code = metalWords[name]
+	}
+	if code == "" {
+		code = AssetForAlias(name)
+	}
+	if code == "" {
+		if a, ok := LookupAsset(name); ok {
+			code = a.Code
+		}
+	}
+	if code == "" {
+		return model.PriceAlert{}, false
+	}
+
+	a := model.PriceAlert{UserID: "", Asset: code, Repeat: m[6] != ""}
+	op := strings.ToLower(strings.Join(strings.Fields(m[2]), " "))
+	suffix := strings.ToLower(m[4])
+	switch op {
+	case ">", ">=", "trên", "tren", "vượt", "vuot":
+		a.Condition = model.AlertAbove
+	case "<", "<=", "dưới", "duoi":
+		a.Condition = model.AlertBelow
+	default:
+		a.Condition = model.AlertChange
+	}
+	if (a.Condition == model.AlertChange) != (suffix == "%") {
+		return model.PriceAlert{}, false // "biến động" phải đi với %, mốc giá thì không
+	}
+
+	amount := m[3]
+	switch suffix {
+	case "k", "m":
+		amount += suffix
+	case "tr", "triệu", "trieu":
+		amount += "m"
+	}
+	threshold, err := ParseAmount(amount)
+	if err != nil || !threshold.IsPositive() {
+		return model.PriceAlert{}, false
+	}
+	a.Threshold = threshold
+
+	if _, metal := assetMetalUnit(code); metal && a.Condition != model.AlertChange {
+		a.Unit = "luong"
+		if m[5] != "" {
+			u, ok := LookupMetalUnit(m[5])
+			if !ok {
+				return model.PriceAlert{}, false
+			}
+			a.Unit = u.Code
+		}
+	} else if m[5] != "" {
+		return model.PriceAlert{}, false
+	}
+	return a, true
+}
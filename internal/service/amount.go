@@ -0,0 +1,390 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/shopspring/decimal"
+)
+
+// amountDigitWords chữ số 0-9 viết bằng chữ (có dấu và không dấu)
+var amountDigitWords = map[string]int64{
+	"không": 0, "khong": 0,
+	"một": 1, "mot": 1, "mốt": 1,
+	"hai": 2, "ba": 3,
+	"bốn": 4, "bon": 4, "tư": 4,
+	"năm": 5, "nam": 5, "lăm": 5, "lam": 5,
+	"sáu": 6, "sau": 6,
+	"bảy": 7, "bay": 7, "bẩy": 7,
+	"tám": 8, "tam": 8,
+	"chín": 9, "chin": 9,
+}
+
+// amountScaleWords hàng đơn vị lớn: "50k", "500 nghìn", "3 lít" (lít = 100 nghìn), "1 củ", "2tr", "1 tỷ"
+var amountScaleWords = map[string]int64{
+	"k": 1_000, "nghìn": 1_000, "nghin": 1_000, "ngàn": 1_000, "ngan": 1_000,
+	"lít": 100_000, "lit": 100_000,
+	"m": 1_000_000, "tr": 1_000_000, "triệu": 1_000_000, "trieu": 1_000_000, "củ": 1_000_000, "cu": 1_000_000,
+	"tỷ": 1_000_000_000, "tỉ": 1_000_000_000, "ty": 1_000_000_000, "tỏi": 1_000_000_000,
+}
+
+// amountWordPattern regex khớp số tiền bắt đầu bằng chữ ("năm trăm nghìn", "mười lăm k")
+func amountWordPattern() string {
+	words := []string{"mười", "muoi"}
+	for w := range amountDigitWords {
+		words = append(words, w)
+	}
+	return `(?:` + longestFirst(words) + `)(?:\s|$)`
+}
+
+// amountLexer đọc số tiền theo cách nói tiếng Việt.
+// Số được gom theo nhóm dưới 1000 (hundreds + units) rồi nhân với hàng lớn (nghìn, triệu, tỷ)
+type amountLexer struct {
+	total      decimal.Decimal // Phần đã nhân hàng lớn
+	hundreds   decimal.Decimal // Hàng trăm của nhóm đang đọc
+	units      decimal.Decimal // Hàng chục + đơn vị của nhóm đang đọc
+	onesEmpty  bool            // Nhóm đang đọc còn trống hàng đơn vị ("hai mươi" còn chờ "lăm")
+	lastScale  int64           // Hàng lớn vừa dùng, 0 nếu chưa có
+	prevDigit  bool            // Token trước là chữ số viết bằng chữ ("hai" trước "mươi")
+	prevHundr  bool            // Token trước là "trăm" ("trăm rưỡi")
+	prevScale  bool            // Token trước là hàng lớn ("triệu rưỡi", "1tr2")
+	hasNumbers bool            // Đã đọc được số nào chưa
+}
+
+func (l *amountLexer) value() decimal.Decimal {
+	return l.total.Add(l.hundreds).Add(l.units)
+}
+
+// groupEmpty nhóm dưới 1000 đang đọc chưa có số
+func (l *amountLexer) groupEmpty() bool {
+	return l.hundreds.IsZero() && l.units.IsZero() && l.onesEmpty
+}
+
+func (l *amountLexer) resetGroup() {
+	l.hundreds, l.units, l.onesEmpty = decimal.Zero, decimal.Zero, true
+}
+
+// number số viết bằng chữ số: chỉ đứng đầu hoặc mở một nhóm mới sau hàng lớn ("1 triệu 200 nghìn")
+func (l *amountLexer) number(n decimal.Decimal) bool {
+	if !l.groupEmpty() {
+		return false
+	}
+	l.units, l.onesEmpty, l.hasNumbers = n, false, true
+	return true
+}
+
+// word xử lý một từ, false nếu từ không thuộc số tiền hoặc sai ngữ pháp (VD: "hai ba")
+func (l *amountLexer) word(w string) bool {
+	prevDigit, prevHundr, prevScale := l.prevDigit, l.prevHundr, l.prevScale
+	l.prevDigit, l.prevHundr, l.prevScale = false, false, false
+
+	if d, ok := amountDigitWords[w]; ok {
+		if !l.onesEmpty {
+			return false
+		}
+		l.units = l.units.Add(decimal.NewFromInt(d))
+		l.onesEmpty, l.prevDigit, l.hasNumbers = false, true, true
+		return true
+	}
+	if s, ok := amountScaleWords[w]; ok {
+		return l.scale(s)
+	}
+
+	switch w {
+	case "mười", "mươi", "muoi":
+		if prevDigit && w != "mười" && l.units.LessThan(decimal.NewFromInt(10)) {
+			l.units = l.units.Mul(decimal.NewFromInt(10)) // "hai mươi"
+		} else if w != "mươi" && l.units.IsZero() && l.onesEmpty {
+			l.units = decimal.NewFromInt(10) // "mười lăm"
+		} else {
+			return false
+		}
+		l.onesEmpty, l.hasNumbers = true, true
+		return true
+	case "trăm", "tram":
+		if !l.hundreds.IsZero() || !l.units.IsPositive() || l.units.GreaterThanOrEqual(decimal.NewFromInt(10)) {
+			return false
+		}
+		l.hundreds, l.units, l.onesEmpty, l.prevHundr = l.units.Mul(decimal.NewFromInt(100)), decimal.Zero, true, true
+		return true
+	case "linh", "lẻ", "le":
+		// "một trăm linh năm": chỉ giữ chỗ hàng chục
+		if l.hundreds.IsZero() || !l.units.IsZero() {
+			return false
+		}
+		return true
+	case "rưỡi", "ruoi":
+		switch {
+		case prevScale:
+			l.total = l.total.Add(decimal.NewFromInt(l.lastScale).Div(decimal.NewFromInt(2)))
+		case prevHundr:
+			l.hundreds = l.hundreds.Add(decimal.NewFromInt(50))
+			l.prevHundr = true // "trăm rưỡi nghìn" vẫn nhân tiếp được
+		default:
+			return false
+		}
+		return true
+	}
+	return false
+}
+
+// scale nhân nhóm đang đọc với hàng lớn. Hàng lớn hơn hàng trước thì nhân cả phần đã đọc ("một trăm nghìn tỷ")
+func (l *amountLexer) scale(s int64) bool {
+	group := l.hundreds.Add(l.units)
+	if !group.IsPositive() {
+		return false
+	}
+	scale := decimal.NewFromInt(s)
+	if l.lastScale != 0 && s >= l.lastScale {
+		l.total = l.total.Add(group).Mul(scale)
+	} else {
+		l.total = l.total.Add(group.Mul(scale))
+	}
+	l.lastScale, l.prevScale = s, true
+	l.resetGroup()
+	return true
+}
+
+// fraction phần lẻ viết liền sau hàng lớn: "1tr2" = 1,2 triệu, "2m5" = 2,5 triệu
+func (l *amountLexer) fraction(digits string) {
+	frac, _ := decimal.NewFromString("0." + digits)
+	l.total = l.total.Add(frac.Mul(decimal.NewFromInt(l.lastScale)))
+	l.prevScale = false
+}
+
+// lexAmount đọc số tiền ở đầu s: "50k", "1.5m", "1tr2", "2 triệu rưỡi", "năm trăm nghìn", "3 lít".
+// Trả về giá trị và số byte đã đọc. Từ thừa phía sau không làm thành số hoàn chỉnh thì bỏ lại cho ghi chú
+// ("50k hai ly": chỉ đọc "50k"). scaled = số tiền có hàng lớn (k, triệu...). ok=false nếu đầu s không phải số tiền
+func lexAmount(s string) (decimal.Decimal, int, bool, bool) {
+	l := amountLexer{onesEmpty: true}
+	negative := strings.HasPrefix(s, "-")
+	i := 0
+	if negative {
+		i = 1
+	}
+
+	good, goodVal, goodScaled := -1, decimal.Zero, false
+	for first := true; ; first = false {
+		j := i
+		if !first {
+			for j < len(s) && (s[j] == ' ' || s[j] == '\t') {
+				j++
+			}
+			if j == i {
+				break // Các từ phải cách nhau bằng khoảng trắng
+			}
+		}
+		if j >= len(s) {
+			break
+		}
+
+		complete := false
+		if isDigit(s[j]) || (first && (s[j] == '.' || s[j] == ',')) {
+			end := j
+			for end < len(s) && (isDigit(s[end]) || s[end] == '.' || s[end] == ',') {
+				end++
+			}
+			n, err := decimal.NewFromString(strings.ReplaceAll(s[j:end], ",", "."))
+			if err != nil || !l.number(n) {
+				break
+			}
+			complete = first
+			l.prevDigit, l.prevHundr, l.prevScale = false, false, false
+			j = end
+
+			// Hàng lớn viết liền: "50k", "2tr", "1tr2", "2m5"
+			wordEnd := letterRunEnd(s, j)
+			w := strings.ToLower(s[j:wordEnd])
+			if sc, ok := amountScaleWords[w]; ok && l.scale(sc) {
+				j, complete = wordEnd, true
+				digitEnd := j
+				for digitEnd < len(s) && isDigit(s[digitEnd]) {
+					digitEnd++
+				}
+				if digitEnd > j && letterRunEnd(s, digitEnd) == digitEnd {
+					l.fraction(s[j:digitEnd])
+					j = digitEnd
+				}
+			} else if w != "" && (w[0] == 'k' || w[0] == 'm') && l.scale(amountScaleWords[w[:1]]) {
+				// "1kg bạc": chỉ đọc "1k", "g bạc" là đơn vị (1000g = 1kg)
+				j, complete = j+1, true
+			}
+		} else {
+			end := letterRunEnd(s, j)
+			w := strings.ToLower(s[j:end])
+			if w == "" || !l.word(w) {
+				break
+			}
+			_, isScale := amountScaleWords[w]
+			complete = isScale || l.prevHundr || w == "rưỡi" || w == "ruoi"
+			j = end
+		}
+
+		i = j
+		if complete && l.hasNumbers {
+			good, goodVal, goodScaled = i, l.value(), l.lastScale != 0
+		}
+	}
+
+	if good < 0 {
+		return decimal.Zero, 0, false, false
+	}
+	if negative {
+		goodVal = goodVal.Neg()
+	}
+	return goodVal, good, goodScaled, true
+}
+
+// letterRunEnd vị trí hết chuỗi chữ cái bắt đầu từ s[i]
+func letterRunEnd(s string, i int) int {
+	for i < len(s) {
+		r, size := utf8.DecodeRuneInString(s[i:])
+		if !unicode.IsLetter(r) {
+			break
+		}
+		i += size
+	}
+	return i
+}
+
+// ParseAmount đọc số tiền dạng "50000", "50k", "1.5m", "1,5m", "1tr2", "2 triệu rưỡi", "năm trăm nghìn"
+// thành decimal chính xác (không qua float)
+func ParseAmount(amountStr string) (decimal.Decimal, error) {
+	s := strings.TrimSpace(amountStr)
+	val, n, _, ok := lexAmount(s)
+	if !ok || n != len(s) {
+		return decimal.Zero, fmt.Errorf("số tiền không hợp lệ: %q", amountStr)
+	}
+	return val, nil
+}
+
+// amountExpr đọc biểu thức số tiền: "3x25k", "120k/4", "50k+30k", "(50k+30k)/2".
+// Toán hạng là số tiền lexAmount đọc được; phép tính: + - x * × /, có ngoặc.
+// Dấu phép tính không có toán hạng phía sau thì trả lại cho ghi chú ("chi 50k + phí ship").
+// Dấu "-" có khoảng trắng hai bên chỉ là phép trừ khi số phía sau có hàng lớn ("50k - 5k"),
+// không thì là gạch ngăn ghi chú ("chi 30k - 1 ly cafe")
+type amountExpr struct {
+	s       string
+	pos     int
+	ops     int  // Số phép tính đã đọc, 0 là số tiền thường
+	divZero bool // Có phép chia cho 0
+	scaled  bool // Toán hạng vừa đọc có hàng lớn hoặc là biểu thức trong ngoặc
+}
+
+// skipSpaces vị trí sau khoảng trắng tính từ i
+func (e *amountExpr) skipSpaces(i int) int {
+	for i < len(e.s) && (e.s[i] == ' ' || e.s[i] == '\t') {
+		i++
+	}
+	return i
+}
+
+// operator đọc dấu phép tính trong ops tại vị trí (bỏ qua khoảng trắng), trả về dấu và vị trí sau dấu
+func (e *amountExpr) operator(ops string) (rune, int, bool) {
+	i := e.skipSpaces(e.pos)
+	r, size := utf8.DecodeRuneInString(e.s[i:])
+	if i >= len(e.s) || !strings.ContainsRune(ops, unicode.ToLower(r)) {
+		return 0, 0, false
+	}
+	return unicode.ToLower(r), i + size, true
+}
+
+// sum := product (('+' | '-') product)*
+func (e *amountExpr) sum() (decimal.Decimal, bool) {
+	val, ok := e.product()
+	if !ok {
+		return val, false
+	}
+	for {
+		op, next, ok := e.operator("+-")
+		if !ok {
+			return val, true
+		}
+		saved := e.pos
+		spaced := e.skipSpaces(saved) > saved || e.skipSpaces(next) > next
+		e.pos = e.skipSpaces(next)
+		e.scaled = false
+		rhs, ok := e.product()
+		if !ok || (op == '-' && spaced && !e.scaled) {
+			e.pos = saved
+			return val, true
+		}
+		e.ops++
+		if op == '+' {
+			val = val.Add(rhs)
+		} else {
+			val = val.Sub(rhs)
+		}
+	}
+}
+
+// product := operand (('x' | '*' | '×' | '/') operand)*
+func (e *amountExpr) product() (decimal.Decimal, bool) {
+	val, ok := e.operand()
+	if !ok {
+		return val, false
+	}
+	for {
+		op, next, ok := e.operator("x*×/")
+		if !ok {
+			return val, true
+		}
+		saved := e.pos
+		e.pos = e.skipSpaces(next)
+		rhs, ok := e.operand()
+		if !ok {
+			e.pos = saved
+			return val, true
+		}
+		e.ops++
+		if op == '/' {
+			if rhs.IsZero() {
+				e.divZero = true
+				return val, true
+			}
+			val = val.Div(rhs)
+		} else {
+			val = val.Mul(rhs)
+		}
+	}
+}
+
+// operand := '(' sum ')' | số tiền
+func (e *amountExpr) operand() (decimal.Decimal, bool) {
+	if e.pos < len(e.s) && e.s[e.pos] == '(' {
+		saved := e.pos
+		e.pos = e.skipSpaces(e.pos + 1)
+		val, ok := e.sum()
+		if end := e.skipSpaces(e.pos); ok && end < len(e.s) && e.s[end] == ')' {
+			e.pos = end + 1
+			e.scaled = true
+			return val, true
+		}
+		e.pos = saved
+		return decimal.Zero, false
+	}
+	val, n, scaled, ok := lexAmount(e.s[e.pos:])
+	if !ok {
+		return val, false
+	}
+	e.pos += n
+	e.scaled = e.scaled || scaled
+	return val, true
+}
+
+// evalAmount đọc số tiền hoặc biểu thức số tiền ở đầu s.
+// Trả về giá trị, số byte đã đọc và biểu thức gốc (rỗng nếu chỉ là số tiền thường).
+// ok=false nếu đầu s không phải số tiền hoặc có chia cho 0
+func evalAmount(s string) (decimal.Decimal, int, string, bool) {
+	e := amountExpr{s: s}
+	val, ok := e.sum()
+	if !ok || e.divZero {
+		return decimal.Zero, 0, "", false
+	}
+	if e.ops == 0 {
+		return val, e.pos, "", true
+	}
+	return val, e.pos, strings.TrimSpace(s[:e.pos]), true
+}
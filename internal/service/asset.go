@@ -0,0 +1,253 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"go-finance/internal/model"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/shopspring/decimal"
+)
+
+// builtinAssets các loại tiền/tài sản có sẵn. Thêm tài sản mới chỉ cần thêm vào đây
+// (kèm nguồn giá trong defaultRateChains nếu dùng mã giá mới), không cần sửa handler
+var builtinAssets = []model.Asset{
+	{Code: "VND", Name: "Việt Nam Đồng", Unit: "đ", Kind: model.AssetKindFiat, Rate: model.AssetRate{Price: 1}},
+	{Code: "USD", Name: "Đô la Mỹ", Unit: "USD", Kind: model.AssetKindFiat, Aliases: []string{"usd", "$"}, Rate: model.AssetRate{Code: AssetUSD}},
+	{Code: "EUR", Name: "Euro", Unit: "EUR", Kind: model.AssetKindFiat, Aliases: []string{"eur", "euro", "€"}, Rate: model.AssetRate{Code: AssetEUR}},
+	{Code: "JPY", Name: "Yên Nhật", Unit: "JPY", Kind: model.AssetKindFiat, Aliases: []string{"jpy", "yên", "¥"}, Rate: model.AssetRate{Code: AssetJPY}},
+	{Code: "CNY", Name: "Nhân dân tệ", Unit: "CNY", Kind: model.AssetKindFiat, Aliases: []string{"cny", "rmb", "nhân dân tệ", "ndt"}, Rate: model.AssetRate{Code: AssetCNY}},
+	{Code: "GOLD", Name: "Vàng SJC", Unit: "chỉ", Kind: model.AssetKindMetal, Aliases: []string{"chỉ vàng"}, Rate: model.AssetRate{Code: AssetSJC}},
+	// Bạc vật chất: giá thế giới quy ra lượng, cộng 5% chênh lệch trong nước
+	{Code: "SILVER", Name: "Bạc", Unit: "lượng", Kind: model.AssetKindMetal, Aliases: []string{"lượng bạc"},
+		Rate: model.AssetRate{Code: AssetXAG, Quote: "USD", Factor: OunceToTael * 1.05}},
+	{Code: "BTC", Name: "Bitcoin", Unit: "BTC", Kind: model.AssetKindCrypto, Aliases: []string{"btc", "bitcoin"}, Rate: model.AssetRate{Code: AssetBTC}},
+	{Code: "ETH", Name: "Ethereum", Unit: "ETH", Kind: model.AssetKindCrypto, Aliases: []string{"eth", "ethereum"}, Rate: model.AssetRate{Code: AssetETH}},
+	{Code: "USDT", Name: "Tether", Unit: "USDT", Kind: model.AssetKindCrypto, Aliases: []string{"usdt", "tether"}, Rate: model.AssetRate{Code: AssetUSDT}},
+}
+
+var (
+	assetsMu    sync.RWMutex
+	assetList   []model.Asset
+	assetByCode map[string]model.Asset
+	assetAlias  map[string]string // alias (chữ thường) -> mã tài sản
+	// Tăng mỗi lần registry đổi để regex dựng từ alias (unitRe) biết cần dựng lại
+	assetVersion int
+)
+
+func init() {
+	resetAssets()
+}
+
+// resetAssets đưa registry về danh sách có sẵn
+func resetAssets() {
+	assetsMu.Lock()
+	defer assetsMu.Unlock()
+	assetList = nil
+	assetByCode = make(map[string]model.Asset)
+	assetAlias = make(map[string]string)
+	for _, a := range builtinAssets {
+		addAsset(a)
+	}
+}
+
+func addAsset(a model.Asset) {
+	assetVersion++
+	assetList = append(assetList, a)
+	assetByCode[a.Code] = a
+	for _, alias := range a.Aliases {
+		assetAlias[alias] = a.Code
+	}
+}
+
+var assetCodeRe = regexp.MustCompile(`^[A-Z][A-Z0-9_]{1,11}$`)
+
+// RegisterAsset thêm tài sản tự định nghĩa (cổ phiếu, quỹ, coin khác...).
+// Giá lấy theo mã giá Rate.Code (cần cấu hình nguồn RATE_PROVIDERS_<CODE>) hoặc giá cố định Rate.Price
+func RegisterAsset(a model.Asset) error {
+	a.Code = strings.ToUpper(strings.TrimSpace(a.Code))
+	if !assetCodeRe.MatchString(a.Code) {
+		return fmt.Errorf("invalid asset code %q", a.Code)
+	}
+	if a.Name == "" {
+		a.Name = a.Code
+	}
+	if a.Unit == "" {
+		a.Unit = a.Code
+	}
+	if a.Kind == "" {
+		a.Kind = model.AssetKindCustom
+	}
+	switch a.Kind {
+	case model.AssetKindFiat, model.AssetKindCrypto, model.AssetKindMetal, model.AssetKindCustom:
+	default:
+		return fmt.Errorf("asset %s: invalid kind %q", a.Code, a.Kind)
+	}
+
+	a.Rate.Code = strings.ToUpper(strings.TrimSpace(a.Rate.Code))
+	switch {
+	case a.Rate.Code == "" && a.Rate.Price <= 0:
+		return fmt.Errorf("asset %s: rate code or a positive price is required", a.Code)
+	case a.Rate.Code != "" && a.Rate.Price != 0:
+		return fmt.Errorf("asset %s: rate code and price cannot be used together", a.Code)
+	case a.Rate.Quote != "" && a.Rate.Quote != "VND" && a.Rate.Quote != "USD":
+		return fmt.Errorf("asset %s: rate quote must be VND or USD", a.Code)
+	case a.Rate.Factor < 0:
+		return fmt.Errorf("asset %s: rate factor must not be negative", a.Code)
+	}
+	if a.Rate.Quote == "VND" {
+		a.Rate.Quote = ""
+	}
+
+	aliases := make([]string, 0, len(a.Aliases))
+	for _, alias := range a.Aliases {
+		if alias = strings.ToLower(strings.Join(strings.Fields(alias), " ")); alias != "" {
+			aliases = append(aliases, alias)
+		}
+	}
+	a.Aliases = aliases
+
+	assetsMu.Lock()
+	defer assetsMu.Unlock()
+	if _, ok := assetByCode[a.Code]; ok {
+		return fmt.Errorf("asset %s already exists", a.Code)
+	}
+	for _, alias := range a.Aliases {
+		if code, ok := assetAlias[alias]; ok {
+			return fmt.Errorf("asset %s: alias %q is already used by %s", a.Code, alias, code)
+		}
+	}
+	addAsset(a)
+	return nil
+}
+
+// LoadAssetsFromEnv đăng ký các tài sản tự định nghĩa trong env CUSTOM_ASSETS (mảng JSON model.Asset).
+// VD: [{"code":"VNM","name":"Cổ phiếu VNM","unit":"cp","aliases":["vnm"],"rate":{"price":65000}}]
+func LoadAssetsFromEnv() error {
+	v := strings.TrimSpace(os.Getenv("CUSTOM_ASSETS"))
+	if v == "" {
+		return nil
+	}
+	var custom []model.Asset
+	if err := json.Unmarshal([]byte(v), &custom); err != nil {
+		return fmt.Errorf("invalid CUSTOM_ASSETS: %w", err)
+	}
+	for _, a := range custom {
+		if err := RegisterAsset(a); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Assets tất cả tài sản theo thứ tự đăng ký (có sẵn trước, tự định nghĩa sau)
+func Assets() []model.Asset {
+	assetsMu.RLock()
+	defer assetsMu.RUnlock()
+	return append([]model.Asset(nil), assetList...)
+}
+
+// LookupAsset tìm tài sản theo mã (không phân biệt hoa thường)
+func LookupAsset(code string) (model.Asset, bool) {
+	assetsMu.RLock()
+	defer assetsMu.RUnlock()
+	a, ok := assetByCode[strings.ToUpper(strings.TrimSpace(code))]
+	return a, ok
+}
+
+// AssetForAlias mã tài sản của cách viết trong tin nhắn ("$", "chỉ vàng"...), rỗng nếu không có
+func AssetForAlias(alias string) string {
+	assetsMu.RLock()
+	defer assetsMu.RUnlock()
+	return assetAlias[strings.ToLower(strings.Join(strings.Fields(alias), " "))]
+}
+
+// assetRegistryVersion phiên bản registry tài sản, đổi mỗi khi thêm tài sản
+func assetRegistryVersion() int {
+	assetsMu.RLock()
+	defer assetsMu.RUnlock()
+	return assetVersion
+}
+
+// assetAliasPattern regex khớp mọi alias, alias dài thử trước ("usdt" trước "usd")
+func assetAliasPattern() string {
+	assetsMu.RLock()
+	aliases := make([]string, 0, len(assetAlias))
+	for alias := range assetAlias {
+		aliases = append(aliases, alias)
+	}
+	assetsMu.RUnlock()
+
+	sort.Slice(aliases, func(i, j int) bool {
+		if len(aliases[i]) != len(aliases[j]) {
+			return len(aliases[i]) > len(aliases[j])
+		}
+		return aliases[i] < aliases[j]
+	})
+	parts := make([]string, len(aliases))
+	for i, alias := range aliases {
+		words := strings.Fields(alias)
+		for k, w := range words {
+			words[k] = regexp.QuoteMeta(w)
+		}
+		parts[i] = strings.Join(words, `\s?`)
+	}
+	return strings.Join(parts, "|")
+}
+
+// assetRateCodes các mã giá cần lấy để định giá mọi tài sản đã đăng ký
+func assetRateCodes() []string {
+	seen := make(map[string]bool)
+	for _, code := range RateAssets {
+		seen[code] = true
+	}
+	for _, a := range Assets() {
+		for _, code := range a.RateCodes() {
+			seen[code] = true
+		}
+	}
+	codes := make([]string, 0, len(seen))
+	for code := range seen {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+	return codes
+}
+
+// RateToVND giá 1 đơn vị tài sản theo VND với bảng giá rates (GOLD theo chỉ SJC, SILVER theo lượng).
+// 1 với VND hoặc mã không có trong registry (dữ liệu cũ)
+func RateToVND(currency string, rates model.ExchangeRates) decimal.Decimal {
+	a, ok := LookupAsset(currency)
+	if !ok {
+		return decimal.NewFromInt(1)
+	}
+	return a.RateIn(rates)
+}
+
+// BidToVND giá mua vào 1 đơn vị tài sản theo VND: giá trị thanh lý, dùng để định giá tài sản đang giữ
+func BidToVND(currency string, rates model.ExchangeRates) decimal.Decimal {
+	a, ok := LookupAsset(currency)
+	if !ok {
+		return decimal.NewFromInt(1)
+	}
+	return a.BidIn(rates)
+}
+
+// AskToVND giá bán ra 1 đơn vị tài sản theo VND: số tiền phải trả khi mua tài sản
+func AskToVND(currency string, rates model.ExchangeRates) decimal.Decimal {
+	a, ok := LookupAsset(currency)
+	if !ok {
+		return decimal.NewFromInt(1)
+	}
+	return a.AskIn(rates)
+}
+
+// RateStale giá dùng để quy đổi currency ra VND có phải giá cũ/mặc định không. VND luôn false
+func RateStale(currency string, rates model.ExchangeRates) bool {
+	a, ok := LookupAsset(currency)
+	return ok && a.StaleIn(rates)
+}
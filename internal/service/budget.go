@@ -0,0 +1,37 @@
+package service
+
+import (
+	"go-finance/internal/model"
+	"regexp"
+	"strings"
+)
+
+// budgetRe bắt lệnh đặt ngân sách: "ngân sách <danh mục> <số tiền>/<tuần|tháng>"
+var budgetRe = regexp.MustCompile(`(?i)^(?:ngân\s*sách|ngan\s*sach)\s+(.+?)\s+([\d.,]+[km]?)\s*(?:/|\s)\s*(tuần|tuan|tháng|thang)$`)
+
+// ParseBudgetText bóc ngân sách từ tin nhắn, VD: "ngân sách ăn uống 3m/tháng", "ngân sách cafe 500k/tuần".
+// Nếu tên danh mục không phải danh mục có sẵn thì được phân loại giống ghi chú giao dịch
+// (cafe -> ăn uống) để ngân sách khớp với category của các giao dịch chi.
+// Trả về ok=false nếu không đúng cú pháp hoặc số tiền không hợp lệ
+func ParseBudgetText(text string) (model.Budget, bool) {
+	m := budgetRe.FindStringSubmatch(strings.TrimSpace(NormalizeText(text)))
+	if m == nil {
+		return model.Budget{}, false
+	}
+
+	limit, err := ParseAmount(m[2])
+	if err != nil || !limit.IsPositive() {
+		return model.Budget{}, false
+	}
+
+	category := strings.ToLower(strings.TrimSpace(m[1]))
+	if _, known := categoryKeywords[category]; !known && category != "khác" {
+		category = CategorizeExpense(category)
+	}
+
+	period := "month"
+	if p := strings.ToLower(m[3]); p == "tuần" || p == "tuan" {
+		period = "week"
+	}
+	return model.Budget{Category: category, Period: period, Limit: limit}, true
+}
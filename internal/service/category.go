@@ -20,23 +20,38 @@ var categoryKeywords = map[string][]string{
 	},
 }
 
-// Hàm phân loại chi tiêu
+// Hàm phân loại chi tiêu.
+// Khớp từ khóa đúng dấu trước, không có thì so khớp không dấu ("an sang", "tien dien")
+// nhưng chỉ khi cả ghi chú gõ không dấu: ghi chú có dấu thì "áo che nắng" không khớp "chè", "quần" không khớp "quán"
 func CategorizeExpense(note string) string {
-	text := strings.ToLower(note)
+	text := strings.ToLower(NormalizeText(note))
 	if text == "" {
 		return "khác"
 	}
 
+	if category, ok := matchCategory(text, func(k string) string { return k }); ok {
+		return category
+	}
+	if folded := FoldText(text); folded == strings.Join(strings.Fields(text), " ") {
+		if category, ok := matchCategory(folded, FoldText); ok {
+			return category
+		}
+	}
+	return "khác"
+}
+
+// matchCategory tìm danh mục có từ khóa (qua hàm chuẩn hóa key) xuất hiện nguyên từ trong text
+func matchCategory(text string, key func(string) string) (string, bool) {
 	for category, keywords := range categoryKeywords {
 		for _, k := range keywords {
 			// (^|[^\p{L}]) : Bắt đầu chuỗi HOẶC ký tự trước đó KHÔNG phải là chữ cái
 			// ([^\p{L}]|$) : Ký tự tiếp theo KHÔNG phải là chữ cái HOẶC kết thúc chuỗi
-			pattern := `(?i)(^|[^\p{L}])` + regexp.QuoteMeta(k) + `([^\p{L}]|$)`
+			pattern := `(?i)(^|[^\p{L}])` + regexp.QuoteMeta(key(k)) + `([^\p{L}]|$)`
 			matched, _ := regexp.MatchString(pattern, text)
 			if matched {
-				return category
+				return category, true
 			}
 		}
 	}
-	return "khác"
+	return "", false
 }
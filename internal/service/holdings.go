@@ -0,0 +1,124 @@
+package service
+
+import (
+	"go-finance/internal/model"
+	"sort"
+	"time"
+
+	"github.com/shopspring/decimal"
+)
+
+// lot một lần nạp tài sản (tiet_kiem), dùng cho FIFO
+type lot struct {
+	quantity decimal.Decimal
+	costVND  decimal.Decimal
+}
+
+// Sale một lần rút/bán tài sản và lãi/lỗ đã thực hiện của lần đó
+type Sale struct {
+	TransactionID int
+	At            time.Time
+	Quantity      decimal.Decimal
+	ProceedsVND   decimal.Decimal // Tiền VND nhận về
+	CostVND       decimal.Decimal // Giá vốn của phần đã bán
+}
+
+// Gain lãi (dương) hoặc lỗ (âm) đã thực hiện
+func (s Sale) Gain() decimal.Decimal {
+	return s.ProceedsVND.Sub(s.CostVND)
+}
+
+// Holding số tài sản đang giữ của một đơn vị tiền và giá vốn còn lại
+type Holding struct {
+	Currency string
+	Quantity decimal.Decimal
+	CostVND  decimal.Decimal // Tổng giá vốn VND của Quantity
+	Sales    []Sale
+
+	lots []lot
+}
+
+// ComputeHoldings tính tài sản đang giữ theo từng đơn vị tiền từ các giao dịch tiet_kiem (nạp)
+// và rut (rút/bán), theo thứ tự thời gian. method là model.CostBasisFIFO hoặc model.CostBasisAverage.
+// Rút quá số đang giữ thì phần vượt được tính giá vốn 0
+func ComputeHoldings(txs []model.Transaction, method string) map[string]*Holding {
+	sorted := make([]model.Transaction, 0, len(txs))
+	for _, t := range txs {
+		if t.Type == "tiet_kiem" || t.Type == "rut" {
+			sorted = append(sorted, t)
+		}
+	}
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if !sorted[i].CreatedAt.Equal(sorted[j].CreatedAt) {
+			return sorted[i].CreatedAt.Before(sorted[j].CreatedAt)
+		}
+		return sorted[i].ID < sorted[j].ID
+	})
+
+	holdings := make(map[string]*Holding)
+	for _, t := range sorted {
+		h := holdings[t.Currency]
+		if h == nil {
+			h = &Holding{Currency: t.Currency}
+			holdings[t.Currency] = h
+		}
+		if t.Type == "tiet_kiem" {
+			h.buy(t.OriginalAmount, t.Amount)
+			continue
+		}
+		cost := h.sell(t.OriginalAmount, method)
+		h.Sales = append(h.Sales, Sale{
+			TransactionID: t.ID,
+			At:            t.CreatedAt,
+			Quantity:      t.OriginalAmount,
+			ProceedsVND:   t.Amount,
+			CostVND:       cost,
+		})
+	}
+	return holdings
+}
+
+func (h *Holding) buy(qty, costVND decimal.Decimal) {
+	h.Quantity = h.Quantity.Add(qty)
+	h.CostVND = h.CostVND.Add(costVND)
+	h.lots = append(h.lots, lot{quantity: qty, costVND: costVND})
+}
+
+// sell bớt qty khỏi tài sản đang giữ, trả về giá vốn của phần đã bán
+func (h *Holding) sell(qty decimal.Decimal, method string) decimal.Decimal {
+	if !h.Quantity.IsPositive() {
+		return decimal.Zero
+	}
+	if qty.GreaterThan(h.Quantity) {
+		qty = h.Quantity
+	}
+
+	var cost decimal.Decimal
+	if method == model.CostBasisFIFO {
+		remaining := qty
+		for len(h.lots) > 0 && remaining.IsPositive() {
+			l := &h.lots[0]
+			if l.quantity.LessThanOrEqual(remaining) {
+				cost = cost.Add(l.costVND)
+				remaining = remaining.Sub(l.quantity)
+				h.lots = h.lots[1:]
+				continue
+			}
+			part := l.costVND.Mul(remaining).Div(l.quantity)
+			cost = cost.Add(part)
+			l.costVND = l.costVND.Sub(part)
+			l.quantity = l.quantity.Sub(remaining)
+			remaining = decimal.Zero
+		}
+	} else {
+		cost = h.CostVND.Mul(qty).Div(h.Quantity)
+	}
+
+	h.Quantity = h.Quantity.Sub(qty)
+	h.CostVND = h.CostVND.Sub(cost)
+	if h.Quantity.IsZero() {
+		h.CostVND = decimal.Zero
+		h.lots = nil
+	}
+	return cost
+}
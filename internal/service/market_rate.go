@@ -1,155 +1,278 @@
 package service
 
 import (
-	"encoding/json"
+	"context"
 	"go-finance/internal/model"
 	"log"
-	"net/http"
+	"os"
 	"sync"
 	"time"
 )
 
-const OunceToTael = 1.20565
-
 var (
-	// Biến toàn cục lưu giá (Cache)
-	cachedRates model.ExchangeRates
+	// Biến toàn cục lưu giá (Cache), kèm id bản ghi trong DB (0 nếu chưa lưu được)
+	cachedSnapshot model.RateSnapshot
 	// Mutex để đảm bảo an toàn khi nhiều luồng đọc/ghi cùng lúc
 	ratesMutex sync.RWMutex
+	// Registry nguồn giá worker đang dùng, để API xem tình trạng nguồn
+	activeRegistry *RateRegistry
+	// Giá lấy lâu hơn thời gian này bị coi là cũ (3 lần cập nhật 10 phút liên tiếp lỗi)
+	rateStaleAfter = 30 * time.Minute
 )
 
-// Hàm khởi chạy worker cập nhật giá (Gọi 1 lần duy nhất ở main.go)
-func StartPriceUpdater() {
+// RateRecorder nơi lưu lịch sử giá (store.Store)
+type RateRecorder interface {
+	SaveRates(at time.Time, rates model.ExchangeRates) (int, error)
+	LatestRates() (model.RateSnapshot, error)
+}
+
+// Hàm khởi chạy worker cập nhật giá (Gọi 1 lần duy nhất ở main.go).
+// Chạy tới khi ctx bị hủy (tắt server), lần lấy giá đang dở cũng bị hủy theo.
+// Sau mỗi lần cập nhật giá sẽ kiểm tra cảnh báo giá của user (alerts = nil để bỏ qua).
+// Registry và RATE_STALE_AFTER chỉ có hiệu lực khi worker đang chạy, dừng thì trả lại như trước
+func StartPriceUpdater(ctx context.Context, recorder RateRecorder, registry *RateRegistry, alerts AlertChecker) {
+	ratesMutex.Lock()
+	prevRegistry, prevStaleAfter := activeRegistry, rateStaleAfter
+	activeRegistry = registry
+	// RATE_STALE_AFTER=2h: giá lấy quá lâu (mọi nguồn lỗi liên tục) bị đánh dấu stale
+	if d, err := time.ParseDuration(os.Getenv("RATE_STALE_AFTER")); err == nil && d > 0 {
+		rateStaleAfter = d
+	}
+	ratesMutex.Unlock()
+	defer func() {
+		ratesMutex.Lock()
+		activeRegistry, rateStaleAfter = prevRegistry, prevStaleAfter
+		ratesMutex.Unlock()
+	}()
+
+	// 0. Dùng giá đã lưu gần nhất trong lúc chờ lần lấy giá đầu tiên (restart không mất giá)
+	if latest, err := recorder.LatestRates(); err == nil {
+		setCachedSnapshot(latest)
+	}
+
 	// 1. Cập nhật ngay lập tức khi khởi động để có dữ liệu liền
-	updateRates()
+	updateRates(ctx, recorder, registry)
+	runAlerts(alerts)
 
 	// 2. Thiết lập định kỳ 10 phút cập nhật 1 lần
 	ticker := time.NewTicker(10 * time.Minute)
-	for range ticker.C {
-		updateRates()
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[CACHE] Dừng cập nhật tỷ giá")
+			return
+		case <-ticker.C:
+			updateRates(ctx, recorder, registry)
+			runAlerts(alerts)
+		}
 	}
 }
 
-// Hàm private thực hiện logic gọi API và lưu vào Cache
-func updateRates() {
+// Hàm private thực hiện logic gọi các nguồn giá, lưu lịch sử vào DB và lưu vào Cache
+func updateRates(ctx context.Context, recorder RateRecorder, registry *RateRegistry) {
 	log.Println("[CACHE] Đang cập nhật tỷ giá mới...")
-	newRates, err := GetMetalPrices() // Gọi hàm cũ của bạn
-	if err != nil {
+	// Hạn chung cho mọi nguồn (kể cả gọi lại), các nguồn được gọi song song
+	ctx, cancel := context.WithTimeout(ctx, 20*time.Second)
+	defer cancel()
+
+	quotes, err := registry.Fetch(ctx)
+	if len(quotes) == 0 {
 		log.Printf("[CACHE ERROR] Không thể cập nhật giá: %v", err)
 		return
 	}
+	if err != nil {
+		// Tài sản không lấy được giữ giá và nguồn lần trước, sẽ bị đánh dấu stale khi quá cũ
+		log.Printf("[CACHE WARN] Cập nhật giá thiếu: %v", err)
+	}
 
+	ratesMutex.RLock()
+	prev := cachedSnapshot.Rates
+	ratesMutex.RUnlock()
+	if prev.UsdVND == 0 {
+		prev = defaultRates()
+	}
+
+	snap := model.RateSnapshot{FetchedAt: time.Now()}
+	snap.Rates = mergeRates(prev, quotes, snap.FetchedAt)
+	if snap.ID, err = recorder.SaveRates(snap.FetchedAt, snap.Rates); err != nil {
+		// Vẫn dùng giá mới, chỉ là giao dịch quy đổi theo giá này sẽ không có rate_snapshot_id
+		log.Printf("[CACHE ERROR] Không thể lưu lịch sử giá: %v", err)
+	}
+	setCachedSnapshot(snap)
+
+	log.Println("[CACHE] Cập nhật tỷ giá thành công!")
+}
+
+// mergeRates ghi giá mới lấy được (theo mã tài sản) đè lên prev, kèm nguồn và thời điểm lấy
+func mergeRates(prev model.ExchangeRates, quotes map[string]Quote, at time.Time) model.ExchangeRates {
+	rates := withDefaultQuotes(prev)
+	rates.Stale = false
+	for code, src := range rates.Sources {
+		src.Stale = false // Tính lại lúc đọc
+		rates.Sources[code] = src
+	}
+
+	fields := map[string]*float64{
+		AssetUSD: &rates.UsdVND,
+		AssetXAU: &rates.GoldUSD,
+		AssetXAG: &rates.SilverUSD,
+		AssetSJC: &rates.VnSJC,
+		AssetBTC: &rates.BtcVND,
+	}
+	for code, q := range quotes {
+		if field, ok := fields[code]; ok {
+			*field = q.Price
+		} else {
+			rates.Quotes[code] = q.Price
+		}
+		// Nguồn mới không có giá mua/bán riêng thì bỏ giá cũ, Bid/Ask quay về giá tham chiếu
+		setOrDelete(rates.Bids, code, q.Bid)
+		setOrDelete(rates.Asks, code, q.Ask)
+		fetchedAt := at
+		rates.Sources[code] = model.RateSource{Source: q.Source, FetchedAt: &fetchedAt}
+	}
+	// Giá bạc VN ước lượng từ giá thế giới
+	rates.VnSilver = rates.SilverUSD * rates.UsdVND * OunceToTael * 1.05
+	return rates
+}
+
+func setOrDelete(m map[string]float64, code string, v float64) {
+	if v > 0 {
+		m[code] = v
+	} else {
+		delete(m, code)
+	}
+}
+
+func setCachedSnapshot(snap model.RateSnapshot) {
 	// KHÓA GHI: Chỉ cho phép 1 luồng được ghi dữ liệu vào biến
 	ratesMutex.Lock()
-	cachedRates = newRates
+	cachedSnapshot = snap
 	ratesMutex.Unlock()
-
-	log.Println("[CACHE] Cập nhật tỷ giá thành công!")
 }
 
 // Hàm Public để các chỗ khác lấy giá từ Cache (Cực nhanh)
 func GetCurrentRates() model.ExchangeRates {
+	return GetCurrentSnapshot().Rates
+}
+
+// GetCurrentSnapshot giá hiện tại kèm id bản ghi lịch sử giá. ID = 0 nếu là giá mặc định
+// hoặc giá chưa lưu được vào DB. Giá mặc định, giá không rõ nguồn hoặc lấy quá RATE_STALE_AFTER
+// được đánh dấu stale
+func GetCurrentSnapshot() model.RateSnapshot {
 	// KHÓA ĐỌC: Cho phép nhiều luồng đọc cùng lúc, nhưng không ai được ghi
 	ratesMutex.RLock()
-	defer ratesMutex.RUnlock()
-
-	// Nếu cache chưa có dữ liệu (lần đầu tiên), trả về giá trị mặc định an toàn
-	if cachedRates.UsdVND == 0 {
-		return model.ExchangeRates{
-			UsdVND:    25400,      // Giá USD ~25,400đ
-			GoldUSD:   2700,       // Giá Vàng TG ~$2,700/oz
-			SilverUSD: 32,         // Giá Bạc TG ~$32/oz
-			VnSJC:     8500000,    // Giá Vàng SJC ~8.5 triệu/chỉ
-			VnSilver:  1000000,    // Giá Bạc VN ước lượng ~1 triệu/cây (lượng)
-			BtcVND:    2500000000, // Bitcoin ~2.5 tỷ VND
-			// GoldDiff và SilverDiff để 0 cũng được vì chỉ dùng để hiển thị báo cáo
-		}
+	snap := cachedSnapshot
+	maxAge := rateStaleAfter
+	ratesMutex.RUnlock()
+
+	// Nếu cache chưa có dữ liệu (lần đầu tiên), trả về giá trị mặc định (luôn stale)
+	if snap.Rates.UsdVND == 0 {
+		snap = model.RateSnapshot{Rates: defaultRates()}
 	}
+	snap.Rates = markStale(withDefaultQuotes(snap.Rates), time.Now(), maxAge)
+	return snap
+}
 
-	return cachedRates
+// HistoricalSnapshot chuẩn hóa bảng giá đọc từ lịch sử (Store.RatesAt) giống GetCurrentSnapshot:
+// điền giá mặc định cho mã giá bản ghi cũ chưa có (EUR, ETH... lưu trước khi có registry),
+// đánh dấu giá cũ so với thời điểm at thay vì bây giờ
+func HistoricalSnapshot(snap model.RateSnapshot, at time.Time) model.RateSnapshot {
+	ratesMutex.RLock()
+	maxAge := rateStaleAfter
+	ratesMutex.RUnlock()
+
+	snap.Rates = markStale(withDefaultQuotes(snap.Rates), at, maxAge)
+	return snap
 }
 
-// GetMetalPrices fetches external APIs
-func GetMetalPrices() (model.ExchangeRates, error) {
+// defaultRates giá mặc định an toàn khi chưa lấy được giá thật
+func defaultRates() model.ExchangeRates {
 	rates := model.ExchangeRates{
-		UsdVND:    25400,
-		GoldUSD:   2700,
-		SilverUSD: 32,
-		VnSJC:     8500000,
-		VnSilver:  1000000,
-		BtcVND:    2500000000,
+		UsdVND:    25400,      // Giá USD ~25,400đ
+		GoldUSD:   2700,       // Giá Vàng TG ~$2,700/oz
+		SilverUSD: 32,         // Giá Bạc TG ~$32/oz
+		VnSJC:     8500000,    // Giá Vàng SJC ~8.5 triệu/chỉ
+		BtcVND:    2500000000, // Bitcoin ~2.5 tỷ VND
+		// GoldDiff và SilverDiff để 0 cũng được vì chỉ dùng để hiển thị báo cáo
+		Sources: make(map[string]model.RateSource, len(RateAssets)),
 	}
+	// Giá Bạc VN ước lượng ~1 triệu/cây (lượng), cùng công thức với giá thật
+	rates.VnSilver = rates.SilverUSD * rates.UsdVND * OunceToTael * 1.05
+	for _, code := range []string{AssetUSD, AssetXAU, AssetXAG, AssetSJC, AssetBTC} {
+		rates.Sources[code] = model.RateSource{Source: model.RateSourceDefault}
+	}
+	return withDefaultQuotes(rates)
+}
 
-	client := http.Client{Timeout: 5 * time.Second}
+// defaultQuotes giá mặc định của các mã giá trong ExchangeRates.Quotes
+var defaultQuotes = map[string]float64{
+	AssetEUR:  27500,    // ~27,500đ
+	AssetJPY:  170,      // ~170đ
+	AssetCNY:  3500,     // ~3,500đ
+	AssetETH:  85000000, // ~85 triệu
+	AssetUSDT: 25400,    // Bám theo USD
+}
 
-	// 1. USD Rate
-	if resp, err := client.Get("https://open.er-api.com/v6/latest/USD"); err == nil {
-		defer resp.Body.Close()
-		var d struct {
-			Rates map[string]float64 `json:"rates"`
-		}
-		if json.NewDecoder(resp.Body).Decode(&d) == nil {
-			rates.UsdVND = d.Rates["VND"]
-		}
+// withDefaultQuotes bản sao của rates, thêm giá mặc định cho mã giá chưa có (bản ghi giá cũ lưu trước khi có mã này).
+// Giá mặc định không có chênh lệch mua/bán
+func withDefaultQuotes(rates model.ExchangeRates) model.ExchangeRates {
+	quotes := make(map[string]float64, len(rates.Quotes)+len(defaultQuotes))
+	for code, v := range rates.Quotes {
+		quotes[code] = v
 	}
-
-	// 2. Gold World
-	if resp, err := client.Get("https://api.gold-api.com/price/XAU"); err == nil {
-		defer resp.Body.Close()
-		var d struct {
-			Price float64 `json:"price"`
-		}
-		if json.NewDecoder(resp.Body).Decode(&d) == nil {
-			rates.GoldUSD = d.Price
+	sources := make(map[string]model.RateSource, len(rates.Sources)+len(defaultQuotes))
+	for code, src := range rates.Sources {
+		sources[code] = src
+	}
+	for code, v := range defaultQuotes {
+		if quotes[code] <= 0 {
+			quotes[code] = v
+			sources[code] = model.RateSource{Source: model.RateSourceDefault}
 		}
 	}
+	rates.Quotes = quotes
+	rates.Sources = sources
+	rates.Bids = copyPrices(rates.Bids)
+	rates.Asks = copyPrices(rates.Asks)
+	return rates
+}
 
-	// 3. Silver World
-	if resp, err := client.Get("https://api.gold-api.com/price/XAG"); err == nil {
-		defer resp.Body.Close()
-		var d struct {
-			Price float64 `json:"price"`
-		}
-		if json.NewDecoder(resp.Body).Decode(&d) == nil {
-			rates.SilverUSD = d.Price
-			// Estimate VN Silver
-			baseVND := rates.SilverUSD * rates.UsdVND * OunceToTael
-			rates.VnSilver = baseVND * 1.05
-		}
+func copyPrices(m map[string]float64) map[string]float64 {
+	c := make(map[string]float64, len(m))
+	for code, v := range m {
+		c[code] = v
 	}
+	return c
+}
 
-	// 4. VN SJC
-	if resp, err := client.Get("https://www.vang.today/api/prices?type=SJL1L10"); err == nil {
-		defer resp.Body.Close()
-		var d struct {
-			Sell float64 `json:"sell"`
-		}
-		if json.NewDecoder(resp.Body).Decode(&d) == nil {
-			rates.VnSJC = d.Sell / 10
+// markStale tính cờ stale của từng giá tại thời điểm now (trả về bản sao, không sửa rates)
+func markStale(rates model.ExchangeRates, now time.Time, maxAge time.Duration) model.ExchangeRates {
+	codes := assetRateCodes()
+	sources := make(map[string]model.RateSource, len(codes))
+	rates.Stale = false
+	for _, code := range codes {
+		src, ok := rates.Sources[code]
+		if !ok {
+			src = model.RateSource{Source: "unknown"} // Bản ghi giá cũ chưa lưu nguồn
 		}
+		src.Stale = src.Source == model.RateSourceDefault || src.FetchedAt == nil || now.Sub(*src.FetchedAt) > maxAge
+		sources[code] = src
+		rates.Stale = rates.Stale || src.Stale
 	}
+	rates.Sources = sources
+	return rates
+}
 
-	// 5. [CẬP NHẬT] Bitcoin Rate (CoinGecko Direct VND)
-	// CoinGecko rất hay chặn request từ Cloud Server, nên cần thêm User-Agent giả lập trình duyệt
-	req, _ := http.NewRequest("GET", "https://api.coingecko.com/api/v3/simple/price?ids=bitcoin&vs_currencies=vnd", nil)
-	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)") // Giả lập Browser
-
-	if resp, err := client.Do(req); err == nil {
-		defer resp.Body.Close()
-
-		var d struct {
-			Bitcoin struct {
-				VND float64 `json:"vnd"`
-			} `json:"bitcoin"`
-		}
+// RateProvidersStatus tình trạng các nguồn giá của worker, rỗng nếu worker không chạy
+func RateProvidersStatus() model.RateProvidersStatus {
+	ratesMutex.RLock()
+	registry := activeRegistry
+	ratesMutex.RUnlock()
 
-		if json.NewDecoder(resp.Body).Decode(&d) == nil {
-			// CHỈ CẬP NHẬT NẾU GIÁ TRỊ > 0
-			if d.Bitcoin.VND > 0 {
-				rates.BtcVND = d.Bitcoin.VND
-			}
-		}
+	if registry == nil {
+		return model.RateProvidersStatus{Chains: map[string][]string{}, Providers: []model.ProviderHealth{}}
 	}
-
-	return rates, nil
+	return registry.Status()
 }
@@ -0,0 +1,195 @@
+package service
+
+import (
+	"fmt"
+	"go-finance/internal/model"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/shopspring/decimal"
+)
+
+// MetalUnit đơn vị khối lượng của vàng, bạc
+type MetalUnit struct {
+	Code    string          // Mã lưu trong cài đặt: chi, luong, g, kg, oz
+	Name    string          // Tên hiển thị
+	Grams   decimal.Decimal // Số gram của 1 đơn vị
+	Aliases []string        // Cách viết trong tin nhắn
+}
+
+// metalUnits 1 lượng (cây) = 10 chỉ = 37.5g, 1 oz (troy) = 31.1034768g
+var metalUnits = []MetalUnit{
+	{Code: "chi", Name: "chỉ", Grams: decimal.RequireFromString("3.75"), Aliases: []string{"chỉ", "chi"}},
+	{Code: "luong", Name: "lượng", Grams: decimal.RequireFromString("37.5"), Aliases: []string{"lượng", "luong", "cây", "cay"}},
+	{Code: "g", Name: "g", Grams: decimal.NewFromInt(1), Aliases: []string{"g", "gr", "gram"}},
+	{Code: "kg", Name: "kg", Grams: decimal.NewFromInt(1000), Aliases: []string{"kg"}},
+	{Code: "oz", Name: "oz", Grams: decimal.RequireFromString("31.1034768"), Aliases: []string{"oz", "ounce"}},
+}
+
+// OunceToTael số oz trong 1 lượng (cây): 37.5g / 31.1034768g ≈ 1.2057. Giá USD/oz * OunceToTael = giá USD/lượng
+var OunceToTael, _ = metalUnits[1].Grams.Div(metalUnits[4].Grams).Float64()
+
+// metalWords tên kim loại trong tin nhắn -> mã tài sản
+var metalWords = map[string]string{
+	"vàng": "GOLD", "vang": "GOLD", "gold": "GOLD",
+	"bạc": "SILVER", "bac": "SILVER", "silver": "SILVER",
+}
+
+// metalPurityPattern độ tinh khiết viết sau tên kim loại, chỉ để ghi nhận (giá tính theo vàng/bạc nguyên chất)
+const metalPurityPattern = `9999|999\.9|99\.99|999|24k`
+
+// LookupMetalUnit tìm đơn vị theo mã hoặc cách viết ("lượng", "cây", "gram"...)
+func LookupMetalUnit(name string) (MetalUnit, bool) {
+	name = strings.ToLower(strings.TrimSpace(name))
+	for _, u := range metalUnits {
+		if u.Code == name {
+			return u, true
+		}
+		for _, alias := range u.Aliases {
+			if alias == name {
+				return u, true
+			}
+		}
+	}
+	return MetalUnit{}, false
+}
+
+// MetalUnitCodes mã các đơn vị khối lượng
+func MetalUnitCodes() []string {
+	codes := make([]string, len(metalUnits))
+	for i, u := range metalUnits {
+		codes[i] = u.Code
+	}
+	return codes
+}
+
+// assetMetalUnit đơn vị gốc (đơn vị lưu số lượng) của tài sản kim loại, ok=false nếu không phải kim loại
+func assetMetalUnit(currency string) (MetalUnit, bool) {
+	a, ok := LookupAsset(currency)
+	if !ok || a.Kind != model.AssetKindMetal {
+		return MetalUnit{}, false
+	}
+	return LookupMetalUnit(a.Unit)
+}
+
+// ConvertMetalQuantity đổi số lượng kim loại currency từ đơn vị unit sang đơn vị gốc của tài sản
+// (GOLD theo chỉ, SILVER theo lượng), làm tròn 8 số lẻ
+func ConvertMetalQuantity(qty decimal.Decimal, unit, currency string) (decimal.Decimal, error) {
+	from, ok := LookupMetalUnit(unit)
+	if !ok {
+		return qty, fmt.Errorf("unknown unit %q, use one of %s", unit, strings.Join(MetalUnitCodes(), ", "))
+	}
+	to, ok := assetMetalUnit(currency)
+	if !ok {
+		return qty, fmt.Errorf("unit is only supported for metal assets, not %s", currency)
+	}
+	return qty.Mul(from.Grams).Div(to.Grams).Round(8), nil
+}
+
+// ConvertMetalPrice đổi giá theo 1 đơn vị from sang giá theo 1 đơn vị to (VD: đ/chỉ -> đ/lượng).
+// Đơn vị không hợp lệ thì trả về giá cũ
+func ConvertMetalPrice(price float64, from, to string) float64 {
+	f, okFrom := LookupMetalUnit(from)
+	t, okTo := LookupMetalUnit(to)
+	if !okFrom || !okTo {
+		return price
+	}
+	ratio, _ := t.Grams.Div(f.Grams).Float64()
+	return price * ratio
+}
+
+// PreferredMetalUnit đơn vị hiển thị user chọn cho tài sản: gold_unit với GOLD, silver_unit với SILVER.
+// ok=false nếu tài sản không phải kim loại hoặc không có cài đặt
+func PreferredMetalUnit(currency string, s model.UserSettings) (MetalUnit, bool) {
+	if _, ok := assetMetalUnit(currency); !ok {
+		return MetalUnit{}, false
+	}
+	var code string
+	switch currency {
+	case "GOLD":
+		code = s.GoldUnit
+	case "SILVER":
+		code = s.SilverUnit
+	}
+	return LookupMetalUnit(code)
+}
+
+// displayRatio số đơn vị gốc trong 1 đơn vị hiển thị của user (1 nếu không phải kim loại)
+func displayRatio(currency string, s model.UserSettings) (MetalUnit, decimal.Decimal, bool) {
+	base, ok := assetMetalUnit(currency)
+	pref, okPref := PreferredMetalUnit(currency, s)
+	if !ok || !okPref {
+		return MetalUnit{}, decimal.NewFromInt(1), false
+	}
+	return pref, pref.Grams.Div(base.Grams), true
+}
+
+// DisplayQuantity số lượng và giá 1 đơn vị (VND) của tài sản theo đơn vị hiển thị của user.
+// Tài sản không phải kim loại giữ nguyên số lượng, giá và đơn vị của registry
+func DisplayQuantity(currency string, qty, rate decimal.Decimal, s model.UserSettings) (string, decimal.Decimal, decimal.Decimal) {
+	pref, ratio, ok := displayRatio(currency, s)
+	if !ok {
+		if a, found := LookupAsset(currency); found {
+			return a.Unit, qty, rate
+		}
+		return currency, qty, rate
+	}
+	return pref.Name, qty.Div(ratio).Round(8), rate.Mul(ratio).Round(0)
+}
+
+// DisplayUnitPrice đổi giá theo đơn vị gốc (VD: giá mua bình quân/chỉ) sang giá theo đơn vị hiển thị của user
+func DisplayUnitPrice(currency string, price decimal.Decimal, s model.UserSettings) decimal.Decimal {
+	if _, ratio, ok := displayRatio(currency, s); ok {
+		return price.Mul(ratio).Round(0)
+	}
+	return price
+}
+
+// metalUnitPattern regex khớp "<đơn vị> <kim loại> [độ tinh khiết]": "lượng vàng", "5g vàng 9999", "kg bạc", "oz gold"
+func metalUnitPattern() string {
+	var words []string
+	for w := range metalWords {
+		words = append(words, w)
+	}
+	return `(?:` + longestFirst(metalUnitAliases()) + `)\s?(?:` + longestFirst(words) + `)(?:\s?(?:` + metalPurityPattern + `))?`
+}
+
+var metalUnitRe = regexp.MustCompile(`(?i)^(` + longestFirst(metalUnitAliases()) + `)\s?(\p{L}+)`)
+
+func metalUnitAliases() []string {
+	var aliases []string
+	for _, u := range metalUnits {
+		aliases = append(aliases, u.Aliases...)
+	}
+	return aliases
+}
+
+// parseMetalUnit tách cụm đơn vị kim loại đã khớp metalUnitPattern thành (mã tài sản, đơn vị)
+func parseMetalUnit(s string) (string, MetalUnit, bool) {
+	m := metalUnitRe.FindStringSubmatch(strings.TrimSpace(s))
+	if m == nil {
+		return "", MetalUnit{}, false
+	}
+	code, ok := metalWords[strings.ToLower(m[2])]
+	if !ok {
+		return "", MetalUnit{}, false
+	}
+	u, _ := LookupMetalUnit(m[1])
+	return code, u, true
+}
+
+// longestFirst nối các cách viết thành regex alternation, dài thử trước ("gram" trước "g")
+func longestFirst(words []string) string {
+	sorted := append([]string(nil), words...)
+	sort.Slice(sorted, func(i, j int) bool {
+		if len(sorted[i]) != len(sorted[j]) {
+			return len(sorted[i]) > len(sorted[j])
+		}
+		return sorted[i] < sorted[j]
+	})
+	for i, w := range sorted {
+		sorted[i] = regexp.QuoteMeta(w)
+	}
+	return strings.Join(sorted, "|")
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// NormalizeText chuẩn hóa Unicode về dạng dựng sẵn (NFC).
+// Bàn phím một số điện thoại gửi chữ có dấu ở dạng tổ hợp (NFD: "e" + dấu mũ + dấu sắc),
+// không chuẩn hóa thì regex viết bằng chữ dựng sẵn ("tiết kiệm") không khớp
+func NormalizeText(s string) string {
+	return norm.NFC.String(s)
+}
+
+// FoldText đưa chuỗi về dạng so khớp không dấu: chữ thường, bỏ dấu thanh và dấu mũ/móc
+// (tách NFD rồi bỏ ký tự dấu kết hợp), đ -> d, gộp khoảng trắng. VD: "Báo  Cáo" -> "bao cao"
+func FoldText(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(strings.ToLower(s)) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r == 'đ':
+			r = 'd'
+		}
+		b.WriteRune(r)
+	}
+	return strings.Join(strings.Fields(b.String()), " ")
+}
+
+// commandTypos số lỗi gõ cho phép với một lệnh đã fold: lệnh ngắn ("tk") phải gõ đúng
+func commandTypos(command string) int {
+	if len([]rune(command)) <= 4 {
+		return 0
+	}
+	return 1
+}
+
+// editDistance khoảng cách Levenshtein giữa 2 chuỗi (tính theo rune), đổi chỗ 2 ký tự liền nhau tính 1 lỗi
+func editDistance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev2 := make([]int, len(rb)+1)
+	prev := make([]int, len(rb)+1)
+	cur := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+			if i > 1 && j > 1 && ra[i-1] == rb[j-2] && ra[i-2] == rb[j-1] {
+				cur[j] = min(cur[j], prev2[j-2]+1) // "boa cao"
+			}
+		}
+		prev2, prev, cur = prev, cur, prev2
+	}
+	return prev[len(rb)]
+}
+
+// MatchCommand kiểm tra tin nhắn bắt đầu bằng lệnh (VD: "báo cáo"), không phân biệt dấu
+// và cho phép gõ sai 1 ký tự với lệnh dài: "bao cao", "báo cao", "bao cáo", "bap cao".
+// Trả về phần còn lại của tin nhắn (giữ nguyên dấu) sau các từ của lệnh
+func MatchCommand(text, command string) (string, bool) {
+	want := FoldText(command)
+	n := len(strings.Fields(want))
+	words := strings.Fields(text)
+	if n == 0 || len(words) < n {
+		return "", false
+	}
+	got := FoldText(strings.Join(words[:n], " "))
+	if got != want && editDistance(got, want) > commandTypos(want) {
+		return "", false
+	}
+	return strings.Join(words[n:], " "), true
+}
+
+// IsCommand cả tin nhắn là lệnh (VD: "tài sản", "tai san"), cho phép lỗi gõ như MatchCommand
+func IsCommand(text, command string) bool {
+	rest, ok := MatchCommand(text, command)
+	return ok && rest == ""
+}
+
+// ContainsCommand tin nhắn bắt đầu bằng lệnh (cho phép lỗi gõ) hoặc có lệnh ở giữa (không dấu, phải gõ đúng).
+// VD: "bao cao thang nay", "xem báo cáo"
+func ContainsCommand(text, command string) bool {
+	if _, ok := MatchCommand(text, command); ok {
+		return true
+	}
+	return strings.Contains(" "+FoldText(text)+" ", " "+FoldText(command)+" ")
+}
+
+// IsCommandMessage tin nhắn là lệnh command: bắt đầu bằng lệnh (cho phép lỗi gõ như MatchCommand),
+// hoặc có lệnh ở giữa (ContainsCommand) mà không có giao dịch nào ("xem báo cáo").
+// "chi 200k mua gia vang cho me" là giao dịch, không phải lệnh "giá vàng"
+func IsCommandMessage(text, command string) bool {
+	if _, ok := MatchCommand(text, command); ok {
+		return true
+	}
+	if !ContainsCommand(text, command) {
+		return false
+	}
+	for _, seg := range ParseTransactionSegments(text) {
+		if seg.Tx != nil {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,187 @@
+package service
+
+import (
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// occurredPeriod buổi đi sau giờ: "8h tối", "2h đêm"
+const occurredPeriod = `sáng|sang|trưa|trua|chiều|chieu|tối|toi|đêm|dem`
+
+// occurredTimeRe giờ ở cuối ghi chú: "20h", "20h30", "8:15", "8h tối", "2h đêm"
+var occurredTimeRe = regexp.MustCompile(`(?i)(?:^|\s)((\d{1,2})(?:h(\d{2})?|:(\d{2}))(?:\s+(` + occurredPeriod + `))?)\s*$`)
+
+// occurredDayRe ngày viết bằng chữ, ở bất kỳ đâu trong ghi chú:
+// "hôm nay", "hôm qua", "hôm kia", "thứ 2 tuần trước", "chủ nhật tuần này", "hôm thứ 6"
+var occurredDayRe = regexp.MustCompile(`(?i)(?:^|\s)((hôm\s+nay|hom\s+nay|hnay|hôm\s+qua|hom\s+qua|hqua|hôm\s+kia|hom\s+kia)|` +
+	`(?:(?:hôm|hom)\s+)?(?:(?:thứ|thu)\s*([2-7])|(chủ\s*nhật|chu\s*nhat|cn))(?:\s+(tuần\s+trước|tuan\s+truoc|tuần\s+này|tuan\s+nay))?)(?:\s|$)`)
+
+// occurredDateRe ngày/tháng ở cuối ghi chú (có thể có giờ theo sau): "15/3", "15/3/2025 20h", "ngày 15-3".
+// Chỉ nhận ở cuối để số trong ghi chú ("1/2 tiền điện") không bị hiểu là ngày;
+// dấu "-" phải có "ngày" đứng trước vì "2-3 cái" là khoảng số lượng
+var occurredDateRe = regexp.MustCompile(`(?i)(?:^|\s)((ngày\s+|ngay\s+)?(\d{1,2})([/\-])(\d{1,2})(?:[/\-](\d{4}|\d{2}))?)` +
+	`(?:\s+\d{1,2}(?:h(?:\d{2})?|:\d{2})(?:\s+(?:` + occurredPeriod + `))?)?\s*$`)
+
+// ExtractOccurredAt tách thời điểm phát sinh giao dịch khỏi ghi chú, tính theo now (đã ở múi giờ của user).
+// Trả về ghi chú đã bỏ phần ngày giờ và nil nếu ghi chú không có ngày giờ.
+// ok=false nếu ngày không có thật (31/2) hoặc ở tương lai.
+// Chỉ có giờ thì là hôm nay, giờ đó chưa tới thì là hôm qua. Chỉ có ngày thì giữ giờ hiện tại.
+// "thứ 2" phải đi kèm "hôm" hoặc "tuần trước/tuần này" để không nhầm với ghi chú ("con thứ 2").
+// Ngày dạng số và giờ chỉ nhận ở cuối ghi chú; giờ chẵn không có buổi từ 12h trở xuống ("thuê xe 12h")
+// là thời lượng, chỉ tính là giờ khi có ngày đi kèm ("hôm qua 8h")
+func ExtractOccurredAt(note string, now time.Time) (string, *time.Time, bool) {
+	day, dayOK, note := extractOccurredDay(note, now)
+	if !dayOK {
+		return note, nil, false
+	}
+	hour, minute, hasTime, note := extractOccurredTime(note, day != nil)
+	if day == nil && !hasTime {
+		return note, nil, true
+	}
+
+	at := now
+	if day != nil {
+		at = *day
+	}
+	if hasTime {
+		at = time.Date(at.Year(), at.Month(), at.Day(), hour, minute, 0, 0, now.Location())
+		if day == nil && at.After(now) {
+			at = at.AddDate(0, 0, -1) // "chi 50k ăn tối 20h" gửi lúc 8h sáng là tối qua
+		}
+	}
+	if at.After(now) {
+		return note, nil, false
+	}
+	return note, &at, true
+}
+
+// extractOccurredDay trả về ngày (giữ giờ của now), nil nếu không có; ok=false nếu ngày không hợp lệ
+func extractOccurredDay(note string, now time.Time) (*time.Time, bool, string) {
+	m := occurredDayRe.FindStringSubmatchIndex(note)
+	if m == nil {
+		return extractOccurredDate(note, now)
+	}
+	group := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return note[m[2*i]:m[2*i+1]]
+	}
+	rest := removePhrase(note, m[2], m[3])
+	shift := func(days int) *time.Time {
+		t := now.AddDate(0, 0, days)
+		return &t
+	}
+
+	switch {
+	case group(2) != "":
+		switch strings.Join(strings.Fields(strings.ToLower(group(2))), " ") {
+		case "hôm qua", "hom qua", "hqua":
+			return shift(-1), true, rest
+		case "hôm kia", "hom kia":
+			return shift(-2), true, rest
+		}
+		return shift(0), true, rest
+
+	default: // thứ 2..7, chủ nhật
+		iso := 7 // chủ nhật
+		if d := group(3); d != "" {
+			n, _ := strconv.Atoi(d)
+			iso = n - 1 // thứ 2 -> 1 (ISO)
+		}
+		week := strings.ToLower(group(5))
+		if week == "" && !strings.HasPrefix(strings.ToLower(group(1)), "h") {
+			return extractOccurredDate(note, now) // "thứ 2" đứng một mình không phải ngày, vẫn tìm ngày dạng số ("con thứ 2 15/3")
+		}
+		today := (int(now.Weekday())+6)%7 + 1
+		if week == "" {
+			// "hôm thứ 6": ngày thứ 6 gần nhất, tính cả hôm nay
+			return shift(-((today - iso + 7) % 7)), true, rest
+		}
+		offset := iso - today // Trong tuần này (tuần bắt đầu từ thứ 2)
+		if strings.HasPrefix(week, "tuần t") || strings.HasPrefix(week, "tuan t") {
+			offset -= 7
+		}
+		return shift(offset), true, rest
+	}
+}
+
+// extractOccurredDate ngày dạng số ở cuối ghi chú, giờ đi sau (nếu có) để lại cho extractOccurredTime
+func extractOccurredDate(note string, now time.Time) (*time.Time, bool, string) {
+	m := occurredDateRe.FindStringSubmatchIndex(note)
+	if m == nil {
+		return nil, true, note
+	}
+	group := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return note[m[2*i]:m[2*i+1]]
+	}
+	if group(4) == "-" && group(2) == "" {
+		return nil, true, note // "bánh 2-3": khoảng số lượng, không phải ngày
+	}
+	rest := removePhrase(note, m[2], m[3])
+
+	year := group(6)
+	if len(year) == 2 {
+		year = "20" + year
+	}
+	d, ok := buildDate(group(3), group(5), year, now)
+	if !ok {
+		return nil, false, rest
+	}
+	d = time.Date(d.Year(), d.Month(), d.Day(), now.Hour(), now.Minute(), now.Second(), 0, now.Location())
+	if year == "" && d.After(now) {
+		d = d.AddDate(-1, 0, 0) // "25/12" gửi đầu tháng 1 là năm ngoái
+	}
+	return &d, true, rest
+}
+
+// extractOccurredTime trả về giờ, phút ở cuối ghi chú; giờ không hợp lệ (VD: "gửi xe 24h") giữ nguyên trong ghi chú,
+// giờ chẵn không buổi từ 12h trở xuống chỉ nhận khi ghi chú có ngày (hasDay)
+func extractOccurredTime(note string, hasDay bool) (int, int, bool, string) {
+	m := occurredTimeRe.FindStringSubmatchIndex(note)
+	if m == nil {
+		return 0, 0, false, note
+	}
+	group := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return note[m[2*i]:m[2*i+1]]
+	}
+	hour, _ := strconv.Atoi(group(2))
+	minute := 0
+	if mm := group(3) + group(4); mm != "" {
+		minute, _ = strconv.Atoi(mm)
+	}
+	if hour > 23 || minute > 59 {
+		return 0, 0, false, note
+	}
+	if !hasDay && hour <= 12 && group(3)+group(4)+group(5) == "" {
+		return 0, 0, false, note // "thuê xe 12h": thời lượng
+	}
+	switch strings.ToLower(group(5)) {
+	case "chiều", "chieu", "tối", "toi":
+		if hour < 12 {
+			hour += 12
+		}
+	case "đêm", "dem":
+		if hour >= 7 && hour < 12 {
+			hour += 12 // "10h đêm" là 22h, "2h đêm" là 2h
+		}
+	case "trưa", "trua":
+		if hour <= 2 {
+			hour += 12 // "1h trưa" là 13h
+		}
+	}
+	return hour, minute, true, removePhrase(note, m[2], m[3])
+}
+
+// removePhrase bỏ note[start:end] và gộp khoảng trắng thừa
+func removePhrase(note string, start, end int) string {
+	return strings.Join(strings.Fields(note[:start]+" "+note[end:]), " ")
+}
@@ -1,126 +1,372 @@
 package service
 
 import (
+	"fmt"
 	"go-finance/internal/model"
 	"regexp"
-	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
+// ParseReason mã lý do một đoạn tin nhắn không thành giao dịch, rỗng là hợp lệ
+type ParseReason string
+
+const (
+	ReasonOK               ParseReason = ""
+	ReasonUnrecognized     ParseReason = "unrecognized"      // Không có từ khóa thu/chi/tk/rút/chuyển hoặc dấu +/-
+	ReasonInvalidAmount    ParseReason = "invalid_amount"    // Số tiền không đọc được
+	ReasonNonPositive      ParseReason = "non_positive"      // Số tiền/khối lượng bằng 0 hoặc âm
+	ReasonTransferAccounts ParseReason = "transfer_accounts" // Chuyển tiền không có đúng 2 tài khoản
+	ReasonTooManyAccounts  ParseReason = "too_many_accounts" // Thu/chi/tiết kiệm chọn hơn 1 tài khoản
+	ReasonUnexpectedNote   ParseReason = "unexpected_note"   // Tiết kiệm, rút/bán có ghi chú
+	ReasonMissingNote      ParseReason = "missing_note"      // Thu/chi thiếu ghi chú
+	ReasonForeignCurrency  ParseReason = "foreign_currency"  // Thu/chi không phải VND
+	ReasonInvalidDate      ParseReason = "invalid_date"      // Ngày không có thật hoặc ở tương lai
+)
+
+// ParsedSegment kết quả đọc một đoạn (một lệnh) trong tin nhắn
+type ParsedSegment struct {
+	Index  int                      // Thứ tự đoạn trong tin nhắn, bắt đầu từ 1
+	Start  int                      // Vị trí ký tự (rune) bắt đầu đoạn trong tin nhắn
+	End    int                      // Vị trí ký tự (rune) ngay sau đoạn
+	Text   string                   // Nội dung đoạn
+	Type   string                   // Loại giao dịch nhận ra từ từ khóa (thu, chi, tiet_kiem, rut, chuyen), rỗng nếu không nhận ra
+	Tx     *model.TransactionCreate // Giao dịch đọc được, nil nếu bị từ chối
+	Reason ParseReason
+}
+
+// Message giải thích lý do đoạn bị từ chối, VD: `đoạn 2 "tk 100k để dành": tiết kiệm không được có ghi chú`
+func (s ParsedSegment) Message() string {
+	var why string
+	switch s.Reason {
+	case ReasonUnrecognized:
+		why = "không hiểu, cần bắt đầu bằng thu/chi/tk/rút/chuyển hoặc +/-"
+	case ReasonInvalidAmount:
+		why = "số tiền không hợp lệ"
+	case ReasonNonPositive:
+		why = "số tiền phải lớn hơn 0"
+	case ReasonTransferAccounts:
+		why = "chuyển tiền cần đúng 2 tài khoản (@từ @đến)"
+	case ReasonTooManyAccounts:
+		why = "chỉ được chọn 1 tài khoản"
+	case ReasonUnexpectedNote:
+		why = "tiết kiệm không được có ghi chú"
+		if s.Type == "rut" {
+			why = "rút/bán không được có ghi chú"
+		}
+	case ReasonMissingNote:
+		why = "thu/chi phải có ghi chú"
+	case ReasonForeignCurrency:
+		why = "thu/chi chỉ dùng VND"
+	case ReasonInvalidDate:
+		why = "ngày giờ không hợp lệ hoặc ở tương lai"
+	default:
+		return fmt.Sprintf("đoạn %d %q: hợp lệ", s.Index, s.Text)
+	}
+	return fmt.Sprintf("đoạn %d %q: %s", s.Index, s.Text, why)
+}
+
+// ParseError các đoạn tin nhắn bị từ chối, các đoạn hợp lệ vẫn được trả về cùng lỗi này
+type ParseError struct {
+	Segments []ParsedSegment
+}
+
+func (e *ParseError) Error() string {
+	msgs := make([]string, len(e.Segments))
+	for i, s := range e.Segments {
+		msgs[i] = s.Message()
+	}
+	return strings.Join(msgs, "; ")
+}
+
 // ParseTransactionText xử lý tin nhắn và trả về danh sách các giao dịch
 // Hỗ trợ cú pháp nhiều lệnh trên 1 dòng, ngăn cách bởi dấu phẩy hoặc xuống dòng
 // Ví dụ: "chi 3k trà đá, +1m lương" -> 2 giao dịch
+// Rút/bán tài sản tiết kiệm: "rút tk 2 chỉ vàng", "bán 0.01 btc"
+// Số tiền kiểu nói ("2 triệu rưỡi", "1tr2") hoặc biểu thức ("3x25k", "120k/4"), biểu thức gốc giữ ở AmountExpr
+// Tài khoản chọn bằng @tên: "chi 50k cafe @momo", "chuyển 2m @vcb @momo" (từ vcb sang momo)
+// Ngày giờ phát sinh ghi trong ghi chú: "chi 200k ăn tối hôm qua", "tk 2m 15/3", "chi 1m vé 15/3/2025 20h"
+// Nếu có đoạn bị từ chối thì trả về *ParseError kèm các giao dịch hợp lệ.
+// Ngày giờ tương đối tính theo giờ Việt Nam, dùng ParseTransactionTextAt để tính theo múi giờ của user
 func ParseTransactionText(text string) ([]model.TransactionCreate, error) {
-	var results []model.TransactionCreate
+	return ParseTransactionTextAt(text, time.Now().In(model.DefaultUserSettings("").Location()))
+}
 
-	// Regex pattern:
-	// Group 1: Keywords (thu, chi, tk, tiết kiệm...)
-	// Group 2: Signs (+, -)
-	// Group 3: Amount (số + k/m), THÊM [-]? ĐỂ BẮT SỐ ÂM
-	// Group 4: Unit (usd, $, btc, chỉ vàng...)
-	// Group 5: Note (chuỗi còn lại cho đến khi gặp dấu phẩy hoặc xuống dòng)
-	// CẬP NHẬT: Thêm [-]? vào trước [\d.,]+ để bắt được trường hợp số âm (ví dụ: -50k)
-	pattern := `(?i)(?:(thu|chi|tk|tiết\s?kiệm|tiet\s?kiem)|([+\-]))\s*([-]?[\d.,]+[km]?)\s*(usd|\$|btc|bitcoin|chỉ\s?vàng)?\s*([^,\n]*)`
-	re := regexp.MustCompile(pattern)
-
-	// FindAllStringSubmatch tìm tất cả các vị trí khớp trong chuỗi
-	matches := re.FindAllStringSubmatch(text, -1)
-
-	for _, match := range matches {
-		// match[0] là toàn bộ chuỗi khớp
-		kwStr := match[1]
-		signStr := match[2]
-		amountStr := match[3]
-		unitStr := match[4]
-		noteStr := match[5]
-
-		// --- 1. Xác định Type ---
-		var transType string
-		kwLower := strings.ToLower(kwStr)
-		if kwLower != "" {
-			if strings.Contains(kwLower, "tk") || strings.Contains(kwLower, "tiết kiệm") {
-				transType = "tiet_kiem"
-			} else {
-				transType = kwLower // thu, chi
-			}
+// ParseTransactionTextAt như ParseTransactionText, "hôm qua", "15/3"... tính theo now (đã ở múi giờ của user)
+func ParseTransactionTextAt(text string, now time.Time) ([]model.TransactionCreate, error) {
+	var results []model.TransactionCreate
+	var rejected []ParsedSegment
+	for _, seg := range ParseTransactionSegmentsAt(text, now) {
+		if seg.Tx != nil {
+			results = append(results, *seg.Tx)
 		} else {
-			// Dùng dấu +/-
-			if signStr == "+" {
-				transType = "thu"
-			} else {
-				transType = "chi"
-			}
-		}
-
-		// --- 2. Xử lý Amount ---
-		multiplier := 1.0
-		amountClean := strings.ToLower(strings.TrimSpace(amountStr))
-
-		// Xử lý suffix k, m
-		if strings.HasSuffix(amountClean, "k") {
-			multiplier = 1000
-			amountClean = amountClean[:len(amountClean)-1]
-		} else if strings.HasSuffix(amountClean, "m") {
-			multiplier = 1000000
-			amountClean = amountClean[:len(amountClean)-1]
-		}
-
-		// Thay thế dấu phẩy bằng dấu chấm để parse float
-		amountClean = strings.ReplaceAll(amountClean, ",", ".")
-		val, err := strconv.ParseFloat(amountClean, 64)
-		if err != nil {
-			continue
+			rejected = append(rejected, seg)
 		}
-		val = val * multiplier
+	}
+	if len(rejected) > 0 {
+		return results, &ParseError{Segments: rejected}
+	}
+	return results, nil
+}
+
+// transactionRe Regex pattern:
+// Group 1: Keywords (thu, chi, tk, tiết kiệm, rút, bán, chuyển...)
+// Group 2: Signs (+, -)
+// Group 3: Phần còn lại cho đến khi gặp dấu phẩy hoặc xuống dòng, bắt đầu bằng số tiền
+// (số, có thể âm: "-50k", chữ: "năm trăm nghìn", hoặc biểu thức: "(50k+30k)/2"). Số tiền, đơn vị và ghi chú
+// được tách tiếp bằng evalAmount và unitRe vì số tiền có thể gồm nhiều từ ("2 triệu rưỡi")
+var transactionRe = regexp.MustCompile(`(?i)(?:(thu|chi|(?:rút|rut)(?:\s?(?:tk|tiết\s?kiệm|tiet\s?kiem))?|bán|ban|tk|tiết\s?kiệm|tiet\s?kiem|chuyển|chuyen)|([+\-]))\s*((?:[-]?[\d.,]+|\(|` + amountWordPattern() + `)[^,\n]*)`)
+
+// unitReCache unitRe đã dựng theo phiên bản registry tài sản, dựng lại khi có tài sản mới (RegisterAsset)
+var unitReCache struct {
+	sync.Mutex
+	version int
+	re      *regexp.Regexp
+}
+
+// unitRe đơn vị ngay sau số tiền: usd, $, btc, chỉ vàng, eur... (alias của các tài sản trong registry),
+// hoặc đơn vị + kim loại: lượng vàng, 5g vàng 9999, kg bạc, oz gold
+func unitRe() *regexp.Regexp {
+	version := assetRegistryVersion()
+	unitReCache.Lock()
+	defer unitReCache.Unlock()
+	if unitReCache.re == nil || unitReCache.version != version {
+		unitReCache.re = regexp.MustCompile(`(?i)^\s*(` + metalUnitPattern() + `|` + assetAliasPattern() + `)`)
+		unitReCache.version = version
+	}
+	return unitReCache.re
+}
+
+// ParseTransactionSegments đọc tin nhắn thành từng đoạn theo thứ tự xuất hiện, mỗi đoạn là
+// một giao dịch hợp lệ hoặc kèm lý do bị từ chối. Phần chữ không khớp cú pháp nào
+// (VD: "ăn sáng 30k" thiếu "chi") cũng thành đoạn ReasonUnrecognized.
+// Ngày giờ tương đối tính theo giờ Việt Nam như ParseTransactionText
+func ParseTransactionSegments(text string) []ParsedSegment {
+	return ParseTransactionSegmentsAt(text, time.Now().In(model.DefaultUserSettings("").Location()))
+}
+
+// ParseTransactionSegmentsAt như ParseTransactionSegments, ngày giờ tương đối tính theo now.
+// Tin nhắn được chuẩn hóa NFC trước (xem NormalizeText), vị trí các đoạn tính trên tin nhắn đã chuẩn hóa
+func ParseTransactionSegmentsAt(text string, now time.Time) []ParsedSegment {
+	text = NormalizeText(text)
+	var segments []ParsedSegment
+	add := func(start, end int, txType string, tx *model.TransactionCreate, reason ParseReason) {
+		// Bỏ khoảng trắng 2 đầu để vị trí đoạn khớp đúng nội dung
+		for start < end && unicode.IsSpace(rune(text[start])) {
+			start++
+		}
+		for end > start && unicode.IsSpace(rune(text[end-1])) {
+			end--
+		}
+		segments = append(segments, ParsedSegment{
+			Index:  len(segments) + 1,
+			Start:  utf8.RuneCountInString(text[:start]),
+			End:    utf8.RuneCountInString(text[:end]),
+			Text:   text[start:end],
+			Type:   txType,
+			Tx:     tx,
+			Reason: reason,
+		})
+	}
+
+	// FindAllStringSubmatchIndex tìm tất cả các vị trí khớp trong chuỗi
+	prevEnd := 0
+	for _, idx := range transactionRe.FindAllStringSubmatchIndex(text, -1) {
+		for _, gap := range unmatchedParts(text, prevEnd, idx[0]) {
+			add(gap[0], gap[1], "", nil, ReasonUnrecognized)
+		}
+		prevEnd = idx[1]
 
-		// Check số âm hoặc bằng 0 -> Bỏ qua (Đây là chỗ sẽ fix được test case)
-		if val <= 0 {
+		tx, reason := parseTransactionMatch(text, idx, now)
+		if reason != ReasonOK {
+			add(idx[0], idx[1], tx.Type, nil, reason)
 			continue
 		}
+		add(idx[0], idx[1], tx.Type, &tx, ReasonOK)
+	}
+	for _, gap := range unmatchedParts(text, prevEnd, len(text)) {
+		add(gap[0], gap[1], "", nil, ReasonUnrecognized)
+	}
+	return segments
+}
+
+// unmatchedParts tách đoạn text[start:end] không khớp cú pháp theo dấu phẩy/xuống dòng,
+// bỏ các phần không có chữ hoặc số. Dấu phẩy giữa 2 chữ số là dấu thập phân, không tách
+func unmatchedParts(text string, start, end int) [][2]int {
+	var parts [][2]int
+	flush := func(from, to int) {
+		if strings.IndexFunc(text[from:to], func(r rune) bool { return unicode.IsLetter(r) || unicode.IsDigit(r) }) >= 0 {
+			parts = append(parts, [2]int{from, to})
+		}
+	}
+	from := start
+	for i := start; i < end; i++ {
+		sep := text[i] == '\n' ||
+			(text[i] == ',' && !(i > start && i+1 < end && isDigit(text[i-1]) && isDigit(text[i+1])))
+		if sep {
+			flush(from, i)
+			from = i + 1
+		}
+	}
+	flush(from, end)
+	return parts
+}
+
+func isDigit(b byte) bool { return b >= '0' && b <= '9' }
 
-		// --- 3. Xác định Currency ---
-		currency := "VND"
-		u := strings.ToLower(unitStr)
-		if u == "usd" || u == "$" {
-			currency = "USD"
-		} else if u == "btc" || u == "bitcoin" {
-			currency = "BTC"
-		} else if strings.Contains(u, "vàng") {
-			currency = "GOLD"
+// parseTransactionMatch dựng giao dịch từ một vị trí khớp transactionRe.
+// Nếu bị từ chối thì trả về lý do, giao dịch chỉ có Type
+func parseTransactionMatch(text string, idx []int, now time.Time) (model.TransactionCreate, ParseReason) {
+	group := func(i int) string {
+		if idx[2*i] < 0 {
+			return ""
 		}
+		return text[idx[2*i]:idx[2*i+1]]
+	}
+	kwStr := group(1)
+	signStr := group(2)
+	rest := group(3)
 
-		// --- 4. Xử lý Note và Validate ---
-		finalNote := strings.TrimSpace(noteStr)
+	// Số tiền (hoặc biểu thức "3x25k") ở đầu, đơn vị (nếu có) ngay sau, phần còn lại là ghi chú
+	val, n, expr, amountOK := evalAmount(rest)
+	rest = rest[n:]
+	unitStr := ""
+	if m := unitRe().FindStringSubmatchIndex(rest); m != nil {
+		// Alias dính liền chữ phía sau là một phần của ghi chú, không phải đơn vị ("chi 50k eurovision")
+		if next, _ := utf8.DecodeRuneInString(rest[m[1]:]); !unicode.IsLetter(next) && !unicode.IsDigit(next) {
+			unitStr = rest[m[2]:m[3]]
+			rest = rest[m[1]:]
+		}
+	}
+	noteStr := strings.TrimSpace(rest)
+	if after, ok := strings.CutPrefix(noteStr, "- "); ok {
+		noteStr = strings.TrimSpace(after) // Gạch ngăn số tiền với ghi chú: "chi 30k - 1 ly cafe"
+	}
 
-		// Rule 1: Tiết kiệm KHÔNG được có note
-		if transType == "tiet_kiem" {
-			if finalNote != "" {
-				continue
-			}
+	// --- 1. Xác định Type ---
+	var transType string
+	kwFold := FoldText(kwStr) // So khớp không dấu: "tiet kiem", "rút tk", "chuyen"
+	if kwFold != "" {
+		if strings.HasPrefix(kwFold, "rut") || strings.HasPrefix(kwFold, "b") {
+			transType = "rut"
+		} else if strings.Contains(kwFold, "tk") || strings.Contains(kwFold, "tiet kiem") || strings.Contains(kwFold, "tietkiem") {
+			transType = "tiet_kiem"
+		} else if strings.HasPrefix(kwFold, "chuy") {
+			transType = "chuyen"
 		} else {
-			// Rule 2: Thu/Chi BẮT BUỘC có note và CHỈ dùng VND
-			if finalNote == "" {
-				continue
-			}
-			if currency != "VND" {
-				continue
-			}
-		}
-
-		// --- 5. Tự động phân loại (Category) ---
-		category := ""
-		if transType == "chi" {
-			category = CategorizeExpense(finalNote)
-		}
-
-		results = append(results, model.TransactionCreate{
-			Type:     transType,
-			Amount:   val,
-			Note:     finalNote,
-			Currency: currency,
-			Category: category,
-		})
+			transType = kwFold // thu, chi
+		}
+	} else {
+		// Dùng dấu +/-
+		if signStr == "+" {
+			transType = "thu"
+		} else {
+			transType = "chi"
+		}
 	}
 
-	return results, nil
+	// --- 2. Xử lý Amount ---
+	if !amountOK {
+		return model.TransactionCreate{Type: transType}, ReasonInvalidAmount
+	}
+
+	// Check số âm hoặc bằng 0
+	if !val.IsPositive() {
+		return model.TransactionCreate{Type: transType}, ReasonNonPositive
+	}
+
+	// --- 3. Xác định Currency ---
+	currency := "VND"
+	if code, unit, ok := parseMetalUnit(unitStr); ok {
+		// Quy về đơn vị gốc của tài sản (vàng theo chỉ, bạc theo lượng).
+		// "1kg bạc": amount bắt "1k", unit "g bạc" -> 1000g, vẫn đúng bằng 1kg
+		currency = code
+		var err error
+		if val, err = ConvertMetalQuantity(val, unit.Code, code); err != nil || !val.IsPositive() {
+			return model.TransactionCreate{Type: transType}, ReasonNonPositive
+		}
+	} else if unitStr != "" {
+		currency = AssetForAlias(unitStr)
+	}
+	if expr != "" && currency == "VND" {
+		// "100k/3": làm tròn tới đồng
+		if val = val.Round(0); !val.IsPositive() {
+			return model.TransactionCreate{Type: transType}, ReasonNonPositive
+		}
+	}
+
+	// --- 4. Xử lý Note và Validate ---
+	// @tài_khoản ở bất kỳ đâu, lịch lặp ở cuối ("tiền nhà hàng tháng ngày 1") và ngày giờ ("hôm qua 20h") không tính là ghi chú
+	finalNote, accounts := extractAccounts(noteStr)
+	finalNote, recurrence := ExtractRecurrence(finalNote)
+	finalNote, occurredAt, ok := ExtractOccurredAt(finalNote, now)
+	if !ok {
+		return model.TransactionCreate{Type: transType}, ReasonInvalidDate
+	}
+
+	account, toAccount := "", ""
+	if len(accounts) > 0 {
+		account = accounts[0]
+	}
+	if len(accounts) > 1 {
+		toAccount = accounts[1]
+	}
+
+	if transType == "chuyen" {
+		// Rule 0: Chuyển tiền BẮT BUỘC có đúng 2 tài khoản (đi, đến), ghi chú tùy ý
+		if len(accounts) != 2 {
+			return model.TransactionCreate{Type: transType}, ReasonTransferAccounts
+		}
+	} else if len(accounts) > 1 {
+		// Loại khác chỉ được chọn 1 tài khoản
+		return model.TransactionCreate{Type: transType}, ReasonTooManyAccounts
+	} else if transType == "tiet_kiem" || transType == "rut" {
+		// Rule 1: Tiết kiệm, rút/bán KHÔNG được có note
+		if finalNote != "" {
+			return model.TransactionCreate{Type: transType}, ReasonUnexpectedNote
+		}
+	} else {
+		// Rule 2: Thu/Chi BẮT BUỘC có note và CHỈ dùng VND
+		if finalNote == "" {
+			return model.TransactionCreate{Type: transType}, ReasonMissingNote
+		}
+		if currency != "VND" {
+			return model.TransactionCreate{Type: transType}, ReasonForeignCurrency
+		}
+	}
+
+	// --- 5. Tự động phân loại (Category) ---
+	category := ""
+	if transType == "chi" {
+		category = CategorizeExpense(finalNote)
+	}
+
+	return model.TransactionCreate{
+		Type:       transType,
+		Amount:     val,
+		Note:       finalNote,
+		Currency:   currency,
+		Category:   category,
+		Account:    account,
+		ToAccount:  toAccount,
+		Recurrence: recurrence,
+		OccurredAt: occurredAt,
+		AmountExpr: expr,
+	}, ReasonOK
+}
+
+// accountRe bắt tên tài khoản dạng @momo
+var accountRe = regexp.MustCompile(`@([\p{L}\p{N}_.\-]+)`)
+
+// extractAccounts tách các @tài_khoản khỏi ghi chú, trả về ghi chú còn lại và tên tài khoản (chữ thường) theo thứ tự xuất hiện
+func extractAccounts(note string) (string, []string) {
+	var accounts []string
+	for _, m := range accountRe.FindAllStringSubmatch(note, -1) {
+		accounts = append(accounts, strings.ToLower(m[1]))
+	}
+	note = accountRe.ReplaceAllString(note, "")
+	return strings.Join(strings.Fields(note), " "), accounts
 }
@@ -0,0 +1,160 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ResolvePeriod tính khoảng [start, end) của kỳ báo cáo chứa thời điểm now,
+// dịch đi offset kỳ (offset = -1 là kỳ trước). Ranh giới ngày tính theo now.Location(),
+// nên truyền now đã đổi sang múi giờ của user. Tuần bắt đầu từ weekStart.
+// Hỗ trợ: day, week, month, quarter, year
+func ResolvePeriod(period string, offset int, now time.Time, weekStart time.Weekday) (time.Time, time.Time, error) {
+	loc := now.Location()
+	y, m, d := now.Date()
+
+	switch period {
+	case "day":
+		start := time.Date(y, m, d+offset, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 1), nil
+	case "week":
+		// Số ngày đã trôi qua kể từ ngày đầu tuần
+		sinceStart := (int(now.Weekday()) - int(weekStart) + 7) % 7
+		start := time.Date(y, m, d-sinceStart+7*offset, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 0, 7), nil
+	case "month":
+		start := time.Date(y, m+time.Month(offset), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 1, 0), nil
+	case "quarter":
+		firstMonth := time.Month((int(m)-1)/3*3 + 1)
+		start := time.Date(y, firstMonth+time.Month(3*offset), 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(0, 3, 0), nil
+	case "year":
+		start := time.Date(y+offset, 1, 1, 0, 0, 0, 0, loc)
+		return start, start.AddDate(1, 0, 0), nil
+	}
+	return time.Time{}, time.Time{}, fmt.Errorf("kỳ báo cáo không hợp lệ: %q", period)
+}
+
+// ReportQuery yêu cầu báo cáo được bóc ra từ tin nhắn của user.
+// Hoặc dùng Period + Offset, hoặc dùng From/To (YYYY-MM-DD, tính cả 2 đầu)
+type ReportQuery struct {
+	Period string
+	Offset int
+	From   string
+	To     string
+	Label  string // Tiêu đề hiển thị, VD: "Tháng trước"
+}
+
+var (
+	// Khớp trên chuỗi đã FoldText (không dấu)
+	reportYearRe  = regexp.MustCompile(`^(?:nam\s*)?(\d{4})$`)
+	reportMonthRe = regexp.MustCompile(`^thang\s*(\d{1,2})(?:[/\-](\d{4}))?$`)
+	reportRangeRe = regexp.MustCompile(`^(\d{1,2})/(\d{1,2})(?:/(\d{4}))?\s*(?:-|den)\s*(\d{1,2})/(\d{1,2})(?:/(\d{4}))?$`)
+)
+
+// reportPhrases các cụm từ cố định sau "báo cáo"
+var reportPhrases = map[string]ReportQuery{
+	"hôm nay":     {Period: "day", Offset: 0, Label: "Hôm nay"},
+	"hôm qua":     {Period: "day", Offset: -1, Label: "Hôm qua"},
+	"tuần này":    {Period: "week", Offset: 0, Label: "Tuần này"},
+	"tuần trước":  {Period: "week", Offset: -1, Label: "Tuần trước"},
+	"tháng này":   {Period: "month", Offset: 0, Label: "Tháng này"},
+	"tháng trước": {Period: "month", Offset: -1, Label: "Tháng trước"},
+	"quý này":     {Period: "quarter", Offset: 0, Label: "Quý này"},
+	"quý trước":   {Period: "quarter", Offset: -1, Label: "Quý trước"},
+	"năm nay":     {Period: "year", Offset: 0, Label: "Năm nay"},
+	"năm ngoái":   {Period: "year", Offset: -1, Label: "Năm ngoái"},
+	"năm trước":   {Period: "year", Offset: -1, Label: "Năm trước"},
+}
+
+// reportPhrasesFolded reportPhrases theo key không dấu để khớp cả "bao cao thang truoc"
+var reportPhrasesFolded = func() map[string]ReportQuery {
+	m := make(map[string]ReportQuery, len(reportPhrases))
+	for k, q := range reportPhrases {
+		m[FoldText(k)] = q
+	}
+	return m
+}()
+
+// ParseReportQuery bóc kỳ báo cáo từ tin nhắn dạng "báo cáo <kỳ>".
+// Trả về ok=false nếu tin nhắn chỉ là "báo cáo" (dùng báo cáo mặc định tuần + tháng)
+// hoặc phần sau không hiểu được. Không phân biệt dấu ("bao cao thang truoc"). Ví dụ:
+//   - "báo cáo tháng trước" -> month, offset -1
+//   - "báo cáo 2025"        -> 01/01/2025 - 31/12/2025
+//   - "báo cáo 01/03-15/03" -> 01/03 - 15/03 năm nay
+func ParseReportQuery(text string, now time.Time) (ReportQuery, bool) {
+	var rest string
+	if r, ok := MatchCommand(text, "báo cáo"); ok {
+		rest = FoldText(r)
+	} else {
+		folded := FoldText(text)
+		idx := strings.Index(folded, "bao cao")
+		if idx < 0 {
+			return ReportQuery{}, false
+		}
+		rest = strings.TrimSpace(folded[idx+len("bao cao"):])
+	}
+	if rest == "" {
+		return ReportQuery{}, false
+	}
+
+	if q, ok := reportPhrasesFolded[rest]; ok {
+		return q, true
+	}
+
+	if m := reportYearRe.FindStringSubmatch(rest); m != nil {
+		return ReportQuery{From: m[1] + "-01-01", To: m[1] + "-12-31", Label: "Năm " + m[1]}, true
+	}
+
+	if m := reportMonthRe.FindStringSubmatch(rest); m != nil {
+		month, _ := strconv.Atoi(m[1])
+		year := now.Year()
+		if m[2] != "" {
+			year, _ = strconv.Atoi(m[2])
+		}
+		if month < 1 || month > 12 {
+			return ReportQuery{}, false
+		}
+		start := time.Date(year, time.Month(month), 1, 0, 0, 0, 0, now.Location())
+		end := start.AddDate(0, 1, -1)
+		return ReportQuery{
+			From:  start.Format("2006-01-02"),
+			To:    end.Format("2006-01-02"),
+			Label: fmt.Sprintf("Tháng %d/%d", month, year),
+		}, true
+	}
+
+	if m := reportRangeRe.FindStringSubmatch(rest); m != nil {
+		from, ok1 := buildDate(m[1], m[2], m[3], now)
+		to, ok2 := buildDate(m[4], m[5], m[6], now)
+		if !ok1 || !ok2 || to.Before(from) {
+			return ReportQuery{}, false
+		}
+		return ReportQuery{
+			From:  from.Format("2006-01-02"),
+			To:    to.Format("2006-01-02"),
+			Label: from.Format("02/01/2006") + " - " + to.Format("02/01/2006"),
+		}, true
+	}
+
+	return ReportQuery{}, false
+}
+
+// buildDate dựng ngày từ chuỗi dd, mm, yyyy (năm rỗng = năm hiện tại), kiểm tra ngày có thật
+func buildDate(dd, mm, yyyy string, now time.Time) (time.Time, bool) {
+	day, _ := strconv.Atoi(dd)
+	month, _ := strconv.Atoi(mm)
+	year := now.Year()
+	if yyyy != "" {
+		year, _ = strconv.Atoi(yyyy)
+	}
+	t := time.Date(year, time.Month(month), day, 0, 0, 0, 0, now.Location())
+	if t.Day() != day || int(t.Month()) != month {
+		return t, false
+	}
+	return t, true
+}
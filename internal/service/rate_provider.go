@@ -0,0 +1,369 @@
+package service
+
+import (
+	"context"
+	"fmt"
+	"go-finance/internal/model"
+	"math/rand/v2"
+	"os"
+	"slices"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Mã tài sản mà các nguồn giá trả về
+const (
+	AssetUSD = "USD" // VND / 1 USD
+	AssetXAU = "XAU" // USD / 1 oz vàng thế giới
+	AssetXAG = "XAG" // USD / 1 oz bạc thế giới
+	AssetSJC = "SJC" // VND / 1 chỉ vàng SJC
+	AssetBTC = "BTC" // VND / 1 BTC
+
+	AssetEUR  = "EUR"  // VND / 1 EUR
+	AssetJPY  = "JPY"  // VND / 1 JPY
+	AssetCNY  = "CNY"  // VND / 1 CNY
+	AssetETH  = "ETH"  // VND / 1 ETH
+	AssetUSDT = "USDT" // VND / 1 USDT
+)
+
+// RateAssets các mã giá có sẵn nguồn, luôn được lấy mỗi lần cập nhật (cùng với mã giá của tài sản tự định nghĩa)
+var RateAssets = []string{AssetUSD, AssetXAU, AssetXAG, AssetSJC, AssetBTC, AssetEUR, AssetJPY, AssetCNY, AssetETH, AssetUSDT}
+
+// RateProvider một nguồn giá bên ngoài
+type RateProvider interface {
+	// Name tên duy nhất, dùng trong cấu hình RATE_PROVIDERS_<ASSET>
+	Name() string
+	// Assets các tài sản nguồn này có thể trả về
+	Assets() []string
+	// Fetch lấy giá các tài sản lấy được (giá > 0). error khác nil nếu có tài sản không lấy được.
+	// Nguồn có giá mua vào/bán ra riêng trả thêm key BidKey(asset), AskKey(asset)
+	Fetch(ctx context.Context) (map[string]float64, error)
+}
+
+// BidKey key giá mua vào của asset trong kết quả RateProvider.Fetch
+func BidKey(asset string) string { return asset + "/bid" }
+
+// AskKey key giá bán ra của asset trong kết quả RateProvider.Fetch
+func AskKey(asset string) string { return asset + "/ask" }
+
+// Quote giá của một tài sản và nguồn đã trả về giá đó. Bid, Ask = 0 nếu nguồn không có giá mua/bán riêng
+type Quote struct {
+	Price  float64
+	Bid    float64
+	Ask    float64
+	Source string
+}
+
+// defaultRateChains thứ tự nguồn giá mặc định cho từng tài sản, nguồn sau chỉ được gọi khi nguồn trước lỗi
+var defaultRateChains = map[string][]string{
+	AssetUSD: {"erapi"},
+	AssetXAU: {"goldapi"},
+	AssetXAG: {"goldapi"},
+	AssetSJC: {"vangtoday"},
+	AssetBTC: {"coingecko", "coinbase"},
+
+	AssetEUR:  {"erapi"},
+	AssetJPY:  {"erapi"},
+	AssetCNY:  {"erapi"},
+	AssetETH:  {"coingecko"},
+	AssetUSDT: {"coingecko"},
+}
+
+// FetchPolicy cách gọi lại và ngắt nguồn giá bị lỗi
+type FetchPolicy struct {
+	Retries     int           // Số lần gọi lại khi nguồn không trả được giá nào (ngoài lần đầu)
+	BaseBackoff time.Duration // Chờ trước lần gọi lại đầu tiên, gấp đôi sau mỗi lần (kèm jitter)
+	MaxBackoff  time.Duration
+
+	// Sau BreakerThreshold lần cập nhật liên tiếp lỗi, bỏ qua nguồn trong BreakerCooldown
+	BreakerThreshold int
+	BreakerCooldown  time.Duration
+}
+
+// DefaultFetchPolicy: gọi lại 2 lần (0.5s, 1s), ngắt nguồn 30 phút sau 3 lần cập nhật lỗi liên tiếp
+var DefaultFetchPolicy = FetchPolicy{
+	Retries:          2,
+	BaseBackoff:      500 * time.Millisecond,
+	MaxBackoff:       4 * time.Second,
+	BreakerThreshold: 3,
+	BreakerCooldown:  30 * time.Minute,
+}
+
+// backoff thời gian chờ trước lần gọi lại thứ attempt+1: BaseBackoff * 2^attempt,
+// tối đa MaxBackoff, lấy ngẫu nhiên trong [d/2, d] để các nguồn không gọi lại cùng lúc
+func (p FetchPolicy) backoff(attempt int) time.Duration {
+	d := p.BaseBackoff << attempt
+	if d <= 0 || d > p.MaxBackoff {
+		d = p.MaxBackoff
+	}
+	if d <= 0 {
+		return 0
+	}
+	return d/2 + rand.N(d/2+1)
+}
+
+// RateRegistry các nguồn giá và thứ tự dự phòng theo từng tài sản, kèm tình trạng của từng nguồn
+type RateRegistry struct {
+	providers map[string]RateProvider
+	chains    map[string][]string
+	policy    FetchPolicy
+
+	mu     sync.RWMutex
+	health map[string]*model.ProviderHealth
+}
+
+// NewRateRegistry tạo registry, báo lỗi nếu chain dùng nguồn không tồn tại hoặc không hỗ trợ tài sản đó
+func NewRateRegistry(providers []RateProvider, chains map[string][]string) (*RateRegistry, error) {
+	r := &RateRegistry{
+		providers: make(map[string]RateProvider),
+		chains:    make(map[string][]string),
+		policy:    DefaultFetchPolicy,
+		health:    make(map[string]*model.ProviderHealth),
+	}
+	for _, p := range providers {
+		if _, dup := r.providers[p.Name()]; dup {
+			return nil, fmt.Errorf("duplicate rate provider %q", p.Name())
+		}
+		r.providers[p.Name()] = p
+		r.health[p.Name()] = &model.ProviderHealth{Name: p.Name(), Assets: p.Assets(), Status: model.ProviderUnknown}
+	}
+	for asset, names := range chains {
+		if len(names) == 0 {
+			continue
+		}
+		for _, name := range names {
+			p, ok := r.providers[name]
+			if !ok {
+				return nil, fmt.Errorf("unknown rate provider %q for %s", name, asset)
+			}
+			if !supportsAsset(p, asset) {
+				return nil, fmt.Errorf("rate provider %q does not support %s", name, asset)
+			}
+		}
+		r.chains[asset] = append([]string(nil), names...)
+	}
+	return r, nil
+}
+
+// NewRateRegistryFromEnv tạo registry với các nguồn có sẵn. Cấu hình qua env:
+//   - RATE_PROVIDERS_<ASSET>=coingecko,coinbase  thứ tự nguồn cho mã giá (USD, XAU, SJC, BTC, EUR, ETH...).
+//     Bắt buộc với mã giá mới của tài sản tự định nghĩa (CUSTOM_ASSETS), VD: RATE_PROVIDERS_GBP=erapi
+//   - RATE_PROVIDER_<NAME>_URL=http://...         đổi địa chỉ của nguồn (proxy, server giả khi test)
+func NewRateRegistryFromEnv() (*RateRegistry, error) {
+	var providers []RateProvider
+	for _, name := range RateProviderNames() {
+		p, err := NewRateProvider(name, os.Getenv("RATE_PROVIDER_"+strings.ToUpper(name)+"_URL"))
+		if err != nil {
+			return nil, err
+		}
+		providers = append(providers, p)
+	}
+
+	chains := make(map[string][]string)
+	for _, asset := range assetRateCodes() {
+		names, ok := defaultRateChains[asset]
+		chains[asset] = names
+		if v := os.Getenv("RATE_PROVIDERS_" + asset); v != "" {
+			chains[asset] = nil
+			for _, name := range strings.Split(v, ",") {
+				if name = strings.ToLower(strings.TrimSpace(name)); name != "" {
+					chains[asset] = append(chains[asset], name)
+				}
+			}
+		} else if !ok {
+			return nil, fmt.Errorf("no rate providers for %s, set RATE_PROVIDERS_%s", asset, asset)
+		}
+	}
+	return NewRateRegistry(providers, chains)
+}
+
+func supportsAsset(p RateProvider, asset string) bool {
+	for _, a := range p.Assets() {
+		if a == asset {
+			return true
+		}
+	}
+	return false
+}
+
+// SetPolicy đổi cách gọi lại/ngắt nguồn, gọi trước khi bắt đầu Fetch
+func (r *RateRegistry) SetPolicy(p FetchPolicy) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.policy = p
+}
+
+// Fetch lấy giá cho mọi tài sản có cấu hình. Các nguồn đầu chain được gọi song song,
+// tài sản nào lỗi thì gọi tiếp (song song) nguồn kế tiếp trong chain, cho tới khi hết nguồn.
+// Mỗi nguồn chỉ được gọi tối đa 1 lần (kể cả gọi lại) dù phục vụ nhiều tài sản, và dừng khi ctx hết hạn.
+// Trả về giá đã lấy được, error liệt kê tài sản mà mọi nguồn đều lỗi
+func (r *RateRegistry) Fetch(ctx context.Context) (map[string]Quote, error) {
+	results := make(map[string]map[string]float64) // Nguồn đã gọi -> giá trả về
+	quotes := make(map[string]Quote)
+	assets := r.assets()
+
+	for {
+		// Mỗi tài sản chưa có giá chọn nguồn kế tiếp chưa gọi trong chain
+		var batch []string
+		picked := make(map[string]bool)
+		for _, asset := range assets {
+			if _, ok := quotes[asset]; ok {
+				continue
+			}
+			for _, name := range r.chains[asset] {
+				if _, called := results[name]; called {
+					continue
+				}
+				if !picked[name] {
+					picked[name] = true
+					batch = append(batch, name)
+				}
+				break
+			}
+		}
+		if len(batch) == 0 {
+			break
+		}
+
+		var wg sync.WaitGroup
+		var mu sync.Mutex
+		for _, name := range batch {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				res := r.call(ctx, name)
+				mu.Lock()
+				results[name] = res
+				mu.Unlock()
+			}()
+		}
+		wg.Wait()
+
+		// Lấy giá theo đúng thứ tự ưu tiên, dừng ở nguồn chưa tới lượt gọi
+		for _, asset := range assets {
+			if _, ok := quotes[asset]; ok {
+				continue
+			}
+			for _, name := range r.chains[asset] {
+				res, called := results[name]
+				if !called {
+					break
+				}
+				if v := res[asset]; v > 0 {
+					quotes[asset] = Quote{Price: v, Bid: res[BidKey(asset)], Ask: res[AskKey(asset)], Source: name}
+					break
+				}
+			}
+		}
+	}
+
+	var failed []string
+	for _, asset := range assets {
+		if _, ok := quotes[asset]; !ok {
+			failed = append(failed, asset)
+		}
+	}
+	if len(failed) > 0 {
+		return quotes, fmt.Errorf("no rate provider succeeded for %s", strings.Join(failed, ", "))
+	}
+	return quotes, nil
+}
+
+// call gọi một nguồn, gọi lại với backoff nếu không có giá nào. Bỏ qua nếu nguồn đang bị ngắt
+func (r *RateRegistry) call(ctx context.Context, name string) map[string]float64 {
+	r.mu.RLock()
+	policy := r.policy
+	h := r.health[name]
+	open := h.CircuitOpenUntil != nil && time.Now().Before(*h.CircuitOpenUntil)
+	r.mu.RUnlock()
+	if open {
+		return nil
+	}
+
+	var res map[string]float64
+	var err error
+	for attempt := 0; ; attempt++ {
+		res, err = r.providers[name].Fetch(ctx)
+		if r.servesAny(name, res) || attempt >= policy.Retries || ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case <-time.After(policy.backoff(attempt)):
+		}
+	}
+	gotPrices := r.servesAny(name, res)
+	if err == nil && !gotPrices {
+		err = fmt.Errorf("no prices returned")
+	}
+	r.record(name, err, gotPrices)
+	return res
+}
+
+// servesAny res có giá (> 0) của ít nhất 1 tài sản lấy từ nguồn name theo chain
+func (r *RateRegistry) servesAny(name string, res map[string]float64) bool {
+	for asset, names := range r.chains {
+		if res[asset] > 0 && slices.Contains(names, name) {
+			return true
+		}
+	}
+	return false
+}
+
+// assets các tài sản có chain, theo thứ tự cố định
+func (r *RateRegistry) assets() []string {
+	assets := make([]string, 0, len(r.chains))
+	for a := range r.chains {
+		assets = append(assets, a)
+	}
+	sort.Strings(assets)
+	return assets
+}
+
+// record cập nhật tình trạng nguồn sau mỗi lần gọi. gotPrices = có giá của ít nhất 1 tài sản cần lấy
+// (lỗi một phần vẫn báo failing nhưng không tính vào ngắt nguồn)
+func (r *RateRegistry) record(name string, err error, gotPrices bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	h := r.health[name]
+	now := time.Now()
+	if gotPrices {
+		h.LastSuccessAt = &now
+		h.ConsecutiveFailures = 0
+		h.CircuitOpenUntil = nil
+	} else {
+		h.ConsecutiveFailures++
+	}
+	if err == nil {
+		h.Status = model.ProviderOK
+		return
+	}
+	h.Status = model.ProviderFailing
+	h.LastErrorAt = &now
+	h.LastError = err.Error()
+
+	if !gotPrices && r.policy.BreakerThreshold > 0 && h.ConsecutiveFailures >= r.policy.BreakerThreshold {
+		until := now.Add(r.policy.BreakerCooldown)
+		h.CircuitOpenUntil = &until
+		h.Status = model.ProviderCircuitOpen
+	}
+}
+
+// Status cấu hình chain và tình trạng từng nguồn (theo tên)
+func (r *RateRegistry) Status() model.RateProvidersStatus {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	st := model.RateProvidersStatus{Chains: make(map[string][]string), Providers: []model.ProviderHealth{}}
+	for asset, names := range r.chains {
+		st.Chains[asset] = append([]string(nil), names...)
+	}
+	for _, h := range r.health {
+		st.Providers = append(st.Providers, *h)
+	}
+	sort.Slice(st.Providers, func(i, j int) bool { return st.Providers[i].Name < st.Providers[j].Name })
+	return st
+}
@@ -0,0 +1,223 @@
+package service
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// rateHTTPClient dùng chung cho các nguồn giá, mỗi request còn bị giới hạn bởi ctx
+var rateHTTPClient = &http.Client{Timeout: 5 * time.Second}
+
+// builtinProviders các nguồn giá có sẵn: tên -> (URL mặc định, hàm tạo)
+var builtinProviders = map[string]struct {
+	url string
+	new func(url string) RateProvider
+}{
+	"erapi":     {"https://open.er-api.com/v6/latest/USD", func(u string) RateProvider { return erAPIProvider{url: u} }},
+	"goldapi":   {"https://api.gold-api.com/price", func(u string) RateProvider { return goldAPIProvider{baseURL: u} }},
+	"vangtoday": {"https://www.vang.today/api/prices?type=SJL1L10", func(u string) RateProvider { return vangTodayProvider{url: u} }},
+	"coingecko": {"https://api.coingecko.com/api/v3/simple/price?ids=bitcoin,ethereum,tether&vs_currencies=vnd", func(u string) RateProvider { return coinGeckoProvider{url: u} }},
+	"coinbase":  {"https://api.coinbase.com/v2/prices/BTC-VND/spot", func(u string) RateProvider { return coinbaseProvider{url: u} }},
+}
+
+// RateProviderNames tên các nguồn giá có sẵn
+func RateProviderNames() []string {
+	names := make([]string, 0, len(builtinProviders))
+	for name := range builtinProviders {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// NewRateProvider tạo nguồn giá có sẵn theo tên. url rỗng thì dùng địa chỉ mặc định
+func NewRateProvider(name, url string) (RateProvider, error) {
+	b, ok := builtinProviders[name]
+	if !ok {
+		return nil, fmt.Errorf("unknown rate provider %q", name)
+	}
+	if url == "" {
+		url = b.url
+	}
+	return b.new(strings.TrimRight(url, "/")), nil
+}
+
+// getJSON gọi GET url và decode JSON vào out, lỗi nếu status khác 2xx
+func getJSON(ctx context.Context, url string, out interface{}) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return err
+	}
+	// CoinGecko rất hay chặn request từ Cloud Server, nên cần thêm User-Agent giả lập trình duyệt
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64)")
+
+	resp, err := rateHTTPClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("GET %s: status %d", url, resp.StatusCode)
+	}
+	return json.NewDecoder(resp.Body).Decode(out)
+}
+
+// positive trả lỗi nếu giá không hợp lệ (nguồn trả về 0 khi lỗi)
+func positive(asset string, v float64) (map[string]float64, error) {
+	if v <= 0 {
+		return nil, fmt.Errorf("invalid %s price %v", asset, v)
+	}
+	return map[string]float64{asset: v}, nil
+}
+
+// erAPICurrencies các ngoại tệ erapi quy ra VND (dùng được cho tài sản tự định nghĩa, VD: GBP)
+var erAPICurrencies = []string{AssetUSD, AssetEUR, AssetJPY, AssetCNY, "GBP", "KRW", "AUD", "SGD", "THB"}
+
+// erAPIProvider tỷ giá ngoại tệ/VND từ open.er-api.com (bảng tỷ giá theo USD).
+// API chỉ có tỷ giá tham chiếu, không có giá mua/bán riêng nên không trả Bid/Ask
+type erAPIProvider struct{ url string }
+
+func (p erAPIProvider) Name() string     { return "erapi" }
+func (p erAPIProvider) Assets() []string { return erAPICurrencies }
+
+func (p erAPIProvider) Fetch(ctx context.Context) (map[string]float64, error) {
+	var d struct {
+		Rates map[string]float64 `json:"rates"`
+	}
+	if err := getJSON(ctx, p.url, &d); err != nil {
+		return nil, err
+	}
+	usdVND := d.Rates["VND"]
+	if usdVND <= 0 {
+		return nil, fmt.Errorf("invalid VND rate %v", usdVND)
+	}
+	prices := make(map[string]float64)
+	var missing []string
+	for _, code := range erAPICurrencies {
+		if perUSD := d.Rates[code]; perUSD > 0 {
+			prices[code] = usdVND / perUSD // 1 USD = perUSD code = usdVND VND
+		} else {
+			missing = append(missing, code)
+		}
+	}
+	if len(missing) > 0 {
+		return prices, fmt.Errorf("missing rates: %s", strings.Join(missing, ", "))
+	}
+	return prices, nil
+}
+
+// goldAPIProvider giá vàng, bạc thế giới (USD/oz) từ gold-api.com, chỉ có giá tham chiếu (không có Bid/Ask)
+type goldAPIProvider struct{ baseURL string }
+
+func (p goldAPIProvider) Name() string     { return "goldapi" }
+func (p goldAPIProvider) Assets() []string { return []string{AssetXAU, AssetXAG} }
+
+func (p goldAPIProvider) Fetch(ctx context.Context) (map[string]float64, error) {
+	prices := make(map[string]float64)
+	var errs []string
+	for _, asset := range p.Assets() {
+		var d struct {
+			Price float64 `json:"price"`
+		}
+		if err := getJSON(ctx, p.baseURL+"/"+asset, &d); err != nil {
+			errs = append(errs, err.Error())
+			continue
+		}
+		if d.Price <= 0 {
+			errs = append(errs, fmt.Sprintf("invalid %s price %v", asset, d.Price))
+			continue
+		}
+		prices[asset] = d.Price
+	}
+	if len(errs) > 0 {
+		return prices, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return prices, nil
+}
+
+// vangTodayProvider giá mua vào/bán ra vàng SJC (VND/lượng, quy ra chỉ) từ vang.today.
+// Giá tham chiếu SJC là giá bán ra như trước đây
+type vangTodayProvider struct{ url string }
+
+func (p vangTodayProvider) Name() string     { return "vangtoday" }
+func (p vangTodayProvider) Assets() []string { return []string{AssetSJC} }
+
+func (p vangTodayProvider) Fetch(ctx context.Context) (map[string]float64, error) {
+	var d struct {
+		Buy  float64 `json:"buy"`
+		Sell float64 `json:"sell"`
+	}
+	if err := getJSON(ctx, p.url, &d); err != nil {
+		return nil, err
+	}
+	prices, err := positive(AssetSJC, ConvertMetalPrice(d.Sell, "luong", "chi"))
+	if err != nil {
+		return nil, err
+	}
+	prices[AskKey(AssetSJC)] = prices[AssetSJC]
+	if d.Buy > 0 {
+		prices[BidKey(AssetSJC)] = ConvertMetalPrice(d.Buy, "luong", "chi")
+	}
+	return prices, nil
+}
+
+// coinGeckoIDs id coin trên CoinGecko -> mã giá
+var coinGeckoIDs = map[string]string{"bitcoin": AssetBTC, "ethereum": AssetETH, "tether": AssetUSDT}
+
+// coinGeckoProvider giá BTC, ETH, USDT theo VND từ CoinGecko (hay chặn IP cloud).
+// simple/price chỉ có giá tham chiếu, không có Bid/Ask
+type coinGeckoProvider struct{ url string }
+
+func (p coinGeckoProvider) Name() string     { return "coingecko" }
+func (p coinGeckoProvider) Assets() []string { return []string{AssetBTC, AssetETH, AssetUSDT} }
+
+func (p coinGeckoProvider) Fetch(ctx context.Context) (map[string]float64, error) {
+	var d map[string]struct {
+		VND float64 `json:"vnd"`
+	}
+	if err := getJSON(ctx, p.url, &d); err != nil {
+		return nil, err
+	}
+	prices := make(map[string]float64)
+	var errs []string
+	for id, code := range coinGeckoIDs {
+		if v := d[id].VND; v > 0 {
+			prices[code] = v
+		} else {
+			errs = append(errs, fmt.Sprintf("invalid %s price %v", code, v))
+		}
+	}
+	if len(errs) > 0 {
+		sort.Strings(errs)
+		return prices, fmt.Errorf("%s", strings.Join(errs, "; "))
+	}
+	return prices, nil
+}
+
+// coinbaseProvider giá BTC/VND từ Coinbase, dự phòng cho CoinGecko. Giá spot không có Bid/Ask
+type coinbaseProvider struct{ url string }
+
+func (p coinbaseProvider) Name() string     { return "coinbase" }
+func (p coinbaseProvider) Assets() []string { return []string{AssetBTC} }
+
+func (p coinbaseProvider) Fetch(ctx context.Context) (map[string]float64, error) {
+	var d struct {
+		Data struct {
+			Amount string `json:"amount"` // Coinbase trả giá dạng chuỗi
+		} `json:"data"`
+	}
+	if err := getJSON(ctx, p.url, &d); err != nil {
+		return nil, err
+	}
+	v, err := strconv.ParseFloat(d.Data.Amount, 64)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BTC price %q", d.Data.Amount)
+	}
+	return positive(AssetBTC, v)
+}
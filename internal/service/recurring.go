@@ -0,0 +1,137 @@
+package service
+
+import (
+	"context"
+	"go-finance/internal/model"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// NextOccurrence thời điểm chạy kế tiếp của quy tắc, sau after (không tính chính after).
+// Giao dịch định kỳ được tạo lúc 0h theo múi giờ của quy tắc
+func NextOccurrence(r model.RecurringRule, after time.Time) time.Time {
+	local := after.In(r.Location())
+	y, m, d := local.Date()
+	today := time.Date(y, m, d, 0, 0, 0, 0, local.Location())
+
+	var next time.Time
+	switch r.Frequency {
+	case "weekly":
+		// ISO: 1 = thứ 2 ... 7 = chủ nhật; time.Weekday: 0 = chủ nhật
+		target := time.Weekday(r.Day % 7)
+		next = today.AddDate(0, 0, (int(target)-int(today.Weekday())+7)%7)
+		if !next.After(after) {
+			next = next.AddDate(0, 0, 7)
+		}
+	case "monthly":
+		next = dayOfMonth(y, m, r.Day, local.Location())
+		if !next.After(after) {
+			next = dayOfMonth(y, m+1, r.Day, local.Location())
+		}
+	default: // daily
+		next = today
+		if !next.After(after) {
+			next = today.AddDate(0, 0, 1)
+		}
+	}
+	return next
+}
+
+// dayOfMonth ngày day của tháng m, tháng thiếu ngày (VD: 31/02) thì lấy ngày cuối tháng
+func dayOfMonth(y int, m time.Month, day int, loc *time.Location) time.Time {
+	first := time.Date(y, m, 1, 0, 0, 0, 0, loc)
+	last := first.AddDate(0, 1, -1).Day()
+	if day > last {
+		day = last
+	}
+	return first.AddDate(0, 0, day-1)
+}
+
+// recurrenceRe bắt lịch lặp ở cuối ghi chú: "hàng tháng ngày 1", "mỗi tuần thứ 2", "hàng ngày"
+var recurrenceRe = regexp.MustCompile(`(?i)\s*(?:hàng|hang|mỗi|moi)\s+(ngày|ngay|tuần|tuan|tháng|thang)(?:\s+(?:ngày|ngay)\s+(\d{1,2})|\s+(?:thứ|thu)\s*([2-7])|\s+(chủ\s*nhật|cn))?\s*$`)
+
+// ExtractRecurrence tách lịch lặp ở cuối ghi chú. Trả về ghi chú đã bỏ phần lịch
+// và nil nếu ghi chú không có lịch lặp. VD: "tiền nhà hàng tháng ngày 1" -> "tiền nhà", monthly ngày 1
+func ExtractRecurrence(note string) (string, *model.Recurrence) {
+	m := recurrenceRe.FindStringSubmatchIndex(note)
+	if m == nil {
+		return note, nil
+	}
+	group := func(i int) string {
+		if m[2*i] < 0 {
+			return ""
+		}
+		return note[m[2*i]:m[2*i+1]]
+	}
+
+	rec := &model.Recurrence{}
+	switch unit := strings.ToLower(group(1)); unit {
+	case "ngày", "ngay":
+		rec.Frequency = "daily"
+		if group(2) != "" || group(3) != "" || group(4) != "" {
+			return note, nil // "hàng ngày ngày 5" không có nghĩa
+		}
+	case "tuần", "tuan":
+		rec.Frequency = "weekly"
+		if group(2) != "" {
+			return note, nil
+		}
+		if d := group(3); d != "" {
+			n, _ := strconv.Atoi(d)
+			rec.Day = n - 1 // thứ 2 -> 1 (ISO)
+		} else if group(4) != "" {
+			rec.Day = 7
+		}
+	default:
+		rec.Frequency = "monthly"
+		if group(3) != "" || group(4) != "" {
+			return note, nil
+		}
+		if d := group(2); d != "" {
+			n, _ := strconv.Atoi(d)
+			if n < 1 || n > 31 {
+				return note, nil
+			}
+			rec.Day = n
+		}
+	}
+	return strings.TrimSpace(note[:m[0]]), rec
+}
+
+// RecurringMaterializer nơi lưu quy tắc định kỳ có thể tạo các giao dịch đến hạn (store.Store)
+type RecurringMaterializer interface {
+	MaterializeDue(now time.Time, next func(model.RecurringRule, time.Time) time.Time) (int, error)
+}
+
+// StartRecurringWorker tạo giao dịch cho các quy tắc định kỳ đến hạn, kiểm tra mỗi phút
+// (Gọi 1 lần duy nhất ở main.go), chạy tới khi ctx bị hủy (tắt server).
+// Store đảm bảo mỗi lần chạy chỉ tạo giao dịch đúng 1 lần, kể cả khi restart hoặc chạy nhiều bản API cùng lúc
+func StartRecurringWorker(ctx context.Context, m RecurringMaterializer) {
+	runRecurring(m)
+
+	ticker := time.NewTicker(1 * time.Minute)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			log.Println("[RECURRING] Dừng tạo giao dịch định kỳ")
+			return
+		case <-ticker.C:
+			runRecurring(m)
+		}
+	}
+}
+
+func runRecurring(m RecurringMaterializer) {
+	n, err := m.MaterializeDue(time.Now(), NextOccurrence)
+	if err != nil {
+		log.Printf("[RECURRING ERROR] Không thể tạo giao dịch định kỳ: %v", err)
+		return
+	}
+	if n > 0 {
+		log.Printf("[RECURRING] Đã tạo %d giao dịch định kỳ", n)
+	}
+}
@@ -0,0 +1,52 @@
+package store
+
+import (
+	"encoding/base64"
+	"errors"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// ErrInvalidCursor được trả về khi cursor phân trang bị sửa hoặc sai định dạng
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// Cursor keyset pagination: vị trí (created_at, id) của bản ghi cuối trang trước.
+// Client chỉ coi nó là chuỗi mờ (opaque), không cần biết bên trong.
+type cursor struct {
+	CreatedAt time.Time
+	ID        int
+}
+
+func encodeCursor(c cursor) string {
+	raw := c.CreatedAt.UTC().Format(time.RFC3339Nano) + "|" + strconv.Itoa(c.ID)
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeCursor(s string) (cursor, error) {
+	raw, err := base64.RawURLEncoding.DecodeString(s)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	ts, idStr, ok := strings.Cut(string(raw), "|")
+	if !ok {
+		return cursor{}, ErrInvalidCursor
+	}
+	createdAt, err := time.Parse(time.RFC3339Nano, ts)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	id, err := strconv.Atoi(idStr)
+	if err != nil {
+		return cursor{}, ErrInvalidCursor
+	}
+	return cursor{CreatedAt: createdAt, ID: id}, nil
+}
+
+// searchTerms tách chuỗi tìm kiếm thành các từ thường, giống parser 'simple' của Postgres
+func searchTerms(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
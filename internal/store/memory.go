@@ -0,0 +1,250 @@
+package store
+
+import (
+	"go-finance/internal/model"
+	"sort"
+	"sync"
+	"time"
+)
+
+// MemoryStore lưu giao dịch trong RAM, dùng cho test và chế độ demo không có DB.
+// Hành vi phải giống hệt PostgresStore (xem bộ test conformance trong tests/).
+type MemoryStore struct {
+	mu       sync.RWMutex
+	nextID   int
+	txs      []model.Transaction
+	settings map[string]model.UserSettings
+	budgets  []model.Budget
+	accounts []model.Account
+
+	recurring   []model.RecurringRule
+	occurrences map[occurrenceKey]bool
+
+	rates []model.RateSnapshot // Theo thứ tự FetchedAt
+
+	alerts        []model.PriceAlert
+	notifications []model.AlertNotification
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		nextID:      1,
+		settings:    make(map[string]model.UserSettings),
+		occurrences: make(map[occurrenceKey]bool),
+	}
+}
+
+func (s *MemoryStore) Create(t model.Transaction) (int, error) {
+	// Giữ cùng quy tắc với PostgresStore.Create
+	t.Category = defaultCategory(t)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	t.ID = s.nextID
+	s.nextID++
+	if t.CreatedAt.IsZero() {
+		t.CreatedAt = time.Now()
+	}
+	s.txs = append(s.txs, t)
+	return t.ID, nil
+}
+
+// readTransaction chuẩn hóa bản ghi khi đọc ra, giống scanTransaction của Postgres
+func readTransaction(t model.Transaction) model.Transaction {
+	if t.Currency == "" {
+		t.Currency = "VND"
+	}
+	return t
+}
+
+// indexOf trả về vị trí giao dịch theo id, -1 nếu không có. Phải giữ lock khi gọi
+func (s *MemoryStore) indexOf(id int) int {
+	for i, t := range s.txs {
+		if t.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MemoryStore) GetByPeriod(userID string, startDate, endDate time.Time) ([]model.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var txs []model.Transaction
+	for _, t := range s.txs {
+		if t.UserID != userID || t.CreatedAt.Before(startDate) {
+			continue
+		}
+		if !endDate.IsZero() && !t.CreatedAt.Before(endDate) {
+			continue
+		}
+		txs = append(txs, readTransaction(t))
+	}
+	return txs, nil
+}
+
+// List lọc và phân trang giống hệt PostgresStore.List
+func (s *MemoryStore) List(f model.TransactionFilter) (model.TransactionPage, error) {
+	page := model.TransactionPage{Items: []model.Transaction{}}
+	limit := pageSize(f.Limit)
+
+	var after *cursor
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return page, err
+		}
+		after = &c
+	}
+	terms := searchTerms(f.Search)
+
+	// before: a đứng trước b theo thứ tự sắp xếp được yêu cầu
+	before := func(a, b cursor) bool {
+		if f.Ascending {
+			return cursorLess(a, b)
+		}
+		return cursorLess(b, a)
+	}
+
+	s.mu.RLock()
+	var matched []model.Transaction
+	for _, t := range s.txs {
+		t = readTransaction(t)
+		if matchesFilter(t, f, terms) && (after == nil || before(*after, cursor{t.CreatedAt, t.ID})) {
+			matched = append(matched, t)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matched, func(i, j int) bool {
+		return before(cursor{matched[i].CreatedAt, matched[i].ID}, cursor{matched[j].CreatedAt, matched[j].ID})
+	})
+
+	if len(matched) > limit {
+		matched = matched[:limit]
+		last := matched[limit-1]
+		page.NextCursor = encodeCursor(cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	page.Items = append(page.Items, matched...)
+	return page, nil
+}
+
+// cursorLess so sánh (created_at, id) giống phép so sánh tuple của Postgres
+func cursorLess(a, b cursor) bool {
+	if a.CreatedAt.Equal(b.CreatedAt) {
+		return a.ID < b.ID
+	}
+	return a.CreatedAt.Before(b.CreatedAt)
+}
+
+// matchesFilter kiểm tra điều kiện lọc (trừ cursor) cho một giao dịch
+func matchesFilter(t model.Transaction, f model.TransactionFilter, terms []string) bool {
+	switch {
+	case t.UserID != f.UserID:
+		return false
+	case !f.From.IsZero() && t.CreatedAt.Before(f.From):
+		return false
+	case !f.To.IsZero() && !t.CreatedAt.Before(f.To):
+		return false
+	case f.Type != "" && t.Type != f.Type:
+		return false
+	case f.Category != "" && t.Category != f.Category:
+		return false
+	case f.Currency != "" && t.Currency != f.Currency:
+		return false
+	case f.MinAmount != nil && t.Amount.LessThan(*f.MinAmount):
+		return false
+	case f.MaxAmount != nil && t.Amount.GreaterThan(*f.MaxAmount):
+		return false
+	}
+
+	if len(terms) > 0 {
+		words := make(map[string]bool)
+		for _, w := range searchTerms(t.Note) {
+			words[w] = true
+		}
+		for _, term := range terms {
+			if !words[term] {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func (s *MemoryStore) GetByID(id int) (model.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := s.indexOf(id)
+	if i < 0 {
+		return model.Transaction{}, ErrNotFound
+	}
+	return readTransaction(s.txs[i]), nil
+}
+
+func (s *MemoryStore) GetLatest(userID string) (model.Transaction, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	// s.txs được append theo thứ tự tạo nên duyệt ngược là gặp bản mới nhất
+	for i := len(s.txs) - 1; i >= 0; i-- {
+		if s.txs[i].UserID == userID {
+			return readTransaction(s.txs[i]), nil
+		}
+	}
+	return model.Transaction{}, ErrNotFound
+}
+
+func (s *MemoryStore) Update(t model.Transaction) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(t.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	cur := &s.txs[i]
+	cur.Type = t.Type
+	cur.Amount = t.Amount
+	cur.Note = t.Note
+	cur.Category = defaultCategory(t)
+	cur.Currency = t.Currency
+	cur.OriginalAmount = t.OriginalAmount
+	cur.AccountID = t.AccountID
+	cur.ToAccountID = t.ToAccountID
+	cur.RateSnapshotID = t.RateSnapshotID
+	cur.AmountExpr = t.AmountExpr
+	return nil
+}
+
+func (s *MemoryStore) Delete(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.indexOf(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.txs = append(s.txs[:i], s.txs[i+1:]...)
+	return nil
+}
+
+// GetAllUserIDs lấy danh sách tất cả user_id duy nhất
+func (s *MemoryStore) GetAllUserIDs() ([]string, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	seen := make(map[string]bool)
+	var userIDs []string
+	for _, t := range s.txs {
+		if seen[t.UserID] {
+			continue
+		}
+		seen[t.UserID] = true
+		userIDs = append(userIDs, t.UserID)
+	}
+	return userIDs, nil
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"go-finance/internal/model"
+	"time"
+)
+
+// accountIndex vị trí tài khoản theo id, -1 nếu không có. Phải giữ lock khi gọi
+func (s *MemoryStore) accountIndex(id int) int {
+	for i, a := range s.accounts {
+		if a.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+// accountNameTaken user đã có tài khoản tên name khác tài khoản exceptID. Phải giữ lock khi gọi
+func (s *MemoryStore) accountNameTaken(userID, name string, exceptID int) bool {
+	for _, a := range s.accounts {
+		if a.UserID == userID && a.Name == name && a.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *MemoryStore) CreateAccount(a model.Account) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.accountNameTaken(a.UserID, a.Name, 0) {
+		return 0, ErrConflict
+	}
+	a.ID = s.nextID
+	s.nextID++
+	a.CreatedAt = time.Now()
+	s.accounts = append(s.accounts, a)
+	return a.ID, nil
+}
+
+func (s *MemoryStore) GetAccount(id int) (model.Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.accountIndex(id); i >= 0 {
+		return s.accounts[i], nil
+	}
+	return model.Account{}, ErrNotFound
+}
+
+func (s *MemoryStore) GetAccountByName(userID, name string) (model.Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, a := range s.accounts {
+		if a.UserID == userID && a.Name == name {
+			return a, nil
+		}
+	}
+	return model.Account{}, ErrNotFound
+}
+
+func (s *MemoryStore) ListAccounts(userID string) ([]model.Account, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var accounts []model.Account
+	for _, a := range s.accounts {
+		if a.UserID == userID {
+			accounts = append(accounts, a)
+		}
+	}
+	return accounts, nil
+}
+
+func (s *MemoryStore) UpdateAccount(a model.Account) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.accountIndex(a.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	if s.accountNameTaken(s.accounts[i].UserID, a.Name, a.ID) {
+		return ErrConflict
+	}
+	s.accounts[i].Name = a.Name
+	s.accounts[i].Currency = a.Currency
+	s.accounts[i].OpeningBalance = a.OpeningBalance
+	return nil
+}
+
+// DeleteAccount giống khóa ngoại của Postgres: không xóa được nếu còn giao dịch gắn với tài khoản
+func (s *MemoryStore) DeleteAccount(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.accountIndex(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	for _, t := range s.txs {
+		if (t.AccountID != nil && *t.AccountID == id) || (t.ToAccountID != nil && *t.ToAccountID == id) {
+			return ErrConflict
+		}
+	}
+	s.accounts = append(s.accounts[:i], s.accounts[i+1:]...)
+	return nil
+}
@@ -0,0 +1,117 @@
+package store
+
+import (
+	"go-finance/internal/model"
+	"time"
+)
+
+func (s *MemoryStore) CreateAlert(a model.PriceAlert) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	a.ID = s.nextID
+	s.nextID++
+	a.CreatedAt = time.Now()
+	s.alerts = append(s.alerts, a)
+	return a.ID, nil
+}
+
+func (s *MemoryStore) alertIndex(id int) int {
+	for i, a := range s.alerts {
+		if a.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MemoryStore) GetAlert(id int) (model.PriceAlert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.alertIndex(id); i >= 0 {
+		return s.alerts[i], nil
+	}
+	return model.PriceAlert{}, ErrNotFound
+}
+
+func (s *MemoryStore) ListAlerts(userID string) ([]model.PriceAlert, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var alerts []model.PriceAlert
+	for _, a := range s.alerts {
+		if a.UserID == userID {
+			alerts = append(alerts, a)
+		}
+	}
+	return alerts, nil
+}
+
+func (s *MemoryStore) DeleteAlert(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.alertIndex(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.alerts = append(s.alerts[:i], s.alerts[i+1:]...)
+	return nil
+}
+
+// CheckAlerts giữ lock ghi trong suốt lượt kiểm tra nên các lần gọi song song không báo trùng
+func (s *MemoryStore) CheckAlerts(check func(model.PriceAlert) (model.PriceAlert, *model.AlertNotification)) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := 0
+	for i := range s.alerts {
+		a := &s.alerts[i]
+		if !a.Active {
+			continue
+		}
+		updated, n := check(*a)
+		a.Active = updated.Active
+		a.BasePrice = updated.BasePrice
+		a.LastPrice = updated.LastPrice
+		a.LastTriggeredAt = updated.LastTriggeredAt
+		if n == nil {
+			continue
+		}
+		note := *n
+		note.ID = s.nextID
+		s.nextID++
+		note.CreatedAt = time.Now()
+		note.SentAt = nil
+		s.notifications = append(s.notifications, note)
+		created++
+	}
+	return created, nil
+}
+
+func (s *MemoryStore) PendingNotifications(limit int) ([]model.AlertNotification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var notes []model.AlertNotification
+	for _, n := range s.notifications {
+		if n.SentAt == nil && len(notes) < pageSize(limit) {
+			notes = append(notes, n)
+		}
+	}
+	return notes, nil
+}
+
+func (s *MemoryStore) MarkNotificationSent(id int, at time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i := range s.notifications {
+		if s.notifications[i].ID == id {
+			s.notifications[i].SentAt = &at
+			return nil
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,86 @@
+package store
+
+import (
+	"go-finance/internal/model"
+	"time"
+)
+
+func (s *MemoryStore) SaveBudget(b model.Budget) (model.Budget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, cur := range s.budgets {
+		if cur.UserID == b.UserID && cur.Category == b.Category && cur.Period == b.Period {
+			s.budgets[i].Limit = b.Limit
+			return s.budgets[i], nil
+		}
+	}
+
+	b.ID = s.nextID
+	s.nextID++
+	b.CreatedAt = time.Now()
+	s.budgets = append(s.budgets, b)
+	return b, nil
+}
+
+func (s *MemoryStore) GetBudget(id int) (model.Budget, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, b := range s.budgets {
+		if b.ID == id {
+			return b, nil
+		}
+	}
+	return model.Budget{}, ErrNotFound
+}
+
+func (s *MemoryStore) ListBudgets(userID string) ([]model.Budget, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var budgets []model.Budget
+	for _, b := range s.budgets {
+		if b.UserID == userID {
+			budgets = append(budgets, b)
+		}
+	}
+	return budgets, nil
+}
+
+func (s *MemoryStore) UpdateBudget(b model.Budget) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	idx := -1
+	for i, cur := range s.budgets {
+		if cur.ID == b.ID {
+			idx = i
+		}
+	}
+	if idx < 0 {
+		return ErrNotFound
+	}
+	for i, cur := range s.budgets {
+		if i != idx && cur.UserID == s.budgets[idx].UserID && cur.Category == b.Category && cur.Period == b.Period {
+			return ErrConflict
+		}
+	}
+	s.budgets[idx].Category = b.Category
+	s.budgets[idx].Period = b.Period
+	s.budgets[idx].Limit = b.Limit
+	return nil
+}
+
+func (s *MemoryStore) DeleteBudget(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for i, b := range s.budgets {
+		if b.ID == id {
+			s.budgets = append(s.budgets[:i], s.budgets[i+1:]...)
+			return nil
+		}
+	}
+	return ErrNotFound
+}
@@ -0,0 +1,59 @@
+package store
+
+import (
+	"go-finance/internal/model"
+	"sort"
+	"time"
+)
+
+func (s *MemoryStore) SaveRates(at time.Time, rates model.ExchangeRates) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	snap := model.RateSnapshot{ID: s.nextID, FetchedAt: at, Rates: rates}
+	s.nextID++
+	// Chèn đúng vị trí để s.rates luôn theo thứ tự thời gian
+	i := sort.Search(len(s.rates), func(i int) bool { return s.rates[i].FetchedAt.After(at) })
+	s.rates = append(s.rates, model.RateSnapshot{})
+	copy(s.rates[i+1:], s.rates[i:])
+	s.rates[i] = snap
+	return snap.ID, nil
+}
+
+func (s *MemoryStore) LatestRates() (model.RateSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if len(s.rates) == 0 {
+		return model.RateSnapshot{}, ErrNotFound
+	}
+	return s.rates[len(s.rates)-1], nil
+}
+
+func (s *MemoryStore) RatesAt(t time.Time) (model.RateSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	i := sort.Search(len(s.rates), func(i int) bool { return s.rates[i].FetchedAt.After(t) })
+	if i == 0 {
+		return model.RateSnapshot{}, ErrNotFound
+	}
+	return s.rates[i-1], nil
+}
+
+func (s *MemoryStore) ListRates(from, to time.Time, limit int) ([]model.RateSnapshot, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var snapshots []model.RateSnapshot
+	for _, r := range s.rates {
+		if r.FetchedAt.Before(from) || (!to.IsZero() && !r.FetchedAt.Before(to)) {
+			continue
+		}
+		if len(snapshots) >= limit {
+			break
+		}
+		snapshots = append(snapshots, r)
+	}
+	return snapshots, nil
+}
@@ -0,0 +1,110 @@
+package store
+
+import (
+	"go-finance/internal/model"
+	"time"
+)
+
+// occurrenceKey một lần chạy của quy tắc định kỳ, giống khóa chính của recurring_occurrences
+type occurrenceKey struct {
+	ruleID     int
+	occurrence int64
+}
+
+func (s *MemoryStore) CreateRecurring(r model.RecurringRule) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	r.ID = s.nextID
+	s.nextID++
+	r.CreatedAt = time.Now()
+	s.recurring = append(s.recurring, r)
+	return r.ID, nil
+}
+
+func (s *MemoryStore) recurringIndex(id int) int {
+	for i, r := range s.recurring {
+		if r.ID == id {
+			return i
+		}
+	}
+	return -1
+}
+
+func (s *MemoryStore) GetRecurring(id int) (model.RecurringRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if i := s.recurringIndex(id); i >= 0 {
+		return s.recurring[i], nil
+	}
+	return model.RecurringRule{}, ErrNotFound
+}
+
+func (s *MemoryStore) ListRecurring(userID string) ([]model.RecurringRule, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var rules []model.RecurringRule
+	for _, r := range s.recurring {
+		if r.UserID == userID {
+			rules = append(rules, r)
+		}
+	}
+	return rules, nil
+}
+
+func (s *MemoryStore) UpdateRecurring(r model.RecurringRule) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.recurringIndex(r.ID)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.recurring[i].Amount = r.Amount
+	s.recurring[i].Note = r.Note
+	s.recurring[i].Paused = r.Paused
+	s.recurring[i].NextRun = r.NextRun
+	return nil
+}
+
+func (s *MemoryStore) DeleteRecurring(id int) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	i := s.recurringIndex(id)
+	if i < 0 {
+		return ErrNotFound
+	}
+	s.recurring = append(s.recurring[:i], s.recurring[i+1:]...)
+	return nil
+}
+
+// MaterializeDue giữ lock ghi trong suốt lượt chạy nên các lần gọi song song không tạo trùng
+func (s *MemoryStore) MaterializeDue(now time.Time, next func(model.RecurringRule, time.Time) time.Time) (int, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	created := 0
+	for i := range s.recurring {
+		r := &s.recurring[i]
+		if r.Paused {
+			continue
+		}
+		for n := 0; n < maxCatchUp && !r.NextRun.After(now); n++ {
+			key := occurrenceKey{r.ID, r.NextRun.UnixNano()}
+			if !s.occurrences[key] {
+				s.occurrences[key] = true
+				t := recurringTransaction(*r)
+				t.Category = defaultCategory(t)
+				t.ID = s.nextID
+				s.nextID++
+				s.txs = append(s.txs, t)
+				created++
+			}
+			r.NextRun = next(*r, r.NextRun)
+		}
+	}
+	return created, nil
+}
@@ -0,0 +1,43 @@
+package store
+
+import "go-finance/internal/model"
+
+func (s *MemoryStore) GetSettings(userID string) (model.UserSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if st, ok := s.settings[userID]; ok {
+		return st, nil
+	}
+	return model.DefaultUserSettings(userID), nil
+}
+
+func (s *MemoryStore) SaveSettings(settings model.UserSettings) error {
+	// Giống các cột DEFAULT của Postgres
+	if settings.CostBasis == "" {
+		settings.CostBasis = model.CostBasisAverage
+	}
+	if settings.GoldUnit == "" {
+		settings.GoldUnit = model.DefaultGoldUnit
+	}
+	if settings.SilverUnit == "" {
+		settings.SilverUnit = model.DefaultSilverUnit
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.settings[settings.UserID] = settings
+	return nil
+}
+
+func (s *MemoryStore) ListSettings() ([]model.UserSettings, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	var list []model.UserSettings
+	for _, st := range s.settings {
+		list = append(list, st)
+	}
+	return list, nil
+}
@@ -0,0 +1,13 @@
+-- Bảng gốc, trước đây được tạo bởi InitSchema.
+-- Giữ IF NOT EXISTS để các deployment cũ (đã có bảng) chuyển sang migration êm.
+CREATE TABLE IF NOT EXISTS transactions (
+	id SERIAL PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	amount FLOAT NOT NULL,
+	note TEXT,
+	category VARCHAR(50),
+	created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+	currency VARCHAR(10) DEFAULT 'VND',
+	original_amount FLOAT DEFAULT 0.0
+);
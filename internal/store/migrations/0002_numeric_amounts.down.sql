@@ -0,0 +1,4 @@
+ALTER TABLE transactions
+	ALTER COLUMN amount TYPE FLOAT USING amount::float8,
+	ALTER COLUMN original_amount TYPE FLOAT USING original_amount::float8,
+	ALTER COLUMN original_amount SET DEFAULT 0.0;
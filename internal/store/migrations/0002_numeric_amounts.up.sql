@@ -0,0 +1,6 @@
+-- Chuyển tiền từ FLOAT sang NUMERIC để không còn sai số làm tròn.
+-- Ép kiểu float8 -> numeric giữ 15 chữ số có nghĩa nên 0.1 được lưu đúng là 0.1.
+ALTER TABLE transactions
+	ALTER COLUMN amount TYPE NUMERIC USING amount::numeric,
+	ALTER COLUMN original_amount TYPE NUMERIC USING original_amount::numeric,
+	ALTER COLUMN original_amount SET DEFAULT 0;
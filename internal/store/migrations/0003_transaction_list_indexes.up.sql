@@ -0,0 +1,7 @@
+-- Index cho GET /transactions: keyset pagination theo (user_id, created_at, id)
+-- và tìm kiếm toàn văn trên note.
+CREATE INDEX IF NOT EXISTS idx_transactions_user_created
+	ON transactions (user_id, created_at, id);
+
+CREATE INDEX IF NOT EXISTS idx_transactions_note_fts
+	ON transactions USING GIN (to_tsvector('simple', COALESCE(note, '')));
@@ -0,0 +1,5 @@
+ALTER TABLE transactions
+	ALTER COLUMN created_at TYPE TIMESTAMP USING created_at AT TIME ZONE 'UTC',
+	ALTER COLUMN created_at SET DEFAULT CURRENT_TIMESTAMP;
+
+DROP TABLE IF EXISTS user_settings;
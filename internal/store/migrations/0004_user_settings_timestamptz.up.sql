@@ -0,0 +1,12 @@
+-- Cài đặt riêng của user: múi giờ IANA và ngày đầu tuần (0 = CN, 1 = T2)
+CREATE TABLE IF NOT EXISTS user_settings (
+	user_id VARCHAR(50) PRIMARY KEY,
+	timezone TEXT NOT NULL DEFAULT 'Asia/Ho_Chi_Minh',
+	week_start SMALLINT NOT NULL DEFAULT 1 CHECK (week_start BETWEEN 0 AND 6),
+	updated_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+-- created_at trước đây là TIMESTAMP không múi giờ, được ghi theo giờ của server API (UTC).
+ALTER TABLE transactions
+	ALTER COLUMN created_at TYPE TIMESTAMPTZ USING created_at AT TIME ZONE 'UTC',
+	ALTER COLUMN created_at SET DEFAULT now();
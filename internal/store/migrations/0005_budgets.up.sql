@@ -0,0 +1,10 @@
+-- Ngân sách chi tiêu theo danh mục, mỗi user 1 hạn mức cho mỗi (category, period)
+CREATE TABLE IF NOT EXISTS budgets (
+	id SERIAL PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL,
+	category VARCHAR(50) NOT NULL,
+	period VARCHAR(10) NOT NULL CHECK (period IN ('week', 'month')),
+	amount_limit NUMERIC NOT NULL CHECK (amount_limit > 0),
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (user_id, category, period)
+);
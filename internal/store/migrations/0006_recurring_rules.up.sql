@@ -0,0 +1,26 @@
+-- Quy tắc giao dịch định kỳ (tiền nhà, lương...). Số tiền luôn là VND
+CREATE TABLE IF NOT EXISTS recurring_rules (
+	id SERIAL PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL,
+	type VARCHAR(20) NOT NULL,
+	amount NUMERIC NOT NULL CHECK (amount > 0),
+	note TEXT NOT NULL DEFAULT '',
+	category VARCHAR(50) NOT NULL DEFAULT '',
+	frequency VARCHAR(10) NOT NULL CHECK (frequency IN ('daily', 'weekly', 'monthly')),
+	day INT NOT NULL DEFAULT 0,
+	timezone VARCHAR(64) NOT NULL,
+	paused BOOLEAN NOT NULL DEFAULT FALSE,
+	next_run TIMESTAMPTZ NOT NULL,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_recurring_rules_due ON recurring_rules (next_run) WHERE NOT paused;
+CREATE INDEX IF NOT EXISTS idx_recurring_rules_user ON recurring_rules (user_id);
+
+-- Mỗi lần chạy của một quy tắc chỉ được tạo giao dịch 1 lần (khóa chính chống trùng)
+CREATE TABLE IF NOT EXISTS recurring_occurrences (
+	rule_id INT NOT NULL REFERENCES recurring_rules (id) ON DELETE CASCADE,
+	occurrence TIMESTAMPTZ NOT NULL,
+	transaction_id INT REFERENCES transactions (id) ON DELETE SET NULL,
+	PRIMARY KEY (rule_id, occurrence)
+);
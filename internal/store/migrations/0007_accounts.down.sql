@@ -0,0 +1,5 @@
+ALTER TABLE transactions
+	DROP COLUMN IF EXISTS to_account_id,
+	DROP COLUMN IF EXISTS account_id;
+
+DROP TABLE IF EXISTS accounts;
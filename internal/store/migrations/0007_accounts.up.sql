@@ -0,0 +1,15 @@
+-- Tài khoản / ví tiền của user (tiền mặt, ngân hàng, ví điện tử...)
+CREATE TABLE IF NOT EXISTS accounts (
+	id SERIAL PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL,
+	name VARCHAR(50) NOT NULL,
+	currency VARCHAR(10) NOT NULL DEFAULT 'VND',
+	opening_balance NUMERIC NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	UNIQUE (user_id, name)
+);
+
+-- Giao dịch cũ không gắn tài khoản (NULL). Không cho xóa tài khoản còn giao dịch
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS account_id INT REFERENCES accounts (id),
+	ADD COLUMN IF NOT EXISTS to_account_id INT REFERENCES accounts (id);
@@ -0,0 +1,4 @@
+-- Cách tính giá vốn khi rút/bán tài sản tiết kiệm: average (bình quân) hoặc fifo
+ALTER TABLE user_settings
+	ADD COLUMN IF NOT EXISTS cost_basis VARCHAR(10) NOT NULL DEFAULT 'average'
+	CHECK (cost_basis IN ('average', 'fifo'));
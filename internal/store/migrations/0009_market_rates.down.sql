@@ -0,0 +1,2 @@
+ALTER TABLE transactions DROP COLUMN IF EXISTS rate_snapshot_id;
+DROP TABLE IF EXISTS market_rates;
@@ -0,0 +1,12 @@
+-- Lịch sử giá thị trường: mỗi lần cập nhật giá thành công lưu 1 dòng
+CREATE TABLE IF NOT EXISTS market_rates (
+	id SERIAL PRIMARY KEY,
+	fetched_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	rates JSONB NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_market_rates_fetched_at ON market_rates (fetched_at);
+
+-- Giá đã dùng để quy đổi giao dịch ngoại tệ/vàng/BTC ra VND. NULL với giao dịch VND và giao dịch cũ
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS rate_snapshot_id INT REFERENCES market_rates (id);
@@ -0,0 +1,2 @@
+ALTER TABLE user_settings DROP COLUMN IF EXISTS silver_unit;
+ALTER TABLE user_settings DROP COLUMN IF EXISTS gold_unit;
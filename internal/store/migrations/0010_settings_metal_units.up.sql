@@ -0,0 +1,7 @@
+-- Đơn vị hiển thị vàng, bạc trong báo cáo: chi, luong, g, kg, oz
+ALTER TABLE user_settings
+	ADD COLUMN IF NOT EXISTS gold_unit VARCHAR(10) NOT NULL DEFAULT 'chi'
+	CHECK (gold_unit IN ('chi', 'luong', 'g', 'kg', 'oz'));
+ALTER TABLE user_settings
+	ADD COLUMN IF NOT EXISTS silver_unit VARCHAR(10) NOT NULL DEFAULT 'luong'
+	CHECK (silver_unit IN ('chi', 'luong', 'g', 'kg', 'oz'));
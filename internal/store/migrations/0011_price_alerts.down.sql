@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS alert_notifications;
+DROP TABLE IF EXISTS price_alerts;
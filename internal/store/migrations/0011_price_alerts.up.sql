@@ -0,0 +1,37 @@
+-- Cảnh báo giá của user, kiểm tra sau mỗi lần cập nhật giá
+CREATE TABLE IF NOT EXISTS price_alerts (
+	id SERIAL PRIMARY KEY,
+	user_id VARCHAR(50) NOT NULL,
+	asset VARCHAR(20) NOT NULL,
+	condition VARCHAR(10) NOT NULL CHECK (condition IN ('above', 'below', 'change')),
+	threshold NUMERIC NOT NULL CHECK (threshold > 0),
+	unit VARCHAR(10) NOT NULL DEFAULT '',
+	repeat BOOLEAN NOT NULL DEFAULT FALSE,
+	active BOOLEAN NOT NULL DEFAULT TRUE,
+	base_price NUMERIC NOT NULL DEFAULT 0,
+	last_price NUMERIC NOT NULL DEFAULT 0,
+	last_triggered_at TIMESTAMPTZ,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now()
+);
+
+CREATE INDEX IF NOT EXISTS idx_price_alerts_user ON price_alerts (user_id);
+CREATE INDEX IF NOT EXISTS idx_price_alerts_active ON price_alerts (id) WHERE active;
+
+-- Outbox: cảnh báo đã kích hoạt chờ bot gửi, sent_at được ghi khi bot gửi xong.
+-- Không khóa ngoại tới price_alerts để xóa cảnh báo không làm mất tin chưa gửi
+CREATE TABLE IF NOT EXISTS alert_notifications (
+	id SERIAL PRIMARY KEY,
+	alert_id INT NOT NULL,
+	user_id VARCHAR(50) NOT NULL,
+	asset VARCHAR(20) NOT NULL,
+	condition VARCHAR(10) NOT NULL,
+	threshold NUMERIC NOT NULL,
+	unit VARCHAR(10) NOT NULL DEFAULT '',
+	repeat BOOLEAN NOT NULL DEFAULT FALSE,
+	price NUMERIC NOT NULL,
+	base_price NUMERIC NOT NULL DEFAULT 0,
+	created_at TIMESTAMPTZ NOT NULL DEFAULT now(),
+	sent_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS idx_alert_notifications_pending ON alert_notifications (id) WHERE sent_at IS NULL;
@@ -0,0 +1,3 @@
+-- Biểu thức gốc của số tiền ("3x25k", "120k/4") để đối chiếu, rỗng với giao dịch nhập số
+ALTER TABLE transactions
+	ADD COLUMN IF NOT EXISTS amount_expr TEXT NOT NULL DEFAULT '';
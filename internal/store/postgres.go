@@ -1,8 +1,12 @@
 package store
 
 import (
+	"context"
 	"database/sql"
+	"fmt"
 	"go-finance/internal/model"
+	"strconv"
+	"strings"
 	"time"
 )
 
@@ -14,45 +18,77 @@ func NewPostgresStore(db *sql.DB) *PostgresStore {
 	return &PostgresStore{db: db}
 }
 
+// InitSchema áp dụng toàn bộ migration còn thiếu (xem migrate.go)
 func (s *PostgresStore) InitSchema() error {
-	query := `
-	CREATE TABLE IF NOT EXISTS transactions (
-		id SERIAL PRIMARY KEY,
-		user_id VARCHAR(50) NOT NULL,
-		type VARCHAR(20) NOT NULL,
-		amount FLOAT NOT NULL,
-		note TEXT,
-		category VARCHAR(50),
-		created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
-		currency VARCHAR(10) DEFAULT 'VND',
-		original_amount FLOAT DEFAULT 0.0
-	);`
-	_, err := s.db.Exec(query)
+	m, err := NewMigrator(s.db)
+	if err != nil {
+		return err
+	}
+	_, err = m.Up(context.Background())
 	return err
 }
 
-func (s *PostgresStore) Create(t model.Transaction) error {
+// transactionColumns thứ tự cột khớp với scanTransaction
+const transactionColumns = `id, user_id, type, amount, note, category, created_at, currency, original_amount, account_id, to_account_id, rate_snapshot_id, amount_expr`
+
+// scanner dùng chung cho *sql.Row và *sql.Rows
+type scanner interface {
+	Scan(dest ...interface{}) error
+}
+
+func scanTransaction(row scanner) (model.Transaction, error) {
+	var t model.Transaction
+	var note, cat, curr sql.NullString // Handle nulls safely
+
+	if err := row.Scan(&t.ID, &t.UserID, &t.Type, &t.Amount, &note, &cat, &t.CreatedAt, &curr, &t.OriginalAmount, &t.AccountID, &t.ToAccountID, &t.RateSnapshotID, &t.AmountExpr); err != nil {
+		return t, err
+	}
+	t.Note = note.String
+	t.Category = cat.String
+	t.Currency = curr.String
+	if t.Currency == "" {
+		t.Currency = "VND"
+	}
+	return t, nil
+}
+
+// defaultCategory tự động phân loại đơn giản nếu chưa có category
+func defaultCategory(t model.Transaction) string {
+	if t.Category == "" && t.Type == "chi" {
+		return "khác" // Logic đơn giản hóa
+	}
+	return t.Category
+}
+
+func (s *PostgresStore) Create(t model.Transaction) (int, error) {
 	query := `
-		INSERT INTO transactions (user_id, type, amount, note, category, currency, original_amount, created_at)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		INSERT INTO transactions (user_id, type, amount, note, category, currency, original_amount, created_at, account_id, to_account_id,
+			rate_snapshot_id, amount_expr)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
+		RETURNING id
 	`
-	// Tự động phân loại đơn giản nếu chưa có category
-	category := t.Category
-	if category == "" && t.Type == "chi" {
-		category = "khác" // Logic đơn giản hóa
+	createdAt := t.CreatedAt
+	if createdAt.IsZero() {
+		createdAt = time.Now()
 	}
-
-	_, err := s.db.Exec(query, t.UserID, t.Type, t.Amount, t.Note, category, t.Currency, t.OriginalAmount, time.Now())
-	return err
+	var id int
+	err := s.db.QueryRow(query, t.UserID, t.Type, t.Amount, t.Note, defaultCategory(t), t.Currency, t.OriginalAmount, createdAt,
+		t.AccountID, t.ToAccountID, t.RateSnapshotID, t.AmountExpr).Scan(&id)
+	return id, err
 }
 
-func (s *PostgresStore) GetByPeriod(userID string, startDate time.Time) ([]model.Transaction, error) {
+func (s *PostgresStore) GetByPeriod(userID string, startDate, endDate time.Time) ([]model.Transaction, error) {
 	query := `
-		SELECT id, user_id, type, amount, note, category, created_at, currency, original_amount
+		SELECT ` + transactionColumns + `
 		FROM transactions
 		WHERE user_id = $1 AND created_at >= $2
 	`
-	rows, err := s.db.Query(query, userID, startDate)
+	args := []interface{}{userID, startDate}
+	if !endDate.IsZero() {
+		query += ` AND created_at < $3`
+		args = append(args, endDate)
+	}
+	rows, err := s.db.Query(query, args...)
 	if err != nil {
 		return nil, err
 	}
@@ -60,23 +96,166 @@ func (s *PostgresStore) GetByPeriod(userID string, startDate time.Time) ([]model
 
 	var txs []model.Transaction
 	for rows.Next() {
-		var t model.Transaction
-		var note, cat, curr sql.NullString // Handle nulls safely
-
-		if err := rows.Scan(&t.ID, &t.UserID, &t.Type, &t.Amount, &note, &cat, &t.CreatedAt, &curr, &t.OriginalAmount); err != nil {
+		t, err := scanTransaction(rows)
+		if err != nil {
 			return nil, err
 		}
-		t.Note = note.String
-		t.Category = cat.String
-		t.Currency = curr.String
-		if t.Currency == "" {
-			t.Currency = "VND"
-		}
 		txs = append(txs, t)
 	}
 	return txs, nil
 }
 
+// List lọc giao dịch và phân trang kiểu keyset: mỗi trang bắt đầu ngay sau
+// (created_at, id) của dòng cuối trang trước nên không bị chậm dần như OFFSET
+func (s *PostgresStore) List(f model.TransactionFilter) (model.TransactionPage, error) {
+	var page model.TransactionPage
+	limit := pageSize(f.Limit)
+
+	conds := []string{"user_id = $1"}
+	args := []interface{}{f.UserID}
+	add := func(cond string, arg interface{}) {
+		args = append(args, arg)
+		conds = append(conds, strings.ReplaceAll(cond, "?", "$"+strconv.Itoa(len(args))))
+	}
+
+	if !f.From.IsZero() {
+		add("created_at >= ?", f.From)
+	}
+	if !f.To.IsZero() {
+		add("created_at < ?", f.To)
+	}
+	if f.Type != "" {
+		add("type = ?", f.Type)
+	}
+	if f.Category != "" {
+		add("category = ?", f.Category)
+	}
+	if f.Currency != "" {
+		add("COALESCE(NULLIF(currency, ''), 'VND') = ?", f.Currency)
+	}
+	if f.MinAmount != nil {
+		add("amount >= ?", *f.MinAmount)
+	}
+	if f.MaxAmount != nil {
+		add("amount <= ?", *f.MaxAmount)
+	}
+	if terms := searchTerms(f.Search); len(terms) > 0 {
+		add("to_tsvector('simple', COALESCE(note, '')) @@ plainto_tsquery('simple', ?)", strings.Join(terms, " "))
+	}
+
+	order := "DESC"
+	cmp := "<"
+	if f.Ascending {
+		order = "ASC"
+		cmp = ">"
+	}
+	if f.Cursor != "" {
+		c, err := decodeCursor(f.Cursor)
+		if err != nil {
+			return page, err
+		}
+		args = append(args, c.CreatedAt, c.ID)
+		conds = append(conds, fmt.Sprintf("(created_at, id) %s ($%d, $%d)", cmp, len(args)-1, len(args)))
+	}
+
+	// Lấy dư 1 dòng để biết còn trang sau hay không
+	query := fmt.Sprintf(`
+		SELECT %s
+		FROM transactions
+		WHERE %s
+		ORDER BY created_at %s, id %s
+		LIMIT %d
+	`, transactionColumns, strings.Join(conds, " AND "), order, order, limit+1)
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return page, err
+	}
+	defer rows.Close()
+
+	page.Items = []model.Transaction{}
+	for rows.Next() {
+		t, err := scanTransaction(rows)
+		if err != nil {
+			return page, err
+		}
+		page.Items = append(page.Items, t)
+	}
+	if err := rows.Err(); err != nil {
+		return page, err
+	}
+
+	if len(page.Items) > limit {
+		page.Items = page.Items[:limit]
+		last := page.Items[limit-1]
+		page.NextCursor = encodeCursor(cursor{CreatedAt: last.CreatedAt, ID: last.ID})
+	}
+	return page, nil
+}
+
+// GetByID lấy một giao dịch theo id, trả về ErrNotFound nếu không tồn tại
+func (s *PostgresStore) GetByID(id int) (model.Transaction, error) {
+	query := `SELECT ` + transactionColumns + ` FROM transactions WHERE id = $1`
+	t, err := scanTransaction(s.db.QueryRow(query, id))
+	if err == sql.ErrNoRows {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+// GetLatest lấy giao dịch được ghi gần nhất của user (dùng cho /undo).
+// Sắp theo id vì đó là thứ tự ghi thực tế
+func (s *PostgresStore) GetLatest(userID string) (model.Transaction, error) {
+	query := `
+		SELECT ` + transactionColumns + `
+		FROM transactions
+		WHERE user_id = $1
+		ORDER BY id DESC
+		LIMIT 1
+	`
+	t, err := scanTransaction(s.db.QueryRow(query, userID))
+	if err == sql.ErrNoRows {
+		return t, ErrNotFound
+	}
+	return t, err
+}
+
+// Update ghi đè các trường có thể sửa của giao dịch (không đổi user_id, created_at)
+func (s *PostgresStore) Update(t model.Transaction) error {
+	query := `
+		UPDATE transactions
+		SET type = $2, amount = $3, note = $4, category = $5, currency = $6, original_amount = $7,
+			account_id = $8, to_account_id = $9, rate_snapshot_id = $10, amount_expr = $11
+		WHERE id = $1
+	`
+	res, err := s.db.Exec(query, t.ID, t.Type, t.Amount, t.Note, defaultCategory(t), t.Currency, t.OriginalAmount,
+		t.AccountID, t.ToAccountID, t.RateSnapshotID, t.AmountExpr)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *PostgresStore) Delete(id int) error {
+	res, err := s.db.Exec(`DELETE FROM transactions WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+// checkAffected trả về ErrNotFound nếu câu lệnh không tác động dòng nào
+func checkAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
 // GetAllUserIDs lấy danh sách tất cả user_id duy nhất
 func (s *PostgresStore) GetAllUserIDs() ([]string, error) {
 	query := `SELECT DISTINCT user_id FROM transactions`
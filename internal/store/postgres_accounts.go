@@ -0,0 +1,88 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"go-finance/internal/model"
+
+	"github.com/lib/pq"
+)
+
+const accountColumns = `id, user_id, name, currency, opening_balance, created_at`
+
+func scanAccount(row scanner) (model.Account, error) {
+	var a model.Account
+	err := row.Scan(&a.ID, &a.UserID, &a.Name, &a.Currency, &a.OpeningBalance, &a.CreatedAt)
+	if err == sql.ErrNoRows {
+		return a, ErrNotFound
+	}
+	return a, err
+}
+
+// isForeignKeyViolation kiểm tra lỗi vi phạm khóa ngoại của Postgres (mã 23503)
+func isForeignKeyViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23503"
+}
+
+func (s *PostgresStore) CreateAccount(a model.Account) (int, error) {
+	var id int
+	err := s.db.QueryRow(`
+		INSERT INTO accounts (user_id, name, currency, opening_balance)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id
+	`, a.UserID, a.Name, a.Currency, a.OpeningBalance).Scan(&id)
+	if isUniqueViolation(err) {
+		return 0, ErrConflict
+	}
+	return id, err
+}
+
+func (s *PostgresStore) GetAccount(id int) (model.Account, error) {
+	return scanAccount(s.db.QueryRow(`SELECT `+accountColumns+` FROM accounts WHERE id = $1`, id))
+}
+
+func (s *PostgresStore) GetAccountByName(userID, name string) (model.Account, error) {
+	return scanAccount(s.db.QueryRow(`SELECT `+accountColumns+` FROM accounts WHERE user_id = $1 AND name = $2`, userID, name))
+}
+
+func (s *PostgresStore) ListAccounts(userID string) ([]model.Account, error) {
+	rows, err := s.db.Query(`SELECT `+accountColumns+` FROM accounts WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var accounts []model.Account
+	for rows.Next() {
+		a, err := scanAccount(rows)
+		if err != nil {
+			return nil, err
+		}
+		accounts = append(accounts, a)
+	}
+	return accounts, rows.Err()
+}
+
+func (s *PostgresStore) UpdateAccount(a model.Account) error {
+	res, err := s.db.Exec(`UPDATE accounts SET name = $2, currency = $3, opening_balance = $4 WHERE id = $1`,
+		a.ID, a.Name, a.Currency, a.OpeningBalance)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *PostgresStore) DeleteAccount(id int) error {
+	res, err := s.db.Exec(`DELETE FROM accounts WHERE id = $1`, id)
+	if isForeignKeyViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
@@ -0,0 +1,146 @@
+package store
+
+import (
+	"database/sql"
+	"go-finance/internal/model"
+	"time"
+)
+
+const alertColumns = `id, user_id, asset, condition, threshold, unit, repeat, active, base_price, last_price, last_triggered_at, created_at`
+
+const notificationColumns = `id, alert_id, user_id, asset, condition, threshold, unit, repeat, price, base_price, created_at, sent_at`
+
+func scanAlert(row scanner) (model.PriceAlert, error) {
+	var a model.PriceAlert
+	err := row.Scan(&a.ID, &a.UserID, &a.Asset, &a.Condition, &a.Threshold, &a.Unit, &a.Repeat, &a.Active,
+		&a.BasePrice, &a.LastPrice, &a.LastTriggeredAt, &a.CreatedAt)
+	return a, err
+}
+
+func scanNotification(row scanner) (model.AlertNotification, error) {
+	var n model.AlertNotification
+	err := row.Scan(&n.ID, &n.AlertID, &n.UserID, &n.Asset, &n.Condition, &n.Threshold, &n.Unit, &n.Repeat,
+		&n.Price, &n.BasePrice, &n.CreatedAt, &n.SentAt)
+	return n, err
+}
+
+func (s *PostgresStore) CreateAlert(a model.PriceAlert) (int, error) {
+	query := `
+		INSERT INTO price_alerts (user_id, asset, condition, threshold, unit, repeat, active, base_price, last_price)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		RETURNING id
+	`
+	var id int
+	err := s.db.QueryRow(query, a.UserID, a.Asset, a.Condition, a.Threshold, a.Unit, a.Repeat, a.Active,
+		a.BasePrice, a.LastPrice).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) GetAlert(id int) (model.PriceAlert, error) {
+	a, err := scanAlert(s.db.QueryRow(`SELECT `+alertColumns+` FROM price_alerts WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return a, ErrNotFound
+	}
+	return a, err
+}
+
+func (s *PostgresStore) ListAlerts(userID string) ([]model.PriceAlert, error) {
+	rows, err := s.db.Query(`SELECT `+alertColumns+` FROM price_alerts WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var alerts []model.PriceAlert
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			return nil, err
+		}
+		alerts = append(alerts, a)
+	}
+	return alerts, rows.Err()
+}
+
+func (s *PostgresStore) DeleteAlert(id int) error {
+	res, err := s.db.Exec(`DELETE FROM price_alerts WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+// CheckAlerts khóa các cảnh báo đang bật bằng FOR UPDATE SKIP LOCKED (giống MaterializeDue),
+// trạng thái mới và thông báo được ghi trong cùng transaction nên không báo trùng hay mất tin
+func (s *PostgresStore) CheckAlerts(check func(model.PriceAlert) (model.PriceAlert, *model.AlertNotification)) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT ` + alertColumns + ` FROM price_alerts WHERE active ORDER BY id FOR UPDATE SKIP LOCKED`)
+	if err != nil {
+		return 0, err
+	}
+	var active []model.PriceAlert
+	for rows.Next() {
+		a, err := scanAlert(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		active = append(active, a)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, a := range active {
+		updated, n := check(a)
+		if _, err := tx.Exec(`
+			UPDATE price_alerts SET active = $2, base_price = $3, last_price = $4, last_triggered_at = $5 WHERE id = $1
+		`, a.ID, updated.Active, updated.BasePrice, updated.LastPrice, updated.LastTriggeredAt); err != nil {
+			return 0, err
+		}
+		if n == nil {
+			continue
+		}
+		if _, err := tx.Exec(`
+			INSERT INTO alert_notifications (alert_id, user_id, asset, condition, threshold, unit, repeat, price, base_price)
+			VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		`, n.AlertID, n.UserID, n.Asset, n.Condition, n.Threshold, n.Unit, n.Repeat, n.Price, n.BasePrice); err != nil {
+			return 0, err
+		}
+		created++
+	}
+	return created, tx.Commit()
+}
+
+func (s *PostgresStore) PendingNotifications(limit int) ([]model.AlertNotification, error) {
+	rows, err := s.db.Query(`SELECT `+notificationColumns+` FROM alert_notifications WHERE sent_at IS NULL ORDER BY id LIMIT $1`, pageSize(limit))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var notes []model.AlertNotification
+	for rows.Next() {
+		n, err := scanNotification(rows)
+		if err != nil {
+			return nil, err
+		}
+		notes = append(notes, n)
+	}
+	return notes, rows.Err()
+}
+
+func (s *PostgresStore) MarkNotificationSent(id int, at time.Time) error {
+	res, err := s.db.Exec(`UPDATE alert_notifications SET sent_at = $2 WHERE id = $1`, id, at)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
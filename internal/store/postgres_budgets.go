@@ -0,0 +1,78 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"go-finance/internal/model"
+
+	"github.com/lib/pq"
+)
+
+const budgetColumns = `id, user_id, category, period, amount_limit, created_at`
+
+func scanBudget(row scanner) (model.Budget, error) {
+	var b model.Budget
+	err := row.Scan(&b.ID, &b.UserID, &b.Category, &b.Period, &b.Limit, &b.CreatedAt)
+	return b, err
+}
+
+// isUniqueViolation kiểm tra lỗi vi phạm UNIQUE của Postgres (mã 23505)
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	return errors.As(err, &pqErr) && pqErr.Code == "23505"
+}
+
+func (s *PostgresStore) SaveBudget(b model.Budget) (model.Budget, error) {
+	query := `
+		INSERT INTO budgets (user_id, category, period, amount_limit)
+		VALUES ($1, $2, $3, $4)
+		ON CONFLICT (user_id, category, period) DO UPDATE SET amount_limit = EXCLUDED.amount_limit
+		RETURNING ` + budgetColumns
+	return scanBudget(s.db.QueryRow(query, b.UserID, b.Category, b.Period, b.Limit))
+}
+
+func (s *PostgresStore) GetBudget(id int) (model.Budget, error) {
+	b, err := scanBudget(s.db.QueryRow(`SELECT `+budgetColumns+` FROM budgets WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return b, ErrNotFound
+	}
+	return b, err
+}
+
+func (s *PostgresStore) ListBudgets(userID string) ([]model.Budget, error) {
+	rows, err := s.db.Query(`SELECT `+budgetColumns+` FROM budgets WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var budgets []model.Budget
+	for rows.Next() {
+		b, err := scanBudget(rows)
+		if err != nil {
+			return nil, err
+		}
+		budgets = append(budgets, b)
+	}
+	return budgets, rows.Err()
+}
+
+func (s *PostgresStore) UpdateBudget(b model.Budget) error {
+	res, err := s.db.Exec(`UPDATE budgets SET category = $2, period = $3, amount_limit = $4 WHERE id = $1`,
+		b.ID, b.Category, b.Period, b.Limit)
+	if isUniqueViolation(err) {
+		return ErrConflict
+	}
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *PostgresStore) DeleteBudget(id int) error {
+	res, err := s.db.Exec(`DELETE FROM budgets WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
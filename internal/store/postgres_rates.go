@@ -0,0 +1,74 @@
+package store
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"go-finance/internal/model"
+	"time"
+)
+
+const rateColumns = `id, fetched_at, rates`
+
+func scanRates(row scanner) (model.RateSnapshot, error) {
+	var r model.RateSnapshot
+	var raw []byte
+	err := row.Scan(&r.ID, &r.FetchedAt, &raw)
+	if err == sql.ErrNoRows {
+		return r, ErrNotFound
+	}
+	if err != nil {
+		return r, err
+	}
+	return r, json.Unmarshal(raw, &r.Rates)
+}
+
+func (s *PostgresStore) SaveRates(at time.Time, rates model.ExchangeRates) (int, error) {
+	raw, err := json.Marshal(rates)
+	if err != nil {
+		return 0, err
+	}
+	var id int
+	err = s.db.QueryRow(`INSERT INTO market_rates (fetched_at, rates) VALUES ($1, $2) RETURNING id`, at, raw).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) LatestRates() (model.RateSnapshot, error) {
+	return scanRates(s.db.QueryRow(`SELECT ` + rateColumns + ` FROM market_rates ORDER BY fetched_at DESC, id DESC LIMIT 1`))
+}
+
+func (s *PostgresStore) RatesAt(t time.Time) (model.RateSnapshot, error) {
+	return scanRates(s.db.QueryRow(`
+		SELECT `+rateColumns+` FROM market_rates
+		WHERE fetched_at <= $1
+		ORDER BY fetched_at DESC, id DESC
+		LIMIT 1
+	`, t))
+}
+
+func (s *PostgresStore) ListRates(from, to time.Time, limit int) ([]model.RateSnapshot, error) {
+	query := `SELECT ` + rateColumns + ` FROM market_rates WHERE fetched_at >= $1`
+	args := []interface{}{from}
+	if !to.IsZero() {
+		args = append(args, to)
+		query += fmt.Sprintf(` AND fetched_at < $%d`, len(args))
+	}
+	args = append(args, limit)
+	query += fmt.Sprintf(` ORDER BY fetched_at, id LIMIT $%d`, len(args))
+
+	rows, err := s.db.Query(query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var snapshots []model.RateSnapshot
+	for rows.Next() {
+		r, err := scanRates(rows)
+		if err != nil {
+			return nil, err
+		}
+		snapshots = append(snapshots, r)
+	}
+	return snapshots, rows.Err()
+}
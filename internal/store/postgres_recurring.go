@@ -0,0 +1,163 @@
+package store
+
+import (
+	"database/sql"
+	"go-finance/internal/model"
+	"time"
+)
+
+const recurringColumns = `id, user_id, type, amount, note, category, frequency, day, timezone, paused, next_run, created_at`
+
+// maxCatchUp số lần chạy bù tối đa cho 1 quy tắc trong 1 lượt (VD: server tắt lâu ngày).
+// Phần còn lại sẽ được tạo ở lượt sau
+const maxCatchUp = 400
+
+// recurringBatch số quy tắc đến hạn xử lý trong 1 transaction
+const recurringBatch = 100
+
+func scanRecurring(row scanner) (model.RecurringRule, error) {
+	var r model.RecurringRule
+	err := row.Scan(&r.ID, &r.UserID, &r.Type, &r.Amount, &r.Note, &r.Category,
+		&r.Frequency, &r.Day, &r.Timezone, &r.Paused, &r.NextRun, &r.CreatedAt)
+	return r, err
+}
+
+func (s *PostgresStore) CreateRecurring(r model.RecurringRule) (int, error) {
+	query := `
+		INSERT INTO recurring_rules (user_id, type, amount, note, category, frequency, day, timezone, paused, next_run)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+		RETURNING id
+	`
+	var id int
+	err := s.db.QueryRow(query, r.UserID, r.Type, r.Amount, r.Note, r.Category,
+		r.Frequency, r.Day, r.Timezone, r.Paused, r.NextRun).Scan(&id)
+	return id, err
+}
+
+func (s *PostgresStore) GetRecurring(id int) (model.RecurringRule, error) {
+	r, err := scanRecurring(s.db.QueryRow(`SELECT `+recurringColumns+` FROM recurring_rules WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return r, ErrNotFound
+	}
+	return r, err
+}
+
+func (s *PostgresStore) ListRecurring(userID string) ([]model.RecurringRule, error) {
+	rows, err := s.db.Query(`SELECT `+recurringColumns+` FROM recurring_rules WHERE user_id = $1 ORDER BY id`, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []model.RecurringRule
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (s *PostgresStore) UpdateRecurring(r model.RecurringRule) error {
+	query := `UPDATE recurring_rules SET amount = $2, note = $3, paused = $4, next_run = $5 WHERE id = $1`
+	res, err := s.db.Exec(query, r.ID, r.Amount, r.Note, r.Paused, r.NextRun)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+func (s *PostgresStore) DeleteRecurring(id int) error {
+	res, err := s.db.Exec(`DELETE FROM recurring_rules WHERE id = $1`, id)
+	if err != nil {
+		return err
+	}
+	return checkAffected(res)
+}
+
+// MaterializeDue khóa các quy tắc đến hạn bằng FOR UPDATE SKIP LOCKED nên nhiều bản API
+// chạy song song sẽ chia nhau xử lý, không bản nào chờ bản nào. Mỗi lần chạy được ghi vào
+// recurring_occurrences (khóa chính rule_id + occurrence) trong cùng transaction với giao dịch
+// tạo ra, nên dù tiến trình chết giữa chừng hay chạy lại thì cũng không tạo trùng
+func (s *PostgresStore) MaterializeDue(now time.Time, next func(model.RecurringRule, time.Time) time.Time) (int, error) {
+	tx, err := s.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`
+		SELECT `+recurringColumns+`
+		FROM recurring_rules
+		WHERE NOT paused AND next_run <= $1
+		ORDER BY next_run
+		LIMIT $2
+		FOR UPDATE SKIP LOCKED
+	`, now, recurringBatch)
+	if err != nil {
+		return 0, err
+	}
+	var due []model.RecurringRule
+	for rows.Next() {
+		r, err := scanRecurring(rows)
+		if err != nil {
+			rows.Close()
+			return 0, err
+		}
+		due = append(due, r)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	created := 0
+	for _, r := range due {
+		for i := 0; i < maxCatchUp && !r.NextRun.After(now); i++ {
+			res, err := tx.Exec(`
+				INSERT INTO recurring_occurrences (rule_id, occurrence) VALUES ($1, $2)
+				ON CONFLICT DO NOTHING
+			`, r.ID, r.NextRun)
+			if err != nil {
+				return 0, err
+			}
+			if n, _ := res.RowsAffected(); n == 1 {
+				var txID int
+				err := tx.QueryRow(`
+					INSERT INTO transactions (user_id, type, amount, note, category, currency, original_amount, created_at)
+					VALUES ($1, $2, $3, $4, $5, 'VND', $3, $6)
+					RETURNING id
+				`, r.UserID, r.Type, r.Amount, r.Note, defaultCategory(recurringTransaction(r)), r.NextRun).Scan(&txID)
+				if err != nil {
+					return 0, err
+				}
+				if _, err := tx.Exec(`UPDATE recurring_occurrences SET transaction_id = $3 WHERE rule_id = $1 AND occurrence = $2`,
+					r.ID, r.NextRun, txID); err != nil {
+					return 0, err
+				}
+				created++
+			}
+			r.NextRun = next(r, r.NextRun)
+		}
+		if _, err := tx.Exec(`UPDATE recurring_rules SET next_run = $2 WHERE id = $1`, r.ID, r.NextRun); err != nil {
+			return 0, err
+		}
+	}
+	return created, tx.Commit()
+}
+
+// recurringTransaction giao dịch được tạo từ một lần chạy của quy tắc (luôn là VND)
+func recurringTransaction(r model.RecurringRule) model.Transaction {
+	return model.Transaction{
+		UserID:         r.UserID,
+		Type:           r.Type,
+		Amount:         r.Amount,
+		Note:           r.Note,
+		Category:       r.Category,
+		Currency:       "VND",
+		OriginalAmount: r.Amount,
+		CreatedAt:      r.NextRun,
+	}
+}
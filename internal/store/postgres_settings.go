@@ -0,0 +1,62 @@
+package store
+
+import (
+	"database/sql"
+	"go-finance/internal/model"
+	"time"
+)
+
+func (s *PostgresStore) GetSettings(userID string) (model.UserSettings, error) {
+	settings := model.DefaultUserSettings(userID)
+	var weekStart int
+	err := s.db.QueryRow(`SELECT timezone, week_start, cost_basis, gold_unit, silver_unit FROM user_settings WHERE user_id = $1`, userID).
+		Scan(&settings.Timezone, &weekStart, &settings.CostBasis, &settings.GoldUnit, &settings.SilverUnit)
+	if err == sql.ErrNoRows {
+		return settings, nil
+	}
+	settings.WeekStart = time.Weekday(weekStart)
+	return settings, err
+}
+
+func (s *PostgresStore) SaveSettings(settings model.UserSettings) error {
+	if settings.CostBasis == "" {
+		settings.CostBasis = model.CostBasisAverage
+	}
+	if settings.GoldUnit == "" {
+		settings.GoldUnit = model.DefaultGoldUnit
+	}
+	if settings.SilverUnit == "" {
+		settings.SilverUnit = model.DefaultSilverUnit
+	}
+	query := `
+		INSERT INTO user_settings (user_id, timezone, week_start, cost_basis, gold_unit, silver_unit, updated_at)
+		VALUES ($1, $2, $3, $4, $5, $6, now())
+		ON CONFLICT (user_id) DO UPDATE
+		SET timezone = EXCLUDED.timezone, week_start = EXCLUDED.week_start,
+			cost_basis = EXCLUDED.cost_basis, gold_unit = EXCLUDED.gold_unit,
+			silver_unit = EXCLUDED.silver_unit, updated_at = now()
+	`
+	_, err := s.db.Exec(query, settings.UserID, settings.Timezone, int(settings.WeekStart), settings.CostBasis,
+		settings.GoldUnit, settings.SilverUnit)
+	return err
+}
+
+func (s *PostgresStore) ListSettings() ([]model.UserSettings, error) {
+	rows, err := s.db.Query(`SELECT user_id, timezone, week_start, cost_basis, gold_unit, silver_unit FROM user_settings`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []model.UserSettings
+	for rows.Next() {
+		var st model.UserSettings
+		var weekStart int
+		if err := rows.Scan(&st.UserID, &st.Timezone, &weekStart, &st.CostBasis, &st.GoldUnit, &st.SilverUnit); err != nil {
+			return nil, err
+		}
+		st.WeekStart = time.Weekday(weekStart)
+		list = append(list, st)
+	}
+	return list, rows.Err()
+}
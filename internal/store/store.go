@@ -0,0 +1,162 @@
+package store
+
+import (
+	"errors"
+	"go-finance/internal/model"
+	"time"
+)
+
+var (
+	// ErrNotFound được trả về khi bản ghi cần đọc/sửa/xóa không tồn tại
+	ErrNotFound = errors.New("not found")
+	// ErrConflict được trả về khi ghi dữ liệu vi phạm ràng buộc duy nhất
+	ErrConflict = errors.New("conflict")
+)
+
+// TransactionStore là interface chung cho mọi nơi lưu trữ giao dịch.
+// Handler chỉ phụ thuộc vào interface này nên có thể chạy với Postgres
+// (production) hoặc MemoryStore (test, demo mode) mà không cần sửa code.
+type TransactionStore interface {
+	// Create lưu một giao dịch mới, CreatedAt zero thì store gán thời điểm hiện tại. Trả về id vừa tạo
+	Create(t model.Transaction) (int, error)
+	// GetByPeriod lấy các giao dịch của user có startDate <= created_at < endDate.
+	// endDate zero nghĩa là không giới hạn trên
+	GetByPeriod(userID string, startDate, endDate time.Time) ([]model.Transaction, error)
+	// List lọc và phân trang giao dịch theo keyset (created_at, id)
+	List(f model.TransactionFilter) (model.TransactionPage, error)
+	// GetByID lấy giao dịch theo id, ErrNotFound nếu không có
+	GetByID(id int) (model.Transaction, error)
+	// GetLatest lấy giao dịch mới nhất của user, ErrNotFound nếu user chưa có giao dịch
+	GetLatest(userID string) (model.Transaction, error)
+	// Update sửa giao dịch theo t.ID, ErrNotFound nếu không có
+	Update(t model.Transaction) error
+	// Delete xóa giao dịch theo id, ErrNotFound nếu không có
+	Delete(id int) error
+	// GetAllUserIDs lấy danh sách tất cả user_id duy nhất
+	GetAllUserIDs() ([]string, error)
+}
+
+// SettingsStore lưu cài đặt riêng của từng user (múi giờ, ngày đầu tuần)
+type SettingsStore interface {
+	// GetSettings trả về cài đặt của user, hoặc model.DefaultUserSettings nếu user chưa cài
+	GetSettings(userID string) (model.UserSettings, error)
+	// SaveSettings tạo mới hoặc ghi đè cài đặt của user
+	SaveSettings(s model.UserSettings) error
+	// ListSettings lấy cài đặt của tất cả user đã từng lưu cài đặt
+	ListSettings() ([]model.UserSettings, error)
+}
+
+// BudgetStore lưu ngân sách theo danh mục. Mỗi user chỉ có 1 ngân sách cho mỗi (category, period)
+type BudgetStore interface {
+	// SaveBudget tạo mới, hoặc ghi đè hạn mức nếu (user, category, period) đã có. Trả về bản đã lưu
+	SaveBudget(b model.Budget) (model.Budget, error)
+	// GetBudget lấy ngân sách theo id, ErrNotFound nếu không có
+	GetBudget(id int) (model.Budget, error)
+	// ListBudgets lấy tất cả ngân sách của user
+	ListBudgets(userID string) ([]model.Budget, error)
+	// UpdateBudget sửa ngân sách theo b.ID, ErrConflict nếu trùng (category, period) với ngân sách khác
+	UpdateBudget(b model.Budget) error
+	// DeleteBudget xóa ngân sách, ErrNotFound nếu không có
+	DeleteBudget(id int) error
+}
+
+// RecurringStore lưu quy tắc giao dịch định kỳ
+type RecurringStore interface {
+	// CreateRecurring lưu quy tắc mới (r.NextRun phải được tính sẵn), trả về id
+	CreateRecurring(r model.RecurringRule) (int, error)
+	// GetRecurring lấy quy tắc theo id, ErrNotFound nếu không có
+	GetRecurring(id int) (model.RecurringRule, error)
+	// ListRecurring lấy tất cả quy tắc của user
+	ListRecurring(userID string) ([]model.RecurringRule, error)
+	// UpdateRecurring sửa số tiền, ghi chú, tạm dừng và lần chạy kế tiếp theo r.ID
+	UpdateRecurring(r model.RecurringRule) error
+	// DeleteRecurring xóa quy tắc (các giao dịch đã tạo vẫn giữ), ErrNotFound nếu không có
+	DeleteRecurring(id int) error
+	// MaterializeDue tạo giao dịch cho mọi lần chạy đã đến hạn (NextRun <= now) của các quy tắc
+	// đang hoạt động, dùng next để tính lần chạy tiếp theo. Mỗi (quy tắc, lần chạy) chỉ tạo
+	// đúng 1 giao dịch kể cả khi nhiều tiến trình cùng gọi. Trả về số giao dịch đã tạo
+	MaterializeDue(now time.Time, next func(model.RecurringRule, time.Time) time.Time) (int, error)
+}
+
+// AccountStore lưu tài khoản / ví tiền. Tên tài khoản là duy nhất với mỗi user
+type AccountStore interface {
+	// CreateAccount tạo tài khoản, trả về id. ErrConflict nếu user đã có tài khoản cùng tên
+	CreateAccount(a model.Account) (int, error)
+	// GetAccount lấy tài khoản theo id, ErrNotFound nếu không có
+	GetAccount(id int) (model.Account, error)
+	// GetAccountByName lấy tài khoản theo tên của user, ErrNotFound nếu không có
+	GetAccountByName(userID, name string) (model.Account, error)
+	// ListAccounts lấy tất cả tài khoản của user, theo thứ tự tạo
+	ListAccounts(userID string) ([]model.Account, error)
+	// UpdateAccount đổi tên, đơn vị tiền và số dư đầu theo a.ID. ErrConflict nếu trùng tên
+	UpdateAccount(a model.Account) error
+	// DeleteAccount xóa tài khoản. ErrConflict nếu còn giao dịch gắn với tài khoản
+	DeleteAccount(id int) error
+}
+
+// RateStore lưu lịch sử giá thị trường
+type RateStore interface {
+	// SaveRates lưu giá lấy được tại thời điểm at, trả về id bản ghi
+	SaveRates(at time.Time, rates model.ExchangeRates) (int, error)
+	// LatestRates lấy bản ghi giá mới nhất, ErrNotFound nếu chưa có
+	LatestRates() (model.RateSnapshot, error)
+	// RatesAt lấy bản ghi giá mới nhất có fetched_at <= t, ErrNotFound nếu không có
+	RatesAt(t time.Time) (model.RateSnapshot, error)
+	// ListRates lấy tối đa limit bản ghi giá có from <= fetched_at < to theo thứ tự thời gian (cũ trước).
+	// to zero nghĩa là không giới hạn trên
+	ListRates(from, to time.Time, limit int) ([]model.RateSnapshot, error)
+}
+
+// AlertStore lưu cảnh báo giá và hàng đợi thông báo (outbox) chờ bot gửi
+type AlertStore interface {
+	// CreateAlert lưu cảnh báo mới, trả về id
+	CreateAlert(a model.PriceAlert) (int, error)
+	// GetAlert lấy cảnh báo theo id, ErrNotFound nếu không có
+	GetAlert(id int) (model.PriceAlert, error)
+	// ListAlerts lấy tất cả cảnh báo của user (kể cả đã tắt), theo thứ tự tạo
+	ListAlerts(userID string) ([]model.PriceAlert, error)
+	// DeleteAlert xóa cảnh báo (thông báo đã tạo vẫn được gửi), ErrNotFound nếu không có
+	DeleteAlert(id int) error
+	// CheckAlerts gọi check với từng cảnh báo đang bật, lưu trạng thái check trả về và thông báo (nếu khác nil)
+	// trong cùng 1 transaction. Nhiều bản API chạy cùng lúc không kiểm tra trùng một cảnh báo.
+	// Trả về số thông báo đã tạo
+	CheckAlerts(check func(model.PriceAlert) (model.PriceAlert, *model.AlertNotification)) (int, error)
+	// PendingNotifications lấy tối đa limit thông báo chưa gửi, cũ trước
+	PendingNotifications(limit int) ([]model.AlertNotification, error)
+	// MarkNotificationSent đánh dấu thông báo đã gửi lúc at, ErrNotFound nếu không có
+	MarkNotificationSent(id int, at time.Time) error
+}
+
+// Store gom tất cả các nhóm chức năng lưu trữ mà API cần
+type Store interface {
+	TransactionStore
+	SettingsStore
+	BudgetStore
+	RecurringStore
+	AccountStore
+	RateStore
+	AlertStore
+}
+
+// Giới hạn số bản ghi mỗi trang của List
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+// pageSize chuẩn hóa Limit người dùng gửi lên
+func pageSize(limit int) int {
+	if limit <= 0 {
+		return DefaultPageSize
+	}
+	if limit > MaxPageSize {
+		return MaxPageSize
+	}
+	return limit
+}
+
+// Đảm bảo các implementation luôn thỏa mãn interface lúc compile
+var (
+	_ Store = (*PostgresStore)(nil)
+	_ Store = (*MemoryStore)(nil)
+)
@@ -1,7 +1,9 @@
 package main
 
 import (
+	"context"
 	"database/sql"
+	"errors"
 	"fmt"
 	"go-finance/internal/handler"
 	"go-finance/internal/service"
@@ -9,7 +11,11 @@ import (
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"sync"
+	"syscall"
 	"time"
+	_ "time/tzdata" // Image alpine không có sẵn dữ liệu múi giờ IANA
 
 	"github.com/joho/godotenv"
 	_ "github.com/lib/pq"
@@ -26,33 +32,34 @@ import (
 func main() {
 	_ = godotenv.Load()
 
-	// 1. Kết nối DB
-	connStr := os.Getenv("DATABASE_URL")
-	if connStr == "" {
-		log.Fatal("DATABASE_URL is required")
-	}
-	db, err := sql.Open("postgres", connStr)
-	if err != nil {
-		log.Fatal(err)
-	}
-	defer db.Close()
-
-	if err := db.Ping(); err != nil {
-		log.Fatal("Cannot connect to DB:", err)
+	// Subcommand quản lý schema: ./main migrate [up|down [n]|status]
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
 	}
-	fmt.Println("Connected to Database successfully!")
 
 	// GIữ cho bot ngủ
 	botURL := os.Getenv("BOT_URL")
 	go keepAliveService(botURL, "BOT-Service")
 
-	// 2. Init Store & Handler
-	pgStore := store.NewPostgresStore(db)
-	if err := pgStore.InitSchema(); err != nil {
-		log.Fatal("Failed to init schema:", err)
+	// 1 + 2. Kết nối DB & Init Store
+	// DEMO_MODE=true: chạy toàn bộ API với store trong RAM, không cần Postgres
+	var dataStore store.Store
+	if os.Getenv("DEMO_MODE") == "true" {
+		log.Println("[CONFIG WARN] DEMO_MODE enabled, using in-memory store (data will be lost on restart)")
+		dataStore = store.NewMemoryStore()
+	} else {
+		db := connectDB()
+		defer db.Close()
+
+		pgStore := store.NewPostgresStore(db)
+		if err := pgStore.InitSchema(); err != nil {
+			log.Fatal("Failed to init schema:", err)
+		}
+		dataStore = pgStore
 	}
 
-	h := handler.NewFinanceHandler(pgStore)
+	h := handler.NewFinanceHandler(dataStore)
 
 	// 3. Router
 	mux := http.NewServeMux()
@@ -61,31 +68,119 @@ func main() {
 		w.Write([]byte("Finance API is running!"))
 	})
 	mux.HandleFunc("POST /transactions", h.CreateTransaction)
+	mux.HandleFunc("GET /transactions", h.ListTransactions)
+	mux.HandleFunc("GET /transactions/{id}", h.GetTransaction)
+	mux.HandleFunc("PATCH /transactions/{id}", h.UpdateTransaction)
+	mux.HandleFunc("DELETE /transactions/{id}", h.DeleteTransaction)
+	mux.HandleFunc("DELETE /transactions/last", h.DeleteLastTransaction)
 	mux.HandleFunc("GET /report", h.GenerateReport)
 	mux.HandleFunc("GET /market-rates", h.GetPrices)
+	mux.HandleFunc("GET /market-rates/history", h.GetRateHistory)
+	mux.HandleFunc("GET /market-rates/providers", h.GetRateProviders)
+	mux.HandleFunc("GET /assets", h.ListAssets)
 	mux.HandleFunc("GET /users", h.GetUsers)
+	mux.HandleFunc("GET /users/settings", h.ListSettings)
+	mux.HandleFunc("GET /users/{id}/settings", h.GetSettings)
+	mux.HandleFunc("PUT /users/{id}/settings", h.UpdateSettings)
+	mux.HandleFunc("POST /budgets", h.CreateBudget)
+	mux.HandleFunc("GET /budgets", h.ListBudgets)
+	mux.HandleFunc("GET /budgets/status", h.GetBudgetStatus)
+	mux.HandleFunc("GET /budgets/{id}", h.GetBudget)
+	mux.HandleFunc("PUT /budgets/{id}", h.UpdateBudget)
+	mux.HandleFunc("DELETE /budgets/{id}", h.DeleteBudget)
+	mux.HandleFunc("POST /recurring", h.CreateRecurring)
+	mux.HandleFunc("GET /recurring", h.ListRecurring)
+	mux.HandleFunc("PATCH /recurring/{id}", h.UpdateRecurring)
+	mux.HandleFunc("DELETE /recurring/{id}", h.DeleteRecurring)
+	mux.HandleFunc("POST /accounts", h.CreateAccount)
+	mux.HandleFunc("GET /accounts", h.ListAccounts)
+	mux.HandleFunc("PUT /accounts/{id}", h.UpdateAccount)
+	mux.HandleFunc("DELETE /accounts/{id}", h.DeleteAccount)
+	mux.HandleFunc("GET /portfolio", h.GetPortfolio)
+	mux.HandleFunc("POST /alerts", h.CreateAlert)
+	mux.HandleFunc("GET /alerts", h.ListAlerts)
+	mux.HandleFunc("DELETE /alerts/{id}", h.DeleteAlert)
+	mux.HandleFunc("GET /alerts/notifications", h.ListAlertNotifications)
+	mux.HandleFunc("POST /alerts/notifications/{id}/ack", h.AckAlertNotification)
 
 	mux.HandleFunc("/swagger/", httpSwagger.Handler(
 		httpSwagger.URL("doc.json"),
 	))
 
 	// Chạy Goroutine cập nhật giá ngầm (Background Worker)
+	// Nguồn giá và thứ tự dự phòng cấu hình qua RATE_PROVIDERS_<ASSET>, RATE_PROVIDER_<NAME>_URL
+	// Tài sản tự định nghĩa (CUSTOM_ASSETS) phải có trước khi cấu hình nguồn giá cho mã giá của chúng
+	if err := service.LoadAssetsFromEnv(); err != nil {
+		log.Fatal("Invalid asset config:", err)
+	}
+	registry, err := service.NewRateRegistryFromEnv()
+	if err != nil {
+		log.Fatal("Invalid rate provider config:", err)
+	}
+	// ctx bị hủy khi nhận SIGINT/SIGTERM để tắt server và worker gọn gàng
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	var workers sync.WaitGroup
 	fmt.Println("Starting Price Updater Service...")
-	go service.StartPriceUpdater()
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		service.StartPriceUpdater(ctx, dataStore, registry, dataStore)
+	}()
+
+	// Worker tạo giao dịch định kỳ (tiền nhà, lương...) khi đến hạn
+	workers.Add(1)
+	go func() {
+		defer workers.Done()
+		service.StartRecurringWorker(ctx, dataStore)
+	}()
 
 	// 4. Start Server
 	port := os.Getenv("PORT")
 	if port == "" {
 		port = "8080"
 	}
-	fmt.Println("Server running on port " + port)
-	http.ListenAndServe(":"+port, enableCORS(mux))
+	srv := &http.Server{Addr: ":" + port, Handler: enableCORS(mux)}
+	go func() {
+		fmt.Println("Server running on port " + port)
+		if err := srv.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			log.Fatal("Server failed:", err)
+		}
+	}()
+
+	<-ctx.Done()
+	log.Println("[API INFO] Shutting down...")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := srv.Shutdown(shutdownCtx); err != nil {
+		log.Printf("[API ERROR] Shutdown failed: %v", err)
+	}
+	workers.Wait()
+}
+
+// connectDB mở kết nối Postgres từ DATABASE_URL, dừng chương trình nếu lỗi
+func connectDB() *sql.DB {
+	connStr := os.Getenv("DATABASE_URL")
+	if connStr == "" {
+		log.Fatal("DATABASE_URL is required (or set DEMO_MODE=true)")
+	}
+	db, err := sql.Open("postgres", connStr)
+	if err != nil {
+		log.Fatal(err)
+	}
+
+	if err := db.Ping(); err != nil {
+		log.Fatal("Cannot connect to DB:", err)
+	}
+	fmt.Println("Connected to Database successfully!")
+	return db
 }
 
 func enableCORS(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		w.Header().Set("Access-Control-Allow-Origin", "*")
-		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
+		w.Header().Set("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, PATCH, DELETE")
 		w.Header().Set("Access-Control-Allow-Headers", "Accept, Content-Type, Content-Length, Accept-Encoding, X-CSRF-Token, Authorization")
 
 		if r.Method == "OPTIONS" {
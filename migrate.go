@@ -0,0 +1,72 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"go-finance/internal/store"
+	"log"
+	"strconv"
+)
+
+// runMigrate xử lý subcommand: ./main migrate [up|down [n]|status]
+func runMigrate(args []string) {
+	db := connectDB()
+	defer db.Close()
+
+	m, err := store.NewMigrator(db)
+	if err != nil {
+		log.Fatal("Failed to load migrations:", err)
+	}
+
+	cmd := "up"
+	if len(args) > 0 {
+		cmd = args[0]
+	}
+	ctx := context.Background()
+
+	switch cmd {
+	case "up":
+		done, err := m.Up(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		if len(done) == 0 {
+			fmt.Println("Schema đã ở phiên bản mới nhất.")
+		}
+		for _, mig := range done {
+			fmt.Printf("Applied   %04d_%s\n", mig.Version, mig.Name)
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				log.Fatal("Số bước rollback không hợp lệ: ", args[1])
+			}
+		}
+		done, err := m.Down(ctx, steps)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, mig := range done {
+			fmt.Printf("Rolled back %04d_%s\n", mig.Version, mig.Name)
+		}
+
+	case "status":
+		statuses, err := m.Status(ctx)
+		if err != nil {
+			log.Fatal(err)
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.Applied {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%-30s %s\n", s.Version, s.Name, state)
+		}
+
+	default:
+		log.Fatalf("Lệnh migrate không hợp lệ: %q (dùng: up | down [n] | status)", cmd)
+	}
+}
@@ -0,0 +1,106 @@
+package tests
+
+import (
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParseAlertText(t *testing.T) {
+	tests := []struct {
+		input     string
+		ok        bool
+		asset     string
+		condition string
+		threshold string
+		unit      string
+		repeat    bool
+	}{
+		{"báo khi vàng SJC > 90 triệu", true, "GOLD", model.AlertAbove, "90000000", "luong", false},
+		{"Báo khi giá vàng vượt 90tr", true, "GOLD", model.AlertAbove, "90000000", "luong", false},
+		{"báo khi vàng > 9tr/chỉ", true, "GOLD", model.AlertAbove, "9000000", "chi", false},
+		{"bao khi usd < 25000", true, "USD", model.AlertBelow, "25000", "", false},
+		{"báo khi đô dưới 25,5k lặp lại", true, "USD", model.AlertBelow, "25500", "", true},
+		{"báo khi btc biến động 5% lặp lại", true, "BTC", model.AlertChange, "5", "", true},
+		{"báo khi vàng thay đổi 2,5%", true, "GOLD", model.AlertChange, "2.5", "", false},
+		{"báo khi bạc >= 1.2m", true, "SILVER", model.AlertAbove, "1200000", "luong", false},
+		{"báo khi btc > 5%", false, "", "", "", "", false},
+		{"báo khi btc biến động 5", false, "", "", "", "", false},
+		{"báo khi usd > 25000/lượng", false, "", "", "", "", false},
+		{"báo khi vàng > 9tr/thùng", false, "", "", "", "", false},
+		{"báo khi xyz > 100", false, "", "", "", "", false},
+		{"báo khi vàng > 0", false, "", "", "", "", false},
+		{"báo cáo tháng này", false, "", "", "", "", false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := service.ParseAlertText(tt.input)
+			require.Equal(t, tt.ok, ok)
+			if !tt.ok {
+				return
+			}
+			assert.Equal(t, tt.asset, got.Asset)
+			assert.Equal(t, tt.condition, got.Condition)
+			assertDecEqual(t, tt.threshold, got.Threshold)
+			assert.Equal(t, tt.unit, got.Unit)
+			assert.Equal(t, tt.repeat, got.Repeat)
+		})
+	}
+}
+
+func TestCheckAlert(t *testing.T) {
+	now := time.Date(2025, 5, 14, 10, 0, 0, 0, time.UTC)
+	above := model.PriceAlert{ID: 1, UserID: "42", Asset: "GOLD", Condition: model.AlertAbove, Threshold: dec("90000000"), Unit: "luong", Active: true}
+
+	// Lần đầu đã vượt mốc thì báo ngay, báo 1 lần thì tự tắt
+	got, n := service.CheckAlert(above, dec("91000000"), now)
+	require.NotNil(t, n)
+	assert.Equal(t, 1, n.AlertID)
+	assertDecEqual(t, "91000000", n.Price)
+	assert.False(t, got.Active)
+	require.NotNil(t, got.LastTriggeredAt)
+	assertDecEqual(t, "91000000", got.LastPrice)
+
+	// Đã tắt thì không báo nữa
+	_, n = service.CheckAlert(got, dec("95000000"), now)
+	assert.Nil(t, n)
+
+	// Lặp lại: chỉ báo khi giá vượt mốc từ phía dưới lên
+	above.Repeat = true
+	steps := []struct {
+		price string
+		fire  bool
+	}{
+		{"88000000", false}, {"90000000", true}, {"92000000", false}, {"89000000", false}, {"90500000", true},
+	}
+	for _, s := range steps {
+		var n *model.AlertNotification
+		above, n = service.CheckAlert(above, dec(s.price), now)
+		assert.Equal(t, s.fire, n != nil, "giá %s", s.price)
+		assert.True(t, above.Active)
+	}
+
+	below := model.PriceAlert{ID: 2, Asset: "USD", Condition: model.AlertBelow, Threshold: dec("25000"), Active: true, LastPrice: dec("25400")}
+	below, n = service.CheckAlert(below, dec("25100"), now)
+	assert.Nil(t, n)
+	_, n = service.CheckAlert(below, dec("24990"), now)
+	assert.NotNil(t, n)
+
+	// Biến động %: chưa có giá gốc thì lấy giá đầu tiên, báo rồi tính lại từ giá lúc báo
+	change := model.PriceAlert{ID: 3, Asset: "BTC", Condition: model.AlertChange, Threshold: dec("5"), Repeat: true, Active: true}
+	change, n = service.CheckAlert(change, dec("2000000000"), now)
+	assert.Nil(t, n)
+	assertDecEqual(t, "2000000000", change.BasePrice)
+	change, n = service.CheckAlert(change, dec("2090000000"), now)
+	assert.Nil(t, n)
+	change, n = service.CheckAlert(change, dec("1890000000"), now)
+	require.NotNil(t, n)
+	assertDecEqual(t, "2000000000", n.BasePrice)
+	assertDecEqual(t, "1890000000", change.BasePrice)
+	assert.True(t, change.Active)
+}
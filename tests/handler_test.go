@@ -0,0 +1,976 @@
+package tests
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"go-finance/internal/handler"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"go-finance/internal/store"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestServer dựng router giống main.go nhưng dùng MemoryStore
+func newTestServer(t *testing.T) (*httptest.Server, *store.MemoryStore) {
+	s := store.NewMemoryStore()
+	h := handler.NewFinanceHandler(s)
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("POST /transactions", h.CreateTransaction)
+	mux.HandleFunc("GET /transactions", h.ListTransactions)
+	mux.HandleFunc("GET /transactions/{id}", h.GetTransaction)
+	mux.HandleFunc("PATCH /transactions/{id}", h.UpdateTransaction)
+	mux.HandleFunc("DELETE /transactions/{id}", h.DeleteTransaction)
+	mux.HandleFunc("DELETE /transactions/last", h.DeleteLastTransaction)
+	mux.HandleFunc("GET /report", h.GenerateReport)
+	mux.HandleFunc("GET /market-rates", h.GetPrices)
+	mux.HandleFunc("GET /market-rates/history", h.GetRateHistory)
+	mux.HandleFunc("GET /market-rates/providers", h.GetRateProviders)
+	mux.HandleFunc("GET /assets", h.ListAssets)
+	mux.HandleFunc("GET /users", h.GetUsers)
+	mux.HandleFunc("GET /users/settings", h.ListSettings)
+	mux.HandleFunc("GET /users/{id}/settings", h.GetSettings)
+	mux.HandleFunc("PUT /users/{id}/settings", h.UpdateSettings)
+	mux.HandleFunc("POST /budgets", h.CreateBudget)
+	mux.HandleFunc("GET /budgets", h.ListBudgets)
+	mux.HandleFunc("GET /budgets/status", h.GetBudgetStatus)
+	mux.HandleFunc("GET /budgets/{id}", h.GetBudget)
+	mux.HandleFunc("PUT /budgets/{id}", h.UpdateBudget)
+	mux.HandleFunc("DELETE /budgets/{id}", h.DeleteBudget)
+	mux.HandleFunc("POST /recurring", h.CreateRecurring)
+	mux.HandleFunc("GET /recurring", h.ListRecurring)
+	mux.HandleFunc("PATCH /recurring/{id}", h.UpdateRecurring)
+	mux.HandleFunc("DELETE /recurring/{id}", h.DeleteRecurring)
+	mux.HandleFunc("POST /accounts", h.CreateAccount)
+	mux.HandleFunc("GET /accounts", h.ListAccounts)
+	mux.HandleFunc("PUT /accounts/{id}", h.UpdateAccount)
+	mux.HandleFunc("DELETE /accounts/{id}", h.DeleteAccount)
+	mux.HandleFunc("GET /portfolio", h.GetPortfolio)
+	mux.HandleFunc("POST /alerts", h.CreateAlert)
+	mux.HandleFunc("GET /alerts", h.ListAlerts)
+	mux.HandleFunc("DELETE /alerts/{id}", h.DeleteAlert)
+	mux.HandleFunc("GET /alerts/notifications", h.ListAlertNotifications)
+	mux.HandleFunc("POST /alerts/notifications/{id}/ack", h.AckAlertNotification)
+
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+	return srv, s
+}
+
+func postTransaction(t *testing.T, srv *httptest.Server, req model.TransactionCreate) *http.Response {
+	data, err := json.Marshal(req)
+	require.NoError(t, err)
+	resp, err := http.Post(srv.URL+"/transactions", "application/json", bytes.NewBuffer(data))
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+// doJSON gửi request với method bất kỳ, body được encode JSON nếu khác nil
+func doJSON(t *testing.T, method, url string, body interface{}) *http.Response {
+	var buf bytes.Buffer
+	if body != nil {
+		require.NoError(t, json.NewEncoder(&buf).Encode(body))
+	}
+	req, err := http.NewRequest(method, url, &buf)
+	require.NoError(t, err)
+	resp, err := http.DefaultClient.Do(req)
+	require.NoError(t, err)
+	t.Cleanup(func() { resp.Body.Close() })
+	return resp
+}
+
+func createdID(t *testing.T, resp *http.Response) int {
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var out struct {
+		ID int `json:"id"`
+	}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&out))
+	require.NotZero(t, out.ID)
+	return out.ID
+}
+
+func TestCreateTransactionAndReport(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	txs := []model.TransactionCreate{
+		{UserID: "42", Type: "thu", Amount: dec("10000000"), Note: "lương", Currency: "VND"},
+		{UserID: "42", Type: "chi", Amount: dec("50000"), Note: "cafe", Currency: "VND", Category: "ăn uống"},
+		{UserID: "42", Type: "chi", Amount: dec("30000"), Note: "phở", Currency: "VND", Category: "ăn uống"},
+		{UserID: "42", Type: "tiet_kiem", Amount: dec("2000000"), Currency: "VND"},
+	}
+	for _, tx := range txs {
+		resp := postTransaction(t, srv, tx)
+		assert.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err := http.Get(srv.URL + "/report?user_id=42&period=month")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assertDecEqual(t, "10000000", report.TotalIncome)
+	assertDecEqual(t, "80000", report.TotalExpense)
+	assertDecEqual(t, "80000", report.ExpenseByCategory["ăn uống"])
+	assertDecEqual(t, "2000000", report.TotalSavingsVND)
+	assertDecEqual(t, "7920000", report.Balance)
+}
+
+func TestReportTotalsEqualSumOfStoredRows(t *testing.T) {
+	srv, s := newTestServer(t)
+
+	// Các số này cộng bằng float64 sẽ lệch (0.1 + 0.2 != 0.3)
+	for _, a := range []string{"0.1", "0.2", "1000000.7", "999999999999.99", "12345.678"} {
+		resp := postTransaction(t, srv, model.TransactionCreate{UserID: "7", Type: "chi", Amount: dec(a), Note: "x", Currency: "VND"})
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+	}
+
+	resp, err := http.Get(srv.URL + "/report?user_id=7&period=month")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+
+	rows, err := s.GetByPeriod("7", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	sum := decimal.Zero
+	for _, r := range rows {
+		sum = sum.Add(r.Amount)
+	}
+	assert.True(t, sum.Equal(report.TotalExpense), "tổng %s != báo cáo %s", sum, report.TotalExpense)
+	assertDecEqual(t, "1000001012346.668", report.TotalExpense)
+}
+
+func TestCreateTransactionInvalidJSON(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	resp, err := http.Post(srv.URL+"/transactions", "application/json", bytes.NewBufferString("{invalid"))
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestGetUsers(t *testing.T) {
+	srv, _ := newTestServer(t)
+	postTransaction(t, srv, model.TransactionCreate{UserID: "1", Type: "thu", Amount: dec("1"), Note: "a", Currency: "VND"})
+	postTransaction(t, srv, model.TransactionCreate{UserID: "2", Type: "thu", Amount: dec("1"), Note: "b", Currency: "VND"})
+
+	resp, err := http.Get(srv.URL + "/users")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	var ids []string
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&ids))
+	assert.ElementsMatch(t, []string{"1", "2"}, ids)
+}
+
+func TestEditAndDeleteTransaction(t *testing.T) {
+	srv, _ := newTestServer(t)
+	id := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("500000"), Note: "cafe", Currency: "VND", AmountExpr: "2x250k"}))
+	txURL := fmt.Sprintf("%s/transactions/%d", srv.URL, id)
+
+	// Người khác không được xem/sửa/xóa
+	assert.Equal(t, http.StatusForbidden, doJSON(t, http.MethodGet, txURL+"?user_id=99", nil).StatusCode)
+	newNote := "hack"
+	assert.Equal(t, http.StatusForbidden, doJSON(t, http.MethodPatch, txURL, model.TransactionUpdate{UserID: "99", Note: &newNote}).StatusCode)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, http.MethodDelete, txURL+"?user_id=99", nil).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodGet, txURL, nil).StatusCode)
+
+	// Biểu thức gốc của số tiền được lưu để đối chiếu
+	resp := doJSON(t, http.MethodGet, txURL+"?user_id=42", nil)
+	var created model.Transaction
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&created))
+	assert.Equal(t, "2x250k", created.AmountExpr)
+
+	// Chủ sở hữu sửa số tiền gõ nhầm, biểu thức cũ không còn đúng nên bị xóa
+	amount := dec("50000")
+	resp = doJSON(t, http.MethodPatch, txURL, model.TransactionUpdate{UserID: "42", Amount: &amount})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var updated model.Transaction
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&updated))
+	assertDecEqual(t, "50000", updated.Amount)
+	assert.Equal(t, "cafe", updated.Note)
+	assert.Empty(t, updated.AmountExpr)
+
+	resp = doJSON(t, http.MethodGet, txURL+"?user_id=42", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var got model.Transaction
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&got))
+	assertDecEqual(t, "50000", got.Amount)
+
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodDelete, txURL+"?user_id=42", nil).StatusCode)
+	assert.Equal(t, http.StatusNotFound, doJSON(t, http.MethodGet, txURL+"?user_id=42", nil).StatusCode)
+}
+
+func TestUndoLastTransaction(t *testing.T) {
+	srv, _ := newTestServer(t)
+	undoURL := srv.URL + "/transactions/last?user_id=42"
+
+	assert.Equal(t, http.StatusNotFound, doJSON(t, http.MethodDelete, undoURL, nil).StatusCode)
+
+	first := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("1000"), Note: "a", Currency: "VND"}))
+	second := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("2000"), Note: "b", Currency: "VND"}))
+
+	resp := doJSON(t, http.MethodDelete, undoURL, nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var deleted model.Transaction
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deleted))
+	assert.Equal(t, second, deleted.ID)
+
+	resp = doJSON(t, http.MethodDelete, undoURL, nil)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deleted))
+	assert.Equal(t, first, deleted.ID)
+}
+
+func TestListTransactions(t *testing.T) {
+	srv, _ := newTestServer(t)
+	for _, note := range []string{"cafe sáng", "phở bò", "cafe chiều"} {
+		postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("30000"), Note: note, Currency: "VND"})
+	}
+
+	resp := doJSON(t, http.MethodGet, srv.URL+"/transactions?user_id=42&q=cafe&limit=1&sort=asc", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var page model.TransactionPage
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "cafe sáng", page.Items[0].Note)
+	require.NotEmpty(t, page.NextCursor)
+
+	resp = doJSON(t, http.MethodGet, srv.URL+"/transactions?user_id=42&q=cafe&limit=1&sort=asc&cursor="+page.NextCursor, nil)
+	page = model.TransactionPage{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&page))
+	require.Len(t, page.Items, 1)
+	assert.Equal(t, "cafe chiều", page.Items[0].Note)
+	assert.Empty(t, page.NextCursor)
+
+	for _, bad := range []string{"", "user_id=42&from=15-03-2025", "user_id=42&min_amount=abc", "user_id=42&sort=up", "user_id=42&cursor=@@"} {
+		assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodGet, srv.URL+"/transactions?"+bad, nil).StatusCode, bad)
+	}
+}
+
+func TestGenerateReportPeriods(t *testing.T) {
+	srv, _ := newTestServer(t)
+	postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("50000"), Note: "cafe", Currency: "VND"})
+
+	getReport := func(query string) (int, model.ReportOutput) {
+		resp := doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42&"+query, nil)
+		var report model.ReportOutput
+		if resp.StatusCode == http.StatusOK {
+			require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+		}
+		return resp.StatusCode, report
+	}
+
+	for _, period := range []string{"day", "week", "month", "quarter", "year"} {
+		status, report := getReport("period=" + period)
+		require.Equal(t, http.StatusOK, status, period)
+		assertDecEqual(t, "50000", report.TotalExpense)
+	}
+
+	// Tháng trước chưa có giao dịch nào
+	status, report := getReport("period=month&offset=-1")
+	require.Equal(t, http.StatusOK, status)
+	assert.True(t, report.TotalExpense.IsZero())
+	assert.Equal(t, time.Now().AddDate(0, 0, -time.Now().Day()).Format("2006-01-02"), report.EndDate)
+
+	today := time.Now().Format("2006-01-02")
+	status, report = getReport("from=" + today + "&to=" + today)
+	require.Equal(t, http.StatusOK, status)
+	assert.Equal(t, "custom", report.Period)
+	assertDecEqual(t, "50000", report.TotalExpense)
+
+	for _, bad := range []string{"period=decade", "period=month&offset=x", "from=2025-13-01", "from=2025-03-10&to=2025-03-01"} {
+		status, _ := getReport(bad)
+		assert.Equal(t, http.StatusBadRequest, status, bad)
+	}
+}
+
+func TestUserSettings(t *testing.T) {
+	srv, _ := newTestServer(t)
+	postTransaction(t, srv, model.TransactionCreate{UserID: "1", Type: "thu", Amount: dec("1"), Note: "a", Currency: "VND"})
+
+	resp := doJSON(t, http.MethodPut, srv.URL+"/users/2/settings", map[string]string{"timezone": "Europe/Berlin"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var st model.UserSettings
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+	assert.Equal(t, "Europe/Berlin", st.Timezone)
+	assert.Equal(t, time.Monday, st.WeekStart, "trường không gửi phải giữ nguyên")
+
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodPut, srv.URL+"/users/2/settings", map[string]string{"timezone": "Mars/Olympus"}).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodPut, srv.URL+"/users/2/settings", map[string]int{"week_start": 9}).StatusCode)
+
+	// Danh sách cho scheduler gồm cả user chỉ có giao dịch (cài đặt mặc định)
+	resp = doJSON(t, http.MethodGet, srv.URL+"/users/settings", nil)
+	var all []model.UserSettings
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&all))
+	assert.ElementsMatch(t, []model.UserSettings{
+		model.DefaultUserSettings("1"),
+		{UserID: "2", Timezone: "Europe/Berlin", WeekStart: time.Monday, CostBasis: model.CostBasisAverage,
+			GoldUnit: model.DefaultGoldUnit, SilverUnit: model.DefaultSilverUnit},
+	}, all)
+}
+
+func TestMetalUnits(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	// Đơn vị hiển thị nhận cách viết tiếng Việt, lưu theo mã
+	resp := doJSON(t, http.MethodPut, srv.URL+"/users/42/settings", map[string]string{"gold_unit": "Lượng"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var st model.UserSettings
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&st))
+	assert.Equal(t, "luong", st.GoldUnit)
+	assert.Equal(t, model.DefaultSilverUnit, st.SilverUnit)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodPut, srv.URL+"/users/42/settings", map[string]string{"silver_unit": "pound"}).StatusCode)
+
+	// 7.5g vàng lưu thành 2 chỉ
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("7.5"), Unit: "g", Currency: "GOLD"}).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("1"), Unit: "g", Currency: "USD"}).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("1"), Unit: "pound", Currency: "GOLD"}).StatusCode)
+
+	resp = doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42&period=month", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	rate := decimal.NewFromFloat(service.GetCurrentRates().VnSJC)
+	gold := report.Holdings["GOLD"]
+	assertDecEqual(t, "2", gold.Quantity)
+	assert.Equal(t, "lượng", gold.DisplayUnit)
+	assertDecEqual(t, "0.2", gold.DisplayQuantity)
+	assert.True(t, rate.Mul(dec("10")).Equal(gold.DisplayRate))
+	assert.True(t, gold.CurrentVND.Equal(gold.DisplayQuantity.Mul(gold.DisplayRate)))
+	assert.Equal(t, "lượng", report.Assets["GOLD"].DisplayUnit)
+
+	resp = doJSON(t, http.MethodGet, srv.URL+"/portfolio?user_id=42", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var p model.Portfolio
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	require.Len(t, p.Holdings, 1)
+	assertDecEqual(t, "0.2", p.Holdings[0].DisplayQuantity)
+	assert.True(t, rate.Mul(dec("10")).Equal(p.Holdings[0].DisplayAvgPrice))
+}
+
+func TestReportUsesUserTimezone(t *testing.T) {
+	srv, _ := newTestServer(t)
+	doJSON(t, http.MethodPut, srv.URL+"/users/42/settings", map[string]interface{}{"timezone": "America/New_York", "week_start": 0})
+
+	resp := doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42&period=week", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+
+	ny, err := time.LoadLocation("America/New_York")
+	require.NoError(t, err)
+	now := time.Now().In(ny)
+	sunday := now.AddDate(0, 0, -int(now.Weekday()))
+	assert.Equal(t, "America/New_York", report.Timezone)
+	assert.Equal(t, sunday.Format("2006-01-02"), report.StartDate)
+	assert.Equal(t, sunday.AddDate(0, 0, 6).Format("2006-01-02"), report.EndDate)
+}
+
+func TestBudgets(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	resp := doJSON(t, http.MethodPost, srv.URL+"/budgets", model.Budget{UserID: "42", Category: "Ăn uống", Period: "month", Limit: dec("100000")})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var b model.Budget
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&b))
+	assert.Equal(t, "ăn uống", b.Category)
+
+	for _, bad := range []model.Budget{
+		{Category: "ăn uống", Period: "month", Limit: dec("1")},
+		{UserID: "42", Category: "ăn uống", Period: "day", Limit: dec("1")},
+		{UserID: "42", Category: "ăn uống", Period: "month", Limit: dec("0")},
+	} {
+		assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodPost, srv.URL+"/budgets", bad).StatusCode)
+	}
+
+	postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("85000"), Note: "phở", Currency: "VND", Category: "ăn uống"})
+	postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("40000"), Note: "xăng", Currency: "VND", Category: "sinh hoạt"})
+
+	resp = doJSON(t, http.MethodGet, srv.URL+"/budgets/status?user_id=42&category=ăn+uống", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var statuses []model.BudgetStatus
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&statuses))
+	require.Len(t, statuses, 1)
+	assertDecEqual(t, "85000", statuses[0].Spent)
+	assertDecEqual(t, "15000", statuses[0].Remaining)
+	assertDecEqual(t, "85", statuses[0].Percent)
+
+	// Báo cáo tháng có mục ngân sách, báo cáo ngày thì không
+	resp = doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42&period=month", nil)
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	require.Len(t, report.Budgets, 1)
+	assertDecEqual(t, "85000", report.Budgets[0].Spent)
+	resp = doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42&period=day", nil)
+	report = model.ReportOutput{}
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Empty(t, report.Budgets)
+
+	budgetURL := fmt.Sprintf("%s/budgets/%d", srv.URL, b.ID)
+	assert.Equal(t, http.StatusForbidden, doJSON(t, http.MethodGet, budgetURL+"?user_id=99", nil).StatusCode)
+	resp = doJSON(t, http.MethodPut, budgetURL, map[string]string{"user_id": "42", "limit": "80000"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&b))
+	assertDecEqual(t, "80000", b.Limit)
+	assert.Equal(t, "month", b.Period)
+
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodDelete, budgetURL+"?user_id=42", nil).StatusCode)
+	assert.Equal(t, http.StatusNotFound, doJSON(t, http.MethodGet, budgetURL+"?user_id=42", nil).StatusCode)
+}
+
+func TestRecurringRules(t *testing.T) {
+	srv, memStore := newTestServer(t)
+
+	resp := doJSON(t, http.MethodPost, srv.URL+"/recurring", model.RecurringRule{UserID: "42", Type: "chi", Amount: dec("5000000"), Note: "tiền nhà",
+		Recurrence: model.Recurrence{Frequency: "monthly", Day: 1}})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rule model.RecurringRule
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rule))
+	assert.Equal(t, model.DefaultTimezone, rule.Timezone)
+	assert.Equal(t, 1, rule.NextRun.In(rule.Location()).Day())
+	assert.True(t, rule.NextRun.After(time.Now()))
+
+	for _, bad := range []model.RecurringRule{
+		{Type: "chi", Amount: dec("1"), Recurrence: model.Recurrence{Frequency: "daily"}},
+		{UserID: "42", Type: "chi", Amount: dec("0"), Recurrence: model.Recurrence{Frequency: "daily"}},
+		{UserID: "42", Type: "chi", Amount: dec("1"), Recurrence: model.Recurrence{Frequency: "yearly"}},
+		{UserID: "42", Type: "chi", Amount: dec("1"), Recurrence: model.Recurrence{Frequency: "weekly", Day: 8}},
+	} {
+		assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodPost, srv.URL+"/recurring", bad).StatusCode)
+	}
+
+	ruleURL := fmt.Sprintf("%s/recurring/%d", srv.URL, rule.ID)
+	paused := true
+	assert.Equal(t, http.StatusForbidden, doJSON(t, http.MethodPatch, ruleURL, model.RecurringRuleUpdate{UserID: "99", Paused: &paused}).StatusCode)
+	resp = doJSON(t, http.MethodPatch, ruleURL, model.RecurringRuleUpdate{UserID: "42", Paused: &paused})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rule))
+	assert.True(t, rule.Paused)
+
+	// Tạm dừng thì worker không tạo giao dịch dù đã quá hạn
+	_, err := memStore.MaterializeDue(rule.NextRun.AddDate(0, 2, 0), service.NextOccurrence)
+	require.NoError(t, err)
+	txs, err := memStore.GetByPeriod("42", time.Time{}, time.Time{})
+	require.NoError(t, err)
+	assert.Empty(t, txs)
+
+	resp = doJSON(t, http.MethodGet, srv.URL+"/recurring?user_id=42", nil)
+	var rules []model.RecurringRule
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rules))
+	require.Len(t, rules, 1)
+
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodDelete, ruleURL+"?user_id=42", nil).StatusCode)
+	assert.Equal(t, http.StatusNotFound, doJSON(t, http.MethodDelete, ruleURL+"?user_id=42", nil).StatusCode)
+}
+
+func TestAccountsAndTransfers(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	for _, a := range []model.Account{
+		{UserID: "42", Name: "@Momo", OpeningBalance: dec("500000")},
+		{UserID: "42", Name: "vcb", Currency: "VND", OpeningBalance: dec("10000000")},
+		{UserID: "42", Name: "binance", Currency: "BTC"},
+	} {
+		require.Equal(t, http.StatusOK, doJSON(t, http.MethodPost, srv.URL+"/accounts", a).StatusCode)
+	}
+	assert.Equal(t, http.StatusConflict, doJSON(t, http.MethodPost, srv.URL+"/accounts", model.Account{UserID: "42", Name: "momo"}).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodPost, srv.URL+"/accounts", model.Account{UserID: "42", Name: "ví", Currency: "XYZ"}).StatusCode)
+
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "thu", Amount: dec("15000000"), Note: "lương", Currency: "VND", Account: "vcb"}).StatusCode)
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("50000"), Note: "cafe", Currency: "VND", Account: "momo"}).StatusCode)
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chuyen", Amount: dec("2000000"), Currency: "VND", Account: "vcb", ToAccount: "momo"}).StatusCode)
+
+	for _, bad := range []model.TransactionCreate{
+		{UserID: "42", Type: "chi", Amount: dec("1"), Note: "x", Currency: "VND", Account: "zalopay"},
+		{UserID: "42", Type: "chuyen", Amount: dec("1"), Currency: "VND", Account: "vcb"},
+		{UserID: "42", Type: "chuyen", Amount: dec("1"), Currency: "VND", Account: "vcb", ToAccount: "vcb"},
+		{UserID: "42", Type: "chi", Amount: dec("1"), Note: "x", Currency: "VND", Account: "binance"}, // ví BTC không nhận VND
+	} {
+		assert.Equal(t, http.StatusBadRequest, postTransaction(t, srv, bad).StatusCode, "%+v", bad)
+	}
+
+	resp := doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42&period=month", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assertDecEqual(t, "15000000", report.TotalIncome)
+	assertDecEqual(t, "50000", report.TotalExpense) // Chuyển khoản không tính là chi
+
+	balances := make(map[string]decimal.Decimal)
+	for _, ab := range report.Accounts {
+		balances[ab.Account.Name] = ab.Balance
+	}
+	require.Len(t, balances, 3)
+	assertDecEqual(t, "2450000", balances["momo"])
+	assertDecEqual(t, "23000000", balances["vcb"])
+	assertDecEqual(t, "0", balances["binance"])
+
+	// Tài khoản còn giao dịch thì không xóa được
+	var accounts []model.AccountBalance
+	require.NoError(t, json.NewDecoder(doJSON(t, http.MethodGet, srv.URL+"/accounts?user_id=42", nil).Body).Decode(&accounts))
+	require.Len(t, accounts, 3)
+	assert.Equal(t, http.StatusConflict, doJSON(t, http.MethodDelete, fmt.Sprintf("%s/accounts/%d?user_id=42", srv.URL, accounts[0].Account.ID), nil).StatusCode)
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodDelete, fmt.Sprintf("%s/accounts/%d?user_id=42", srv.URL, accounts[2].Account.ID), nil).StatusCode)
+}
+
+func TestWithdrawSavings(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("2"), Currency: "GOLD"}).StatusCode)
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("2"), Currency: "GOLD"}).StatusCode)
+
+	// Rút quá số đang giữ
+	resp := postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "rut", Amount: dec("5"), Currency: "GOLD"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	resp = postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "rut", Amount: dec("1"), Currency: "BTC"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	resp = postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "rut", Amount: dec("3"), Currency: "GOLD"})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	id := createdID(t, resp)
+
+	// Sửa thành rút 4 chỉ vẫn hợp lệ vì không tính chính giao dịch đang sửa, 5 chỉ thì không
+	amount := dec("4")
+	assert.Equal(t, http.StatusOK, doJSON(t, http.MethodPatch, fmt.Sprintf("%s/transactions/%d", srv.URL, id), model.TransactionUpdate{UserID: "42", Amount: &amount}).StatusCode)
+	amount = dec("5")
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodPatch, fmt.Sprintf("%s/transactions/%d", srv.URL, id), model.TransactionUpdate{UserID: "42", Amount: &amount}).StatusCode)
+	amount = dec("3")
+	require.Equal(t, http.StatusOK, doJSON(t, http.MethodPatch, fmt.Sprintf("%s/transactions/%d", srv.URL, id), model.TransactionUpdate{UserID: "42", Amount: &amount}).StatusCode)
+
+	require.Equal(t, http.StatusOK, doJSON(t, http.MethodPut, srv.URL+"/users/42/settings", map[string]string{"cost_basis": "fifo"}).StatusCode)
+
+	resp = doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42&period=month", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, model.CostBasisFIFO, report.CostBasis)
+	assertDecEqual(t, "1", report.Assets["GOLD"].Quantity)
+	require.Contains(t, report.Holdings, "GOLD")
+	assertDecEqual(t, "1", report.Holdings["GOLD"].Quantity)
+}
+
+func TestPortfolio(t *testing.T) {
+	srv, s := newTestServer(t)
+
+	// Giá 2 ngày trước: vàng 8tr/chỉ. Chưa có giá từ 1 tuần trước
+	past := service.GetCurrentRates()
+	past.VnSJC = 8000000
+	_, err := s.SaveRates(time.Now().Add(-48*time.Hour), past)
+	require.NoError(t, err)
+
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("2"), Currency: "GOLD"}).StatusCode)
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("3000000"), Currency: "VND"}).StatusCode)
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "rut", Amount: dec("1"), Currency: "GOLD"}).StatusCode)
+
+	assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodGet, srv.URL+"/portfolio", nil).StatusCode)
+	resp := doJSON(t, http.MethodGet, srv.URL+"/portfolio?user_id=42", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var p model.Portfolio
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+
+	rate := decimal.NewFromFloat(service.GetCurrentRates().VnSJC)
+	require.Len(t, p.Holdings, 2)
+	gold, cash := p.Holdings[0], p.Holdings[1]
+	assert.Equal(t, "GOLD", gold.Currency)
+	assertDecEqual(t, "1", gold.Quantity)
+	assert.True(t, rate.Equal(gold.AvgPriceVND))
+	assert.True(t, rate.Equal(gold.CurrentVND))
+	assertDecEqual(t, "0", gold.UnrealizedPL)
+	assert.Equal(t, "VND", cash.Currency)
+	assertDecEqual(t, "3000000", cash.CurrentVND)
+	assert.True(t, rate.Add(dec("3000000")).Equal(p.TotalCurrentVND))
+
+	require.Contains(t, gold.Changes, "day")
+	assertDecEqual(t, "8000000", gold.Changes["day"].Rate)
+	assert.True(t, rate.Sub(dec("8000000")).Equal(gold.Changes["day"].ChangeVND))
+	assert.NotContains(t, gold.Changes, "week")
+	assert.NotContains(t, p.Changes, "month")
+	assert.True(t, gold.Changes["day"].ChangeVND.Equal(p.Changes["day"].ChangeVND))
+}
+
+func TestRateHistory(t *testing.T) {
+	srv, s := newTestServer(t)
+
+	loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	require.NoError(t, err)
+	for _, p := range []struct {
+		at  time.Time
+		sjc float64
+	}{
+		{time.Date(2025, 5, 14, 8, 0, 0, 0, loc), 8000000},
+		{time.Date(2025, 5, 14, 12, 0, 0, 0, loc), 8300000},
+		{time.Date(2025, 5, 14, 20, 0, 0, 0, loc), 8100000},
+		{time.Date(2025, 5, 15, 0, 30, 0, 0, loc), 8200000}, // 17h30 ngày 14 giờ UTC nhưng là ngày 15 giờ VN
+		{time.Date(2025, 5, 15, 9, 0, 0, 0, loc), 0},        // Nguồn giá vàng lỗi lần này
+	} {
+		_, err := s.SaveRates(p.at, model.ExchangeRates{UsdVND: 25400, VnSJC: p.sjc})
+		require.NoError(t, err)
+	}
+
+	resp := doJSON(t, http.MethodGet, srv.URL+"/market-rates/history?asset=gold&from=2025-05-14&to=2025-05-15", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var points []model.RatePoint
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&points))
+	require.Len(t, points, 2)
+	assert.True(t, time.Date(2025, 5, 14, 0, 0, 0, 0, loc).Equal(points[0].Time))
+	assertDecEqual(t, "8000000", points[0].Open)
+	assertDecEqual(t, "8300000", points[0].High)
+	assertDecEqual(t, "8000000", points[0].Low)
+	assertDecEqual(t, "8100000", points[0].Close)
+	assert.Equal(t, 3, points[0].Samples)
+	assertDecEqual(t, "8200000", points[1].Close)
+	assert.Equal(t, 1, points[1].Samples)
+
+	resp = doJSON(t, http.MethodGet, srv.URL+"/market-rates/history?asset=USD&from=2025-05-14&to=2025-05-15&interval=raw", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&points))
+	assert.Len(t, points, 5)
+
+	for _, q := range []string{"asset=XYZ", "asset=VND", "asset=GOLD&interval=minute", "asset=GOLD&from=2025-05-15&to=2025-05-14", "asset=GOLD&from=yesterday",
+		"asset=GOLD&from=2024-05-14&to=2025-05-15", "asset=GOLD&from=2025-05-01&to=2025-05-15&interval=raw", "asset=GOLD&from=2025-01-01"} {
+		assert.Equal(t, http.StatusBadRequest, doJSON(t, http.MethodGet, srv.URL+"/market-rates/history?"+q, nil).StatusCode, q)
+	}
+}
+
+func TestRatesStaleWithFallbackValues(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	// Worker giá không chạy trong test nên mọi giá đều là giá mặc định
+	resp := doJSON(t, http.MethodGet, srv.URL+"/market-rates", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var rates model.ExchangeRates
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&rates))
+	assert.True(t, rates.Stale)
+	require.Contains(t, rates.Sources, "BTC")
+	assert.Equal(t, model.RateSourceDefault, rates.Sources["BTC"].Source)
+	assert.True(t, rates.Sources["BTC"].Stale)
+	assert.Nil(t, rates.Sources["BTC"].FetchedAt)
+
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "thu", Amount: dec("1000000"), Note: "lương", Currency: "VND"}).StatusCode)
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42", nil).Body).Decode(&report))
+	assert.False(t, report.RatesStale, "chỉ có VND thì không phụ thuộc tỷ giá")
+
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("0.01"), Currency: "BTC"}).StatusCode)
+	require.NoError(t, json.NewDecoder(doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42", nil).Body).Decode(&report))
+	assert.True(t, report.RatesStale)
+	assert.True(t, report.Assets["BTC"].Stale)
+	assert.True(t, report.Holdings["BTC"].Stale)
+}
+
+func TestAssetRegistry(t *testing.T) {
+	srv, _ := newTestServer(t)
+
+	// Ngoại tệ mới được định giá theo tỷ giá (mặc định EUR ~27,500đ), không phải 1đ
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("100"), Currency: "eur"}).StatusCode)
+	assert.Equal(t, http.StatusBadRequest, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("1"), Currency: "XYZ"}).StatusCode)
+
+	// Tài sản tự định nghĩa giá cố định
+	if _, ok := service.LookupAsset("VNM"); !ok {
+		require.NoError(t, service.RegisterAsset(model.Asset{Code: "vnm", Name: "Cổ phiếu VNM", Unit: "cp", Aliases: []string{"CP  VNM"}, Rate: model.AssetRate{Price: 65000}}))
+	}
+	assert.Error(t, service.RegisterAsset(model.Asset{Code: "VNM", Rate: model.AssetRate{Price: 1}}), "trùng mã")
+	assert.Error(t, service.RegisterAsset(model.Asset{Code: "FPT", Aliases: []string{"eur"}, Rate: model.AssetRate{Price: 1}}), "trùng alias")
+	assert.Error(t, service.RegisterAsset(model.Asset{Code: "FPT"}), "thiếu giá")
+	assert.Error(t, service.RegisterAsset(model.Asset{Code: "FPT", Rate: model.AssetRate{Code: "FPT", Quote: "JPY"}}))
+
+	txs, err := service.ParseTransactionText("tk 100 cp vnm")
+	require.NoError(t, err)
+	require.Len(t, txs, 1)
+	assert.Equal(t, "VNM", txs[0].Currency)
+	txs[0].UserID = "42"
+	require.Equal(t, http.StatusOK, postTransaction(t, srv, txs[0]).StatusCode)
+
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42", nil).Body).Decode(&report))
+	assert.True(t, dec("2750000").Equal(report.Assets["EUR"].CurrentVND), report.Assets["EUR"].CurrentVND.String())
+	assert.True(t, report.Assets["EUR"].Stale)
+	assert.True(t, dec("6500000").Equal(report.Assets["VNM"].CurrentVND), report.Assets["VNM"].CurrentVND.String())
+	assert.False(t, report.Assets["VNM"].Stale, "giá cố định không bao giờ cũ")
+
+	resp := doJSON(t, http.MethodGet, srv.URL+"/assets", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var assets []model.AssetQuote
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&assets))
+	byCode := make(map[string]model.AssetQuote)
+	for _, a := range assets {
+		byCode[a.Code] = a
+	}
+	for _, code := range []string{"VND", "USD", "EUR", "JPY", "CNY", "GOLD", "SILVER", "BTC", "ETH", "USDT", "VNM"} {
+		assert.Contains(t, byCode, code)
+	}
+	assert.Equal(t, []string{"cp vnm"}, byCode["VNM"].Aliases)
+	assert.True(t, dec("27500").Equal(byCode["EUR"].RateVND))
+	assert.False(t, byCode["EUR"].HasSpread, "erapi không có giá mua/bán riêng")
+	assert.True(t, byCode["SILVER"].RateVND.GreaterThan(dec("900000")), "bạc tính theo lượng")
+}
+
+func TestRateStale(t *testing.T) {
+	fetched := time.Now()
+	rates := model.ExchangeRates{Sources: map[string]model.RateSource{
+		"USD": {Source: "erapi", FetchedAt: &fetched},
+		"SJC": {Source: "vangtoday", FetchedAt: &fetched, Stale: true},
+		"XAG": {Source: "goldapi", FetchedAt: &fetched},
+	}}
+	assert.False(t, service.RateStale("VND", rates))
+	assert.False(t, service.RateStale("USD", rates))
+	assert.True(t, service.RateStale("GOLD", rates))
+	assert.True(t, service.RateStale("BTC", rates), "không rõ nguồn thì coi là cũ")
+	assert.False(t, service.RateStale("SILVER", rates))
+	assert.True(t, service.RateStale("EUR", rates))
+}
+
+func TestBidAskRates(t *testing.T) {
+	rates := model.ExchangeRates{
+		UsdVND: 25400,
+		VnSJC:  8600000,
+		Bids:   map[string]float64{"SJC": 8400000},
+		Asks:   map[string]float64{"SJC": 8600000},
+	}
+	assertDecEqual(t, "8400000", service.BidToVND("GOLD", rates))
+	assertDecEqual(t, "8600000", service.AskToVND("GOLD", rates))
+	assertDecEqual(t, "8600000", service.RateToVND("GOLD", rates))
+	// Nguồn không có giá mua/bán riêng thì cả 2 bằng giá tham chiếu
+	assertDecEqual(t, "25400", service.BidToVND("USD", rates))
+	assertDecEqual(t, "25400", service.AskToVND("USD", rates))
+	assertDecEqual(t, "1", service.BidToVND("VND", rates))
+
+	for code, want := range map[string]bool{"GOLD": true, "USD": false, "SILVER": false, "VND": false} {
+		a, ok := service.LookupAsset(code)
+		require.True(t, ok)
+		assert.Equal(t, want, a.SpreadIn(rates), code)
+	}
+}
+
+func TestPriceAlerts(t *testing.T) {
+	srv, s := newTestServer(t)
+
+	create := func(a model.PriceAlert) *http.Response {
+		return doJSON(t, http.MethodPost, srv.URL+"/alerts", a)
+	}
+	goldID := createdID(t, create(model.PriceAlert{UserID: "42", Asset: "gold", Condition: "above", Threshold: dec("90000000"), Unit: "lượng"}))
+	usdID := createdID(t, create(model.PriceAlert{UserID: "42", Asset: "USD", Condition: "below", Threshold: dec("25000"), Repeat: true}))
+	createdID(t, create(model.PriceAlert{UserID: "7", Asset: "BTC", Condition: "change", Threshold: dec("5")}))
+
+	for _, bad := range []model.PriceAlert{
+		{Asset: "GOLD", Condition: "above", Threshold: dec("1")},
+		{UserID: "42", Asset: "XYZ", Condition: "above", Threshold: dec("1")},
+		{UserID: "42", Asset: "VND", Condition: "above", Threshold: dec("1")},
+		{UserID: "42", Asset: "GOLD", Condition: "equal", Threshold: dec("1")},
+		{UserID: "42", Asset: "GOLD", Condition: "above", Threshold: dec("0")},
+		{UserID: "42", Asset: "USD", Condition: "above", Threshold: dec("1"), Unit: "luong"},
+		{UserID: "42", Asset: "GOLD", Condition: "above", Threshold: dec("1"), Unit: "thùng"},
+	} {
+		assert.Equal(t, http.StatusBadRequest, create(bad).StatusCode, "%+v", bad)
+	}
+
+	resp := doJSON(t, http.MethodGet, srv.URL+"/alerts?user_id=42", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var alerts []model.PriceAlert
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&alerts))
+	require.Len(t, alerts, 2)
+	assert.Equal(t, "GOLD", alerts[0].Asset)
+	assert.Equal(t, "luong", alerts[0].Unit)
+	assert.True(t, alerts[0].Active)
+
+	// Giá mặc định (không rõ nguồn) không được dùng để báo
+	n, err := service.EvaluateAlerts(s, model.ExchangeRates{UsdVND: 24000, VnSJC: 9500000}, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n)
+
+	fetched := time.Now()
+	rates := model.ExchangeRates{UsdVND: 25400, VnSJC: 9100000, Sources: map[string]model.RateSource{
+		"USD": {Source: "erapi", FetchedAt: &fetched},
+		"SJC": {Source: "vangtoday", FetchedAt: &fetched},
+	}}
+	n, err = service.EvaluateAlerts(s, rates, time.Now())
+	require.NoError(t, err)
+	assert.Equal(t, 1, n, "vàng 91tr/lượng vượt mốc 90tr, usd chưa xuống dưới 25000")
+	n, err = service.EvaluateAlerts(s, rates, time.Now())
+	require.NoError(t, err)
+	assert.Zero(t, n, "cảnh báo 1 lần đã tắt")
+
+	resp = doJSON(t, http.MethodGet, srv.URL+"/alerts/notifications", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var pending []model.AlertNotification
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&pending))
+	require.Len(t, pending, 1)
+	assert.Equal(t, goldID, pending[0].AlertID)
+	assert.Equal(t, "42", pending[0].UserID)
+	assertDecEqual(t, "91000000", pending[0].Price)
+
+	resp = doJSON(t, http.MethodPost, fmt.Sprintf("%s/alerts/notifications/%d/ack", srv.URL, pending[0].ID), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doJSON(t, http.MethodPost, srv.URL+"/alerts/notifications/99999/ack", nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+	resp = doJSON(t, http.MethodGet, srv.URL+"/alerts/notifications", nil)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&pending))
+	assert.Empty(t, pending)
+
+	gold, err := s.GetAlert(goldID)
+	require.NoError(t, err)
+	assert.False(t, gold.Active)
+	assert.NotNil(t, gold.LastTriggeredAt)
+
+	resp = doJSON(t, http.MethodDelete, fmt.Sprintf("%s/alerts/%d?user_id=7", srv.URL, usdID), nil)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	resp = doJSON(t, http.MethodDelete, fmt.Sprintf("%s/alerts/%d?user_id=42", srv.URL, usdID), nil)
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	resp = doJSON(t, http.MethodDelete, fmt.Sprintf("%s/alerts/%d?user_id=42", srv.URL, usdID), nil)
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
+
+func TestBackdatedTransaction(t *testing.T) {
+	srv, s := newTestServer(t)
+	lastMonth := time.Now().AddDate(0, -1, 0).Truncate(time.Second)
+	snapID, err := s.SaveRates(lastMonth.Add(-time.Hour), model.ExchangeRates{UsdVND: 24000, VnSJC: 8000000})
+	require.NoError(t, err)
+
+	createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("50000"), Note: "cafe"}))
+	id := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("2"),
+		Currency: "GOLD", OccurredAt: &lastMonth}))
+
+	got, err := s.GetByID(id)
+	require.NoError(t, err)
+	assert.True(t, lastMonth.Equal(got.CreatedAt), "lưu thời điểm user gửi, không phải lúc ghi")
+	assertDecEqual(t, "16000000", got.Amount) // Quy đổi theo giá đã lưu trước thời điểm đó
+	require.NotNil(t, got.RateSnapshotID)
+	assert.Equal(t, snapID, *got.RateSnapshotID)
+
+	// Giao dịch ghi bù nằm ở kỳ báo cáo tháng trước, /undo vẫn xóa giao dịch vừa ghi
+	resp := doJSON(t, http.MethodGet, srv.URL+"/report?user_id=42&period=month", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var report model.ReportOutput
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assertDecEqual(t, "0", report.TotalSavingsVND)
+	resp = doJSON(t, http.MethodDelete, srv.URL+"/transactions/last?user_id=42", nil)
+	var deleted model.Transaction
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&deleted))
+	assert.Equal(t, id, deleted.ID)
+
+	future := time.Now().Add(time.Hour)
+	tooOld := time.Now().AddDate(-6, 0, 0)
+	for _, at := range []*time.Time{&future, &tooOld} {
+		resp := postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "chi", Amount: dec("1000"), Note: "x", OccurredAt: at})
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	}
+}
+
+func TestBackdatedWithdrawalChecksHoldingsAtThatTime(t *testing.T) {
+	srv, _ := newTestServer(t)
+	at := func(d time.Duration) *time.Time {
+		v := time.Now().Add(-d).Truncate(time.Second)
+		return &v
+	}
+	post := func(txType, amount string, occurredAt *time.Time) int {
+		resp := postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: txType, Amount: dec(amount), Currency: "GOLD", OccurredAt: occurredAt})
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+	day := 24 * time.Hour
+
+	require.Equal(t, http.StatusOK, post("tiet_kiem", "2", at(10*day)))
+	require.Equal(t, http.StatusOK, post("rut", "1", nil))
+
+	// Bán trước khi mua
+	assert.Equal(t, http.StatusBadRequest, post("rut", "1", at(30*day)))
+	// Ghi bù lần bán còn đủ cho lần bán sau: 2 -> 1 -> 0
+	assert.Equal(t, http.StatusOK, post("rut", "1", at(5*day)))
+	// Làm lần bán ghi sau (hôm nay) không còn đủ hàng
+	assert.Equal(t, http.StatusBadRequest, post("rut", "0.5", at(3*day)))
+
+	resp := doJSON(t, http.MethodGet, srv.URL+"/portfolio?user_id=42", nil)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	var p model.Portfolio
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&p))
+	for _, hd := range p.Holdings {
+		assert.NotEqual(t, "GOLD", hd.Currency, "đã bán hết vàng")
+	}
+}
+
+func TestBackdatedTransactionFallsBackWhenOldRatesLackAsset(t *testing.T) {
+	srv, s := newTestServer(t)
+	lastMonth := time.Now().AddDate(0, -1, 0).Truncate(time.Second)
+	// Bản ghi giá cũ lưu trước khi có EUR trong registry
+	snapID, err := s.SaveRates(lastMonth.Add(-time.Hour), model.ExchangeRates{UsdVND: 24000, VnSJC: 8000000})
+	require.NoError(t, err)
+
+	id := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("100"),
+		Currency: "EUR", OccurredAt: &lastMonth}))
+	got, err := s.GetByID(id)
+	require.NoError(t, err)
+	assert.True(t, got.Amount.IsPositive(), "không quy đổi theo giá 0: %s", got.Amount)
+	if got.RateSnapshotID != nil {
+		assert.NotEqual(t, snapID, *got.RateSnapshotID, "dùng giá hiện tại, không gắn bản ghi thiếu EUR")
+	}
+}
+
+func TestUpdateBackdatedTransactionKeepsHistoricalRate(t *testing.T) {
+	srv, s := newTestServer(t)
+	lastMonth := time.Now().AddDate(0, -1, 0).Truncate(time.Second)
+	snapID, err := s.SaveRates(lastMonth.Add(-time.Hour), model.ExchangeRates{UsdVND: 24000, VnSJC: 8000000})
+	require.NoError(t, err)
+
+	usdID := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("100"),
+		Currency: "USD", OccurredAt: &lastMonth}))
+	goldID := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("1"),
+		Currency: "GOLD", OccurredAt: &lastMonth}))
+
+	amount := dec("200")
+	resp := doJSON(t, http.MethodPatch, fmt.Sprintf("%s/transactions/%d", srv.URL, usdID), model.TransactionUpdate{UserID: "42", Amount: &amount})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	gold := "GOLD"
+	amount = dec("2")
+	resp = doJSON(t, http.MethodPatch, fmt.Sprintf("%s/transactions/%d", srv.URL, goldID), model.TransactionUpdate{UserID: "42", Amount: &amount, Currency: &gold})
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for id, want := range map[int]string{usdID: "4800000", goldID: "16000000"} {
+		got, err := s.GetByID(id)
+		require.NoError(t, err)
+		assertDecEqual(t, want, got.Amount) // Giá tháng trước, không phải giá hôm nay
+		require.NotNil(t, got.RateSnapshotID)
+		assert.Equal(t, snapID, *got.RateSnapshotID)
+	}
+}
+
+func TestUpdateTransactionTypeValidatesAndReconverts(t *testing.T) {
+	srv, s := newTestServer(t)
+	lastMonth := time.Now().AddDate(0, -1, 0).Truncate(time.Second)
+	_, err := s.SaveRates(lastMonth.Add(-time.Hour), model.ExchangeRates{UsdVND: 24000, VnSJC: 8000000,
+		Bids: map[string]float64{service.AssetSJC: 7800000}, Asks: map[string]float64{service.AssetSJC: 8200000}})
+	require.NoError(t, err)
+
+	small := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("1"),
+		Currency: "GOLD", OccurredAt: &lastMonth}))
+	big := createdID(t, postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "tiet_kiem", Amount: dec("3"),
+		Currency: "GOLD", OccurredAt: &lastMonth}))
+	patchType := func(id int, txType string) int {
+		resp := doJSON(t, http.MethodPatch, fmt.Sprintf("%s/transactions/%d", srv.URL, id), model.TransactionUpdate{UserID: "42", Type: &txType})
+		defer resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	assert.Equal(t, http.StatusBadRequest, patchType(small, "ban"))
+	resp := postTransaction(t, srv, model.TransactionCreate{UserID: "42", Type: "ban", Amount: dec("1000"), Note: "x"})
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+	// Đổi thành rút 3 chỉ khi chỉ còn giữ 1 chỉ
+	assert.Equal(t, http.StatusBadRequest, patchType(big, "rut"))
+
+	require.Equal(t, http.StatusOK, patchType(small, "rut"))
+	got, err := s.GetByID(small)
+	require.NoError(t, err)
+	assert.Equal(t, "rut", got.Type)
+	assertDecEqual(t, "7800000", got.Amount) // Bán theo giá mua vào thay vì giá bán ra 8.200.000
+}
@@ -0,0 +1,19 @@
+package tests
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"github.com/stretchr/testify/assert"
+)
+
+// dec tạo decimal từ chuỗi, dùng cho giá trị kỳ vọng trong test
+func dec(s string) decimal.Decimal {
+	return decimal.RequireFromString(s)
+}
+
+// assertDecEqual so sánh theo giá trị (1.50 == 1.5), không so sánh biểu diễn nội bộ
+func assertDecEqual(t *testing.T, want string, got decimal.Decimal) {
+	t.Helper()
+	assert.True(t, dec(want).Equal(got), "want %s, got %s", want, got)
+}
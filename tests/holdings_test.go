@@ -0,0 +1,59 @@
+package tests
+
+import (
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestComputeHoldings(t *testing.T) {
+	day := func(d int) time.Time { return time.Date(2025, 5, d, 9, 0, 0, 0, time.UTC) }
+	txs := []model.Transaction{
+		// Bán trước trong slice nhưng sau về thời gian: phải xử lý theo CreatedAt
+		{ID: 3, Type: "rut", Currency: "GOLD", OriginalAmount: dec("3"), Amount: dec("27000000"), CreatedAt: day(10)},
+		{ID: 1, Type: "tiet_kiem", Currency: "GOLD", OriginalAmount: dec("2"), Amount: dec("16000000"), CreatedAt: day(1)},
+		{ID: 2, Type: "tiet_kiem", Currency: "GOLD", OriginalAmount: dec("2"), Amount: dec("20000000"), CreatedAt: day(5)},
+		{ID: 4, Type: "chi", Currency: "VND", Amount: dec("50000"), CreatedAt: day(2)},
+	}
+
+	tests := []struct {
+		method   string
+		wantCost string // Giá vốn 1 chỉ còn lại
+		wantGain string
+	}{
+		// FIFO: bán 2 chỉ giá 8tr + 1 chỉ giá 10tr = 26tr
+		{model.CostBasisFIFO, "10000000", "1000000"},
+		// Bình quân: 36tr / 4 chỉ = 9tr/chỉ, bán 3 chỉ = 27tr
+		{model.CostBasisAverage, "9000000", "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.method, func(t *testing.T) {
+			holdings := service.ComputeHoldings(txs, tt.method)
+			require.Len(t, holdings, 1)
+			gold := holdings["GOLD"]
+			require.NotNil(t, gold)
+			assertDecEqual(t, "1", gold.Quantity)
+			assertDecEqual(t, tt.wantCost, gold.CostVND)
+			require.Len(t, gold.Sales, 1)
+			assert.Equal(t, 3, gold.Sales[0].TransactionID)
+			assertDecEqual(t, tt.wantGain, gold.Sales[0].Gain())
+		})
+	}
+}
+
+func TestComputeHoldingsOversell(t *testing.T) {
+	txs := []model.Transaction{
+		{ID: 1, Type: "tiet_kiem", Currency: "BTC", OriginalAmount: dec("0.1"), Amount: dec("200000000")},
+		{ID: 2, Type: "rut", Currency: "BTC", OriginalAmount: dec("0.5"), Amount: dec("300000000")},
+	}
+	btc := service.ComputeHoldings(txs, model.CostBasisFIFO)["BTC"]
+	require.NotNil(t, btc)
+	assertDecEqual(t, "0", btc.Quantity)
+	assertDecEqual(t, "0", btc.CostVND)
+	assertDecEqual(t, "100000000", btc.Sales[0].Gain())
+}
@@ -4,8 +4,10 @@ import (
 	"go-finance/internal/model"
 	"go-finance/internal/service"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
 func TestParseTransactionText(t *testing.T) {
@@ -22,28 +24,28 @@ func TestParseTransactionText(t *testing.T) {
 			name:  "Chi tiêu thông thường (k)",
 			input: "chi 50k ăn sáng",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 50000, Note: "ăn sáng", Currency: "VND", Category: "ăn uống"},
+				{Type: "chi", Amount: dec("50000"), Note: "ăn sáng", Currency: "VND", Category: "ăn uống"},
 			},
 		},
 		{
 			name:  "Thu nhập thông thường (m)",
 			input: "thu 10m lương",
 			expected: []model.TransactionCreate{
-				{Type: "thu", Amount: 10000000, Note: "lương", Currency: "VND", Category: ""},
+				{Type: "thu", Amount: dec("10000000"), Note: "lương", Currency: "VND", Category: ""},
 			},
 		},
 		{
 			name:  "Tiết kiệm (viết tắt tk, không note)",
 			input: "tk 2m",
 			expected: []model.TransactionCreate{
-				{Type: "tiet_kiem", Amount: 2000000, Note: "", Currency: "VND", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("2000000"), Note: "", Currency: "VND", Category: ""},
 			},
 		},
 		{
 			name:  "Tiết kiệm (viết đầy đủ)",
 			input: "tiết kiệm 500k",
 			expected: []model.TransactionCreate{
-				{Type: "tiet_kiem", Amount: 500000, Note: "", Currency: "VND", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("500000"), Note: "", Currency: "VND", Category: ""},
 			},
 		},
 
@@ -54,14 +56,14 @@ func TestParseTransactionText(t *testing.T) {
 			name:  "Dùng dấu trừ (-) đại diện cho Chi",
 			input: "- 20k tiền nước",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 20000, Note: "tiền nước", Currency: "VND", Category: "sinh hoạt"},
+				{Type: "chi", Amount: dec("20000"), Note: "tiền nước", Currency: "VND", Category: "sinh hoạt"},
 			},
 		},
 		{
 			name:  "Dùng dấu cộng (+) đại diện cho Thu",
 			input: "+ 500k thưởng nóng",
 			expected: []model.TransactionCreate{
-				{Type: "thu", Amount: 500000, Note: "thưởng nóng", Currency: "VND", Category: ""},
+				{Type: "thu", Amount: dec("500000"), Note: "thưởng nóng", Currency: "VND", Category: ""},
 			},
 		},
 
@@ -72,21 +74,21 @@ func TestParseTransactionText(t *testing.T) {
 			name:  "Số thập phân dùng dấu chấm (1.5m)",
 			input: "chi 1.5m tiền trọ",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 1500000, Note: "tiền trọ", Currency: "VND", Category: "khác"},
+				{Type: "chi", Amount: dec("1500000"), Note: "tiền trọ", Currency: "VND", Category: "khác"},
 			},
 		},
 		{
 			name:  "Số thập phân dùng dấu phẩy (1,5m)",
 			input: "chi 1,5m tiền trọ",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 1500000, Note: "tiền trọ", Currency: "VND", Category: "khác"},
+				{Type: "chi", Amount: dec("1500000"), Note: "tiền trọ", Currency: "VND", Category: "khác"},
 			},
 		},
 		{
 			name:  "Số thường không đơn vị (mặc định là số trần)",
 			input: "chi 50000 trà sữa",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 50000, Note: "trà sữa", Currency: "VND", Category: "ăn uống"},
+				{Type: "chi", Amount: dec("50000"), Note: "trà sữa", Currency: "VND", Category: "ăn uống"},
 			},
 		},
 
@@ -98,21 +100,87 @@ func TestParseTransactionText(t *testing.T) {
 			name:  "Tiết kiệm USD",
 			input: "tk 100 usd",
 			expected: []model.TransactionCreate{
-				{Type: "tiet_kiem", Amount: 100, Note: "", Currency: "USD", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("100"), Note: "", Currency: "USD", Category: ""},
 			},
 		},
 		{
 			name:  "Tiết kiệm Bitcoin (BTC)",
 			input: "tk 0.5 btc",
 			expected: []model.TransactionCreate{
-				{Type: "tiet_kiem", Amount: 0.5, Note: "", Currency: "BTC", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("0.5"), Note: "", Currency: "BTC", Category: ""},
 			},
 		},
 		{
 			name:  "Tiết kiệm Vàng (chỉ vàng)",
 			input: "tk 5 chỉ vàng",
 			expected: []model.TransactionCreate{
-				{Type: "tiet_kiem", Amount: 5, Note: "", Currency: "GOLD", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("5"), Note: "", Currency: "GOLD", Category: ""},
+			},
+		},
+		{
+			name:  "Rút tiết kiệm vàng",
+			input: "rút tk 2 chỉ vàng",
+			expected: []model.TransactionCreate{
+				{Type: "rut", Amount: dec("2"), Note: "", Currency: "GOLD", Category: ""},
+			},
+		},
+		{
+			name:  "Bán Bitcoin",
+			input: "bán 0.01 btc",
+			expected: []model.TransactionCreate{
+				{Type: "rut", Amount: dec("0.01"), Note: "", Currency: "BTC", Category: ""},
+			},
+		},
+		{
+			name:     "Rút có note -> Bỏ qua",
+			input:    "rút 1m mua xe",
+			expected: nil,
+		},
+		{
+			name:  "Tiết kiệm Euro",
+			input: "tk 100 eur",
+			expected: []model.TransactionCreate{
+				{Type: "tiet_kiem", Amount: dec("100"), Note: "", Currency: "EUR", Category: ""},
+			},
+		},
+		{
+			name:  "USDT không bị nhận nhầm là USD",
+			input: "tk 50 usdt",
+			expected: []model.TransactionCreate{
+				{Type: "tiet_kiem", Amount: dec("50"), Note: "", Currency: "USDT", Category: ""},
+			},
+		},
+		{
+			name:  "Bán Ethereum, tiết kiệm bạc và yên Nhật",
+			input: "bán 0.5 ETH, tk 2 lượng bạc, tk 10000 ¥",
+			expected: []model.TransactionCreate{
+				{Type: "rut", Amount: dec("0.5"), Note: "", Currency: "ETH", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("2"), Note: "", Currency: "SILVER", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("10000"), Note: "", Currency: "JPY", Category: ""},
+			},
+		},
+		{
+			name:  "Vàng bạc theo lượng, gram, kg, ounce quy về chỉ (vàng) và lượng (bạc)",
+			input: "tk 1 lượng vàng, tk 5g vàng 9999, tk 1kg bạc, tk 2 oz gold",
+			expected: []model.TransactionCreate{
+				{Type: "tiet_kiem", Amount: dec("10"), Note: "", Currency: "GOLD", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("1.33333333"), Note: "", Currency: "GOLD", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("26.66666667"), Note: "", Currency: "SILVER", Category: ""},
+				{Type: "tiet_kiem", Amount: dec("16.58852096"), Note: "", Currency: "GOLD", Category: ""},
+			},
+		},
+		{
+			name:  "Cây vàng",
+			input: "rút tk 3 cây vàng",
+			expected: []model.TransactionCreate{
+				{Type: "rut", Amount: dec("30"), Note: "", Currency: "GOLD", Category: ""},
+			},
+		},
+		{
+			name:  "Tên đơn vị dính liền chữ khác là ghi chú",
+			input: "chi 200k eurovision",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("200000"), Note: "eurovision", Currency: "VND", Category: "khác"},
 			},
 		},
 
@@ -123,16 +191,16 @@ func TestParseTransactionText(t *testing.T) {
 			name:  "Hai lệnh trên một dòng ngăn cách bởi dấu phẩy",
 			input: "chi 50k ăn trưa, + 200k bán đồ cũ",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 50000, Note: "ăn trưa", Currency: "VND", Category: "ăn uống"},
-				{Type: "thu", Amount: 200000, Note: "bán đồ cũ", Currency: "VND", Category: ""},
+				{Type: "chi", Amount: dec("50000"), Note: "ăn trưa", Currency: "VND", Category: "ăn uống"},
+				{Type: "thu", Amount: dec("200000"), Note: "bán đồ cũ", Currency: "VND", Category: ""},
 			},
 		},
 		{
 			name:  "Hai lệnh ngăn cách bởi xuống dòng",
 			input: "chi 30k cafe\ntk 100 usd",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 30000, Note: "cafe", Currency: "VND", Category: "ăn uống"},
-				{Type: "tiet_kiem", Amount: 100, Note: "", Currency: "USD", Category: ""},
+				{Type: "chi", Amount: dec("30000"), Note: "cafe", Currency: "VND", Category: "ăn uống"},
+				{Type: "tiet_kiem", Amount: dec("100"), Note: "", Currency: "USD", Category: ""},
 			},
 		},
 
@@ -175,30 +243,211 @@ func TestParseTransactionText(t *testing.T) {
 			name:  "Phân loại: Ăn uống (từ khóa 'phở')",
 			input: "chi 40k phở bò",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 40000, Note: "phở bò", Currency: "VND", Category: "ăn uống"},
+				{Type: "chi", Amount: dec("40000"), Note: "phở bò", Currency: "VND", Category: "ăn uống"},
 			},
 		},
 		{
 			name:  "Phân loại: Sinh hoạt (từ khóa 'xăng')",
 			input: "chi 100k đổ xăng",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 100000, Note: "đổ xăng", Currency: "VND", Category: "sinh hoạt"},
+				{Type: "chi", Amount: dec("100000"), Note: "đổ xăng", Currency: "VND", Category: "sinh hoạt"},
+			},
+		},
+		{
+			name:  "Giao dịch định kỳ: lịch lặp không nằm trong ghi chú",
+			input: "chi 5m tiền nhà hàng tháng ngày 1",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("5000000"), Note: "tiền nhà", Currency: "VND", Category: "khác",
+					Recurrence: &model.Recurrence{Frequency: "monthly", Day: 1}},
 			},
 		},
+		{
+			name:  "Chọn tài khoản bằng @",
+			input: "chi 50k cafe @MoMo",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("50000"), Note: "cafe", Currency: "VND", Category: "ăn uống", Account: "momo"},
+			},
+		},
+		{
+			name:  "Chuyển tiền giữa 2 tài khoản",
+			input: "chuyển 2m @vcb @momo nạp ví",
+			expected: []model.TransactionCreate{
+				{Type: "chuyen", Amount: dec("2000000"), Note: "nạp ví", Currency: "VND", Account: "vcb", ToAccount: "momo"},
+			},
+		},
+		{
+			name:     "Chuyển tiền thiếu tài khoản nhận -> Bỏ qua",
+			input:    "chuyen 2m @vcb",
+			expected: nil,
+		},
+		{
+			name:     "Chi tiêu với 2 tài khoản -> Bỏ qua",
+			input:    "chi 50k cafe @momo @vcb",
+			expected: nil,
+		},
 		{
 			name:  "Phân loại: Hưởng thụ (từ khóa 'massage')",
 			input: "chi 300k đi massage",
 			expected: []model.TransactionCreate{
-				{Type: "chi", Amount: 300000, Note: "đi massage", Currency: "VND", Category: "hưởng thụ"},
+				{Type: "chi", Amount: dec("300000"), Note: "đi massage", Currency: "VND", Category: "hưởng thụ"},
+			},
+		},
+		// =================================================================
+		// NHÓM: SỐ TIỀN VIẾT THEO KIỂU NÓI
+		// =================================================================
+		{
+			name:  "Số tiền: triệu rưỡi",
+			input: "chi 2 triệu rưỡi tiền nhà",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("2500000"), Note: "tiền nhà", Currency: "VND", Category: "khác"},
+			},
+		},
+		{
+			name:  "Số tiền: củ",
+			input: "thu 1 củ thưởng",
+			expected: []model.TransactionCreate{
+				{Type: "thu", Amount: dec("1000000"), Note: "thưởng", Currency: "VND", Category: ""},
+			},
+		},
+		{
+			name:  "Số tiền viết bằng chữ",
+			input: "chi năm trăm nghìn quà sinh nhật",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("500000"), Note: "quà sinh nhật", Currency: "VND", Category: "khác"},
+			},
+		},
+		{
+			name:  "Số tiền viết liền 1tr2",
+			input: "chi 1tr2 đổ xăng, tk 2m5",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("1200000"), Note: "đổ xăng", Currency: "VND", Category: "sinh hoạt"},
+				{Type: "tiet_kiem", Amount: dec("2500000"), Note: "", Currency: "VND", Category: ""},
+			},
+		},
+		{
+			name:  "Số tiền: lít (100k)",
+			input: "chi 3 lít xăng",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("300000"), Note: "xăng", Currency: "VND", Category: "sinh hoạt"},
+			},
+		},
+		{
+			name:  "Số tiền: tỷ",
+			input: "chi 1 tỷ mua nhà",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("1000000000"), Note: "mua nhà", Currency: "VND", Category: "khác"},
+			},
+		},
+		{
+			name:  "Số chữ phía sau không thành số tiền thì là ghi chú",
+			input: "chi 50k hai ly cafe",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("50000"), Note: "hai ly cafe", Currency: "VND", Category: "ăn uống"},
+			},
+		},
+		{
+			name:  "Gõ không dấu",
+			input: "chi 50k an sang, tiet kiem 500k",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("50000"), Note: "an sang", Currency: "VND", Category: "ăn uống"},
+				{Type: "tiet_kiem", Amount: dec("500000"), Note: "", Currency: "VND", Category: ""},
+			},
+		},
+		{
+			name:  "Chữ có dấu dạng tổ hợp (NFD)",
+			input: "ti\u0065\u0302\u0301t kiệm 2m",
+			expected: []model.TransactionCreate{
+				{Type: "tiet_kiem", Amount: dec("2000000"), Note: "", Currency: "VND", Category: ""},
+			},
+		},
+		// =================================================================
+		// NHÓM: BIỂU THỨC TRONG SỐ TIỀN
+		// =================================================================
+		{
+			name:  "Biểu thức: nhân số lượng",
+			input: "chi 3x25k trà sữa",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("75000"), Note: "trà sữa", Currency: "VND", Category: "ăn uống", AmountExpr: "3x25k"},
+			},
+		},
+		{
+			name:  "Biểu thức: chia đều",
+			input: "chi 120k/4 ăn lẩu chia đều",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("30000"), Note: "ăn lẩu chia đều", Currency: "VND", Category: "ăn uống", AmountExpr: "120k/4"},
 			},
 		},
+		{
+			name:  "Biểu thức: cộng, có ngoặc và khoảng trắng",
+			input: "chi 50k+30k cafe và bánh, chi (100k + 50k) * 2 / 3 taxi",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("80000"), Note: "cafe và bánh", Currency: "VND", Category: "ăn uống", AmountExpr: "50k+30k"},
+				{Type: "chi", Amount: dec("100000"), Note: "taxi", Currency: "VND", Category: "khác", AmountExpr: "(100k + 50k) * 2 / 3"},
+			},
+		},
+		{
+			name:  "Biểu thức: làm tròn tới đồng",
+			input: "chi 100k/3 quà",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("33333"), Note: "quà", Currency: "VND", Category: "khác", AmountExpr: "100k/3"},
+			},
+		},
+		{
+			name:  "Biểu thức với tài sản",
+			input: "tk 2+3 chỉ vàng",
+			expected: []model.TransactionCreate{
+				{Type: "tiet_kiem", Amount: dec("5"), Note: "", Currency: "GOLD", Category: "", AmountExpr: "2+3"},
+			},
+		},
+		{
+			name:  "Dấu phép tính không có toán hạng thì là ghi chú",
+			input: "chi 50k + phí ship",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("50000"), Note: "+ phí ship", Currency: "VND", Category: "sinh hoạt"},
+			},
+		},
+		{
+			name:  "Gạch có khoảng trắng trước số không có hàng lớn là ngăn ghi chú, không phải phép trừ",
+			input: "chi 30k - 1 ly cafe",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("30000"), Note: "1 ly cafe", Currency: "VND", Category: "ăn uống"},
+			},
+		},
+		{
+			name:  "Phép trừ có khoảng trắng với số có hàng lớn",
+			input: "chi 50k - 5k giảm giá",
+			expected: []model.TransactionCreate{
+				{Type: "chi", Amount: dec("45000"), Note: "giảm giá", Currency: "VND", Category: "khác", AmountExpr: "50k - 5k"},
+			},
+		},
+		{
+			name:     "Biểu thức chia cho 0 -> Bỏ qua",
+			input:    "chi 120k/0 lẩu",
+			expected: nil,
+		},
+		{
+			name:     "Biểu thức ra số âm -> Bỏ qua",
+			input:    "chi 20k-50k cafe",
+			expected: nil,
+		},
+		{
+			name:     "Số tiền bằng chữ thiếu hàng đơn vị -> Bỏ qua",
+			input:    "chi hai ly cafe",
+			expected: nil,
+		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			got, err := service.ParseTransactionText(tt.input)
 
-			assert.NoError(t, err)
+			if tt.expected == nil {
+				// Đoạn bị từ chối được báo lỗi kèm lý do thay vì bỏ qua im lặng
+				var parseErr *service.ParseError
+				assert.ErrorAs(t, err, &parseErr)
+			} else {
+				assert.NoError(t, err)
+			}
 
 			if tt.expected == nil {
 				assert.Empty(t, got, "Mong đợi danh sách rỗng cho input không hợp lệ")
@@ -206,12 +455,307 @@ func TestParseTransactionText(t *testing.T) {
 				assert.Equal(t, len(tt.expected), len(got), "Số lượng giao dịch không khớp")
 				for i, want := range tt.expected {
 					assert.Equal(t, want.Type, got[i].Type)
-					assert.Equal(t, want.Amount, got[i].Amount)
+					assert.True(t, want.Amount.Equal(got[i].Amount), "Amount: want %s, got %s", want.Amount, got[i].Amount)
 					assert.Equal(t, want.Note, got[i].Note)
 					assert.Equal(t, want.Currency, got[i].Currency)
 					assert.Equal(t, want.Category, got[i].Category)
+					assert.Equal(t, want.Recurrence, got[i].Recurrence)
+					assert.Equal(t, want.Account, got[i].Account)
+					assert.Equal(t, want.ToAccount, got[i].ToAccount)
+					assert.Equal(t, want.AmountExpr, got[i].AmountExpr)
 				}
 			}
 		})
 	}
 }
+
+func TestParseAmount(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // rỗng: không đọc được
+	}{
+		{"50000", "50000"},
+		{"50k", "50000"},
+		{"1.5m", "1500000"},
+		{"1,5m", "1500000"},
+		{"2tr", "2000000"},
+		{"1tr2", "1200000"},
+		{"1tr25", "1250000"},
+		{"2m5", "2500000"},
+		{"1k5", "1500"},
+		{"2 triệu", "2000000"},
+		{"2 triệu rưỡi", "2500000"},
+		{"2 trieu ruoi", "2500000"},
+		{"1 củ", "1000000"},
+		{"1 củ rưỡi", "1500000"},
+		{"2 cu", "2000000"},
+		{"3 lít", "300000"},
+		{"1 tỷ", "1000000000"},
+		{"1,2 tỷ", "1200000000"},
+		{"500 nghìn", "500000"},
+		{"500 ngàn", "500000"},
+		{"1 triệu 200 nghìn", "1200000"},
+		{"năm trăm nghìn", "500000"},
+		{"nam tram nghin", "500000"},
+		{"hai mươi lăm nghìn", "25000"},
+		{"mười lăm k", "15000"},
+		{"hai mươi mốt triệu", "21000000"},
+		{"một trăm linh năm nghìn", "105000"},
+		{"hai trăm năm mươi nghìn", "250000"},
+		{"một trăm rưỡi nghìn", "150000"},
+		{"hai triệu năm trăm nghìn", "2500000"},
+		{"một tỷ hai trăm triệu", "1200000000"},
+		{"hai trăm", "200"},
+		{"-50k", "-50000"},
+		{"hai", ""},
+		{"nghìn", ""},
+		{"hai ba nghìn", ""},
+		{"50k hai", ""},
+		{"abc", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, err := service.ParseAmount(tt.input)
+			if tt.want == "" {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assertDecEqual(t, tt.want, got)
+		})
+	}
+}
+
+func TestFoldText(t *testing.T) {
+	tests := []struct{ input, want string }{
+		{"Báo  Cáo", "bao cao"},
+		{"Đổ xăng", "do xang"},
+		{"tiết kiệm", "tiet kiem"},
+		{"ti\u0065\u0302\u0301t kiệm", "tiet kiem"}, // Chữ có dấu dạng tổ hợp (NFD)
+		{"giá VÀNG", "gia vang"},
+		{"cafe", "cafe"},
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, service.FoldText(tt.input), tt.input)
+	}
+}
+
+func TestMatchCommand(t *testing.T) {
+	tests := []struct {
+		input, command string
+		ok             bool
+		rest           string
+	}{
+		{"báo cáo tháng này", "báo cáo", true, "tháng này"},
+		{"bao cao", "báo cáo", true, ""},
+		{"Báo cao tuần trước", "báo cáo", true, "tuần trước"},
+		{"bap cao", "báo cáo", true, ""},  // Gõ nhầm 1 ký tự
+		{"boa cao", "báo cáo", true, ""},  // Đảo 2 ký tự
+		{"bap cap", "báo cáo", false, ""}, // Sai 2 ký tự
+		{"bao khi vàng > 90tr", "báo khi", true, "vàng > 90tr"},
+		{"ngan sach an uong 3m/thang", "ngân sách", true, "an uong 3m/thang"},
+		{"gia vang", "giá vàng", true, ""},
+		{"tk", "tk", true, ""},
+		{"tl", "tk", false, ""}, // Lệnh ngắn phải gõ đúng
+		{"chi 50k ăn sáng", "báo cáo", false, ""},
+	}
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			rest, ok := service.MatchCommand(tt.input, tt.command)
+			assert.Equal(t, tt.ok, ok)
+			assert.Equal(t, tt.rest, rest)
+		})
+	}
+
+	assert.True(t, service.IsCommand("tai san", "tài sản"))
+	assert.False(t, service.IsCommand("tài sản của tôi", "tài sản"))
+	assert.True(t, service.ContainsCommand("xem bao cao", "báo cáo"))
+	assert.False(t, service.ContainsCommand("chi 50k bap cao", "báo cáo"), "giữa câu phải gõ đúng")
+
+	assert.True(t, service.IsCommandMessage("gia vang", "giá vàng"))
+	assert.True(t, service.IsCommandMessage("xem báo cáo", "báo cáo"))
+	assert.False(t, service.IsCommandMessage("chi 200k mua gia vang cho me", "giá vàng"), "giao dịch có ghi chú trùng lệnh")
+	assert.False(t, service.IsCommandMessage("chi 100k in bao cao", "báo cáo"))
+}
+
+func TestCategorizeExpenseWithoutAccents(t *testing.T) {
+	tests := []struct{ note, want string }{
+		{"ăn sáng", "ăn uống"},
+		{"an sang", "ăn uống"},
+		{"tien dien thang 5", "sinh hoạt"},
+		{"do xang", "sinh hoạt"},
+		{"xem phim", "hưởng thụ"},
+		{"cat toc", "hưởng thụ"},
+		{"quà sinh nhật", "khác"},
+		// Từ có dấu không so khớp không dấu
+		{"mua quần áo", "khác"},
+		{"mua bìa sách", "khác"},
+		{"áo che nắng", "khác"},
+		{"vé biểu diễn", "khác"},
+		{"mũ an toàn", "khác"},
+		{"bảo hiểm an toàn", "sinh hoạt"}, // khớp "bảo hiểm", không phải "an"
+	}
+	for _, tt := range tests {
+		assert.Equal(t, tt.want, service.CategorizeExpense(tt.note), tt.note)
+	}
+}
+
+func TestParseBudgetText(t *testing.T) {
+	tests := []struct {
+		input    string
+		ok       bool
+		category string
+		period   string
+		limit    string
+	}{
+		{"ngân sách ăn uống 3m/tháng", true, "ăn uống", "month", "3000000"},
+		{"Ngân sách sinh hoạt 1,5m / tuần", true, "sinh hoạt", "week", "1500000"},
+		{"ngan sach cafe 500k tuan", true, "ăn uống", "week", "500000"}, // cafe thuộc nhóm ăn uống
+		{"ngân sách du lịch 10m/tháng", true, "hưởng thụ", "month", "10000000"},
+		{"ngân sách ăn uống 0/tháng", false, "", "", ""},
+		{"ngân sách ăn uống 3m", false, "", "", ""},
+		{"ngân sách", false, "", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := service.ParseBudgetText(tt.input)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.category, got.Category)
+				assert.Equal(t, tt.period, got.Period)
+				assert.True(t, dec(tt.limit).Equal(got.Limit), "Limit: want %s, got %s", tt.limit, got.Limit)
+			}
+		})
+	}
+}
+
+func TestParseTransactionSegments(t *testing.T) {
+	text := "chi 50k cafe, tk 100k để dành\năn sáng 30k, rút 1m mua xe, +1,5m tiền lãi, chi 0k test"
+	segs := service.ParseTransactionSegments(text)
+
+	want := []struct {
+		text   string
+		reason service.ParseReason
+	}{
+		{"chi 50k cafe", service.ReasonOK},
+		{"tk 100k để dành", service.ReasonUnexpectedNote},
+		{"ăn sáng 30k", service.ReasonUnrecognized},
+		{"rút 1m mua xe", service.ReasonUnexpectedNote},
+		{"+1,5m tiền lãi", service.ReasonOK},
+		{"chi 0k test", service.ReasonNonPositive},
+	}
+	require.Len(t, segs, len(want))
+	runes := []rune(text)
+	for i, w := range want {
+		assert.Equal(t, i+1, segs[i].Index)
+		assert.Equal(t, w.text, segs[i].Text)
+		assert.Equal(t, w.text, string(runes[segs[i].Start:segs[i].End]), "vị trí ký tự của đoạn %d", i+1)
+		assert.Equal(t, w.reason, segs[i].Reason, segs[i].Text)
+		assert.Equal(t, w.reason == service.ReasonOK, segs[i].Tx != nil)
+	}
+	assert.Equal(t, `đoạn 2 "tk 100k để dành": tiết kiệm không được có ghi chú`, segs[1].Message())
+	assert.Equal(t, `đoạn 4 "rút 1m mua xe": rút/bán không được có ghi chú`, segs[3].Message())
+
+	// Đoạn hợp lệ vẫn được trả về cùng lỗi liệt kê các đoạn bị từ chối
+	txs, err := service.ParseTransactionText(text)
+	assert.Len(t, txs, 2)
+	var parseErr *service.ParseError
+	require.ErrorAs(t, err, &parseErr)
+	assert.Len(t, parseErr.Segments, 4)
+
+	assert.Equal(t, service.ReasonMissingNote, service.ParseTransactionSegments("chi 50k, +1m lương")[0].Reason)
+	assert.Equal(t, service.ReasonTooManyAccounts, service.ParseTransactionSegments("tk 10 usd @vcb @momo")[0].Reason)
+	assert.Equal(t, service.ReasonForeignCurrency, service.ParseTransactionSegments("chi 10 usd mua game")[0].Reason)
+	assert.Equal(t, service.ReasonTransferAccounts, service.ParseTransactionSegments("chuyển 2m @vcb")[0].Reason)
+}
+
+func TestExtractOccurredAt(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	require.NoError(t, err)
+	// Thứ 4, 14/05/2025 15:30
+	now := time.Date(2025, 5, 14, 15, 30, 0, 0, loc)
+	atYear := func(y int, m time.Month, d, h, min int) *time.Time {
+		t := time.Date(y, m, d, h, min, 0, 0, loc)
+		return &t
+	}
+	at := func(m time.Month, d, h, min int) *time.Time { return atYear(2025, m, d, h, min) }
+
+	tests := []struct {
+		note     string
+		wantNote string
+		want     *time.Time
+		ok       bool
+	}{
+		{"ăn tối hôm qua", "ăn tối", at(5, 13, 15, 30), true},
+		{"hôm kia đi chợ", "đi chợ", at(5, 12, 15, 30), true},
+		{"ăn tối hôm qua 20h", "ăn tối", at(5, 13, 20, 0), true},
+		{"cafe 8h30 sáng", "cafe", at(5, 14, 8, 30), true},
+		{"ăn tối 20h", "ăn tối", at(5, 13, 20, 0), true}, // 20h hôm nay chưa tới
+		{"karaoke 10h tối hôm qua", "karaoke", at(5, 13, 22, 0), true},
+		{"taxi thứ 2 tuần trước", "taxi", at(5, 5, 15, 30), true},
+		{"taxi thứ 2 tuần này", "taxi", at(5, 12, 15, 30), true},
+		{"đi chợ chủ nhật tuần trước", "đi chợ", at(5, 11, 15, 30), true}, // Tuần tính từ thứ 2
+		{"hôm thứ 6 ăn lẩu", "ăn lẩu", at(5, 9, 15, 30), true},
+		{"vé máy bay 15/3", "vé máy bay", at(3, 15, 15, 30), true},
+		{"khách sạn ngày 15/3/2025 20h", "khách sạn", at(3, 15, 20, 0), true},
+		{"quà noel 25/12", "quà noel", atYear(2024, 12, 25, 15, 30), true}, // Chưa tới thì là năm ngoái
+		{"quà sinh nhật con thứ 2", "quà sinh nhật con thứ 2", nil, true},
+		{"quà con thứ 2 15/3", "quà con thứ 2", at(3, 15, 15, 30), true},
+		{"gửi xe 24h", "gửi xe 24h", nil, true},
+		{"cafe", "cafe", nil, true},
+		{"vé 31/2", "vé", nil, false},
+		{"đặt cọc 20/5/2025", "đặt cọc", nil, false}, // tương lai
+		{"thứ 7 tuần này", "", nil, false},
+		// Số trong ghi chú không phải ngày giờ
+		{"mua 2-3 cái bánh", "mua 2-3 cái bánh", nil, true},
+		{"bánh 2-3", "bánh 2-3", nil, true},
+		{"1/2 tiền điện", "1/2 tiền điện", nil, true},
+		{"thuê xe 12h", "thuê xe 12h", nil, true},
+		{"cafe 8h30 sáng với bạn", "cafe 8h30 sáng với bạn", nil, true},
+		{"sửa xe ngày 10-5", "sửa xe", at(5, 10, 15, 30), true},
+		{"ăn sáng hôm qua 8h", "ăn sáng", at(5, 13, 8, 0), true},
+		{"vé 20h 13/5", "vé", at(5, 13, 20, 0), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.note, func(t *testing.T) {
+			note, got, ok := service.ExtractOccurredAt(tt.note, now)
+			assert.Equal(t, tt.ok, ok)
+			if !tt.ok {
+				return
+			}
+			assert.Equal(t, tt.wantNote, note)
+			if tt.want == nil {
+				assert.Nil(t, got)
+				return
+			}
+			require.NotNil(t, got)
+			assert.True(t, tt.want.Equal(*got), "want %v, got %v", tt.want, got)
+		})
+	}
+
+	txs, err := service.ParseTransactionTextAt("chi 200k ăn tối hôm qua 20h, tk 2m 15/3, chi 50k cafe", now)
+	require.NoError(t, err)
+	require.Len(t, txs, 3)
+	assert.Equal(t, "ăn tối", txs[0].Note)
+	require.NotNil(t, txs[0].OccurredAt)
+	assert.True(t, at(5, 13, 20, 0).Equal(*txs[0].OccurredAt))
+	assert.Empty(t, txs[1].Note, "ngày không tính là ghi chú của tiết kiệm")
+	require.NotNil(t, txs[1].OccurredAt)
+	assert.Nil(t, txs[2].OccurredAt)
+
+	for _, input := range []string{"chi 50k mua 2-3 cái bánh", "chi 100k 1/2 tiền điện", "chi 200k thuê xe 12h"} {
+		txs, err := service.ParseTransactionTextAt(input, now)
+		require.NoError(t, err, input)
+		require.Len(t, txs, 1, input)
+		assert.Nil(t, txs[0].OccurredAt, input)
+	}
+	txs, err = service.ParseTransactionTextAt("chi 50k mua 2-3 cái bánh", now)
+	require.NoError(t, err)
+	assert.Equal(t, "mua 2-3 cái bánh", txs[0].Note)
+
+	segs := service.ParseTransactionSegmentsAt("chi 50k vé 31/2", now)
+	require.Len(t, segs, 1)
+	assert.Equal(t, service.ReasonInvalidDate, segs[0].Reason)
+}
@@ -0,0 +1,104 @@
+package tests
+
+import (
+	"go-finance/internal/service"
+	"testing"
+	"time"
+	_ "time/tzdata"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestResolvePeriod(t *testing.T) {
+	loc := time.FixedZone("ICT", 7*3600)
+	// Thứ 4, 14/05/2025 15:30
+	now := time.Date(2025, 5, 14, 15, 30, 0, 0, loc)
+	d := func(y int, m time.Month, day int) time.Time { return time.Date(y, m, day, 0, 0, 0, 0, loc) }
+
+	tests := []struct {
+		period     string
+		offset     int
+		start, end time.Time
+	}{
+		{"day", 0, d(2025, 5, 14), d(2025, 5, 15)},
+		{"day", -1, d(2025, 5, 13), d(2025, 5, 14)},
+		{"week", 0, d(2025, 5, 12), d(2025, 5, 19)},
+		{"week", -1, d(2025, 5, 5), d(2025, 5, 12)},
+		{"month", 0, d(2025, 5, 1), d(2025, 6, 1)},
+		{"month", -1, d(2025, 4, 1), d(2025, 5, 1)},
+		{"month", -5, d(2024, 12, 1), d(2025, 1, 1)},
+		{"quarter", 0, d(2025, 4, 1), d(2025, 7, 1)},
+		{"quarter", -2, d(2024, 10, 1), d(2025, 1, 1)},
+		{"year", 0, d(2025, 1, 1), d(2026, 1, 1)},
+		{"year", -1, d(2024, 1, 1), d(2025, 1, 1)},
+	}
+
+	for _, tt := range tests {
+		start, end, err := service.ResolvePeriod(tt.period, tt.offset, now, time.Monday)
+		require.NoError(t, err, tt.period)
+		assert.True(t, tt.start.Equal(start), "%s %d start: want %v, got %v", tt.period, tt.offset, tt.start, start)
+		assert.True(t, tt.end.Equal(end), "%s %d end: want %v, got %v", tt.period, tt.offset, tt.end, end)
+	}
+
+	// Chủ nhật vẫn thuộc tuần bắt đầu từ thứ 2 trước đó
+	start, _, err := service.ResolvePeriod("week", 0, time.Date(2025, 5, 18, 23, 0, 0, 0, loc), time.Monday)
+	require.NoError(t, err)
+	assert.True(t, d(2025, 5, 12).Equal(start))
+
+	// Tuần bắt đầu từ Chủ nhật: Chủ nhật 18/05 mở ra tuần mới
+	start, end, err := service.ResolvePeriod("week", 0, time.Date(2025, 5, 18, 1, 0, 0, 0, loc), time.Sunday)
+	require.NoError(t, err)
+	assert.True(t, d(2025, 5, 18).Equal(start))
+	assert.True(t, d(2025, 5, 25).Equal(end))
+	start, _, err = service.ResolvePeriod("week", 0, now, time.Sunday)
+	require.NoError(t, err)
+	assert.True(t, d(2025, 5, 11).Equal(start))
+
+	// Ranh giới ngày theo múi giờ của now: 23h ngày 14 ở Berlin vẫn là ngày 14 dù ở VN đã là ngày 15
+	berlin, err := time.LoadLocation("Europe/Berlin")
+	require.NoError(t, err)
+	start, _, err = service.ResolvePeriod("day", 0, time.Date(2025, 5, 14, 23, 0, 0, 0, berlin), time.Monday)
+	require.NoError(t, err)
+	assert.True(t, time.Date(2025, 5, 14, 0, 0, 0, 0, berlin).Equal(start))
+
+	_, _, err = service.ResolvePeriod("decade", 0, now, time.Monday)
+	assert.Error(t, err)
+}
+
+func TestParseReportQuery(t *testing.T) {
+	now := time.Date(2025, 5, 14, 15, 30, 0, 0, time.UTC)
+
+	tests := []struct {
+		input    string
+		ok       bool
+		expected service.ReportQuery
+	}{
+		{"báo cáo", false, service.ReportQuery{}},
+		{"Báo cáo tháng trước", true, service.ReportQuery{Period: "month", Offset: -1, Label: "Tháng trước"}},
+		{"báo cáo   tuần này", true, service.ReportQuery{Period: "week", Offset: 0, Label: "Tuần này"}},
+		{"báo cáo quý trước", true, service.ReportQuery{Period: "quarter", Offset: -1, Label: "Quý trước"}},
+		{"báo cáo 2025", true, service.ReportQuery{From: "2025-01-01", To: "2025-12-31", Label: "Năm 2025"}},
+		{"báo cáo năm 2024", true, service.ReportQuery{From: "2024-01-01", To: "2024-12-31", Label: "Năm 2024"}},
+		{"báo cáo tháng 2", true, service.ReportQuery{From: "2025-02-01", To: "2025-02-28", Label: "Tháng 2/2025"}},
+		{"báo cáo 01/03-15/03", true, service.ReportQuery{From: "2025-03-01", To: "2025-03-15", Label: "01/03/2025 - 15/03/2025"}},
+		{"báo cáo 20/12/2024 - 05/01/2025", true, service.ReportQuery{From: "2024-12-20", To: "2025-01-05", Label: "20/12/2024 - 05/01/2025"}},
+		{"báo cáo 15/03-01/03", false, service.ReportQuery{}},
+		{"báo cáo 31/02-01/03", false, service.ReportQuery{}},
+		{"báo cáo linh tinh", false, service.ReportQuery{}},
+		{"bao cao thang truoc", true, service.ReportQuery{Period: "month", Offset: -1, Label: "Tháng trước"}},
+		{"bap cao tuần này", true, service.ReportQuery{Period: "week", Offset: 0, Label: "Tuần này"}},
+		{"xem bao cao quy nay", true, service.ReportQuery{Period: "quarter", Offset: 0, Label: "Quý này"}},
+		{"bao cao 01/03 den 15/03", true, service.ReportQuery{From: "2025-03-01", To: "2025-03-15", Label: "01/03/2025 - 15/03/2025"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			got, ok := service.ParseReportQuery(tt.input, now)
+			assert.Equal(t, tt.ok, ok)
+			if tt.ok {
+				assert.Equal(t, tt.expected, got)
+			}
+		})
+	}
+}
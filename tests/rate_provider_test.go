@@ -0,0 +1,385 @@
+package tests
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// barrier chặn các nguồn được gọi tới khi đủ n nguồn cùng đang chạy
+type barrier struct {
+	n   int
+	mu  sync.Mutex
+	all chan struct{}
+}
+
+func newBarrier(n int) *barrier {
+	return &barrier{n: n, all: make(chan struct{})}
+}
+
+func (b *barrier) wait(ctx context.Context) error {
+	b.mu.Lock()
+	if b.n--; b.n == 0 {
+		close(b.all)
+	}
+	b.mu.Unlock()
+
+	select {
+	case <-b.all:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// fakeProvider nguồn giá giả, đếm số lần được gọi (an toàn khi gọi song song).
+// delay giả lập nguồn chậm, failFirst số lần đầu trả lỗi trước khi có giá,
+// barrier (nếu có) chờ các nguồn khác cùng được gọi, partialErr trả kèm giá (thiếu một số tài sản)
+type fakeProvider struct {
+	name       string
+	prices     map[string]float64
+	err        error
+	partialErr error
+	delay      time.Duration
+	failFirst  int
+	barrier    *barrier
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (p *fakeProvider) Name() string { return p.name }
+
+func (p *fakeProvider) Assets() []string {
+	var assets []string
+	for a := range p.prices {
+		assets = append(assets, a)
+	}
+	return assets
+}
+
+func (p *fakeProvider) Fetch(ctx context.Context) (map[string]float64, error) {
+	p.mu.Lock()
+	p.calls++
+	call := p.calls
+	p.mu.Unlock()
+
+	if p.barrier != nil {
+		if err := p.barrier.wait(ctx); err != nil {
+			return nil, err
+		}
+	}
+	if p.delay > 0 {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(p.delay):
+		}
+	}
+	if p.err != nil {
+		return nil, p.err
+	}
+	if call <= p.failFirst {
+		return nil, errors.New("status 503")
+	}
+	return p.prices, p.partialErr
+}
+
+func (p *fakeProvider) callCount() int {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.calls
+}
+
+// fastPolicy chính sách gọi lại với thời gian chờ rất ngắn cho test
+var fastPolicy = service.FetchPolicy{
+	Retries:          2,
+	BaseBackoff:      time.Millisecond,
+	MaxBackoff:       5 * time.Millisecond,
+	BreakerThreshold: 3,
+	BreakerCooldown:  time.Hour,
+}
+
+// providerHealth tình trạng các nguồn theo tên
+func providerHealth(reg *service.RateRegistry) map[string]model.ProviderHealth {
+	health := make(map[string]model.ProviderHealth)
+	for _, h := range reg.Status().Providers {
+		health[h.Name] = h
+	}
+	return health
+}
+
+func TestRateRegistryFallback(t *testing.T) {
+	blocked := &fakeProvider{name: "blocked", prices: map[string]float64{service.AssetBTC: 1}, err: errors.New("status 403")}
+	backup := &fakeProvider{name: "backup", prices: map[string]float64{service.AssetBTC: 2500000000, service.AssetUSD: 25400}}
+	unused := &fakeProvider{name: "unused", prices: map[string]float64{service.AssetUSD: 1}}
+
+	reg, err := service.NewRateRegistry([]service.RateProvider{blocked, backup, unused}, map[string][]string{
+		service.AssetBTC: {"blocked", "backup"},
+		service.AssetUSD: {"backup", "unused"},
+	})
+	require.NoError(t, err)
+	reg.SetPolicy(fastPolicy)
+
+	quotes, err := reg.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, service.Quote{Price: 2500000000, Source: "backup"}, quotes[service.AssetBTC])
+	assert.Equal(t, service.Quote{Price: 25400, Source: "backup"}, quotes[service.AssetUSD])
+	assert.Equal(t, 1, backup.callCount(), "mỗi nguồn chỉ gọi 1 lần dù dùng cho nhiều tài sản")
+	assert.Equal(t, 0, unused.callCount(), "nguồn dự phòng chỉ gọi khi nguồn trước lỗi")
+
+	assert.Equal(t, []string{"blocked", "backup"}, reg.Status().Chains[service.AssetBTC])
+	health := providerHealth(reg)
+	assert.Equal(t, model.ProviderFailing, health["blocked"].Status)
+	assert.Equal(t, 1, health["blocked"].ConsecutiveFailures)
+	assert.Contains(t, health["blocked"].LastError, "403")
+	assert.Equal(t, model.ProviderOK, health["backup"].Status)
+	assert.NotNil(t, health["backup"].LastSuccessAt)
+	assert.Equal(t, model.ProviderUnknown, health["unused"].Status)
+
+	// Mọi nguồn của tài sản đều lỗi: vẫn trả giá các tài sản khác
+	backup.err = errors.New("timeout")
+	unused.prices = map[string]float64{service.AssetUSD: 25500}
+	quotes, err = reg.Fetch(context.Background())
+	assert.ErrorContains(t, err, service.AssetBTC)
+	assert.Equal(t, map[string]service.Quote{service.AssetUSD: {Price: 25500, Source: "unused"}}, quotes)
+}
+
+func TestRateRegistryFetchesInParallel(t *testing.T) {
+	// Mỗi nguồn chỉ trả giá khi cả 3 nguồn cùng đang được gọi: gọi lần lượt thì nguồn đầu chờ tới hết hạn
+	all := newBarrier(3)
+	usd := &fakeProvider{name: "usd", prices: map[string]float64{service.AssetUSD: 25400}, barrier: all}
+	btc := &fakeProvider{name: "btc", prices: map[string]float64{service.AssetBTC: 2500000000}, barrier: all}
+	slow := &fakeProvider{name: "slow", prices: map[string]float64{service.AssetSJC: 8500000}, barrier: all, delay: time.Minute}
+
+	reg, err := service.NewRateRegistry([]service.RateProvider{usd, btc, slow}, map[string][]string{
+		service.AssetUSD: {"usd"},
+		service.AssetBTC: {"btc"},
+		service.AssetSJC: {"slow"},
+	})
+	require.NoError(t, err)
+	reg.SetPolicy(fastPolicy)
+
+	// Hạn chung: nguồn treo bị hủy khi hết hạn, các nguồn khác vẫn có giá
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	quotes, err := reg.Fetch(ctx)
+
+	assert.ErrorContains(t, err, service.AssetSJC)
+	assert.Len(t, quotes, 2, "các nguồn phải được gọi song song")
+	assert.Equal(t, 1, slow.callCount(), "không gọi lại khi đã hết hạn")
+}
+
+func TestRateRegistryRetryWithBackoff(t *testing.T) {
+	flaky := &fakeProvider{name: "flaky", prices: map[string]float64{service.AssetUSD: 25400}, failFirst: 2}
+	backup := &fakeProvider{name: "backup", prices: map[string]float64{service.AssetUSD: 1}}
+
+	reg, err := service.NewRateRegistry([]service.RateProvider{flaky, backup}, map[string][]string{
+		service.AssetUSD: {"flaky", "backup"},
+	})
+	require.NoError(t, err)
+	reg.SetPolicy(fastPolicy)
+
+	quotes, err := reg.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, service.Quote{Price: 25400, Source: "flaky"}, quotes[service.AssetUSD])
+	assert.Equal(t, 3, flaky.callCount(), "1 lần đầu + 2 lần gọi lại")
+	assert.Equal(t, 0, backup.callCount())
+	assert.Equal(t, model.ProviderOK, providerHealth(reg)["flaky"].Status)
+}
+
+func TestRateRegistryCircuitBreaker(t *testing.T) {
+	down := &fakeProvider{name: "down", prices: map[string]float64{service.AssetBTC: 1}, err: errors.New("status 429")}
+	backup := &fakeProvider{name: "backup", prices: map[string]float64{service.AssetBTC: 2500000000}}
+
+	reg, err := service.NewRateRegistry([]service.RateProvider{down, backup}, map[string][]string{
+		service.AssetBTC: {"down", "backup"},
+	})
+	require.NoError(t, err)
+	policy := fastPolicy
+	policy.Retries = 0
+	policy.BreakerCooldown = 100 * time.Millisecond
+	reg.SetPolicy(policy)
+
+	for i := 0; i < 3; i++ {
+		_, err := reg.Fetch(context.Background())
+		require.NoError(t, err)
+	}
+	assert.Equal(t, 3, down.callCount())
+	h := providerHealth(reg)["down"]
+	assert.Equal(t, model.ProviderCircuitOpen, h.Status)
+	assert.Equal(t, 3, h.ConsecutiveFailures)
+	require.NotNil(t, h.CircuitOpenUntil)
+
+	// Nguồn bị ngắt: bỏ qua, dùng luôn nguồn dự phòng
+	quotes, err := reg.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "backup", quotes[service.AssetBTC].Source)
+	assert.Equal(t, 3, down.callCount())
+
+	// Hết thời gian ngắt: thử lại nguồn, thành công thì đóng lại
+	time.Sleep(120 * time.Millisecond)
+	down.mu.Lock()
+	down.err = nil
+	down.mu.Unlock()
+	quotes, err = reg.Fetch(context.Background())
+	require.NoError(t, err)
+	assert.Equal(t, "down", quotes[service.AssetBTC].Source)
+	assert.Equal(t, 4, down.callCount())
+	h = providerHealth(reg)["down"]
+	assert.Equal(t, model.ProviderOK, h.Status)
+	assert.Nil(t, h.CircuitOpenUntil)
+}
+
+func TestRateRegistryPartialResultsDoNotTripBreaker(t *testing.T) {
+	// Có BTC nhưng thiếu ETH: vẫn là nguồn sống
+	partial := &fakeProvider{name: "partial", prices: map[string]float64{service.AssetBTC: 2500000000, service.AssetETH: 0},
+		partialErr: errors.New("ETH missing")}
+	// Giá USD bằng 0, chỉ có giá tài sản không lấy từ nguồn này: coi như lỗi
+	offTopic := &fakeProvider{name: "off-topic", prices: map[string]float64{service.AssetUSD: 0, service.AssetEUR: 27000}}
+
+	reg, err := service.NewRateRegistry([]service.RateProvider{partial, offTopic}, map[string][]string{
+		service.AssetBTC: {"partial"},
+		service.AssetETH: {"partial"},
+		service.AssetUSD: {"off-topic"},
+	})
+	require.NoError(t, err)
+	policy := fastPolicy
+	policy.Retries = 0
+	reg.SetPolicy(policy)
+
+	for i := 0; i < 3; i++ {
+		_, err := reg.Fetch(context.Background())
+		assert.Error(t, err)
+	}
+	h := providerHealth(reg)
+	assert.Equal(t, model.ProviderFailing, h["partial"].Status)
+	assert.Equal(t, 0, h["partial"].ConsecutiveFailures)
+	assert.Nil(t, h["partial"].CircuitOpenUntil)
+	assert.Equal(t, 3, partial.callCount())
+
+	assert.Equal(t, model.ProviderCircuitOpen, h["off-topic"].Status)
+	assert.Equal(t, 3, h["off-topic"].ConsecutiveFailures)
+}
+
+// emptyRateRecorder không có lịch sử giá, bỏ qua mọi lần lưu
+type emptyRateRecorder struct{}
+
+func (emptyRateRecorder) SaveRates(time.Time, model.ExchangeRates) (int, error) { return 0, nil }
+func (emptyRateRecorder) LatestRates() (model.RateSnapshot, error) {
+	return model.RateSnapshot{}, errors.New("not found")
+}
+
+func TestStartPriceUpdaterStopsOnCancel(t *testing.T) {
+	// Nguồn treo tới khi ctx bị hủy: lần lấy giá đang dở cũng phải dừng theo
+	hang := &fakeProvider{name: "hang", prices: map[string]float64{service.AssetUSD: 25400}, delay: time.Hour}
+	reg, err := service.NewRateRegistry([]service.RateProvider{hang}, map[string][]string{
+		service.AssetUSD: {"hang"},
+	})
+	require.NoError(t, err)
+	reg.SetPolicy(fastPolicy)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.StartPriceUpdater(ctx, emptyRateRecorder{}, reg, nil)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return hang.callCount() > 0 }, time.Second, 5*time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("StartPriceUpdater không dừng sau khi ctx bị hủy")
+	}
+	assert.Equal(t, 1, hang.callCount())
+	assert.Empty(t, service.RateProvidersStatus().Providers, "worker dừng thì trả lại registry như trước")
+}
+
+func TestRateRegistryConfigErrors(t *testing.T) {
+	p := &fakeProvider{name: "usd-only", prices: map[string]float64{service.AssetUSD: 1}}
+
+	_, err := service.NewRateRegistry([]service.RateProvider{p}, map[string][]string{service.AssetUSD: {"missing"}})
+	assert.Error(t, err)
+	_, err = service.NewRateRegistry([]service.RateProvider{p}, map[string][]string{service.AssetBTC: {"usd-only"}})
+	assert.Error(t, err)
+	_, err = service.NewRateProvider("yahoo", "")
+	assert.Error(t, err)
+
+	t.Setenv("RATE_PROVIDERS_BTC", "coinbase, coingecko")
+	reg, err := service.NewRateRegistryFromEnv()
+	require.NoError(t, err)
+	assert.Equal(t, []string{"coinbase", "coingecko"}, reg.Status().Chains[service.AssetBTC])
+
+	t.Setenv("RATE_PROVIDERS_BTC", "erapi")
+	_, err = service.NewRateRegistryFromEnv()
+	assert.Error(t, err, "erapi không có giá BTC")
+}
+
+func TestBuiltinRateProvidersAgainstFakeServer(t *testing.T) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/coingecko", func(w http.ResponseWriter, r *http.Request) {
+		http.Error(w, "blocked", http.StatusForbidden)
+	})
+	mux.HandleFunc("/coinbase", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"data":{"base":"BTC","currency":"VND","amount":"2612345678.5"}}`)
+	})
+	mux.HandleFunc("/erapi", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"result":"success","rates":{"USD":1,"VND":26250.5,"EUR":0.5,"JPY":150}}`)
+	})
+	mux.HandleFunc("/gold/XAU", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"Gold","price":2650.1}`)
+	})
+	mux.HandleFunc("/gold/XAG", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"name":"Silver","price":0}`)
+	})
+	mux.HandleFunc("/vangtoday", func(w http.ResponseWriter, r *http.Request) {
+		fmt.Fprint(w, `{"buy":84000000,"sell":86000000}`)
+	})
+	srv := httptest.NewServer(mux)
+	t.Cleanup(srv.Close)
+
+	urls := map[string]string{
+		"coingecko": srv.URL + "/coingecko",
+		"coinbase":  srv.URL + "/coinbase",
+		"erapi":     srv.URL + "/erapi",
+		"goldapi":   srv.URL + "/gold/",
+		"vangtoday": srv.URL + "/vangtoday",
+	}
+	var providers []service.RateProvider
+	for name, url := range urls {
+		p, err := service.NewRateProvider(name, url)
+		require.NoError(t, err)
+		providers = append(providers, p)
+	}
+	reg, err := service.NewRateRegistry(providers, map[string][]string{
+		service.AssetUSD: {"erapi"},
+		service.AssetEUR: {"erapi"},
+		service.AssetXAU: {"goldapi"},
+		service.AssetXAG: {"goldapi"},
+		service.AssetSJC: {"vangtoday"},
+		service.AssetBTC: {"coingecko", "coinbase"},
+	})
+	require.NoError(t, err)
+
+	quotes, err := reg.Fetch(context.Background())
+	assert.ErrorContains(t, err, service.AssetXAG, "giá bạc 0 là lỗi")
+	assert.Equal(t, map[string]service.Quote{
+		service.AssetUSD: {Price: 26250.5, Source: "erapi"},
+		service.AssetEUR: {Price: 52501, Source: "erapi"},
+		service.AssetXAU: {Price: 2650.1, Source: "goldapi"},
+		service.AssetSJC: {Price: 8600000, Bid: 8400000, Ask: 8600000, Source: "vangtoday"},
+		service.AssetBTC: {Price: 2612345678.5, Source: "coinbase"},
+	}, quotes)
+}
@@ -0,0 +1,98 @@
+package tests
+
+import (
+	"context"
+	"go-finance/internal/model"
+	"go-finance/internal/service"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	loc, err := time.LoadLocation("Asia/Ho_Chi_Minh")
+	require.NoError(t, err)
+	at := func(y int, m time.Month, d, h int) time.Time { return time.Date(y, m, d, h, 0, 0, 0, loc) }
+	rule := func(freq string, day int) model.RecurringRule {
+		return model.RecurringRule{Recurrence: model.Recurrence{Frequency: freq, Day: day}, Timezone: "Asia/Ho_Chi_Minh"}
+	}
+
+	tests := []struct {
+		name  string
+		rule  model.RecurringRule
+		after time.Time
+		want  time.Time
+	}{
+		{"Hàng ngày", rule("daily", 0), at(2025, 5, 14, 15), at(2025, 5, 15, 0)},
+		{"Hàng ngày đúng 0h thì sang hôm sau", rule("daily", 0), at(2025, 5, 14, 0), at(2025, 5, 15, 0)},
+		{"Hàng tuần thứ 2, hôm nay thứ 4", rule("weekly", 1), at(2025, 5, 14, 15), at(2025, 5, 19, 0)},
+		{"Hàng tuần thứ 5, hôm nay thứ 4", rule("weekly", 4), at(2025, 5, 14, 15), at(2025, 5, 15, 0)},
+		{"Hàng tuần chủ nhật", rule("weekly", 7), at(2025, 5, 14, 15), at(2025, 5, 18, 0)},
+		{"Hàng tháng ngày 1", rule("monthly", 1), at(2025, 5, 14, 15), at(2025, 6, 1, 0)},
+		{"Hàng tháng ngày 20", rule("monthly", 20), at(2025, 5, 14, 15), at(2025, 5, 20, 0)},
+		{"Ngày 31 ở tháng 2 là ngày cuối tháng", rule("monthly", 31), at(2025, 1, 31, 0), at(2025, 2, 28, 0)},
+		{"Sau ngày cuối tháng 2 quay về ngày 31", rule("monthly", 31), at(2025, 2, 28, 0), at(2025, 3, 31, 0)},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := service.NextOccurrence(tt.rule, tt.after)
+			assert.True(t, tt.want.Equal(got), "want %v, got %v", tt.want, got)
+		})
+	}
+}
+
+func TestExtractRecurrence(t *testing.T) {
+	tests := []struct {
+		note     string
+		wantNote string
+		want     *model.Recurrence
+	}{
+		{"tiền nhà hàng tháng ngày 1", "tiền nhà", &model.Recurrence{Frequency: "monthly", Day: 1}},
+		{"internet mỗi tháng", "internet", &model.Recurrence{Frequency: "monthly"}},
+		{"học bơi hàng tuần thứ 7", "học bơi", &model.Recurrence{Frequency: "weekly", Day: 6}},
+		{"đi chợ hàng tuần CN", "đi chợ", &model.Recurrence{Frequency: "weekly", Day: 7}},
+		{"gửi xe hàng ngày", "gửi xe", &model.Recurrence{Frequency: "daily"}},
+		{"ăn nhà hàng", "ăn nhà hàng", nil},
+		{"tiền nhà hàng tháng ngày 32", "tiền nhà hàng tháng ngày 32", nil},
+		{"gửi xe hàng ngày thứ 2", "gửi xe hàng ngày thứ 2", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.note, func(t *testing.T) {
+			note, rec := service.ExtractRecurrence(tt.note)
+			assert.Equal(t, tt.wantNote, note)
+			assert.Equal(t, tt.want, rec)
+		})
+	}
+}
+
+// countingMaterializer đếm số lần worker kiểm tra quy tắc đến hạn
+type countingMaterializer struct{ calls atomic.Int32 }
+
+func (m *countingMaterializer) MaterializeDue(time.Time, func(model.RecurringRule, time.Time) time.Time) (int, error) {
+	m.calls.Add(1)
+	return 0, nil
+}
+
+func TestStartRecurringWorkerStopsOnCancel(t *testing.T) {
+	m := &countingMaterializer{}
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		service.StartRecurringWorker(ctx, m)
+		close(done)
+	}()
+
+	require.Eventually(t, func() bool { return m.calls.Load() > 0 }, time.Second, 5*time.Millisecond)
+	cancel()
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("StartRecurringWorker không dừng sau khi ctx bị hủy")
+	}
+	assert.Equal(t, int32(1), m.calls.Load(), "chạy ngay 1 lần khi khởi động")
+}
@@ -0,0 +1,583 @@
+package tests
+
+import (
+	"database/sql"
+	"fmt"
+	"go-finance/internal/model"
+	"go-finance/internal/store"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/lib/pq"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// Bộ test conformance: mọi implementation của store.Store
+// phải cho ra cùng một kết quả. Postgres chỉ chạy khi có TEST_DATABASE_URL.
+
+func TestMemoryStoreConformance(t *testing.T) {
+	runStoreConformance(t, func(t *testing.T) store.Store {
+		return store.NewMemoryStore()
+	}, nil)
+}
+
+func TestPostgresStoreConformance(t *testing.T) {
+	connStr := os.Getenv("TEST_DATABASE_URL")
+	if connStr == "" {
+		t.Skip("TEST_DATABASE_URL không được thiết lập, bỏ qua test Postgres")
+	}
+	db, err := sql.Open("postgres", connStr)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	runStoreConformance(t, func(t *testing.T) store.Store {
+		s := store.NewPostgresStore(db)
+		require.NoError(t, s.InitSchema())
+		return s
+	}, func(t *testing.T, rateIDs []int) {
+		// Bảng market_rates dùng chung, không theo user: xóa bản ghi giá (và giao dịch gắn với nó) mà test đã tạo
+		ids := pq.Array(rateIDs)
+		_, err := db.Exec(`DELETE FROM transactions WHERE rate_snapshot_id = ANY($1)`, ids)
+		assert.NoError(t, err)
+		_, err = db.Exec(`DELETE FROM market_rates WHERE id = ANY($1)`, ids)
+		assert.NoError(t, err)
+	})
+}
+
+// uniqueUser sinh user_id riêng cho mỗi test để không cần dọn DB giữa các lần chạy
+func uniqueUser(name string) string {
+	return fmt.Sprintf("conf-%s-%d", name, time.Now().UnixNano())
+}
+
+func mustCreate(t *testing.T, s store.TransactionStore, tx model.Transaction) int {
+	t.Helper()
+	id, err := s.Create(tx)
+	require.NoError(t, err)
+	require.NotZero(t, id)
+	return id
+}
+
+// deleteRates dọn các bản ghi giá test đã lưu; nil nếu store không dùng chung giữa các lần chạy
+func runStoreConformance(t *testing.T, newStore func(t *testing.T) store.Store, deleteRates func(t *testing.T, rateIDs []int)) {
+	t.Run("Create rồi GetByPeriod trả về đúng giao dịch", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("create")
+		before := time.Now().Add(-time.Minute)
+
+		mustCreate(t, s, model.Transaction{
+			UserID: user, Type: "chi", Amount: dec("50000"), OriginalAmount: dec("50000"),
+			Note: "ăn sáng", Category: "ăn uống", Currency: "VND",
+		})
+		mustCreate(t, s, model.Transaction{
+			UserID: user, Type: "tiet_kiem", Amount: dec("2540000"), OriginalAmount: dec("100"), Currency: "USD",
+		})
+
+		txs, err := s.GetByPeriod(user, before, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, txs, 2)
+
+		byType := map[string]model.Transaction{}
+		for _, tx := range txs {
+			assert.NotZero(t, tx.ID)
+			assert.Equal(t, user, tx.UserID)
+			assert.False(t, tx.CreatedAt.Before(before))
+			byType[tx.Type] = tx
+		}
+		assertDecEqual(t, "50000", byType["chi"].Amount)
+		assert.Equal(t, "ăn sáng", byType["chi"].Note)
+		assert.Equal(t, "ăn uống", byType["chi"].Category)
+		assertDecEqual(t, "100", byType["tiet_kiem"].OriginalAmount)
+		assert.Equal(t, "USD", byType["tiet_kiem"].Currency)
+	})
+
+	t.Run("Số tiền được lưu chính xác, không sai số float", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("exact")
+		amounts := []string{"0.1", "0.2", "0.00012345", "123456789012.34"}
+		for _, a := range amounts {
+			mustCreate(t, s, model.Transaction{
+				UserID: user, Type: "tiet_kiem", Amount: dec(a), OriginalAmount: dec(a), Currency: "BTC",
+			})
+		}
+
+		txs, err := s.GetByPeriod(user, time.Now().Add(-time.Minute), time.Time{})
+		require.NoError(t, err)
+		require.Len(t, txs, len(amounts))
+
+		got := map[string]bool{}
+		for _, tx := range txs {
+			got[tx.OriginalAmount.String()] = true
+		}
+		for _, a := range amounts {
+			assert.True(t, got[a], "thiếu số tiền %s", a)
+		}
+	})
+
+	t.Run("Chi không có category được gán 'khác'", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("category")
+		mustCreate(t, s, model.Transaction{UserID: user, Type: "chi", Amount: dec("1000"), Note: "x", Currency: "VND"})
+
+		txs, err := s.GetByPeriod(user, time.Now().Add(-time.Minute), time.Time{})
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+		assert.Equal(t, "khác", txs[0].Category)
+	})
+
+	t.Run("Currency rỗng được đọc ra là VND", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("currency")
+		mustCreate(t, s, model.Transaction{UserID: user, Type: "thu", Amount: dec("1000"), Note: "x"})
+
+		txs, err := s.GetByPeriod(user, time.Now().Add(-time.Minute), time.Time{})
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+		assert.Equal(t, "VND", txs[0].Currency)
+	})
+
+	t.Run("GetByPeriod lọc theo user và khoảng thời gian", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("filter")
+		other := uniqueUser("filter-other")
+		mustCreate(t, s, model.Transaction{UserID: user, Type: "thu", Amount: dec("1"), Note: "a", Currency: "VND"})
+		mustCreate(t, s, model.Transaction{UserID: other, Type: "thu", Amount: dec("2"), Note: "b", Currency: "VND"})
+
+		txs, err := s.GetByPeriod(user, time.Now().Add(-time.Minute), time.Time{})
+		require.NoError(t, err)
+		require.Len(t, txs, 1)
+		assert.Equal(t, "a", txs[0].Note)
+
+		txs, err = s.GetByPeriod(user, time.Now().Add(time.Hour), time.Time{})
+		require.NoError(t, err)
+		assert.Empty(t, txs)
+
+		txs, err = s.GetByPeriod(user, time.Now().Add(-time.Hour), time.Now().Add(-time.Minute))
+		require.NoError(t, err)
+		assert.Empty(t, txs)
+
+		txs, err = s.GetByPeriod(user, time.Now().Add(-time.Hour), time.Now().Add(time.Minute))
+		require.NoError(t, err)
+		assert.Len(t, txs, 1)
+	})
+
+	t.Run("GetAllUserIDs trả về user duy nhất", func(t *testing.T) {
+		s := newStore(t)
+		u1 := uniqueUser("u1")
+		u2 := uniqueUser("u2")
+		for _, u := range []string{u1, u1, u2} {
+			mustCreate(t, s, model.Transaction{UserID: u, Type: "thu", Amount: dec("1"), Note: "x", Currency: "VND"})
+		}
+
+		ids, err := s.GetAllUserIDs()
+		require.NoError(t, err)
+		count := map[string]int{}
+		for _, id := range ids {
+			count[id]++
+		}
+		assert.Equal(t, 1, count[u1])
+		assert.Equal(t, 1, count[u2])
+	})
+
+	t.Run("GetByID trả về đúng giao dịch, ErrNotFound nếu không có", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("getbyid")
+		id := mustCreate(t, s, model.Transaction{UserID: user, Type: "chi", Amount: dec("20000"), OriginalAmount: dec("20000"), Note: "trà đá", Currency: "VND"})
+
+		tx, err := s.GetByID(id)
+		require.NoError(t, err)
+		assert.Equal(t, id, tx.ID)
+		assert.Equal(t, user, tx.UserID)
+		assert.Equal(t, "trà đá", tx.Note)
+		assertDecEqual(t, "20000", tx.Amount)
+
+		_, err = s.GetByID(id + 1000000)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("Update sửa các trường, giữ nguyên user và thời điểm tạo", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("update")
+		id := mustCreate(t, s, model.Transaction{UserID: user, Type: "chi", Amount: dec("500000"), OriginalAmount: dec("500000"), Note: "cafe", Category: "ăn uống", Currency: "VND", AmountExpr: "2x250k"})
+		before, err := s.GetByID(id)
+		require.NoError(t, err)
+		assert.Equal(t, "2x250k", before.AmountExpr)
+
+		err = s.Update(model.Transaction{ID: id, Type: "chi", Amount: dec("50000"), OriginalAmount: dec("50000"), Note: "cafe sữa", Currency: "VND"})
+		require.NoError(t, err)
+
+		after, err := s.GetByID(id)
+		require.NoError(t, err)
+		assertDecEqual(t, "50000", after.Amount)
+		assert.Equal(t, "cafe sữa", after.Note)
+		assert.Equal(t, "khác", after.Category)
+		assert.Empty(t, after.AmountExpr)
+		assert.Equal(t, user, after.UserID)
+		assert.True(t, before.CreatedAt.Equal(after.CreatedAt))
+
+		err = s.Update(model.Transaction{ID: id + 1000000, Type: "chi"})
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+
+	t.Run("Delete xóa giao dịch, xóa lần 2 trả về ErrNotFound", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("delete")
+		id := mustCreate(t, s, model.Transaction{UserID: user, Type: "thu", Amount: dec("1"), Note: "x", Currency: "VND"})
+
+		require.NoError(t, s.Delete(id))
+		_, err := s.GetByID(id)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		assert.ErrorIs(t, s.Delete(id), store.ErrNotFound)
+	})
+
+	t.Run("GetLatest trả về giao dịch ghi sau cùng của user", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("latest")
+		_, err := s.GetLatest(user)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		mustCreate(t, s, model.Transaction{UserID: user, Type: "thu", Amount: dec("1"), Note: "first", Currency: "VND"})
+		last := mustCreate(t, s, model.Transaction{UserID: user, Type: "thu", Amount: dec("2"), Note: "second", Currency: "VND"})
+		mustCreate(t, s, model.Transaction{UserID: uniqueUser("latest-other"), Type: "thu", Amount: dec("3"), Note: "other", Currency: "VND"})
+
+		tx, err := s.GetLatest(user)
+		require.NoError(t, err)
+		assert.Equal(t, last, tx.ID)
+	})
+
+	t.Run("List lọc theo type, category, currency, số tiền và từ khóa", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("list-filter")
+		mustCreate(t, s, model.Transaction{UserID: user, Type: "chi", Amount: dec("50000"), OriginalAmount: dec("50000"), Note: "cafe sáng", Category: "ăn uống", Currency: "VND"})
+		mustCreate(t, s, model.Transaction{UserID: user, Type: "chi", Amount: dec("300000"), OriginalAmount: dec("300000"), Note: "đi massage", Category: "hưởng thụ", Currency: "VND"})
+		mustCreate(t, s, model.Transaction{UserID: user, Type: "thu", Amount: dec("10000000"), OriginalAmount: dec("10000000"), Note: "lương", Currency: "VND"})
+		mustCreate(t, s, model.Transaction{UserID: user, Type: "tiet_kiem", Amount: dec("2540000"), OriginalAmount: dec("100"), Currency: "USD"})
+		mustCreate(t, s, model.Transaction{UserID: uniqueUser("list-other"), Type: "chi", Amount: dec("1"), Note: "cafe", Currency: "VND"})
+
+		notes := func(f model.TransactionFilter) []string {
+			f.UserID = user
+			page, err := s.List(f)
+			require.NoError(t, err)
+			var out []string
+			for _, tx := range page.Items {
+				out = append(out, tx.Note)
+			}
+			return out
+		}
+		minAmt, maxAmt := dec("100000"), dec("5000000")
+
+		assert.Len(t, notes(model.TransactionFilter{}), 4)
+		assert.ElementsMatch(t, []string{"cafe sáng", "đi massage"}, notes(model.TransactionFilter{Type: "chi"}))
+		assert.Equal(t, []string{"đi massage"}, notes(model.TransactionFilter{Category: "hưởng thụ"}))
+		assert.Equal(t, []string{""}, notes(model.TransactionFilter{Currency: "USD"}))
+		assert.ElementsMatch(t, []string{"đi massage", ""}, notes(model.TransactionFilter{MinAmount: &minAmt, MaxAmount: &maxAmt}))
+		assert.Equal(t, []string{"cafe sáng"}, notes(model.TransactionFilter{Search: "CAFE"}))
+		assert.Empty(t, notes(model.TransactionFilter{Search: "cafe tối"}))
+		assert.Empty(t, notes(model.TransactionFilter{From: time.Now().Add(time.Hour)}))
+		assert.Empty(t, notes(model.TransactionFilter{To: time.Now().Add(-time.Hour)}))
+	})
+
+	t.Run("List phân trang bằng cursor theo cả hai chiều", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("list-page")
+		var ids []int
+		for i := 0; i < 7; i++ {
+			ids = append(ids, mustCreate(t, s, model.Transaction{UserID: user, Type: "thu", Amount: dec("1"), Note: fmt.Sprint(i), Currency: "VND"}))
+		}
+
+		collect := func(asc bool) []int {
+			var got []int
+			f := model.TransactionFilter{UserID: user, Limit: 3, Ascending: asc}
+			for {
+				page, err := s.List(f)
+				require.NoError(t, err)
+				assert.LessOrEqual(t, len(page.Items), 3)
+				for _, tx := range page.Items {
+					got = append(got, tx.ID)
+				}
+				if page.NextCursor == "" {
+					return got
+				}
+				f.Cursor = page.NextCursor
+			}
+		}
+
+		assert.Equal(t, ids, collect(true))
+		desc := collect(false)
+		require.Len(t, desc, len(ids))
+		for i := range ids {
+			assert.Equal(t, ids[len(ids)-1-i], desc[i])
+		}
+
+		_, err := s.List(model.TransactionFilter{UserID: user, Cursor: "không-hợp-lệ"})
+		assert.ErrorIs(t, err, store.ErrInvalidCursor)
+	})
+
+	t.Run("Settings mặc định, lưu và ghi đè", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("settings")
+
+		st, err := s.GetSettings(user)
+		require.NoError(t, err)
+		assert.Equal(t, model.DefaultUserSettings(user), st)
+
+		require.NoError(t, s.SaveSettings(model.UserSettings{UserID: user, Timezone: "Europe/Berlin", WeekStart: time.Sunday}))
+		require.NoError(t, s.SaveSettings(model.UserSettings{UserID: user, Timezone: "Asia/Tokyo", WeekStart: time.Sunday}))
+
+		st, err = s.GetSettings(user)
+		require.NoError(t, err)
+		assert.Equal(t, "Asia/Tokyo", st.Timezone)
+		assert.Equal(t, time.Sunday, st.WeekStart)
+
+		list, err := s.ListSettings()
+		require.NoError(t, err)
+		count := 0
+		for _, item := range list {
+			if item.UserID == user {
+				count++
+				assert.Equal(t, st, item)
+			}
+		}
+		assert.Equal(t, 1, count)
+	})
+
+	t.Run("Budget upsert, sửa, trùng và xóa", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("budget")
+
+		first, err := s.SaveBudget(model.Budget{UserID: user, Category: "ăn uống", Period: "month", Limit: dec("3000000")})
+		require.NoError(t, err)
+		assert.NotZero(t, first.ID)
+
+		// Cùng (category, period) thì ghi đè hạn mức, không tạo bản mới
+		again, err := s.SaveBudget(model.Budget{UserID: user, Category: "ăn uống", Period: "month", Limit: dec("2500000.5")})
+		require.NoError(t, err)
+		assert.Equal(t, first.ID, again.ID)
+		assertDecEqual(t, "2500000.5", again.Limit)
+
+		weekly, err := s.SaveBudget(model.Budget{UserID: user, Category: "ăn uống", Period: "week", Limit: dec("700000")})
+		require.NoError(t, err)
+
+		list, err := s.ListBudgets(user)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+
+		weekly.Period = "month"
+		assert.ErrorIs(t, s.UpdateBudget(weekly), store.ErrConflict)
+		weekly.Category = "sinh hoạt"
+		require.NoError(t, s.UpdateBudget(weekly))
+		got, err := s.GetBudget(weekly.ID)
+		require.NoError(t, err)
+		assert.Equal(t, "sinh hoạt", got.Category)
+		assert.Equal(t, "month", got.Period)
+
+		require.NoError(t, s.DeleteBudget(first.ID))
+		assert.ErrorIs(t, s.DeleteBudget(first.ID), store.ErrNotFound)
+		_, err = s.GetBudget(first.ID)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+		assert.ErrorIs(t, s.UpdateBudget(first), store.ErrNotFound)
+	})
+
+	t.Run("MaterializeDue tạo bù và không tạo trùng", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("recurring")
+		// Mọi store chạy chung DB nên chỉ xét tới "now" giả lập để quy tắc của test khác không ảnh hưởng
+		now := time.Now().Add(-24 * time.Hour).Truncate(time.Second)
+		daily := func(r model.RecurringRule, after time.Time) time.Time { return after.Add(24 * time.Hour) }
+
+		activeID, err := s.CreateRecurring(model.RecurringRule{UserID: user, Type: "chi", Amount: dec("5000000"), Note: "tiền nhà",
+			Recurrence: model.Recurrence{Frequency: "daily"}, Timezone: "UTC", NextRun: now.Add(-50 * time.Hour)})
+		require.NoError(t, err)
+		_, err = s.CreateRecurring(model.RecurringRule{UserID: user, Type: "thu", Amount: dec("1"), Note: "tạm dừng",
+			Recurrence: model.Recurrence{Frequency: "daily"}, Timezone: "UTC", Paused: true, NextRun: now.Add(-50 * time.Hour)})
+		require.NoError(t, err)
+
+		_, err = s.MaterializeDue(now, daily)
+		require.NoError(t, err)
+		n, err := s.MaterializeDue(now, daily)
+		require.NoError(t, err)
+		assert.Zero(t, n, "chạy lại không được tạo thêm")
+
+		txs, err := s.GetByPeriod(user, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		require.Len(t, txs, 3, "-50h, -26h, -2h")
+		for _, tx := range txs {
+			assert.Equal(t, "tiền nhà", tx.Note)
+			assert.Equal(t, "khác", tx.Category)
+			assertDecEqual(t, "5000000", tx.Amount)
+			assert.True(t, !tx.CreatedAt.After(now), "giao dịch mang thời điểm đến hạn, không phải lúc chạy")
+		}
+
+		rule, err := s.GetRecurring(activeID)
+		require.NoError(t, err)
+		assert.True(t, rule.NextRun.After(now))
+
+		// Xóa quy tắc không xóa giao dịch đã tạo
+		require.NoError(t, s.DeleteRecurring(activeID))
+		assert.ErrorIs(t, s.DeleteRecurring(activeID), store.ErrNotFound)
+		txs, err = s.GetByPeriod(user, time.Time{}, time.Time{})
+		require.NoError(t, err)
+		assert.Len(t, txs, 3)
+
+		rules, err := s.ListRecurring(user)
+		require.NoError(t, err)
+		require.Len(t, rules, 1)
+		assert.True(t, rules[0].Paused)
+	})
+
+	t.Run("Account trùng tên, gắn giao dịch và không xóa được khi còn giao dịch", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("account")
+
+		momoID, err := s.CreateAccount(model.Account{UserID: user, Name: "momo", Currency: "VND", OpeningBalance: dec("500000")})
+		require.NoError(t, err)
+		_, err = s.CreateAccount(model.Account{UserID: user, Name: "momo", Currency: "VND"})
+		assert.ErrorIs(t, err, store.ErrConflict)
+		_, err = s.CreateAccount(model.Account{UserID: uniqueUser("account-other"), Name: "momo", Currency: "VND"})
+		assert.NoError(t, err, "tên chỉ cần duy nhất trong phạm vi 1 user")
+		vcbID, err := s.CreateAccount(model.Account{UserID: user, Name: "vcb", Currency: "VND"})
+		require.NoError(t, err)
+
+		byName, err := s.GetAccountByName(user, "momo")
+		require.NoError(t, err)
+		assert.Equal(t, momoID, byName.ID)
+		assertDecEqual(t, "500000", byName.OpeningBalance)
+		_, err = s.GetAccountByName(user, "zalopay")
+		assert.ErrorIs(t, err, store.ErrNotFound)
+
+		txID := mustCreate(t, s, model.Transaction{UserID: user, Type: "chuyen", Amount: dec("200000"), OriginalAmount: dec("200000"),
+			Currency: "VND", AccountID: &vcbID, ToAccountID: &momoID})
+		got, err := s.GetByID(txID)
+		require.NoError(t, err)
+		require.NotNil(t, got.AccountID)
+		require.NotNil(t, got.ToAccountID)
+		assert.Equal(t, vcbID, *got.AccountID)
+		assert.Equal(t, momoID, *got.ToAccountID)
+		assert.Empty(t, got.Category, "chuyen không tự gán danh mục")
+
+		byName.Name = "vcb"
+		assert.ErrorIs(t, s.UpdateAccount(byName), store.ErrConflict)
+		assert.ErrorIs(t, s.DeleteAccount(momoID), store.ErrConflict)
+
+		got.ToAccountID = nil
+		got.Type = "chi"
+		require.NoError(t, s.Update(got))
+		require.NoError(t, s.DeleteAccount(momoID))
+		assert.ErrorIs(t, s.DeleteAccount(momoID), store.ErrNotFound)
+
+		list, err := s.ListAccounts(user)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		assert.Equal(t, "vcb", list[0].Name)
+	})
+
+	t.Run("Lịch sử giá lưu, tìm theo thời điểm và gắn vào giao dịch", func(t *testing.T) {
+		s := newStore(t)
+		// Bảng market_rates dùng chung: lấy mốc ở tương lai xa để bản ghi của lần chạy này luôn là mới nhất
+		base := time.Now().AddDate(100, 0, 0).Truncate(time.Second)
+		ids := make([]int, 3)
+		if deleteRates != nil {
+			t.Cleanup(func() { deleteRates(t, ids) })
+		}
+		for i, sjc := range []float64{8000000, 8100000, 8200000} {
+			var err error
+			// Lưu không theo thứ tự thời gian
+			ids[i], err = s.SaveRates(base.Add(time.Duration(2-i)*time.Hour), model.ExchangeRates{UsdVND: 25400, VnSJC: sjc})
+			require.NoError(t, err)
+		}
+
+		latest, err := s.LatestRates()
+		require.NoError(t, err)
+		assert.Equal(t, ids[0], latest.ID)
+		assert.Equal(t, 8000000.0, latest.Rates.VnSJC)
+
+		at, err := s.RatesAt(base.Add(90 * time.Minute))
+		require.NoError(t, err)
+		assert.Equal(t, ids[1], at.ID)
+		assert.True(t, base.Add(time.Hour).Equal(at.FetchedAt))
+
+		list, err := s.ListRates(base, base.Add(2*time.Hour), 10)
+		require.NoError(t, err)
+		require.Len(t, list, 2)
+		assert.Equal(t, ids[2], list[0].ID)
+		assert.Equal(t, ids[1], list[1].ID)
+		list, err = s.ListRates(base, time.Time{}, 2)
+		require.NoError(t, err)
+		require.Len(t, list, 2, "chỉ lấy tối đa limit bản ghi, cũ trước")
+		assert.Equal(t, ids[2], list[0].ID)
+
+		user := uniqueUser("rates")
+		txID := mustCreate(t, s, model.Transaction{UserID: user, Type: "tiet_kiem", Amount: dec("16200000"), OriginalAmount: dec("2"),
+			Currency: "GOLD", RateSnapshotID: &ids[1]})
+		got, err := s.GetByID(txID)
+		require.NoError(t, err)
+		require.NotNil(t, got.RateSnapshotID)
+		assert.Equal(t, ids[1], *got.RateSnapshotID)
+	})
+	t.Run("Cảnh báo giá lưu trạng thái và thông báo chờ gửi", func(t *testing.T) {
+		s := newStore(t)
+		user := uniqueUser("alert")
+		id, err := s.CreateAlert(model.PriceAlert{UserID: user, Asset: "GOLD", Condition: model.AlertAbove,
+			Threshold: dec("90000000"), Unit: "luong", Active: true})
+		require.NoError(t, err)
+		require.NotZero(t, id)
+
+		// Bảng cảnh báo dùng chung: chỉ kích hoạt cảnh báo của lần chạy này
+		triggered := time.Now().Truncate(time.Second)
+		n, err := s.CheckAlerts(func(a model.PriceAlert) (model.PriceAlert, *model.AlertNotification) {
+			if a.ID != id {
+				return a, nil
+			}
+			a.Active = false
+			a.LastPrice = dec("91000000")
+			a.LastTriggeredAt = &triggered
+			return a, &model.AlertNotification{AlertID: a.ID, UserID: a.UserID, Asset: a.Asset, Condition: a.Condition,
+				Threshold: a.Threshold, Unit: a.Unit, Price: a.LastPrice}
+		})
+		require.NoError(t, err)
+		assert.GreaterOrEqual(t, n, 1)
+
+		got, err := s.GetAlert(id)
+		require.NoError(t, err)
+		assert.False(t, got.Active)
+		assertDecEqual(t, "91000000", got.LastPrice)
+		require.NotNil(t, got.LastTriggeredAt)
+		assert.True(t, triggered.Equal(*got.LastTriggeredAt))
+
+		// Cảnh báo đã tắt không được kiểm tra lại
+		_, err = s.CheckAlerts(func(a model.PriceAlert) (model.PriceAlert, *model.AlertNotification) {
+			assert.NotEqual(t, id, a.ID)
+			return a, nil
+		})
+		require.NoError(t, err)
+
+		pending, err := s.PendingNotifications(store.MaxPageSize)
+		require.NoError(t, err)
+		var mine []model.AlertNotification
+		for _, p := range pending {
+			if p.UserID == user {
+				mine = append(mine, p)
+			}
+		}
+		require.Len(t, mine, 1)
+		assert.Equal(t, id, mine[0].AlertID)
+		assertDecEqual(t, "91000000", mine[0].Price)
+
+		require.NoError(t, s.MarkNotificationSent(mine[0].ID, time.Now()))
+		pending, err = s.PendingNotifications(store.MaxPageSize)
+		require.NoError(t, err)
+		for _, p := range pending {
+			assert.NotEqual(t, mine[0].ID, p.ID)
+		}
+		assert.ErrorIs(t, s.MarkNotificationSent(-1, time.Now()), store.ErrNotFound)
+
+		list, err := s.ListAlerts(user)
+		require.NoError(t, err)
+		require.Len(t, list, 1)
+		require.NoError(t, s.DeleteAlert(id))
+		assert.ErrorIs(t, s.DeleteAlert(id), store.ErrNotFound)
+		_, err = s.GetAlert(id)
+		assert.ErrorIs(t, err, store.ErrNotFound)
+	})
+}